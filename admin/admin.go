@@ -6,27 +6,57 @@
 package admin
 
 import (
-	"log"
+	"crypto/subtle"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/mats93/paragliding/logging"
 	"github.com/mats93/paragliding/mongodb"
 )
 
 // Collection is the MongoDB collection to use. Gets injected from main or test.
 var Collection string
 
+// Token is the admin token, given as 'Authorization: Bearer <token>'. Gets injected from main or test.
+// No requests are admin requests if it is empty.
+var Token string
+
+// IsAdmin checks if the request has the admin token.
+func IsAdmin(r *http.Request) bool {
+	given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if Token == "" || given == "" {
+		return false
+	}
+	// Compares in constant time, so the token can not be guessed from the response time.
+	return subtle.ConstantTimeCompare([]byte(given), []byte(Token)) == 1
+}
+
+// Only lets the requests with the admin token through to the handler, the others get 401 (Unauthorized).
+// Used for the routes of the admin API.
+func Only(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(r) {
+			// Sets the header code to 401 (Unauthorized), and returns error message.
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("Error: The admin API needs the admin token, with the 'Authorization: Bearer <token>' header"))
+			return
+		}
+		handler(w, r)
+	}
+}
+
 // GetTrackCount - GET: Returns the current count of all tracks in the DB.
 // Output: text/plain
 func GetTrackCount(w http.ResponseWriter, r *http.Request) {
 	// Connects to the database.
-	database := mongodb.DatabaseInit(Collection)
+	database := mongodb.DatabaseContext(r.Context(), Collection)
 
 	count, err := database.GetCount()
 	if err != nil {
 		// Sets header status code to 500 "Internal server error" and logs the error.
 		w.WriteHeader(http.StatusInternalServerError)
-		log.Fatal(err)
+		logging.FromContext(r.Context()).Error(err.Error())
 	} else {
 		// Sets header content-type to text/plain and status code to 200 (OK).
 		w.Header().Set("Content-Type", "text/plain")
@@ -50,21 +80,21 @@ func DeleteAllTracks(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 	} else {
 		// Connects to the database.
-		database := mongodb.DatabaseInit(Collection)
+		database := mongodb.DatabaseContext(r.Context(), Collection)
 
 		// Gets the current count of the database.
 		count, err := database.GetCount()
 		if err != nil {
 			// Sets header status code to 500 "Internal server error" and logs the error.
 			w.WriteHeader(http.StatusInternalServerError)
-			log.Fatal(err)
+			logging.FromContext(r.Context()).Error(err.Error())
 		} else {
 			// Deletes all tracks from the database.
 			err := database.DeleteAll()
 			if err != nil {
 				// Sets header status code to 500 "Internal server error" and logs the error.
 				w.WriteHeader(http.StatusInternalServerError)
-				log.Fatal(err)
+				logging.FromContext(r.Context()).Error(err.Error())
 			} else {
 				// Sets header content-type to text/plain and status code to 200 (OK).
 				w.Header().Set("Content-Type", "text/plain")
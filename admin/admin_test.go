@@ -103,11 +103,11 @@ func Test_DeleteAllTracks(t *testing.T) {
 
 	// Connets to the DB and fills it with 5 tracks.
 	database := mongodb.DatabaseInit(Collection)
-	database.Insert(mongodb.Track{1, 11, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.test"})
-	database.Insert(mongodb.Track{2, 12, time.Now(), "pilot2", "glider2", "glider_id2", 20.2, "http://test2.test"})
-	database.Insert(mongodb.Track{3, 13, time.Now(), "pilot3", "glider3", "glider_id3", 20.3, "http://test3.test"})
-	database.Insert(mongodb.Track{4, 14, time.Now(), "pilot4", "glider4", "glider_id4", 20.4, "http://test4.test"})
-	database.Insert(mongodb.Track{5, 15, time.Now(), "pilot5", "glider5", "glider_id5", 20.5, "http://test5.test"})
+	database.Insert(mongodb.Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.test"})
+	database.Insert(mongodb.Track{ID: 2, Timestamp: 12, HDate: time.Now(), Pilot: "pilot2", Glider: "glider2", GliderID: "glider_id2", TrackLength: 20.2, TrackSrcURL: "http://test2.test"})
+	database.Insert(mongodb.Track{ID: 3, Timestamp: 13, HDate: time.Now(), Pilot: "pilot3", Glider: "glider3", GliderID: "glider_id3", TrackLength: 20.3, TrackSrcURL: "http://test3.test"})
+	database.Insert(mongodb.Track{ID: 4, Timestamp: 14, HDate: time.Now(), Pilot: "pilot4", Glider: "glider4", GliderID: "glider_id4", TrackLength: 20.4, TrackSrcURL: "http://test4.test"})
+	database.Insert(mongodb.Track{ID: 5, Timestamp: 15, HDate: time.Now(), Pilot: "pilot5", Glider: "glider5", GliderID: "glider_id5", TrackLength: 20.5, TrackSrcURL: "http://test5.test"})
 
 	// Expected return for the function is 5, because 5 tracks where deletet (all).
 	expected := "5"
@@ -152,3 +152,51 @@ func Test_DeleteAllTracks(t *testing.T) {
 			actualCount, expectedCount)
 	}
 }
+
+// Function to test: IsAdmin().
+// Test that only requests with the admin token are admin requests.
+func Test_IsAdmin(t *testing.T) {
+	request, _ := http.NewRequest("GET", "/paragliding/api/track/1", nil)
+
+	// No token is configured, nobody is admin.
+	Token = ""
+	request.Header.Set("Authorization", "Bearer ")
+	if IsAdmin(request) {
+		t.Error("Function returned true when no admin token is configured")
+	}
+
+	Token = "secret"
+	defer func() { Token = "" }()
+	request.Header.Set("Authorization", "Bearer wrong")
+	if IsAdmin(request) {
+		t.Error("Function returned true for a wrong token")
+	}
+	request.Header.Set("Authorization", "Bearer secret")
+	if !IsAdmin(request) {
+		t.Error("Function returned false for the admin token")
+	}
+}
+
+// Function to test: Only().
+// Test that only the requests with the admin token get through to the handler.
+func Test_Only(t *testing.T) {
+	Token = "secret"
+	defer func() { Token = "" }()
+	handler := Only(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	request, _ := http.NewRequest("DELETE", "/paragliding/admin/api/tracks", nil)
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Handler returned wrong status code: got %v want %v", recorder.Code, http.StatusUnauthorized)
+	}
+
+	request.Header.Set("Authorization", "Bearer secret")
+	recorder = httptest.NewRecorder()
+	handler(recorder, request)
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("Handler returned wrong status code: got %v want %v", recorder.Code, http.StatusNoContent)
+	}
+}
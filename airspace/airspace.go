@@ -0,0 +1,123 @@
+/*
+	File: airspace.go
+  Contains functions used by API calls to the "Airspace paths", and loading of the airspace file.
+*/
+
+package airspace
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	igc "github.com/marni/goigc"
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/user"
+	"github.com/mats93/paragliding/webhook"
+)
+
+// Format for the airspace check of a track.
+type trackAirspace struct {
+	TrackID       int            `json:"track_id"`
+	Infringements []Infringement `json:"infringements"`
+}
+
+// Airspaces are the loaded airspaces, nil if no airspace file is loaded.
+var Airspaces []Airspace
+
+// Collection is the MongoDB collection to use for tracks. Gets injected from main or test.
+var Collection string
+
+// Load reads and parses an OpenAir file, and uses its airspaces. The airspaces that can not be parsed are
+// logged and skipped.
+func Load(path string) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	airspaces, skipped := Parse(string(content))
+	for i := 0; i < len(skipped); i++ {
+		log.Printf("%s: %v, the airspace is skipped", path, skipped[i])
+	}
+	if airspaces == nil {
+		airspaces = []Airspace{}
+	}
+	Airspaces = airspaces
+	return nil
+}
+
+// CheckTrack checks the fixes of a new track against the loaded airspaces, the result is stored with the track.
+// It is nil if no airspace file is loaded.
+func CheckTrack(date time.Time, points []igc.Point) *mongodb.Airspace {
+	if Airspaces == nil {
+		return nil
+	}
+	infringements := Check(Airspaces, date, points)
+	if infringements == nil {
+		infringements = []Infringement{}
+	}
+	return &mongodb.Airspace{Infringements: infringements}
+}
+
+// NotifyInfringements notifies the webhooks subscribing to airspace events if the new track infringed any
+// airspace when it was checked. The context has the logger of the request or job that added the track.
+func NotifyInfringements(ctx context.Context, trackID int, checked *mongodb.Airspace) {
+	if checked == nil || len(checked.Infringements) == 0 {
+		return
+	}
+	infringements := checked.Infringements
+
+	// Formats the message, with one line for each infringement.
+	lines := []string{fmt.Sprintf("Track id%d infringed airspace %d times:", trackID, len(infringements))}
+	for i := 0; i < len(infringements); i++ {
+		lines = append(lines, fmt.Sprintf("%s (class %s) at %s, %.0f m inside vertically, %.0f m horizontally",
+			infringements[i].Airspace, infringements[i].Class, infringements[i].Start.Format("15:04:05"),
+			infringements[i].VerticalMargin, infringements[i].HorizontalMargin))
+	}
+	webhook.NotifyEvent(ctx, webhook.EventAirspace, strings.Join(lines, "\n"))
+}
+
+// GetTrackAirspace - GET: Returns the airspace infringements of the track with the provided '<id>', as they
+// were found when the track was stored.
+// Output: application/json
+func GetTrackAirspace(w http.ResponseWriter, r *http.Request) {
+	var id int
+	// Gets the ID from the URL and converts it to an integer.
+	fmt.Sscanf(r.URL.Path, "/paragliding/api/track/%d/airspace", &id)
+
+	// Connects to the database, and finds the track.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+	tracks, err := database.FindByID(id)
+	if err != nil || !user.ViewerOf(r).CanView(tracks[0]) {
+		// A track with the given ID does not exist, or is hidden from the request.
+		// Sets the header code to 404 (Not found).
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if tracks[0].Airspace == nil {
+		// The track was stored before the checks, or without an airspace file.
+		// Sets the header code to 404 (Not found), and returns error message.
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Error: The track was not checked against airspaces when it was stored"))
+		return
+	}
+
+	json, err := json.Marshal(trackAirspace{id, tracks[0].Airspace.Infringements})
+	if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+	// Sets header content-type to application/json and status code to 200 (OK).
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(json)
+}
@@ -0,0 +1,105 @@
+/*
+  File: airspace_test.go
+  Contains unit tests for airspace.go
+*/
+
+package airspace
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	igc "github.com/marni/goigc"
+	"github.com/mats93/paragliding/mongodb"
+)
+
+// Function to test: Load().
+// Test that the airspaces of a file are loaded, also when one is invalid, and a missing file returns an error.
+func Test_Load(t *testing.T) {
+	defer func() { Airspaces = nil }()
+
+	dir, _ := ioutil.TempDir("", "airspace")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.txt")
+	// An airspace that can not be parsed is skipped.
+	ioutil.WriteFile(path, []byte("AC D\nAN BROKEN\nAL high\n"+testOpenAir), 0644)
+
+	if err := Load(path); err != nil {
+		t.Fatalf("Function returned unexpected error: %v", err)
+	}
+	if len(Airspaces) != 3 {
+		t.Errorf("Function loaded wrong number of airspaces: got %d want %d", len(Airspaces), 3)
+	}
+
+	if err := Load(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("Function did not return error for a missing file")
+	}
+}
+
+// Function to test: CheckTrack().
+// Test that tracks are not checked when no airspace file is loaded, and a check without infringements is kept.
+func Test_CheckTrack(t *testing.T) {
+	defer func() { Airspaces = nil }()
+	date := time.Date(2018, 10, 20, 0, 0, 0, 0, time.UTC)
+	points := []igc.Point{testPoint(59.9, 6.2, 1500, 0), testPoint(60.1, 6.2, 1500, 60)}
+
+	Airspaces = nil
+	if checked := CheckTrack(date, points); checked != nil {
+		t.Errorf("Function checked the track without airspaces: got %+v", checked)
+	}
+
+	Airspaces = []Airspace{testAirspace}
+	checked := CheckTrack(date, points)
+	if checked == nil || len(checked.Infringements) != 1 {
+		t.Errorf("Function returned wrong check: got %+v", checked)
+	}
+	checked = CheckTrack(date, points[:1])
+	if checked == nil || checked.Infringements == nil || len(checked.Infringements) != 0 {
+		t.Errorf("Function returned wrong check without infringements: got %+v", checked)
+	}
+}
+
+// Function to test: GetTrackAirspace().
+// Test that the infringements stored with a track are returned, and a track without them is not found.
+func Test_GetTrackAirspace(t *testing.T) {
+	// Injects the MongoDB collection to use.
+	Collection = "TestTracks"
+
+	// Connects the the database, and adds a checked track and one that was not checked to the DB.
+	database := mongodb.DatabaseInit(Collection)
+	checked := mongodb.Airspace{Infringements: []Infringement{{Airspace: "TEST", Class: "C", VerticalMargin: 500}}}
+	database.Insert(mongodb.Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", Airspace: &checked})
+	database.Insert(mongodb.Track{ID: 2, Timestamp: 12, HDate: time.Now(), Pilot: "pilot2"})
+
+	// Creates the router.
+	router := mux.NewRouter()
+	router.HandleFunc("/paragliding/api/track/{id:[0-9]+}/airspace", GetTrackAirspace).Methods("GET")
+
+	// The stored infringements are returned, without fetching the IGC file again.
+	request, _ := http.NewRequest("GET", "/paragliding/api/track/1/airspace", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var actual trackAirspace
+	if err := json.Unmarshal(recorder.Body.Bytes(), &actual); err != nil || actual.TrackID != 1 ||
+		len(actual.Infringements) != 1 || actual.Infringements[0].Airspace != "TEST" {
+		t.Errorf("Handler returned wrong data: got %s", recorder.Body.String())
+	}
+
+	request, _ = http.NewRequest("GET", "/paragliding/api/track/2/airspace", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Handler returned wrong status code: got %v want %v", recorder.Code, http.StatusNotFound)
+	}
+
+	// Removes the test data.
+	database.DeleteAll()
+}
@@ -0,0 +1,142 @@
+/*
+	File: check.go
+  Checks the fixes of a track against airspaces.
+*/
+
+package airspace
+
+import (
+	"math"
+	"time"
+
+	igc "github.com/marni/goigc"
+	"github.com/mats93/paragliding/mongodb"
+)
+
+// Infringement is a continuous period a track was inside an airspace, it is stored with the track.
+type Infringement = mongodb.Infringement
+
+// GroundElevation returns the ground elevation in meters at a position, used for AGL limits.
+// If it is nil or returns false, the ground is assumed to be at sea level.
+var GroundElevation func(lat float64, lng float64) (float64, bool)
+
+// Check returns the infringements of the airspaces by the fixes of a track.
+// The date is the flight date, since the fixes only contain the time of day.
+func Check(airspaces []Airspace, date time.Time, points []igc.Point) []Infringement {
+	var infringements []Infringement
+
+	for i := 0; i < len(airspaces); i++ {
+		var current *Infringement
+		for j := 0; j < len(points); j++ {
+			inside, vertical, horizontal, approximate := airspaces[i].contains(points[j])
+			if !inside {
+				current = nil
+				continue
+			}
+
+			at := time.Date(date.Year(), date.Month(), date.Day(), points[j].Time.Hour(),
+				points[j].Time.Minute(), points[j].Time.Second(), 0, time.UTC)
+
+			// Starts a new infringement, or extends the current one.
+			if current == nil {
+				infringements = append(infringements, Infringement{
+					Airspace: airspaces[i].Name,
+					Class:    airspaces[i].Class,
+					Start:    at,
+					Floor:    airspaces[i].Floor,
+					Ceiling:  airspaces[i].Ceiling,
+				})
+				current = &infringements[len(infringements)-1]
+			}
+			current.End = at
+			current.VerticalMargin = math.Max(current.VerticalMargin, vertical)
+			current.HorizontalMargin = math.Max(current.HorizontalMargin, horizontal)
+			current.Approximate = current.Approximate || approximate
+		}
+	}
+	return infringements
+}
+
+// Checks if a fix is inside the airspace, and returns how far inside it is vertically and horizontally.
+func (a Airspace) contains(p igc.Point) (inside bool, vertical float64, horizontal float64, approximate bool) {
+	position := LatLng{p.Lat.Degrees(), p.Lng.Degrees()}
+
+	// Checks the vertical limits first, it is cheaper.
+	floorAltitude, floorApproximate := altitudeFor(p, position, a.Floor.Reference)
+	aboveFloor := floorAltitude - a.Floor.Value
+
+	belowCeiling := math.Inf(1)
+	ceilingApproximate := false
+	if !a.Ceiling.Unlimited {
+		var ceilingAltitude float64
+		ceilingAltitude, ceilingApproximate = altitudeFor(p, position, a.Ceiling.Reference)
+		belowCeiling = a.Ceiling.Value - ceilingAltitude
+	}
+	if aboveFloor < 0 || belowCeiling < 0 {
+		return false, 0, 0, false
+	}
+
+	// Checks the lateral limits.
+	insidePolygon, margin := polygonContains(a.Polygon, position)
+	if !insidePolygon {
+		return false, 0, 0, false
+	}
+	return true, math.Min(aboveFloor, belowCeiling), margin, floorApproximate || ceilingApproximate
+}
+
+// Returns the altitude of a fix in meters, relative to the given reference.
+// Flight levels use the pressure altitude, the rest the GNSS altitude, if available.
+func altitudeFor(p igc.Point, position LatLng, reference string) (float64, bool) {
+	gnss, pressure := float64(p.GNSSAltitude), float64(p.PressureAltitude)
+	if gnss == 0 {
+		gnss = pressure
+	}
+	if pressure == 0 {
+		pressure = gnss
+	}
+
+	switch reference {
+	case FL:
+		return pressure, false
+	case AGL:
+		if GroundElevation != nil {
+			if ground, ok := GroundElevation(position.Lat, position.Lng); ok {
+				return gnss - ground, false
+			}
+		}
+		return gnss, true
+	}
+	return gnss, false
+}
+
+// Checks if a position is inside a polygon, and returns its distance in meters to the closest edge.
+// The polygon is projected to a plane around the position, which is accurate for the size of airspaces.
+func polygonContains(polygon []LatLng, position LatLng) (bool, float64) {
+	// Projects a point to meters east (x) and north (y) of the position.
+	cosLat := math.Cos(position.Lat * math.Pi / 180)
+	project := func(p LatLng) (float64, float64) {
+		return (p.Lng - position.Lng) * math.Pi / 180 * earthRadius * cosLat,
+			(p.Lat - position.Lat) * math.Pi / 180 * earthRadius
+	}
+
+	inside := false
+	closest := math.Inf(1)
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		xi, yi := project(polygon[i])
+		xj, yj := project(polygon[j])
+
+		// Ray casting, counts the edges crossing the positive x axis.
+		if (yi > 0) != (yj > 0) && xi+(0-yi)*(xj-xi)/(yj-yi) > 0 {
+			inside = !inside
+		}
+
+		// Distance from the origin to the edge.
+		dx, dy := xj-xi, yj-yi
+		t := 0.0
+		if length := dx*dx + dy*dy; length > 0 {
+			t = math.Max(0, math.Min(1, -(xi*dx+yi*dy)/length))
+		}
+		closest = math.Min(closest, math.Hypot(xi+t*dx, yi+t*dy))
+	}
+	return inside, closest
+}
@@ -0,0 +1,91 @@
+/*
+  File: check_test.go
+  Contains unit tests for check.go
+*/
+
+package airspace
+
+import (
+	"testing"
+	"time"
+
+	igc "github.com/marni/goigc"
+)
+
+// Creates a fix at the given position and altitude, seconds after 12:00.
+func testPoint(lat, lng float64, altitude int64, seconds int) igc.Point {
+	p := igc.NewPointFromLatLng(lat, lng)
+	p.Time = time.Date(0, 1, 1, 12, 0, seconds, 0, time.UTC)
+	p.GNSSAltitude = altitude
+	p.PressureAltitude = altitude
+	return p
+}
+
+// A square airspace from 1000 to 2000 meters AMSL.
+var testAirspace = Airspace{
+	Name:    "TEST",
+	Class:   "C",
+	Floor:   Altitude{Value: 1000, Reference: AMSL},
+	Ceiling: Altitude{Value: 2000, Reference: AMSL},
+	Polygon: []LatLng{{60.0, 6.0}, {60.2, 6.0}, {60.2, 6.4}, {60.0, 6.4}},
+}
+
+// Function to test: Check().
+// Test that entering the airspace is reported once, with the deepest margins.
+func Test_Check(t *testing.T) {
+	date := time.Date(2018, 10, 20, 0, 0, 0, 0, time.UTC)
+	points := []igc.Point{
+		// Outside laterally.
+		testPoint(59.9, 6.2, 1500, 0),
+		// Inside, 500 m above the floor and 2 km from the south edge.
+		testPoint(60.018, 6.2, 1500, 60),
+		// Inside, 100 m above the floor.
+		testPoint(60.1, 6.2, 1100, 120),
+		// Below the floor.
+		testPoint(60.1, 6.2, 900, 180),
+	}
+
+	infringements := Check([]Airspace{testAirspace}, date, points)
+	if len(infringements) != 1 {
+		t.Fatalf("Function returned wrong number of infringements: got %d want %d", len(infringements), 1)
+	}
+
+	actual := infringements[0]
+	if !actual.Start.Equal(date.Add(12*time.Hour+time.Minute)) || !actual.End.Equal(date.Add(12*time.Hour+2*time.Minute)) {
+		t.Errorf("Function returned wrong times: got %v to %v", actual.Start, actual.End)
+	}
+	if actual.VerticalMargin != 500 {
+		t.Errorf("Function returned wrong vertical margin: got %v want %v", actual.VerticalMargin, 500.0)
+	}
+	// The second fix is 0.1 degrees (~11 km) from the north and south edges, and ~11 km from the east and west.
+	if actual.HorizontalMargin < 10000 || actual.HorizontalMargin > 12000 {
+		t.Errorf("Function returned wrong horizontal margin: got %v", actual.HorizontalMargin)
+	}
+}
+
+// Function to test: Check().
+// Test that AGL limits use the ground elevation, and are approximate without it.
+func Test_Check_AGL(t *testing.T) {
+	date := time.Date(2018, 10, 20, 0, 0, 0, 0, time.UTC)
+	airspace := testAirspace
+	airspace.Floor = Altitude{Value: 0, Reference: AGL}
+	airspace.Ceiling = Altitude{Value: 300, Reference: AGL}
+	points := []igc.Point{testPoint(60.1, 6.2, 1000, 0)}
+
+	// Without the ground elevation, the altitude is used as the height.
+	GroundElevation = nil
+	if infringements := Check([]Airspace{airspace}, date, points); len(infringements) != 0 {
+		t.Errorf("Function returned infringements when 1000 m is above the ceiling: got %v", infringements)
+	}
+
+	// With the ground at 800 m, the glider is 200 m above the ground.
+	GroundElevation = func(lat, lng float64) (float64, bool) {
+		return 800, true
+	}
+	defer func() { GroundElevation = nil }()
+
+	infringements := Check([]Airspace{airspace}, date, points)
+	if len(infringements) != 1 || infringements[0].Approximate || infringements[0].VerticalMargin != 100 {
+		t.Errorf("Function returned wrong infringements: got %v", infringements)
+	}
+}
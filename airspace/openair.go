@@ -0,0 +1,328 @@
+/*
+	File: openair.go
+  Parses airspace definitions in the OpenAir format.
+*/
+
+package airspace
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mats93/paragliding/mongodb"
+)
+
+// Altitude references.
+const (
+	AMSL = "AMSL"
+	AGL  = "AGL"
+	FL   = "FL"
+)
+
+// Meters per foot and per nautical mile.
+const (
+	feet         = 0.3048
+	nauticalMile = 1852.0
+)
+
+// The earth radius in meters.
+const earthRadius = 6371000.0
+
+// Degrees between the points used to approximate arcs and circles.
+const arcStep = 5.0
+
+// Altitude is a floor or ceiling of an airspace, the value is in meters.
+// Flight levels are stored in meters of standard pressure altitude.
+type Altitude = mongodb.AirspaceAltitude
+
+// LatLng is a position in degrees.
+type LatLng struct {
+	Lat float64
+	Lng float64
+}
+
+// Airspace is an airspace with its lateral limits approximated as a polygon.
+type Airspace struct {
+	Name    string
+	Class   string
+	Floor   Altitude
+	Ceiling Altitude
+	Polygon []LatLng
+}
+
+// Matches a coordinate like "60:39:00 N 006:25:30 E" or "60:39.5N 6:25.5E".
+var coordinatePattern = regexp.MustCompile(
+	`(\d+):(\d+(?:\.\d+)?)(?::(\d+(?:\.\d+)?))?\s*([NSns])\s*,?\s*(\d+):(\d+(?:\.\d+)?)(?::(\d+(?:\.\d+)?))?\s*([EWew])`)
+
+// Matches the number, unit and reference of an altitude, the reference may follow the unit without a space
+// like in "1500MSL" or "1000FTAGL".
+var altitudePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(FT|F|M)?\s*(AMSL|MSL|AGL|AGND|GND|ASFC|SFC)?\b`)
+
+// Parser state for the airspace being read.
+type parser struct {
+	airspaces []Airspace
+	current   *Airspace
+	center    LatLng
+	clockwise bool
+}
+
+// Parse parses all airspaces in an OpenAir file. An airspace with an invalid record is skipped, and the
+// error of each skipped airspace is returned, so one bad airspace does not stop the rest of the file.
+func Parse(content string) (airspaces []Airspace, skipped []error) {
+	p := parser{clockwise: true}
+
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+
+		// Removes comments, and skips empty lines.
+		if j := strings.Index(line, "*"); j != -1 {
+			line = strings.TrimSpace(line[:j])
+		}
+		if len(line) < 2 {
+			continue
+		}
+
+		// The record type is the first word.
+		fields := strings.SplitN(line, " ", 2)
+		record := strings.ToUpper(fields[0])
+		value := ""
+		if len(fields) > 1 {
+			value = strings.TrimSpace(fields[1])
+		}
+
+		if err := p.parseRecord(record, value); err != nil {
+			// Skips the rest of the airspace, the records until the next airspace are ignored.
+			skipped = append(skipped, fmt.Errorf("line %d: airspace %q: %v", i+1, p.current.Name, err))
+			p.current = nil
+		}
+	}
+	p.finish()
+	return p.airspaces, skipped
+}
+
+// Parses one record of the file.
+func (p *parser) parseRecord(record string, value string) error {
+	// A new airspace starts with its class.
+	if record == "AC" {
+		p.finish()
+		p.current = &Airspace{Class: value}
+		p.clockwise = true
+		return nil
+	}
+	if p.current == nil {
+		// Records before the first airspace (e.g. labels), and the rest of a skipped airspace, are ignored.
+		return nil
+	}
+
+	var err error
+	switch record {
+	case "AN":
+		p.current.Name = value
+	case "AL":
+		p.current.Floor, err = ParseAltitude(value)
+	case "AH":
+		p.current.Ceiling, err = ParseAltitude(value)
+	case "DP":
+		var point LatLng
+		point, err = parseCoordinate(value)
+		p.current.Polygon = append(p.current.Polygon, point)
+	case "V":
+		err = p.parseVariable(value)
+	case "DC":
+		err = p.parseCircle(value)
+	case "DA":
+		err = p.parseArcAngles(value)
+	case "DB":
+		err = p.parseArcPoints(value)
+	}
+	return err
+}
+
+// Adds the airspace being read to the list, if it has lateral limits.
+func (p *parser) finish() {
+	if p.current != nil && len(p.current.Polygon) >= 3 {
+		p.airspaces = append(p.airspaces, *p.current)
+	}
+	p.current = nil
+}
+
+// Parses a variable assignment, the arc direction (D=+/-) or the arc center (X=<coordinate>).
+func (p *parser) parseVariable(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid variable %q", value)
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(parts[0])) {
+	case "D":
+		p.clockwise = strings.TrimSpace(parts[1]) != "-"
+	case "X":
+		center, err := parseCoordinate(parts[1])
+		if err != nil {
+			return err
+		}
+		p.center = center
+	}
+	return nil
+}
+
+// Parses a circle around the center, the radius is in nautical miles.
+func (p *parser) parseCircle(value string) error {
+	radius, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return fmt.Errorf("invalid radius %q", value)
+	}
+	for bearing := 0.0; bearing < 360; bearing += arcStep {
+		p.current.Polygon = append(p.current.Polygon, destination(p.center, bearing, radius*nauticalMile))
+	}
+	return nil
+}
+
+// Parses an arc around the center given by radius (nautical miles), start and end angle.
+func (p *parser) parseArcAngles(value string) error {
+	parts := strings.Split(value, ",")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid arc %q", value)
+	}
+	var numbers [3]float64
+	for i := 0; i < 3; i++ {
+		number, err := strconv.ParseFloat(strings.TrimSpace(parts[i]), 64)
+		if err != nil {
+			return fmt.Errorf("invalid arc %q", value)
+		}
+		numbers[i] = number
+	}
+	p.addArc(numbers[0]*nauticalMile, numbers[1], numbers[2])
+	return nil
+}
+
+// Parses an arc around the center from one coordinate to another.
+func (p *parser) parseArcPoints(value string) error {
+	matches := coordinatePattern.FindAllString(value, 2)
+	if len(matches) != 2 {
+		return fmt.Errorf("invalid arc %q", value)
+	}
+	start, err := parseCoordinate(matches[0])
+	if err != nil {
+		return err
+	}
+	end, err := parseCoordinate(matches[1])
+	if err != nil {
+		return err
+	}
+
+	radius := distance(p.center, start)
+	p.addArc(radius, bearing(p.center, start), bearing(p.center, end))
+	return nil
+}
+
+// Adds the points of an arc around the center, in the current direction.
+func (p *parser) addArc(radius float64, start float64, end float64) {
+	// The angle to cover, in the direction of the arc.
+	sweep := math.Mod(end-start+360, 360)
+	direction := 1.0
+	if !p.clockwise {
+		sweep = math.Mod(start-end+360, 360)
+		direction = -1
+	}
+
+	for angle := 0.0; angle < sweep; angle += arcStep {
+		p.current.Polygon = append(p.current.Polygon, destination(p.center, start+direction*angle, radius))
+	}
+	p.current.Polygon = append(p.current.Polygon, destination(p.center, end, radius))
+}
+
+// ParseAltitude parses an OpenAir altitude, e.g. "GND", "FL95", "3500ft", "1000ft AGL" or "UNL".
+// Altitudes without a reference are AMSL, and without a unit in feet.
+func ParseAltitude(value string) (Altitude, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+
+	switch {
+	case s == "GND" || s == "SFC":
+		return Altitude{Value: 0, Reference: AGL}, nil
+	case strings.HasPrefix(s, "UNL"):
+		return Altitude{Reference: AMSL, Unlimited: true}, nil
+	case strings.HasPrefix(s, "FL"):
+		level, err := strconv.ParseFloat(strings.TrimSpace(s[2:]), 64)
+		if err != nil {
+			return Altitude{}, fmt.Errorf("invalid flight level %q", value)
+		}
+		return Altitude{Value: level * 100 * feet, Reference: FL}, nil
+	}
+
+	match := altitudePattern.FindStringSubmatch(s)
+	if match == nil {
+		return Altitude{}, fmt.Errorf("invalid altitude %q", value)
+	}
+	number, _ := strconv.ParseFloat(match[1], 64)
+	if match[2] != "M" {
+		number *= feet
+	}
+
+	reference := AMSL
+	if strings.Contains(s, "AGL") || strings.Contains(s, "GND") || strings.Contains(s, "SFC") {
+		reference = AGL
+	}
+	return Altitude{Value: number, Reference: reference}, nil
+}
+
+// Parses a coordinate in degrees, minutes and optional seconds.
+func parseCoordinate(value string) (LatLng, error) {
+	match := coordinatePattern.FindStringSubmatch(value)
+	if match == nil {
+		return LatLng{}, fmt.Errorf("invalid coordinate %q", value)
+	}
+
+	// Converts degrees, minutes and seconds to decimal degrees.
+	decimal := func(d, m, s, hemisphere string) float64 {
+		degrees, _ := strconv.ParseFloat(d, 64)
+		minutes, _ := strconv.ParseFloat(m, 64)
+		seconds, _ := strconv.ParseFloat(s, 64)
+		result := degrees + minutes/60 + seconds/3600
+		if hemisphere == "S" || hemisphere == "W" {
+			result = -result
+		}
+		return result
+	}
+
+	return LatLng{
+		decimal(match[1], match[2], match[3], strings.ToUpper(match[4])),
+		decimal(match[5], match[6], match[7], strings.ToUpper(match[8])),
+	}, nil
+}
+
+// Returns the point at the given distance (meters) and bearing (degrees) from a point.
+func destination(from LatLng, bearing float64, distance float64) LatLng {
+	lat1 := from.Lat * math.Pi / 180
+	lng1 := from.Lng * math.Pi / 180
+	brng := bearing * math.Pi / 180
+	d := distance / earthRadius
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(brng))
+	lng2 := lng1 + math.Atan2(math.Sin(brng)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	return LatLng{lat2 * 180 / math.Pi, lng2 * 180 / math.Pi}
+}
+
+// Returns the great circle distance in meters between two points.
+func distance(a, b LatLng) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
+
+// Returns the initial bearing in degrees from one point to another.
+func bearing(from, to LatLng) float64 {
+	lat1, lat2 := from.Lat*math.Pi/180, to.Lat*math.Pi/180
+	dLng := (to.Lng - from.Lng) * math.Pi / 180
+
+	y := math.Sin(dLng) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLng)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
@@ -0,0 +1,146 @@
+/*
+  File: openair_test.go
+  Contains unit tests for openair.go
+*/
+
+package airspace
+
+import (
+	"math"
+	"testing"
+)
+
+// An OpenAir file with a polygon, a circle and an arc.
+const testOpenAir = `* Test airspaces
+AC D
+AN VOSS TMA
+AL 1500ft AGL
+AH FL95
+DP 60:30:00 N 006:00:00 E
+DP 60:45:00 N 006:00:00 E
+DP 60:45:00 N 006:45:00 E
+DP 60:30:00 N 006:45:00 E
+
+AC R
+AN CIRCLE
+AL GND
+AH 3500ft
+V X=60:38:00 N 006:25:00 E
+DC 2
+
+AC C
+AN ARC
+AL 2000m
+AH UNL
+DP 60:00:00 N 005:00:00 E
+V D=-
+V X=60:00:00 N 005:30:00 E
+DB 60:00:00 N 005:00:00 E, 60:00:00 N 006:00:00 E
+`
+
+// Function to test: Parse().
+// Test that the airspaces and their limits are read.
+func Test_Parse(t *testing.T) {
+	airspaces, skipped := Parse(testOpenAir)
+	if skipped != nil {
+		t.Fatalf("Function skipped airspaces: %v", skipped)
+	}
+	if len(airspaces) != 3 {
+		t.Fatalf("Function returned wrong number of airspaces: got %d want %d", len(airspaces), 3)
+	}
+
+	tma := airspaces[0]
+	if tma.Name != "VOSS TMA" || tma.Class != "D" || len(tma.Polygon) != 4 {
+		t.Errorf("Function returned wrong airspace: got %s (%s) with %d points", tma.Name, tma.Class, len(tma.Polygon))
+	}
+	if tma.Floor.Reference != AGL || math.Abs(tma.Floor.Value-457.2) > 0.01 {
+		t.Errorf("Function returned wrong floor: got %v", tma.Floor)
+	}
+	if tma.Ceiling.Reference != FL || math.Abs(tma.Ceiling.Value-2895.6) > 0.01 {
+		t.Errorf("Function returned wrong ceiling: got %v", tma.Ceiling)
+	}
+
+	// The circle is approximated by a point every 5 degrees, 2 nautical miles from the center.
+	circle := airspaces[1]
+	if len(circle.Polygon) != 72 {
+		t.Errorf("Function returned wrong number of circle points: got %d want %d", len(circle.Polygon), 72)
+	}
+	if d := distance(LatLng{60 + 38.0/60, 6 + 25.0/60}, circle.Polygon[10]); math.Abs(d-2*nauticalMile) > 1 {
+		t.Errorf("Function returned wrong circle radius: got %v want %v", d, 2*nauticalMile)
+	}
+
+	// The counter-clockwise arc from west to east goes through the south.
+	arc := airspaces[2]
+	if !arc.Ceiling.Unlimited || arc.Floor.Value != 2000 {
+		t.Errorf("Function returned wrong limits: got %v and %v", arc.Floor, arc.Ceiling)
+	}
+	south := 0
+	for i := 0; i < len(arc.Polygon); i++ {
+		if arc.Polygon[i].Lat < 60 {
+			south++
+		}
+	}
+	if south == 0 {
+		t.Error("Function returned an arc in the wrong direction")
+	}
+}
+
+// Function to test: Parse().
+// Test that an airspace with an invalid record is skipped, and the airspaces after it are read.
+func Test_Parse_Skipped(t *testing.T) {
+	content := `AC D
+AN BROKEN
+AL high
+AH FL95
+DP 60:30:00 N 006:00:00 E
+DP 60:45:00 N 006:00:00 E
+DP 60:45:00 N 006:45:00 E
+` + testOpenAir
+
+	airspaces, skipped := Parse(content)
+	if len(airspaces) != 3 || airspaces[0].Name != "VOSS TMA" {
+		t.Errorf("Function returned wrong airspaces: got %d", len(airspaces))
+	}
+	if len(skipped) != 1 || skipped[0].Error() != `line 3: airspace "BROKEN": invalid altitude "high"` {
+		t.Errorf("Function returned wrong errors: got %v", skipped)
+	}
+}
+
+// Function to test: ParseAltitude().
+func Test_ParseAltitude(t *testing.T) {
+	tests := []struct {
+		input     string
+		value     float64
+		reference string
+	}{
+		{"GND", 0, AGL},
+		{"SFC", 0, AGL},
+		{"FL 65", 1981.2, FL},
+		{"3500ft", 1066.8, AMSL},
+		{"3500 ft MSL", 1066.8, AMSL},
+		{"1000F AGL", 304.8, AGL},
+		{"2000m", 2000, AMSL},
+		{"4500", 1371.6, AMSL},
+		{"1500MSL", 457.2, AMSL},
+		{"1500FTMSL", 457.2, AMSL},
+		{"500M AMSL", 500, AMSL},
+		{"1000FTAGL", 304.8, AGL},
+		{"300MAGL", 300, AGL},
+	}
+
+	for i := 0; i < len(tests); i++ {
+		actual, err := ParseAltitude(tests[i].input)
+		if err != nil {
+			t.Errorf("Function returned unexpected error for %q: %v", tests[i].input, err)
+			continue
+		}
+		if math.Abs(actual.Value-tests[i].value) > 0.01 || actual.Reference != tests[i].reference {
+			t.Errorf("Function returned wrong altitude for %q: got %v want %v %s",
+				tests[i].input, actual, tests[i].value, tests[i].reference)
+		}
+	}
+
+	if _, err := ParseAltitude("high"); err == nil {
+		t.Error("Function did not return error for an invalid altitude")
+	}
+}
@@ -0,0 +1,162 @@
+/*
+	File: analysis.go
+  Contains the analysis of IGC files: the metadata stored for a track, and the statistics of the flight.
+  It uses no database, so it is used both when tracks are added, and for files on disk.
+*/
+
+package analysis
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	igc "github.com/marni/goigc"
+	"github.com/mats93/paragliding/distance"
+	"github.com/mats93/paragliding/grecord"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/quality"
+	"github.com/mats93/paragliding/site"
+	"github.com/mats93/paragliding/terrain"
+)
+
+// ErrParse is returned when the content is not valid IGC data.
+var ErrParse = errors.New("could not parse the IGC data")
+
+// Analysis is the analysis of an IGC file.
+// Track is the metadata the API stores for it, without the ID, timestamp, sites and owner. The metadata,
+// statistics and scores are of the cleaned fixes, Quality is the report of the cleaning.
+type Analysis struct {
+	Track   mongodb.Track   `json:"track"`
+	Stats   Stats           `json:"stats"`
+	Score   Score           `json:"score"`
+	Quality mongodb.Quality `json:"quality"`
+}
+
+// Cleaning is the cleaning of the fixes before they are analyzed, and stored as a track.
+var Cleaning = quality.Default
+
+// GroundElevation returns the ground elevation in meters at a position, for the heights above the ground.
+// If it is nil, the tracks have no min AGL and terrain.
+var GroundElevation func(lat float64, lng float64) (float64, bool)
+
+// Stats is the statistics of a flight. The altitudes are the GNSS altitudes in meters.
+type Stats struct {
+	Fixes int `json:"fixes"`
+	// The times of the first and the last fix.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Duration is the time between the first and the last fix, in seconds.
+	Duration            int64 `json:"duration"`
+	MinAltitude         int64 `json:"min_altitude"`
+	MaxAltitude         int64 `json:"max_altitude"`
+	MaxPressureAltitude int64 `json:"max_pressure_altitude"`
+	// AltitudeGain is the sum of all climbs.
+	AltitudeGain int64 `json:"altitude_gain"`
+}
+
+// Analyze parses the IGC content, and analyzes it.
+func Analyze(content string) (Analysis, error) {
+	trackFile, err := igc.Parse(content)
+	if err != nil {
+		return Analysis{}, ErrParse
+	}
+	cleaned, report := Clean(trackFile)
+	return Analysis{
+		Track:   Metadata(content, trackFile, cleaned),
+		Stats:   Statistics(cleaned.Header.Date, cleaned.Points),
+		Score:   Scores(cleaned.Points),
+		Quality: report,
+	}, nil
+}
+
+// Clean returns the parsed IGC file with its fixes cleaned with Cleaning, and the report of the cleaning.
+func Clean(trackFile igc.Track) (igc.Track, mongodb.Quality) {
+	points, report := quality.Clean(trackFile.Header.Date, trackFile.Points, Cleaning)
+	trackFile.Points = points
+	return trackFile, report
+}
+
+// Metadata returns the metadata of a parsed IGC file, the ID, timestamp, source url and sites are set
+// when it is stored. The track_length is of all the fixes of the file, the clean length and the terrain
+// of the fixes cleaned by Clean.
+func Metadata(content string, trackFile igc.Track, cleaned igc.Track) mongodb.Track {
+	track := mongodb.Track{
+		HDate:            trackFile.Header.Date,
+		Pilot:            trackFile.Pilot,
+		Glider:           trackFile.GliderType,
+		GliderID:         trackFile.GliderID,
+		TrackLength:      Length(trackFile.Points),
+		TrackLengthClean: distance.Length(cleaned.Points, distance.Clean),
+		Validation:       grecord.Check(content, trackFile),
+		Checksum:         fmt.Sprintf("%x", sha256.Sum256([]byte(content))),
+	}
+	if GroundElevation != nil {
+		track.MinAGL, track.Terrain = Terrain(cleaned.Points)
+	}
+	return track
+}
+
+// Terrain returns the lowest height in meters above the ground during the flight, nil if the ground under it is
+// unknown or there was no flight, and the coverage of the ground under the fixes. GroundElevation must be set.
+func Terrain(points []igc.Point) (*float64, string) {
+	heights := terrain.Heights(points, GroundElevation)
+	takeoff, landing, ok := site.DetectFlightAbove(points, heights)
+	if !ok {
+		return nil, terrain.Coverage(heights)
+	}
+	// The fixes of the takeoff and landing are on the ground, only those between them are in the air.
+	min, ok := terrain.MinAGL(heights[takeoff+1 : landing])
+	if !ok {
+		return nil, terrain.Coverage(heights)
+	}
+	return &min, terrain.Coverage(heights)
+}
+
+// Length returns the length of a track in km, the sum of the distances between all the fixes on a sphere.
+// It is stored as track_length, the cleaned length as track_length_clean.
+func Length(points []igc.Point) float64 {
+	return distance.Length(points, distance.Raw)
+}
+
+// Statistics returns the statistics of the fixes of a flight.
+// The date is the flight date, since the fixes only contain the time of day.
+func Statistics(date time.Time, points []igc.Point) Stats {
+	var stats Stats
+	stats.Fixes = len(points)
+	if len(points) == 0 {
+		return stats
+	}
+
+	stats.Start = at(date, points[0].Time)
+	stats.End = at(date, points[len(points)-1].Time)
+	if stats.End.Before(stats.Start) {
+		// The flight went past midnight UTC.
+		stats.End = stats.End.AddDate(0, 0, 1)
+	}
+	stats.Duration = int64(stats.End.Sub(stats.Start) / time.Second)
+	stats.MinAltitude = points[0].GNSSAltitude
+	stats.MaxAltitude = points[0].GNSSAltitude
+	stats.MaxPressureAltitude = points[0].PressureAltitude
+	for i := 1; i < len(points); i++ {
+		if points[i].GNSSAltitude < stats.MinAltitude {
+			stats.MinAltitude = points[i].GNSSAltitude
+		}
+		if points[i].GNSSAltitude > stats.MaxAltitude {
+			stats.MaxAltitude = points[i].GNSSAltitude
+		}
+		if points[i].PressureAltitude > stats.MaxPressureAltitude {
+			stats.MaxPressureAltitude = points[i].PressureAltitude
+		}
+		if climb := points[i].GNSSAltitude - points[i-1].GNSSAltitude; climb > 0 {
+			stats.AltitudeGain += climb
+		}
+	}
+	return stats
+}
+
+// Returns the time of day on the date.
+func at(date time.Time, t time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
@@ -0,0 +1,186 @@
+/*
+	File: analysis_test.go
+  Contains unit tests for analysis.go, and the regression tests against the IGC files in testdata.
+*/
+
+package analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	igc "github.com/marni/goigc"
+	"github.com/mats93/paragliding/grecord"
+)
+
+// Writes the golden files from the current analysis, instead of comparing with them.
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// Format of the golden files, the analysis with the checksum, which is not in the json of the track.
+type golden struct {
+	Analysis
+	Checksum string `json:"checksum"`
+}
+
+// Function to test: Analyze().
+// Test that the analysis of each IGC file in testdata matches its golden file.
+func Test_Analyze_Corpus(t *testing.T) {
+	files, _ := filepath.Glob(filepath.Join("testdata", "*.igc"))
+	if len(files) == 0 {
+		t.Fatal("No IGC files in testdata")
+	}
+
+	for i := 0; i < len(files); i++ {
+		content, err := os.ReadFile(files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		analysis, err := Analyze(string(content))
+		if err != nil {
+			t.Errorf("%s: Function returned an error: %v", files[i], err)
+			continue
+		}
+		actual, _ := json.MarshalIndent(golden{analysis, analysis.Track.Checksum}, "", "  ")
+		actual = append(actual, '\n')
+
+		if *update {
+			if err := os.WriteFile(files[i]+".golden", actual, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		expected, err := os.ReadFile(files[i] + ".golden")
+		if err != nil {
+			t.Errorf("%s: No golden file, run the test with -update: %v", files[i], err)
+			continue
+		}
+		if !bytes.Equal(actual, expected) {
+			t.Errorf("%s: Function returned wrong analysis: got\n%s\nwant\n%s", files[i], actual, expected)
+		}
+	}
+}
+
+// Function to test: Analyze().
+// Test the metadata and statistics of the made up triangle flight.
+func Test_Analyze(t *testing.T) {
+	content, _ := os.ReadFile(filepath.Join("testdata", "triangle.igc"))
+	actual, err := Analyze(string(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	track := actual.Track
+	if track.Pilot != "Test Pilot" || track.Glider != "Test Glider" || track.GliderID != "TG-1" ||
+		track.Validation != grecord.StatusUnsigned || !track.HDate.Equal(time.Date(2018, 10, 20, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Function returned wrong metadata: got %+v", track)
+	}
+
+	stats := actual.Stats
+	if stats.Fixes != 6 || stats.Duration != 25*60 || stats.MinAltitude != 500 || stats.MaxAltitude != 1600 ||
+		stats.MaxPressureAltitude != 1600 || stats.AltitudeGain != 250+250+200 {
+		t.Errorf("Function returned wrong statistics: got %+v", stats)
+	}
+}
+
+// Function to test: Analyze().
+// Test that content that is not IGC data returns ErrParse.
+func Test_Analyze_Invalid(t *testing.T) {
+	if _, err := Analyze("not an IGC file"); err != ErrParse {
+		t.Errorf("Function returned wrong error: got %v want %v", err, ErrParse)
+	}
+}
+
+// Function to test: Length().
+func Test_Length(t *testing.T) {
+	points := []igc.Point{
+		igc.NewPointFromLatLng(60, 6),
+		igc.NewPointFromLatLng(61, 6),
+		igc.NewPointFromLatLng(60, 6),
+	}
+	// A degree of latitude is about 111.2 km.
+	actual := Length(points)
+	if actual < 2*111.1 || actual > 2*111.3 {
+		t.Errorf("Function returned wrong length: got %v want about %v", actual, 2*111.2)
+	}
+	if Length(points[:1]) != 0 {
+		t.Error("Function returned a length for one point")
+	}
+}
+
+// Function to test: Statistics().
+// Test that a flight past midnight UTC ends the next day.
+func Test_Statistics_Midnight(t *testing.T) {
+	date := time.Date(2018, 10, 20, 0, 0, 0, 0, time.UTC)
+	first := igc.NewPointFromLatLng(60, 6)
+	first.Time = time.Date(0, 1, 1, 23, 50, 0, 0, time.UTC)
+	last := igc.NewPointFromLatLng(60, 6)
+	last.Time = time.Date(0, 1, 1, 0, 10, 0, 0, time.UTC)
+
+	actual := Statistics(date, []igc.Point{first, last})
+	if !actual.End.Equal(time.Date(2018, 10, 21, 0, 10, 0, 0, time.UTC)) || actual.Duration != 20*60 {
+		t.Errorf("Function returned wrong end: got %v and %d s", actual.End, actual.Duration)
+	}
+}
+
+// Function to test: Metadata().
+// Test the min AGL and terrain of the made up flight, with the ground known under its western fixes.
+func Test_Metadata_Terrain(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testdata", "triangle.igc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trackFile, err := igc.Parse(string(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without elevation tiles, there is no min AGL or terrain.
+	GroundElevation = nil
+	if actual := Metadata(string(content), trackFile, trackFile); actual.MinAGL != nil || actual.Terrain != "" {
+		t.Errorf("Function returned terrain without elevation tiles: got %v, %q", actual.MinAGL, actual.Terrain)
+	}
+
+	// The ground is at 200 m west of 6.5 degrees, the fixes 3 and 4 are east of it.
+	GroundElevation = func(lat float64, lng float64) (float64, bool) {
+		return 200, lng < 6.5
+	}
+	defer func() { GroundElevation = nil }()
+	actual := Metadata(string(content), trackFile, trackFile)
+	// The fixes in the air are at 1250, 1500, 1400 and 1600 m, the known ground is under the first two.
+	if actual.MinAGL == nil || *actual.MinAGL != 1050 || actual.Terrain != "partial" {
+		t.Errorf("Function returned wrong terrain: got %v, %q want %v, %q", actual.MinAGL, actual.Terrain, 1050, "partial")
+	}
+}
+
+// Function to test: Metadata().
+// Test that a spike removed by the cleaning is left out of the clean length, but not of the raw length.
+func Test_Metadata_Spike(t *testing.T) {
+	// A flight north with a fix every 10 seconds, and a jump of 1 degree, 111 km, in the middle.
+	var trackFile igc.Track
+	for i := 0; i < 10; i++ {
+		p := igc.NewPointFromLatLng(60+0.0009*float64(i), 6)
+		p.Time = time.Date(0, 1, 1, 12, 0, 10*i, 0, time.UTC)
+		trackFile.Points = append(trackFile.Points, p)
+	}
+	trackFile.Points[4] = igc.NewPointFromLatLng(61, 6)
+	trackFile.Points[4].Time = time.Date(0, 1, 1, 12, 0, 40, 0, time.UTC)
+
+	cleaned, report := Clean(trackFile)
+	if report.Removed != 1 {
+		t.Fatalf("Clean did not remove the spike: got %+v", report)
+	}
+	actual := Metadata("", trackFile, cleaned)
+
+	// The raw length goes to the spike and back, the clean length does not.
+	if actual.TrackLength != Length(trackFile.Points) || actual.TrackLength < 2*111 {
+		t.Errorf("Function returned wrong raw length: got %v want %v", actual.TrackLength, Length(trackFile.Points))
+	}
+	if actual.TrackLengthClean > 1 {
+		t.Errorf("Function returned clean length with the spike: got %v want less than 1 km", actual.TrackLengthClean)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+	File: score.go
+  Contains the scoring of flights by distance: straight, from the takeoff, and free distance via turnpoints.
+*/
+
+package analysis
+
+import (
+	igc "github.com/marni/goigc"
+)
+
+// Turnpoints is the number of turnpoints of the free distance, as in cross-country leagues.
+const Turnpoints = 3
+
+// MaxScoredFixes is the number of fixes the free distance is optimized on, longer flights are sampled
+// evenly down to it. The free distance of a sampled flight can be slightly shorter than the exact optimum.
+const MaxScoredFixes = 400
+
+// Score is the distances of a flight in km.
+type Score struct {
+	// StraightDistance is the distance from the first to the last fix.
+	StraightDistance float64 `json:"straight_distance"`
+	// MaxDistance is the longest distance from the first fix.
+	MaxDistance float64 `json:"max_distance"`
+	// FreeDistance is the longest distance from a start, via up to 3 turnpoints, to a finish, in the order
+	// they were flown.
+	FreeDistance float64 `json:"free_distance"`
+}
+
+// Scores returns the distances of the fixes of a flight.
+func Scores(points []igc.Point) Score {
+	var score Score
+	if len(points) < 2 {
+		return score
+	}
+
+	score.StraightDistance = points[0].Distance(points[len(points)-1])
+	for i := 1; i < len(points); i++ {
+		if d := points[0].Distance(points[i]); d > score.MaxDistance {
+			score.MaxDistance = d
+		}
+	}
+	score.FreeDistance = FreeDistance(points, Turnpoints)
+	return score
+}
+
+// FreeDistance returns the longest distance in km from a start, via up to n turnpoints, to a finish,
+// in the order they were flown.
+func FreeDistance(points []igc.Point, n int) float64 {
+	points = sample(points, MaxScoredFixes)
+	if len(points) < 2 {
+		return 0
+	}
+
+	// best[j] is the longest distance with the legs so far, that ends at fix j.
+	// Each round adds a leg, the first round is from the start to a fix.
+	best := make([]float64, len(points))
+	next := make([]float64, len(points))
+	var longest float64
+	for leg := 0; leg <= n; leg++ {
+		for j := 0; j < len(points); j++ {
+			next[j] = 0
+			for i := 0; i < j; i++ {
+				if d := best[i] + points[i].Distance(points[j]); d > next[j] {
+					next[j] = d
+				}
+			}
+			if next[j] > longest {
+				longest = next[j]
+			}
+		}
+		best, next = next, best
+	}
+	return longest
+}
+
+// Returns at most max of the points, evenly spread, with the first and the last point.
+func sample(points []igc.Point, max int) []igc.Point {
+	if len(points) <= max {
+		return points
+	}
+	sampled := make([]igc.Point, max)
+	for i := 0; i < max; i++ {
+		sampled[i] = points[i*(len(points)-1)/(max-1)]
+	}
+	return sampled
+}
@@ -0,0 +1,76 @@
+/*
+	File: score_test.go
+  Contains unit tests for score.go
+*/
+
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	igc "github.com/marni/goigc"
+)
+
+// Returns the distance of a degree of latitude in km.
+func degree() float64 {
+	start := igc.NewPointFromLatLng(60, 6)
+	return start.Distance(igc.NewPointFromLatLng(61, 6))
+}
+
+// Returns the points of the latitudes, on the same longitude.
+func meridian(latitudes ...float64) []igc.Point {
+	points := make([]igc.Point, len(latitudes))
+	for i := 0; i < len(latitudes); i++ {
+		points[i] = igc.NewPointFromLatLng(latitudes[i], 6)
+	}
+	return points
+}
+
+// Function to test: Scores().
+// Test the distances of an out and return flight, with a detour back past the start.
+func Test_Scores(t *testing.T) {
+	degree := degree()
+	points := meridian(60, 60.5, 61, 60.5, 59.5, 60)
+
+	actual := Scores(points)
+	expected := Score{
+		StraightDistance: 0,
+		MaxDistance:      degree,
+		// From the start north to 61, south to 59.5 and back to 60.
+		FreeDistance: 1.0*degree + 1.5*degree + 0.5*degree,
+	}
+	if math.Abs(actual.StraightDistance-expected.StraightDistance) > 0.01 ||
+		math.Abs(actual.MaxDistance-expected.MaxDistance) > 0.01 ||
+		math.Abs(actual.FreeDistance-expected.FreeDistance) > 0.01 {
+		t.Errorf("Function returned wrong score: got %+v want %+v", actual, expected)
+	}
+}
+
+// Function to test: FreeDistance().
+// Test that the number of turnpoints limits the legs.
+func Test_FreeDistance(t *testing.T) {
+	degree := degree()
+	// Zig-zags of one degree, each leg adds a degree.
+	points := meridian(60, 61, 60, 61, 60, 61, 60)
+
+	for n := 0; n <= 3; n++ {
+		actual := FreeDistance(points, n)
+		if math.Abs(actual-float64(n+1)*degree) > 0.01 {
+			t.Errorf("Function returned wrong distance with %d turnpoints: got %v want %v", n, actual, float64(n+1)*degree)
+		}
+	}
+}
+
+// Function to test: sample().
+func Test_sample(t *testing.T) {
+	points := meridian(0, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+
+	actual := sample(points, 4)
+	if len(actual) != 4 || actual[0].LatLng != points[0].LatLng || actual[3].LatLng != points[9].LatLng {
+		t.Errorf("Function returned wrong points: got %d points", len(actual))
+	}
+	if len(sample(points, 20)) != len(points) {
+		t.Error("Function sampled fewer points than the maximum")
+	}
+}
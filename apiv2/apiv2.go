@@ -0,0 +1,98 @@
+/*
+	File: apiv2.go
+  Contains the JSON envelopes and errors of the v2 API, and its information endpoint.
+  The v2 handlers use the same operations as v1, but every response body is an envelope with the data or an
+  error, lists are never 404, and deletes return 204 without a body.
+*/
+
+package apiv2
+
+import (
+	"net/http"
+
+	"github.com/mats93/paragliding/internal/httpjson"
+	"github.com/mats93/paragliding/logging"
+)
+
+// VERSION = The version of the API served by this package.
+const VERSION = "v2"
+
+// Prefix is the path prefix of the v2 routes.
+const Prefix = "/paragliding/api/v2"
+
+// Error codes of the v2 API, fetch errors use the code of the fetch package.
+const (
+	CodeBadRequest       = "bad_request"
+	CodeInvalidBody      = "invalid_body"
+	CodeInvalidIGC       = "invalid_igc"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeConflict         = "conflict"
+	CodeUnavailable      = "unavailable"
+	CodeInternal         = "internal"
+)
+
+// Format of all the v2 response bodies, with either the data or the error.
+type envelope struct {
+	Data  interface{} `json:"data,omitempty"`
+	Error *apiError   `json:"error,omitempty"`
+}
+
+// Format of an error, with the ID of the request to find it in the logs.
+type apiError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Format for the ID of a created resource.
+type id struct {
+	ID interface{} `json:"id"`
+}
+
+// Writes the data in an envelope, with the given status code.
+func writeData(w http.ResponseWriter, status int, data interface{}) {
+	httpjson.Write(w, status, envelope{Data: data})
+}
+
+// Writes the data of a created resource in an envelope, with its path in the Location header.
+func writeCreated(w http.ResponseWriter, status int, location string, data interface{}) {
+	w.Header().Set("Location", location)
+	writeData(w, status, data)
+}
+
+// Writes an error in an envelope, with the given status code and error code.
+func writeError(w http.ResponseWriter, r *http.Request, status int, code string, message string) {
+	httpjson.Write(w, status, envelope{Error: &apiError{code, message, logging.RequestID(r.Context())}})
+}
+
+// Logs an unexpected error, and writes 500 (Internal server error) without its details.
+func writeInternal(w http.ResponseWriter, r *http.Request, err error) {
+	logging.FromContext(r.Context()).Error(err.Error())
+	writeError(w, r, http.StatusInternalServerError, CodeInternal, "internal server error")
+}
+
+// Writes 401 (Unauthorized) if the request is not logged in, and 403 (Forbidden) if it is.
+func writeDenied(w http.ResponseWriter, r *http.Request, loggedIn bool, message string) {
+	if loggedIn {
+		writeError(w, r, http.StatusForbidden, CodeForbidden, message)
+	} else {
+		writeError(w, r, http.StatusUnauthorized, CodeUnauthorized, "log in with the 'Authorization: Bearer <token>' header")
+	}
+}
+
+// MethodNotAllowed writes 405 (Method not allowed), for the v2 routes requested with another method.
+// Output: application/json
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, r.Method+" is not allowed for "+r.URL.Path)
+}
+
+// GetInfo - GET: Returns information about the API.
+// Output: application/json
+func GetInfo(w http.ResponseWriter, r *http.Request) {
+	info := Data.Info(r.Context())
+	info.Version = VERSION
+	writeData(w, http.StatusOK, info)
+}
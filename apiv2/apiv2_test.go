@@ -0,0 +1,94 @@
+/*
+  File: apiv2_test.go
+  Contains unit tests for the v2 handlers that answer before the database is used.
+*/
+
+package apiv2
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mats93/paragliding/logging"
+)
+
+// Serves a request with the handler, and returns the status code and decoded envelope.
+func serve(handler http.HandlerFunc, method string, path string, body string) (int, envelope) {
+	request, _ := http.NewRequest(method, path, strings.NewReader(body))
+	request = request.WithContext(logging.WithRequestID(request.Context(), "test-request"))
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+
+	var actual envelope
+	json.NewDecoder(recorder.Body).Decode(&actual)
+	return recorder.Code, actual
+}
+
+// Checks the status code and the code of the error.
+func checkError(t *testing.T, code int, actual envelope, wantCode int, wantError string) {
+	t.Helper()
+	if code != wantCode {
+		t.Errorf("Handler returned wrong status code: got %v want %v", code, wantCode)
+	}
+	if actual.Error == nil || actual.Error.Code != wantError || actual.Data != nil {
+		t.Fatalf("Handler returned wrong envelope: got %+v want error %s", actual, wantError)
+	}
+	if actual.Error.RequestID != "test-request" {
+		t.Errorf("Handler returned wrong request ID: got %s want %s", actual.Error.RequestID, "test-request")
+	}
+}
+
+// Function to test: MethodNotAllowed().
+func Test_MethodNotAllowed(t *testing.T) {
+	code, actual := serve(MethodNotAllowed, "PUT", Prefix+"/track", "")
+	checkError(t, code, actual, http.StatusMethodNotAllowed, CodeMethodNotAllowed)
+}
+
+// Function to test: AddTrack().
+// Test that tracks can only be added when logged in.
+func Test_AddTrack_Unauthorized(t *testing.T) {
+	code, actual := serve(AddTrack, "POST", Prefix+"/track", `{"url": "http://test.test/track.igc"}`)
+	checkError(t, code, actual, http.StatusUnauthorized, CodeUnauthorized)
+}
+
+// Function to test: ListTracks().
+// Test that an unknown validation status is a bad request.
+func Test_ListTracks_UnknownValidation(t *testing.T) {
+	code, actual := serve(ListTracks, "GET", Prefix+"/track?validation=signed", "")
+	checkError(t, code, actual, http.StatusBadRequest, CodeBadRequest)
+}
+
+// Function to test: AddWebhook().
+// Test that the body is validated against the OpenAPI document, and the invalid field is returned.
+func Test_AddWebhook_InvalidBody(t *testing.T) {
+	tests := map[string]string{
+		`{"minTriggerValue": 2}`: "body.webhookURL is required",
+		`{"webhookURL": "http://webhook.local", "events": ["track.unknown"]}`: "body.events[0] should be one of new_track, track.airspace, track.deleted",
+		`{"webhookURL": "http://webhook.local", "minTriggerValue": "2"}`:      "body.minTriggerValue should be an integer",
+		`{"webhookURL": `: "the body is not valid json",
+	}
+	for body, message := range tests {
+		code, actual := serve(AddWebhook, "POST", Prefix+"/webhook", body)
+		checkError(t, code, actual, http.StatusBadRequest, CodeInvalidBody)
+		if actual.Error.Message != message {
+			t.Errorf("Handler returned wrong message for %s: got %s want %s", body, actual.Error.Message, message)
+		}
+	}
+}
+
+// Function to test: writeData().
+// Test that an empty list is returned as data, and not left out.
+func Test_writeData(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writeData(recorder, http.StatusOK, []int{})
+
+	if actual := recorder.Body.String(); actual != `{"data":[]}` {
+		t.Errorf("Function returned wrong body: got %s want %s", actual, `{"data":[]}`)
+	}
+	if content := recorder.Header().Get("Content-Type"); content != "application/json" {
+		t.Errorf("Function returned wrong content-type: got %s want %s", content, "application/json")
+	}
+}
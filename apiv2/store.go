@@ -0,0 +1,296 @@
+/*
+	File: store.go
+  Contains the store of the tracks, jobs and webhooks the v2 handlers read and change, the MongoDB one used by
+  the service, and an in-memory one for tests of the API and its clients.
+*/
+
+package apiv2
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/mats93/paragliding/job"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/ticker"
+	"github.com/mats93/paragliding/track"
+	"github.com/mats93/paragliding/user"
+	"github.com/mats93/paragliding/webhook"
+)
+
+// Store is the data of the v2 handlers. The errors are those of the track and webhook packages,
+// so the handlers turn them into the same responses for every store.
+type Store interface {
+	// Info returns the information about the API.
+	Info(ctx context.Context) track.APIInfo
+	// TrackIDs returns the IDs of the tracks the viewer can see, with the validation status if not empty.
+	TrackIDs(ctx context.Context, viewer user.Viewer, validation string) ([]int, error)
+	// FindTrack returns the track with the ID, or track.ErrNotFound if it is hidden from the viewer.
+	FindTrack(ctx context.Context, viewer user.Viewer, id int) (mongodb.Track, error)
+	// AddTrack fetches the IGC file at the url, and stores it as a new track. Returns the tracks ID.
+	AddTrack(ctx context.Context, url string, owner int, privacy string, clubs []int) (int, error)
+	// QueueTrack queues a job adding the track at the url. Returns the jobs ID.
+	QueueTrack(ctx context.Context, url string, owner int, privacy string, clubs []int) (int, error)
+	// CorrectTrack applies a correction of the track by the actor, and returns the corrected track.
+	CorrectTrack(ctx context.Context, viewer user.Viewer, t mongodb.Track, actor string, c track.Correction) (mongodb.Track, error)
+	// DeleteTrack deletes the track by the actor.
+	DeleteTrack(ctx context.Context, t mongodb.Track, actor string) error
+	// FindJob returns the job with the ID.
+	FindJob(ctx context.Context, id int) (mongodb.Job, error)
+	// PublicTracks returns the public tracks, for the tickers.
+	PublicTracks(ctx context.Context) ([]mongodb.Track, error)
+	// PublicTracksAfter returns the public tracks added after the timestamp.
+	PublicTracksAfter(ctx context.Context, ts int64) ([]mongodb.Track, error)
+	// RegisterWebhook registers a new webhook by the viewer, and returns its ID.
+	RegisterWebhook(ctx context.Context, viewer user.Viewer, hook mongodb.Webhook) (string, error)
+	// FindWebhook returns the webhook with the ID.
+	FindWebhook(ctx context.Context, id string) (mongodb.Webhook, error)
+	// DeleteWebhook deletes the webhook with the ID.
+	DeleteWebhook(ctx context.Context, id string) error
+}
+
+// Data is the store used by the handlers, MongoDB by default. Tests inject a MemoryStore.
+var Data Store = mongoStore{}
+
+// The store of the service, the operations of the track, job and webhook packages on MongoDB.
+type mongoStore struct{}
+
+// Info returns the information about the API, with the number of tracks in the database.
+func (mongoStore) Info(ctx context.Context) track.APIInfo {
+	return track.Info(ctx)
+}
+
+// TrackIDs returns the IDs of the tracks the viewer can see.
+func (mongoStore) TrackIDs(ctx context.Context, viewer user.Viewer, validation string) ([]int, error) {
+	return track.IDs(ctx, viewer, validation)
+}
+
+// FindTrack returns the track with the ID, if the viewer can see it.
+func (mongoStore) FindTrack(ctx context.Context, viewer user.Viewer, id int) (mongodb.Track, error) {
+	return track.Find(ctx, viewer, id)
+}
+
+// AddTrack fetches and stores the track at the url.
+func (mongoStore) AddTrack(ctx context.Context, url string, owner int, privacy string, clubs []int) (int, error) {
+	return track.Ingest(ctx, url, owner, privacy, clubs)
+}
+
+// QueueTrack queues a job for the job workers.
+func (mongoStore) QueueTrack(ctx context.Context, url string, owner int, privacy string, clubs []int) (int, error) {
+	return job.Enqueue(ctx, url, owner, privacy, clubs)
+}
+
+// CorrectTrack corrects the track, and logs the changes in the audit log.
+func (mongoStore) CorrectTrack(ctx context.Context, viewer user.Viewer, t mongodb.Track, actor string, c track.Correction) (mongodb.Track, error) {
+	return track.Correct(ctx, viewer, t, actor, c)
+}
+
+// DeleteTrack deletes the track, and logs it in the audit log.
+func (mongoStore) DeleteTrack(ctx context.Context, t mongodb.Track, actor string) error {
+	return track.Delete(ctx, t, actor)
+}
+
+// FindJob finds the job in the database.
+func (mongoStore) FindJob(ctx context.Context, id int) (mongodb.Job, error) {
+	database := mongodb.DatabaseContext(ctx, job.Collection)
+	return database.FindJob(id)
+}
+
+// PublicTracks finds the public tracks in the database.
+func (mongoStore) PublicTracks(ctx context.Context) ([]mongodb.Track, error) {
+	database := mongodb.DatabaseContext(ctx, ticker.Collection)
+	return database.FindAllPublic()
+}
+
+// PublicTracksAfter finds the public tracks added after the timestamp in the database.
+func (mongoStore) PublicTracksAfter(ctx context.Context, ts int64) ([]mongodb.Track, error) {
+	database := mongodb.DatabaseContext(ctx, ticker.Collection)
+	return database.FindPublicTrackHigherThen(ts)
+}
+
+// RegisterWebhook checks and stores the webhook.
+func (mongoStore) RegisterWebhook(ctx context.Context, viewer user.Viewer, hook mongodb.Webhook) (string, error) {
+	return webhook.Register(ctx, viewer, hook)
+}
+
+// FindWebhook finds the webhook in the database.
+func (mongoStore) FindWebhook(ctx context.Context, id string) (mongodb.Webhook, error) {
+	database := mongodb.DatabaseContext(ctx, webhook.CollectionWebhook)
+	return database.FindWebhook(id)
+}
+
+// DeleteWebhook deletes the webhook from the database.
+func (mongoStore) DeleteWebhook(ctx context.Context, id string) error {
+	database := mongodb.DatabaseContext(ctx, webhook.CollectionWebhook)
+	_, err := database.DeleteWebhook(id)
+	return err
+}
+
+// ErrNotStored is returned by the MemoryStore for what it does not store, and when something is not found.
+var ErrNotStored = errors.New("not found in the memory store")
+
+// ErrUnsupported is returned by the MemoryStore for adding tracks, which fetches IGC files.
+var ErrUnsupported = errors.New("adding tracks from urls is not supported by the memory store")
+
+// MemoryStore is a Store that keeps the tracks and webhooks in memory, for tests that should not need the
+// database. Tracks are inserted with InsertTrack, it does not fetch IGC files, and has no jobs.
+type MemoryStore struct {
+	lock     sync.Mutex
+	tracks   []mongodb.Track
+	webhooks map[string]mongodb.Webhook
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{webhooks: map[string]mongodb.Webhook{}}
+}
+
+// InsertTrack stores a track with the ID and timestamp it has.
+func (s *MemoryStore) InsertTrack(t mongodb.Track) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.tracks = append(s.tracks, t)
+}
+
+// Returns the tracks that are not deleted, for which keep is true.
+func (s *MemoryStore) find(keep func(mongodb.Track) bool) []mongodb.Track {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	tracks := []mongodb.Track{}
+	for i := 0; i < len(s.tracks); i++ {
+		if !s.tracks[i].Deleted && keep(s.tracks[i]) {
+			tracks = append(tracks, s.tracks[i])
+		}
+	}
+	// Sorted by ID, like the IDs are given in the database.
+	sort.Slice(tracks, func(i, j int) bool { return tracks[i].ID < tracks[j].ID })
+	return tracks
+}
+
+// Replaces the stored track with the same ID.
+func (s *MemoryStore) update(t mongodb.Track) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i := 0; i < len(s.tracks); i++ {
+		if s.tracks[i].ID == t.ID {
+			s.tracks[i] = t
+		}
+	}
+}
+
+// Info returns the information about the API, with the number of tracks in memory.
+func (s *MemoryStore) Info(ctx context.Context) track.APIInfo {
+	all := s.find(func(mongodb.Track) bool { return true })
+	return track.NewInfo(len(all))
+}
+
+// TrackIDs returns the IDs of the tracks the viewer can see.
+func (s *MemoryStore) TrackIDs(ctx context.Context, viewer user.Viewer, validation string) ([]int, error) {
+	if err := track.CheckValidation(validation); err != nil {
+		return nil, err
+	}
+	tracks := s.find(func(t mongodb.Track) bool {
+		return viewer.CanView(t) && (validation == "" || t.Validation == validation)
+	})
+	ids := []int{}
+	for i := 0; i < len(tracks); i++ {
+		ids = append(ids, tracks[i].ID)
+	}
+	return ids, nil
+}
+
+// FindTrack returns the track with the ID, if the viewer can see it.
+func (s *MemoryStore) FindTrack(ctx context.Context, viewer user.Viewer, id int) (mongodb.Track, error) {
+	tracks := s.find(func(t mongodb.Track) bool { return t.ID == id })
+	if len(tracks) == 0 || !viewer.CanView(tracks[0]) {
+		return mongodb.Track{}, track.ErrNotFound
+	}
+	return tracks[0], nil
+}
+
+// AddTrack is not supported.
+func (s *MemoryStore) AddTrack(ctx context.Context, url string, owner int, privacy string, clubs []int) (int, error) {
+	return 0, ErrUnsupported
+}
+
+// QueueTrack is not supported.
+func (s *MemoryStore) QueueTrack(ctx context.Context, url string, owner int, privacy string, clubs []int) (int, error) {
+	return 0, ErrUnsupported
+}
+
+// CorrectTrack corrects the track in memory.
+func (s *MemoryStore) CorrectTrack(ctx context.Context, viewer user.Viewer, t mongodb.Track, actor string, c track.Correction) (mongodb.Track, error) {
+	t, _, err := track.Apply(viewer, t, c)
+	if err != nil {
+		return t, err
+	}
+	s.update(t)
+	return t, nil
+}
+
+// DeleteTrack marks the track as deleted.
+func (s *MemoryStore) DeleteTrack(ctx context.Context, t mongodb.Track, actor string) error {
+	t.Deleted = true
+	s.update(t)
+	return nil
+}
+
+// FindJob finds no jobs.
+func (s *MemoryStore) FindJob(ctx context.Context, id int) (mongodb.Job, error) {
+	return mongodb.Job{}, ErrNotStored
+}
+
+// PublicTracks returns the public tracks.
+func (s *MemoryStore) PublicTracks(ctx context.Context) ([]mongodb.Track, error) {
+	return s.find(mongodb.Track.Public), nil
+}
+
+// PublicTracksAfter returns the public tracks added after the timestamp.
+func (s *MemoryStore) PublicTracksAfter(ctx context.Context, ts int64) ([]mongodb.Track, error) {
+	return s.find(func(t mongodb.Track) bool { return t.Public() && t.Timestamp > ts }), nil
+}
+
+// RegisterWebhook checks the webhook like the service, and keeps it in memory.
+func (s *MemoryStore) RegisterWebhook(ctx context.Context, viewer user.Viewer, hook mongodb.Webhook) (string, error) {
+	hook, err := webhook.Check(viewer, hook)
+	if err != nil {
+		return "", err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, stored := range s.webhooks {
+		if stored.WebhookURL == hook.WebhookURL {
+			return "", mongodb.ErrWebhookExists
+		}
+	}
+	id := bson.NewObjectId().Hex()
+	s.webhooks[id] = hook
+	return id, nil
+}
+
+// FindWebhook returns the webhook with the ID.
+func (s *MemoryStore) FindWebhook(ctx context.Context, id string) (mongodb.Webhook, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	hook, ok := s.webhooks[id]
+	if !ok {
+		return mongodb.Webhook{}, ErrNotStored
+	}
+	return hook, nil
+}
+
+// DeleteWebhook deletes the webhook with the ID.
+func (s *MemoryStore) DeleteWebhook(ctx context.Context, id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.webhooks[id]; !ok {
+		return ErrNotStored
+	}
+	delete(s.webhooks, id)
+	return nil
+}
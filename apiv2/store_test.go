@@ -0,0 +1,51 @@
+/*
+  File: store_test.go
+  Contains unit tests for store.go
+*/
+
+package apiv2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/track"
+	"github.com/mats93/paragliding/user"
+)
+
+// Function to test: MemoryStore.TrackIDs() and MemoryStore.FindTrack().
+// Test that the memory store hides private and deleted tracks like the database.
+func Test_MemoryStore_Tracks(t *testing.T) {
+	store := NewMemoryStore()
+	store.InsertTrack(mongodb.Track{ID: 2, Privacy: mongodb.PrivacyPrivate, Owner: 7})
+	store.InsertTrack(mongodb.Track{ID: 1})
+	store.InsertTrack(mongodb.Track{ID: 3, Deleted: true})
+	ctx := context.Background()
+
+	ids, err := store.TrackIDs(ctx, user.Viewer{}, "")
+	if err != nil || len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("Function returned wrong IDs: got %v, %v want [1]", ids, err)
+	}
+	ids, _ = store.TrackIDs(ctx, user.Viewer{UserID: 7}, "")
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("Function returned wrong IDs for the owner: got %v want [1 2]", ids)
+	}
+	if _, err := store.TrackIDs(ctx, user.Viewer{}, "signed"); err != track.ErrUnknownValidation {
+		t.Errorf("Function returned wrong error: got %v want %v", err, track.ErrUnknownValidation)
+	}
+
+	if _, err := store.FindTrack(ctx, user.Viewer{}, 2); err != track.ErrNotFound {
+		t.Errorf("Function returned a private track: got %v want %v", err, track.ErrNotFound)
+	}
+	if _, err := store.FindTrack(ctx, user.Viewer{Admin: true}, 3); err != track.ErrNotFound {
+		t.Errorf("Function returned a deleted track: got %v want %v", err, track.ErrNotFound)
+	}
+
+	// A deleted track is gone.
+	found, _ := store.FindTrack(ctx, user.Viewer{}, 1)
+	store.DeleteTrack(ctx, found, "admin")
+	if _, err := store.FindTrack(ctx, user.Viewer{}, 1); err != track.ErrNotFound {
+		t.Errorf("Function returned the track after it was deleted: got %v want %v", err, track.ErrNotFound)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+	File: ticker.go
+  Contains the v2 handlers of the "Ticker paths", of the public tracks.
+*/
+
+package apiv2
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/ticker"
+)
+
+// Format for the timestamp of the last added track.
+type latest struct {
+	TimeLatest int64 `json:"t_latest"`
+}
+
+// Writes the timestamp information of the tracks, with no IDs if there are none.
+func writePage(w http.ResponseWriter, r *http.Request, tracks []mongodb.Track, err error, start time.Time) {
+	if err != nil {
+		writeInternal(w, r, err)
+		return
+	}
+	page, ok := ticker.Page(tracks, start)
+	if !ok {
+		page.Tracks = []int{}
+		page.Processing = time.Since(start)
+	}
+	writeData(w, http.StatusOK, page)
+}
+
+// GetTickerLatest - GET: Returns the timestamp of the last added track, 404 (Not found) if there are none.
+// Output: application/json
+func GetTickerLatest(w http.ResponseWriter, r *http.Request) {
+	// Gets the public tracks, the tickers are seen by everyone.
+	tracks, err := Data.PublicTracks(r.Context())
+	if err != nil {
+		writeInternal(w, r, err)
+		return
+	}
+
+	timestamp, ok := ticker.Latest(tracks)
+	if !ok {
+		writeError(w, r, http.StatusNotFound, CodeNotFound, "there are no tracks")
+		return
+	}
+	writeData(w, http.StatusOK, latest{timestamp})
+}
+
+// GetTicker - GET: Returns timestamp information of the first tracks.
+// Output: application/json
+func GetTicker(w http.ResponseWriter, r *http.Request) {
+	// Start time of the request.
+	start := time.Now()
+
+	tracks, err := Data.PublicTracks(r.Context())
+	writePage(w, r, tracks, err, start)
+}
+
+// GetTickerAfter - GET: Returns timestamp information of the tracks added after the provided '<timestamp>'.
+// Output: application/json
+func GetTickerAfter(w http.ResponseWriter, r *http.Request) {
+	// Start time of the request.
+	start := time.Now()
+
+	var ts int64
+	// Gets the timestamp from the URL.
+	fmt.Sscanf(r.URL.Path, Prefix+"/ticker/%d", &ts)
+
+	tracks, err := Data.PublicTracksAfter(r.Context(), ts)
+	writePage(w, r, tracks, err, start)
+}
@@ -0,0 +1,247 @@
+/*
+	File: track.go
+  Contains the v2 handlers of the "Track paths" and the jobs adding tracks.
+*/
+
+package apiv2
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mats93/paragliding/fetch"
+	"github.com/mats93/paragliding/grecord"
+	"github.com/mats93/paragliding/job"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/openapi"
+	"github.com/mats93/paragliding/track"
+	"github.com/mats93/paragliding/user"
+)
+
+// Format for a new track, the url of the IGC file, its privacy level and the clubs it is shared with.
+type registration struct {
+	URL     string `json:"url"`
+	Privacy string `json:"privacy"`
+	Clubs   []int  `json:"clubs"`
+}
+
+// Format for a track, with its ID.
+type trackData struct {
+	ID int `json:"id"`
+	mongodb.Track
+}
+
+// Format for a field of a track.
+type fieldData struct {
+	Field string      `json:"field"`
+	Value interface{} `json:"value"`
+}
+
+// Format for the ID of a job adding a track.
+type jobID struct {
+	JobID int `json:"job_id"`
+}
+
+// Reads the body of a request, and validates it against the schema of the OpenAPI document.
+// Writes the error response and returns false if it is not valid.
+func readBody(w http.ResponseWriter, r *http.Request, schema string) ([]byte, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = openapi.Validate(schema, body)
+	}
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, err.Error())
+		return nil, false
+	}
+	return body, true
+}
+
+// ListTracks - GET: Returns the IDs of the tracks the request can see, an empty list if there are none.
+// The tracks can be filtered by their G-record validation status with '?validation=<status>'.
+// Output: application/json
+func ListTracks(w http.ResponseWriter, r *http.Request) {
+	ids, err := Data.TrackIDs(r.Context(), user.ViewerOf(r), r.URL.Query().Get("validation"))
+	if err == track.ErrUnknownValidation {
+		writeError(w, r, http.StatusBadRequest, CodeBadRequest,
+			"unknown validation status, should be among: "+strings.Join(grecord.Statuses, ", "))
+	} else if err != nil {
+		writeInternal(w, r, err)
+	} else {
+		writeData(w, http.StatusOK, ids)
+	}
+}
+
+// AddTrack - POST: Adds the track at the url, owned by the logged in user. Returns 201 (Created) with the ID
+// of the track, or with '?async=true' 202 (Accepted) with the ID of the job adding it.
+// Input/Output: application/json
+func AddTrack(w http.ResponseWriter, r *http.Request) {
+	// Every track has an owner.
+	owner, ok := user.Authenticate(r)
+	if !ok {
+		writeDenied(w, r, false, "")
+		return
+	}
+
+	body, ok := readBody(w, r, "TrackRegistration")
+	if !ok {
+		return
+	}
+	var newTrack registration
+	json.Unmarshal(body, &newTrack)
+
+	switch track.CheckNew(owner.ID, newTrack.Privacy, newTrack.Clubs) {
+	case track.ErrInvalidPrivacy:
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, track.ErrInvalidPrivacy.Error())
+		return
+	case track.ErrNotMember:
+		writeDenied(w, r, true, track.ErrNotMember.Error())
+		return
+	}
+	if newTrack.Privacy == "" {
+		newTrack.Privacy = mongodb.PrivacyPublic
+	}
+
+	if r.URL.Query().Get("async") == "true" {
+		// Queues a job for the track, the status is found at the jobs path.
+		queued, err := Data.QueueTrack(r.Context(), newTrack.URL, owner.ID, newTrack.Privacy, newTrack.Clubs)
+		if err != nil {
+			writeError(w, r, http.StatusServiceUnavailable, CodeUnavailable, err.Error())
+			return
+		}
+		writeCreated(w, http.StatusAccepted, fmt.Sprintf("%s/job/%d", Prefix, queued), jobID{queued})
+		return
+	}
+
+	newID, err := Data.AddTrack(r.Context(), newTrack.URL, owner.ID, newTrack.Privacy, newTrack.Clubs)
+	if code := fetch.Code(err); code != "" {
+		// The url was not allowed, or the fetch failed.
+		writeError(w, r, http.StatusBadRequest, code, "could not fetch the IGC file, "+err.Error())
+	} else if err == track.ErrParse {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidIGC, err.Error())
+	} else if err != nil {
+		writeInternal(w, r, err)
+	} else {
+		writeCreated(w, http.StatusCreated, fmt.Sprintf("%s/track/%d", Prefix, newID), id{newID})
+	}
+}
+
+// Finds the track with the ID in the path. Writes the error response and returns false if it is not found.
+func findTrack(w http.ResponseWriter, r *http.Request) (mongodb.Track, bool) {
+	var id int
+	// Gets the ID from the URL and converts it to an integer.
+	fmt.Sscanf(r.URL.Path, Prefix+"/track/%d", &id)
+
+	t, err := Data.FindTrack(r.Context(), user.ViewerOf(r), id)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, CodeNotFound, err.Error())
+		return t, false
+	}
+	return t, true
+}
+
+// Finds the track with the ID in the path, and checks that the request may change it.
+// Writes the error response and returns false if not.
+func editableTrack(w http.ResponseWriter, r *http.Request) (mongodb.Track, string, bool) {
+	t, ok := findTrack(w, r)
+	if !ok {
+		return t, "", false
+	}
+	actor, ok := track.Authorize(r, t)
+	if !ok {
+		viewer := user.ViewerOf(r)
+		writeDenied(w, r, viewer.UserID != 0 || viewer.Admin, "only the owner of the track or admins can change it")
+		return t, "", false
+	}
+	return t, actor, true
+}
+
+// GetTrack - GET: Returns the track with the provided '<id>'.
+// Output: application/json
+func GetTrack(w http.ResponseWriter, r *http.Request) {
+	if t, ok := findTrack(w, r); ok {
+		writeData(w, http.StatusOK, trackData{t.ID, t})
+	}
+}
+
+// UpdateTrack - PATCH: Corrects the pilot, glider, glider ID, privacy level and clubs of the track with the
+// provided '<id>', only for the owner or admins.
+// Input/Output: application/json
+func UpdateTrack(w http.ResponseWriter, r *http.Request) {
+	t, actor, ok := editableTrack(w, r)
+	if !ok {
+		return
+	}
+
+	body, ok := readBody(w, r, "TrackUpdate")
+	if !ok {
+		return
+	}
+	var correction track.Correction
+	json.Unmarshal(body, &correction)
+
+	t, err := Data.CorrectTrack(r.Context(), user.ViewerOf(r), t, actor, correction)
+	switch err {
+	case nil:
+		writeData(w, http.StatusOK, trackData{t.ID, t})
+	case track.ErrInvalidPrivacy:
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, err.Error())
+	case track.ErrNotMember:
+		writeDenied(w, r, true, err.Error())
+	default:
+		writeInternal(w, r, err)
+	}
+}
+
+// DeleteTrack - DELETE: Deletes the track with the provided '<id>', only for the owner or admins.
+// Returns 204 (No content) without a body.
+func DeleteTrack(w http.ResponseWriter, r *http.Request) {
+	t, actor, ok := editableTrack(w, r)
+	if !ok {
+		return
+	}
+	if err := Data.DeleteTrack(r.Context(), t, actor); err != nil {
+		writeInternal(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetTrackField - GET: Returns the '<field>' of the track with the provided '<id>', as a json value.
+// Output: application/json
+func GetTrackField(w http.ResponseWriter, r *http.Request) {
+	t, ok := findTrack(w, r)
+	if !ok {
+		return
+	}
+
+	var field string
+	// Gets the field from the URL, after the ID.
+	fmt.Sscanf(r.URL.Path, Prefix+"/track/%d/%s", new(int), &field)
+
+	value, ok := track.Field(t, field)
+	if !ok {
+		writeError(w, r, http.StatusNotFound, CodeNotFound, "unknown field "+field)
+		return
+	}
+	writeData(w, http.StatusOK, fieldData{field, value})
+}
+
+// GetJob - GET: Returns the status of the job with the provided '<id>', with the track ID or error when finished.
+// Only the owner of the job and admins can see it.
+// Output: application/json
+func GetJob(w http.ResponseWriter, r *http.Request) {
+	var id int
+	// Gets the ID from the URL and converts it to an integer.
+	fmt.Sscanf(r.URL.Path, Prefix+"/job/%d", &id)
+
+	// Finds the job.
+	j, err := Data.FindJob(r.Context(), id)
+	if err != nil || !job.CanView(r, j) {
+		writeError(w, r, http.StatusNotFound, CodeNotFound, "the job does not exist")
+		return
+	}
+	writeData(w, http.StatusOK, j)
+}
@@ -0,0 +1,81 @@
+/*
+	File: webhook.go
+  Contains the v2 handlers of the "Webhook paths".
+*/
+
+package apiv2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mats93/paragliding/fetch"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/user"
+	"github.com/mats93/paragliding/webhook"
+)
+
+// Format for a webhook, with its ID.
+type webhookData struct {
+	ID string `json:"id"`
+	mongodb.Webhook
+}
+
+// AddWebhook - POST: Registers a webhook, returns 201 (Created) with its ID.
+// Input/Output: application/json
+func AddWebhook(w http.ResponseWriter, r *http.Request) {
+	body, ok := readBody(w, r, "WebhookRegistration")
+	if !ok {
+		return
+	}
+	var hook mongodb.Webhook
+	json.Unmarshal(body, &hook)
+
+	viewer := user.ViewerOf(r)
+	hookID, err := Data.RegisterWebhook(r.Context(), viewer, hook)
+	if code := fetch.Code(err); code != "" {
+		// The url is not allowed, e.g. an internal address.
+		writeError(w, r, http.StatusBadRequest, code, "the webhook url is not allowed, "+err.Error())
+	} else if err == webhook.ErrUnknownEvent {
+		writeError(w, r, http.StatusBadRequest, CodeInvalidBody, err.Error())
+	} else if err == webhook.ErrNotMember {
+		writeDenied(w, r, viewer.UserID != 0, err.Error())
+	} else if err == mongodb.ErrWebhookExists {
+		writeError(w, r, http.StatusConflict, CodeConflict, "the webhook already exists")
+	} else if err != nil {
+		writeInternal(w, r, err)
+	} else {
+		writeCreated(w, http.StatusCreated, Prefix+"/webhook/"+hookID, id{hookID})
+	}
+}
+
+// Returns the ID of the webhook in the path.
+func pathWebhookID(r *http.Request) string {
+	var id string
+	// Gets the ID from the URL and converts it to a string.
+	fmt.Sscanf(r.URL.Path, Prefix+"/webhook/%s", &id)
+	return id
+}
+
+// GetWebhook - GET: Returns the webhook with the provided '<id>'.
+// Output: application/json
+func GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id := pathWebhookID(r)
+	hook, err := Data.FindWebhook(r.Context(), id)
+	if err != nil {
+		writeError(w, r, http.StatusNotFound, CodeNotFound, "the webhook does not exist")
+		return
+	}
+	writeData(w, http.StatusOK, webhookData{id, hook})
+}
+
+// DeleteWebhook - DELETE: Deletes the webhook with the provided '<id>'.
+// Returns 204 (No content) without a body.
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := Data.DeleteWebhook(r.Context(), pathWebhookID(r)); err != nil {
+		writeError(w, r, http.StatusNotFound, CodeNotFound, "the webhook does not exist")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,43 @@
+/*
+	File: admin.go
+  Contains the methods of the client for the admin API, they need the admin token as the token of the client.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Path of the admin API.
+const adminPath = "/paragliding/admin/api"
+
+// TrackCount returns the number of tracks. Only for admins.
+func (c *Client) TrackCount(ctx context.Context) (int, error) {
+	_, data, err := c.send(ctx, "GET", adminPath+"/tracks_count", "", nil)
+	if err != nil {
+		return 0, err
+	}
+	return parseCount(data)
+}
+
+// DeleteAllTracks deletes all tracks, and returns how many were deleted. Only for admins.
+func (c *Client) DeleteAllTracks(ctx context.Context) (int, error) {
+	_, data, err := c.send(ctx, "DELETE", adminPath+"/tracks", "", nil)
+	if err != nil {
+		return 0, err
+	}
+	return parseCount(data)
+}
+
+// Parses the count in the text body of the admin API.
+func parseCount(data []byte) (int, error) {
+	count, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("paragliding: malformed response: %v", err)
+	}
+	return count, nil
+}
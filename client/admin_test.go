@@ -0,0 +1,39 @@
+/*
+	File: admin_test.go
+  Contains unit tests for admin.go
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// Function to test: TrackCount() and DeleteAllTracks().
+// Test that the admin API is not open without the admin token.
+func Test_Admin_Unauthorized(t *testing.T) {
+	c, _ := newTestClient(t, nil)
+	c.Token = "not-the-admin-token"
+
+	_, err := c.TrackCount(context.Background())
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Function returned wrong error: got %v want 401", err)
+	}
+	if _, err := c.DeleteAllTracks(context.Background()); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Function returned wrong error: got %v want 401", err)
+	}
+}
+
+// Function to test: parseCount().
+func Test_ParseCount(t *testing.T) {
+	if count, err := parseCount([]byte("12")); err != nil || count != 12 {
+		t.Errorf("Function returned wrong count: got %d, %v want 12", count, err)
+	}
+	if _, err := parseCount([]byte("Error: something")); err == nil {
+		t.Errorf("Function did not return an error for a malformed count")
+	}
+}
@@ -0,0 +1,109 @@
+/*
+	File: batch.go
+  Contains the method of the client importing local IGC files, with the batch import of the API.
+*/
+
+package client
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Path of the batch import, it is only in the v1 API.
+const batchPath = "/paragliding/api/track/batch"
+
+// IGCFile is a local IGC file to import.
+type IGCFile struct {
+	Name string
+	Data []byte
+}
+
+// ImportOptions are the privacy level of the imported tracks, public if empty, and the clubs they are
+// shared with. With DryRun the files are checked, but not stored.
+type ImportOptions struct {
+	Privacy string
+	Clubs   []int
+	DryRun  bool
+}
+
+// ImportResult is the result of a file of an import, Duplicate is set when the same IGC file is stored
+// as the track with the ID, and Error when it failed.
+type ImportResult struct {
+	Source    string `json:"source"`
+	ID        int    `json:"id,omitempty"`
+	Duplicate bool   `json:"duplicate"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Import is the result of an import, with a result for each file in the same order.
+type Import struct {
+	DryRun      bool           `json:"dry_run"`
+	Inserted    int            `json:"inserted"`
+	WouldInsert int            `json:"would_insert"`
+	Duplicates  int            `json:"duplicates"`
+	Failed      int            `json:"failed"`
+	Results     []ImportResult `json:"results"`
+}
+
+// ImportIGC imports local IGC files as tracks owned by the logged in user, sent as a zip.
+// The files that fail are in the results, only a failed request is returned as an error.
+func (c *Client) ImportIGC(ctx context.Context, files []IGCFile, options ImportOptions) (Import, error) {
+	var result Import
+
+	// Zips the files, the batch import only reads files ending with .igc.
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for i := 0; i < len(files); i++ {
+		name := filepath.Base(files[i].Name)
+		if !strings.HasSuffix(strings.ToLower(name), ".igc") {
+			name += ".igc"
+		}
+		writer, err := archive.Create(name)
+		if err != nil {
+			return result, err
+		}
+		if _, err := writer.Write(files[i].Data); err != nil {
+			return result, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return result, err
+	}
+
+	// The options are given in the query.
+	query := url.Values{}
+	if options.Privacy != "" {
+		query.Set("privacy", options.Privacy)
+	}
+	if len(options.Clubs) > 0 {
+		clubs := make([]string, len(options.Clubs))
+		for i := 0; i < len(options.Clubs); i++ {
+			clubs[i] = strconv.Itoa(options.Clubs[i])
+		}
+		query.Set("clubs", strings.Join(clubs, ","))
+	}
+	if options.DryRun {
+		query.Set("dry_run", "true")
+	}
+	path := batchPath
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	_, data, err := c.send(ctx, "POST", path, "application/zip", &buffer)
+	if err != nil {
+		return result, err
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return result, fmt.Errorf("paragliding: malformed response: %v", err)
+	}
+	return result, nil
+}
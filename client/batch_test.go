@@ -0,0 +1,24 @@
+/*
+	File: batch_test.go
+  Contains unit tests for batch.go
+*/
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// Function to test: ImportIGC().
+// Test that the text error of the v1 batch import is returned as *Error.
+func Test_ImportIGC_Unauthorized(t *testing.T) {
+	c, _ := newTestClient(t, nil)
+
+	_, err := c.ImportIGC(context.Background(), []IGCFile{{Name: "track.igc", Data: []byte("AXXX")}}, ImportOptions{})
+	apiErr, ok := err.(*Error)
+	if !ok || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Function returned wrong error: got %v want 401", err)
+	}
+}
@@ -0,0 +1,189 @@
+/*
+	File: client.go
+  Contains the client of the paragliding API, for Go programs using the v2 API, and the errors it returns.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors for the status codes the callers handle, compared with errors.Is.
+var (
+	// ErrNotFound is returned for 404 (Not found), the resource does not exist or is hidden.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned for 409 (Conflict), the resource exists already.
+	ErrConflict = errors.New("conflict")
+	// ErrRateLimited is returned for 429 (Too many requests), the request can be retried after RetryAfter.
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// Error is an error response of the API.
+type Error struct {
+	StatusCode int
+	// Code is the error code of the v2 API, like "not_found", empty if the body was not an envelope.
+	Code    string
+	Message string
+	// RequestID is the ID of the request, to find it in the logs of the service.
+	RequestID string
+	// RetryAfter is the time to wait before retrying, for 429 (Too many requests).
+	RetryAfter time.Duration
+}
+
+// Error returns the status code, and the message of the error.
+func (e *Error) Error() string {
+	message := e.Message
+	if message == "" {
+		message = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("paragliding: %d %s", e.StatusCode, message)
+}
+
+// Is makes errors.Is match ErrNotFound, ErrConflict and ErrRateLimited by the status code.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// Client is a client of the API at BaseURL, like "https://paragliding-api.herokuapp.com".
+type Client struct {
+	BaseURL string
+	// Token is the bearer token of a logged in user, or the admin token. Empty if not logged in.
+	Token string
+	// HTTPClient sends the requests, http.DefaultClient if nil.
+	HTTPClient *http.Client
+}
+
+// New returns a client of the API at the base url.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Path of the v2 API.
+const apiPath = "/paragliding/api/v2"
+
+// Format of the v2 response bodies, with either the data or the error.
+type envelope struct {
+	Data  json.RawMessage `json:"data"`
+	Error *struct {
+		Code      string `json:"code"`
+		Message   string `json:"message"`
+		RequestID string `json:"request_id"`
+	} `json:"error"`
+}
+
+// Sends a request to the path of the v2 API, with the body as json if it is not nil, and decodes the data
+// of the response into out if it is not nil.
+func (c *Client) do(ctx context.Context, method string, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	contentType := ""
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+		contentType = "application/json"
+	}
+
+	status, data, err := c.send(ctx, method, apiPath+path, contentType, reader)
+	if err != nil || out == nil || status == http.StatusNoContent {
+		return err
+	}
+
+	var result envelope
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("paragliding: malformed response: %v", err)
+	}
+	return json.Unmarshal(result.Data, out)
+}
+
+// Sends a request to the path, and returns the status code and body of the response.
+// Responses that are not 2xx are returned as *Error.
+func (c *Client) send(ctx context.Context, method string, path string, contentType string, body io.Reader) (int, []byte, error) {
+	request, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, data, responseError(response, data)
+	}
+	return response.StatusCode, data, nil
+}
+
+// Returns the error of a response. The body is an envelope with the error, or text from the routes
+// in front of the v2 API, like the rate limits.
+func responseError(response *http.Response, body []byte) error {
+	apiErr := &Error{StatusCode: response.StatusCode, Message: strings.TrimSpace(string(body))}
+
+	var result envelope
+	if json.Unmarshal(body, &result) == nil && result.Error != nil {
+		apiErr.Code = result.Error.Code
+		apiErr.Message = result.Error.Message
+		apiErr.RequestID = result.Error.RequestID
+	}
+	if apiErr.RequestID == "" {
+		apiErr.RequestID = response.Header.Get("X-Request-ID")
+	}
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return apiErr
+}
+
+// Info is the information about the API.
+type Info struct {
+	Uptime    string `json:"uptime"`
+	Info      string `json:"info"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+	Storage   string `json:"storage"`
+	Tracks    int    `json:"tracks"`
+	TickerCap int    `json:"ticker_cap"`
+}
+
+// Info returns the information about the API.
+func (c *Client) Info(ctx context.Context) (Info, error) {
+	var info Info
+	err := c.do(ctx, "GET", "", nil, &info)
+	return info, err
+}
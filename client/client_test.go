@@ -0,0 +1,117 @@
+/*
+	File: client_test.go
+  Contains unit tests for client.go, against the router of the API with the v2 data in memory.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mats93/paragliding/apiv2"
+	"github.com/mats93/paragliding/ratelimit"
+	"github.com/mats93/paragliding/server"
+	"github.com/mats93/paragliding/webhook"
+)
+
+// Starts the router of the API with an empty in-memory store, wrapped by the middleware if it is not nil,
+// and returns a client of it and the store. The store and the webhook URL policy are restored after the test.
+func newTestClient(t *testing.T, middleware func(http.Handler) http.Handler) (*Client, *apiv2.MemoryStore) {
+	store := apiv2.NewMemoryStore()
+	data, allowPrivate := apiv2.Data, webhook.URLPolicy.AllowPrivate
+	t.Cleanup(func() {
+		apiv2.Data, webhook.URLPolicy.AllowPrivate = data, allowPrivate
+	})
+	apiv2.Data = store
+	// The test webhooks use ".local" hosts that do not resolve, so their addresses are not checked.
+	webhook.URLPolicy.AllowPrivate = true
+
+	var handler http.Handler = server.Router()
+	if middleware != nil {
+		handler = middleware(handler)
+	}
+	testServer := httptest.NewServer(handler)
+	t.Cleanup(testServer.Close)
+	return New(testServer.URL + "/"), store
+}
+
+// Function to test: AddTrack().
+// Test that the error of the response is returned, with its code.
+func Test_Error_Unauthorized(t *testing.T) {
+	c, _ := newTestClient(t, nil)
+
+	_, err := c.AddTrack(context.Background(), NewTrack{URL: "http://test.test/track.igc"})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Function returned wrong error: got %v want *Error", err)
+	}
+	if apiErr.StatusCode != http.StatusUnauthorized || apiErr.Code != "unauthorized" {
+		t.Errorf("Function returned wrong error: got %d %s want %d %s",
+			apiErr.StatusCode, apiErr.Code, http.StatusUnauthorized, "unauthorized")
+	}
+}
+
+// Function to test: TrackIDs().
+// Test that a bad request is not matched by the typed errors.
+func Test_Error_BadRequest(t *testing.T) {
+	c, _ := newTestClient(t, nil)
+
+	_, err := c.TrackIDs(context.Background(), "signed")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Code != "bad_request" {
+		t.Errorf("Function returned wrong error: got %v want 400 bad_request", err)
+	}
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) || errors.Is(err, ErrRateLimited) {
+		t.Errorf("Function returned error matching the wrong type: %v", err)
+	}
+}
+
+// Function to test: do().
+// Test that the text response of the rate limits is ErrRateLimited, with the time to wait.
+func Test_Error_RateLimited(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.Config{
+		Ingest:  ratelimit.Limit{Requests: 1, Per: time.Minute},
+		Read:    ratelimit.Limit{Requests: 1, Per: time.Minute},
+		Webhook: ratelimit.Limit{Requests: 1, Per: time.Minute},
+	})
+	c, _ := newTestClient(t, limiter.Middleware)
+
+	// The first request is let through, and the second is over the limit.
+	c.TrackIDs(context.Background(), "signed")
+	_, err := c.TrackIDs(context.Background(), "signed")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Function returned wrong error: got %v want %v", err, ErrRateLimited)
+	}
+	var apiErr *Error
+	errors.As(err, &apiErr)
+	if apiErr.RetryAfter <= 0 || apiErr.Message != "Error: Too many requests, try again later" {
+		t.Errorf("Function returned wrong error: got %v retry after %v", apiErr.Message, apiErr.RetryAfter)
+	}
+}
+
+// Function to test: Error.Is().
+func Test_Error_Is(t *testing.T) {
+	tests := []struct {
+		status int
+		target error
+	}{
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusConflict, ErrConflict},
+		{http.StatusTooManyRequests, ErrRateLimited},
+	}
+
+	for i := 0; i < len(tests); i++ {
+		err := error(&Error{StatusCode: tests[i].status})
+		if !errors.Is(err, tests[i].target) {
+			t.Errorf("Function did not match %d with %v", tests[i].status, tests[i].target)
+		}
+		if errors.Is(&Error{StatusCode: http.StatusInternalServerError}, tests[i].target) {
+			t.Errorf("Function matched 500 with %v", tests[i].target)
+		}
+	}
+}
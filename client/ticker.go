@@ -0,0 +1,107 @@
+/*
+	File: ticker.go
+  Contains the methods of the client for the ticker of the public tracks, and the iterator paging through it.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TickerPage is a page of the ticker, with the IDs of at most the ticker cap tracks.
+// TStop is the timestamp of the last track of the page, the next page is after it.
+type TickerPage struct {
+	TLatest    int64         `json:"t_latest"`
+	TStart     int64         `json:"t_start"`
+	TStop      int64         `json:"t_stop"`
+	Tracks     []int         `json:"tracks"`
+	Processing time.Duration `json:"processing"`
+}
+
+// TickerLatest returns the timestamp of the last added public track, ErrNotFound if there are none.
+func (c *Client) TickerLatest(ctx context.Context) (int64, error) {
+	var result struct {
+		TLatest int64 `json:"t_latest"`
+	}
+	err := c.do(ctx, "GET", "/ticker/latest", nil, &result)
+	return result.TLatest, err
+}
+
+// Ticker returns the page of the public tracks added after the timestamp, the first page if it is 0.
+func (c *Client) Ticker(ctx context.Context, after int64) (TickerPage, error) {
+	path := "/ticker"
+	if after > 0 {
+		path = fmt.Sprintf("/ticker/%d", after)
+	}
+	var page TickerPage
+	err := c.do(ctx, "GET", path, nil, &page)
+	return page, err
+}
+
+// TickerIterator iterates over the IDs of the public tracks, a page at a time:
+//
+//	it := c.TickerIterator(ctx, 0)
+//	for it.Next() {
+//		fmt.Println(it.ID())
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type TickerIterator struct {
+	client *Client
+	ctx    context.Context
+	// The timestamp the next page is after.
+	after int64
+	// The IDs of the page left to iterate over.
+	ids []int
+	id  int
+	err error
+	// Set when the last page was empty.
+	done bool
+}
+
+// TickerIterator returns an iterator over the public tracks added after the timestamp, from the start if it is 0.
+// It follows t_stop of each page, and stops at the first empty page.
+func (c *Client) TickerIterator(ctx context.Context, after int64) *TickerIterator {
+	return &TickerIterator{client: c, ctx: ctx, after: after}
+}
+
+// Next advances to the next track, and returns false when there are no more tracks or a page failed.
+func (it *TickerIterator) Next() bool {
+	for len(it.ids) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		page, err := it.client.Ticker(it.ctx, it.after)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if len(page.Tracks) == 0 || page.TStop <= it.after {
+			it.done = true
+			return false
+		}
+		it.ids = page.Tracks
+		it.after = page.TStop
+	}
+	it.id, it.ids = it.ids[0], it.ids[1:]
+	return true
+}
+
+// ID returns the ID of the current track.
+func (it *TickerIterator) ID() int {
+	return it.id
+}
+
+// Timestamp returns the timestamp the next page is after, to continue from later.
+func (it *TickerIterator) Timestamp() int64 {
+	return it.after
+}
+
+// Err returns the error that stopped the iteration, nil if it stopped after the last track.
+func (it *TickerIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,48 @@
+/*
+	File: ticker_test.go
+  Contains unit tests for ticker.go
+*/
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/ticker"
+)
+
+// Function to test: TickerIterator().
+// Test that the iterator follows t_stop over more than one page, and stops after the last track.
+func Test_TickerIterator(t *testing.T) {
+	c, store := newTestClient(t, nil)
+
+	// Inserts more tracks than fit on a page.
+	count := ticker.CAP + 2
+	for i := 1; i <= count; i++ {
+		store.InsertTrack(mongodb.Track{ID: i, Timestamp: int64(i * 111), HDate: time.Now(), Pilot: "pilot"})
+	}
+
+	it := c.TickerIterator(context.Background(), 0)
+	var ids []int
+	for it.Next() {
+		ids = append(ids, it.ID())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Iterator returned an error: %v", err)
+	}
+
+	if len(ids) != count {
+		t.Fatalf("Iterator returned wrong number of tracks: got %v want %d", ids, count)
+	}
+	for i := 0; i < len(ids); i++ {
+		if ids[i] != i+1 {
+			t.Errorf("Iterator returned wrong track: got %d want %d", ids[i], i+1)
+		}
+	}
+	if it.Timestamp() != int64(count*111) {
+		t.Errorf("Iterator returned wrong timestamp: got %d want %d", it.Timestamp(), count*111)
+	}
+}
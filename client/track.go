@@ -0,0 +1,154 @@
+/*
+	File: track.go
+  Contains the methods of the client for tracks, and the jobs adding them.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Privacy levels of a track.
+const (
+	PrivacyPublic  = "public"
+	PrivacyClub    = "club"
+	PrivacyPrivate = "private"
+)
+
+// NewTrack is a track to add, from the IGC file at the url. Privacy is public if empty.
+type NewTrack struct {
+	URL     string `json:"url"`
+	Privacy string `json:"privacy,omitempty"`
+	Clubs   []int  `json:"clubs,omitempty"`
+}
+
+// Track is the metadata of a track. TrackLength is the length in km of all fixes on a sphere, and
+// TrackLengthClean of the flight only without the GPS jitter, on the WGS84 ellipsoid. MinAGL is the lowest
+// height in meters above the ground during the flight, nil if the ground is unknown, and Terrain "complete",
+// "partial" or "missing" by how much of the ground is known, empty if the server has no elevation tiles.
+type Track struct {
+	ID               int       `json:"id"`
+	HDate            time.Time `json:"H_date"`
+	Pilot            string    `json:"pilot"`
+	Glider           string    `json:"glider"`
+	GliderID         string    `json:"glider_id"`
+	TrackLength      float64   `json:"track_length"`
+	TrackLengthClean float64   `json:"track_length_clean"`
+	TrackSrcURL      string    `json:"track_src_url"`
+	TakeoffSite      int       `json:"takeoff_site"`
+	LandingSite      int       `json:"landing_site"`
+	MinAGL           *float64  `json:"min_agl"`
+	Terrain          string    `json:"terrain"`
+	Validation       string    `json:"validation"`
+	Owner            int       `json:"owner"`
+	Privacy          string    `json:"privacy"`
+	Clubs            []int     `json:"clubs"`
+}
+
+// Correction is a correction of a track, only the fields that are not nil are changed.
+type Correction struct {
+	Pilot    *string `json:"pilot,omitempty"`
+	Glider   *string `json:"glider,omitempty"`
+	GliderID *string `json:"glider_id,omitempty"`
+	Privacy  *string `json:"privacy,omitempty"`
+	Clubs    *[]int  `json:"clubs,omitempty"`
+}
+
+// Job statuses.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Job is a job adding a track, TrackID is set when it succeeded and Error when it failed.
+type Job struct {
+	ID      int       `json:"id"`
+	URL     string    `json:"url"`
+	Status  string    `json:"status"`
+	TrackID int       `json:"track_id"`
+	Error   string    `json:"error"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// Format for the ID of a new track or job.
+type id struct {
+	ID    int `json:"id"`
+	JobID int `json:"job_id"`
+}
+
+// AddTrack adds a track owned by the logged in user, and returns its ID.
+func (c *Client) AddTrack(ctx context.Context, track NewTrack) (int, error) {
+	var created id
+	err := c.do(ctx, "POST", "/track", track, &created)
+	return created.ID, err
+}
+
+// AddTrackAsync queues a job adding a track owned by the logged in user, and returns the ID of the job.
+func (c *Client) AddTrackAsync(ctx context.Context, track NewTrack) (int, error) {
+	var created id
+	err := c.do(ctx, "POST", "/track?async=true", track, &created)
+	return created.JobID, err
+}
+
+// Job returns the job with the ID, only the owner of the job and admins can see it.
+func (c *Client) Job(ctx context.Context, jobID int) (Job, error) {
+	var job Job
+	err := c.do(ctx, "GET", fmt.Sprintf("/job/%d", jobID), nil, &job)
+	return job, err
+}
+
+// TrackIDs returns the IDs of the tracks the client can see, only those with the G-record validation status
+// if it is not empty.
+func (c *Client) TrackIDs(ctx context.Context, validation string) ([]int, error) {
+	path := "/track"
+	if validation != "" {
+		path += "?validation=" + url.QueryEscape(validation)
+	}
+	var ids []int
+	err := c.do(ctx, "GET", path, nil, &ids)
+	return ids, err
+}
+
+// Track returns the track with the ID.
+func (c *Client) Track(ctx context.Context, trackID int) (Track, error) {
+	var track Track
+	err := c.do(ctx, "GET", fmt.Sprintf("/track/%d", trackID), nil, &track)
+	return track, err
+}
+
+// TrackField returns a field of the track with the ID as text, like "Pilot Name" or "102.7".
+func (c *Client) TrackField(ctx context.Context, trackID int, field string) (string, error) {
+	var result struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := c.do(ctx, "GET", fmt.Sprintf("/track/%d/%s", trackID, url.PathEscape(field)), nil, &result); err != nil {
+		return "", err
+	}
+
+	// Strings are unquoted, other values are returned as they are.
+	var text string
+	if json.Unmarshal(result.Value, &text) == nil {
+		return text, nil
+	}
+	return string(result.Value), nil
+}
+
+// CorrectTrack corrects the track with the ID, and returns the corrected track. Only for its owner or admins.
+func (c *Client) CorrectTrack(ctx context.Context, trackID int, correction Correction) (Track, error) {
+	var track Track
+	err := c.do(ctx, "PATCH", fmt.Sprintf("/track/%d", trackID), correction, &track)
+	return track, err
+}
+
+// DeleteTrack deletes the track with the ID. Only for its owner or admins.
+func (c *Client) DeleteTrack(ctx context.Context, trackID int) error {
+	return c.do(ctx, "DELETE", fmt.Sprintf("/track/%d", trackID), nil, nil)
+}
@@ -0,0 +1,39 @@
+/*
+	File: track_test.go
+  Contains unit tests for track.go
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mats93/paragliding/mongodb"
+)
+
+// Function to test: Track() and TrackField().
+// Test the metadata and a field of a track, and that an unknown track is ErrNotFound.
+func Test_Track(t *testing.T) {
+	c, store := newTestClient(t, nil)
+
+	// Inserts a track.
+	store.InsertTrack(mongodb.Track{ID: 1, Timestamp: 111, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1",
+		GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.local"})
+
+	actual, err := c.Track(context.Background(), 1)
+	if err != nil || actual.ID != 1 || actual.Pilot != "pilot1" || actual.TrackLength != 20.1 {
+		t.Errorf("Function returned wrong track: got %+v, %v", actual, err)
+	}
+
+	pilot, err := c.TrackField(context.Background(), 1, "pilot")
+	if err != nil || pilot != "pilot1" {
+		t.Errorf("Function returned wrong field: got %q, %v want %q", pilot, err, "pilot1")
+	}
+
+	if _, err := c.Track(context.Background(), 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Function returned wrong error: got %v want %v", err, ErrNotFound)
+	}
+}
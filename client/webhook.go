@@ -0,0 +1,51 @@
+/*
+	File: webhook.go
+  Contains the methods of the client for webhooks.
+*/
+
+package client
+
+import (
+	"context"
+	"net/url"
+)
+
+// Event types a webhook can subscribe to.
+const (
+	EventNewTrack = "new_track"
+	EventAirspace = "track.airspace"
+	EventDeleted  = "track.deleted"
+)
+
+// Webhook is a subscription to events. MinTriggerValue is the number of new tracks before it is notified,
+// 1 if 0. No events means new tracks only, and Club scopes it to the tracks shared with a club.
+type Webhook struct {
+	ID              string   `json:"id,omitempty"`
+	URL             string   `json:"webhookURL"`
+	MinTriggerValue int      `json:"minTriggerValue,omitempty"`
+	Events          []string `json:"events,omitempty"`
+	Club            int      `json:"club,omitempty"`
+}
+
+// AddWebhook registers a webhook, and returns its ID. ErrConflict is returned if the url is registered.
+func (c *Client) AddWebhook(ctx context.Context, hook Webhook) (string, error) {
+	// The ID is set by the service.
+	hook.ID = ""
+	var created struct {
+		ID string `json:"id"`
+	}
+	err := c.do(ctx, "POST", "/webhook", hook, &created)
+	return created.ID, err
+}
+
+// Webhook returns the webhook with the ID.
+func (c *Client) Webhook(ctx context.Context, webhookID string) (Webhook, error) {
+	var hook Webhook
+	err := c.do(ctx, "GET", "/webhook/"+url.PathEscape(webhookID), nil, &hook)
+	return hook, err
+}
+
+// DeleteWebhook deletes the webhook with the ID.
+func (c *Client) DeleteWebhook(ctx context.Context, webhookID string) error {
+	return c.do(ctx, "DELETE", "/webhook/"+url.PathEscape(webhookID), nil, nil)
+}
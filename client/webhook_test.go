@@ -0,0 +1,39 @@
+/*
+	File: webhook_test.go
+  Contains unit tests for webhook.go
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// Function to test: AddWebhook(), Webhook() and DeleteWebhook().
+// Test registering a webhook twice is ErrConflict, and that a deleted webhook is ErrNotFound.
+func Test_Webhook(t *testing.T) {
+	c, _ := newTestClient(t, nil)
+
+	hook := Webhook{URL: "http://test1.local", MinTriggerValue: 3}
+	id, err := c.AddWebhook(context.Background(), hook)
+	if err != nil || id == "" {
+		t.Fatalf("Function returned wrong ID: got %q, %v", id, err)
+	}
+	if _, err := c.AddWebhook(context.Background(), hook); !errors.Is(err, ErrConflict) {
+		t.Errorf("Function returned wrong error: got %v want %v", err, ErrConflict)
+	}
+
+	actual, err := c.Webhook(context.Background(), id)
+	if err != nil || actual.URL != hook.URL || actual.MinTriggerValue != 3 {
+		t.Errorf("Function returned wrong webhook: got %+v, %v", actual, err)
+	}
+
+	if err := c.DeleteWebhook(context.Background(), id); err != nil {
+		t.Errorf("Function returned an error: %v", err)
+	}
+	if _, err := c.Webhook(context.Background(), id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Function returned wrong error: got %v want %v", err, ErrNotFound)
+	}
+}
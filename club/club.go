@@ -0,0 +1,379 @@
+/*
+	File: club.go
+  Contains functions used by API calls to the "Club paths", clubs of users that share tracks.
+*/
+
+package club
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mats93/paragliding/admin"
+	"github.com/mats93/paragliding/internal/httpjson"
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/user"
+	"github.com/mats93/paragliding/webhook"
+)
+
+// Format for the body of a new club.
+type newClub struct {
+	Name string `json:"name"`
+}
+
+// Format for the body of a new member, or a new role of a member.
+type newMember struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// Format for the id to be returned when a new club is created.
+type id struct {
+	ID int `json:"id"`
+}
+
+// Format for a pilot on the leaderboard of a club.
+type leader struct {
+	UserID      int     `json:"user_id"`
+	Username    string  `json:"username"`
+	Flights     int     `json:"flights"`
+	TotalLength float64 `json:"total_length"`
+	Longest     float64 `json:"longest"`
+}
+
+// Collection is the MongoDB collection to use for clubs. Gets injected from main or test.
+var Collection string
+
+// CollectionTrack is the MongoDB collection to use for tracks. Gets injected from main or test.
+var CollectionTrack string
+
+// CollectionUser is the MongoDB collection to use for users. Gets injected from main or test.
+var CollectionUser string
+
+// Finds the club with the ID in the path, writes 404 and returns false if it does not exist.
+func findClub(w http.ResponseWriter, r *http.Request) (mongodb.Club, bool) {
+	var id int
+	// Gets the ID from the URL and converts it to an integer.
+	fmt.Sscanf(r.URL.Path, "/paragliding/api/club/%d", &id)
+
+	// Connects to the database, and finds the club.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+	club, err := database.FindClub(id)
+	if err != nil {
+		// A club with the given ID does not exist.
+		// Sets the header code to 404 (Not found).
+		w.WriteHeader(http.StatusNotFound)
+		return mongodb.Club{}, false
+	}
+	return club, true
+}
+
+// Checks if the request is made by an admin of the club, or an admin of the service.
+func isClubAdmin(r *http.Request, club mongodb.Club) bool {
+	if admin.IsAdmin(r) {
+		return true
+	}
+	account, ok := user.Authenticate(r)
+	return ok && club.Role(account.ID) == mongodb.ClubAdmin
+}
+
+// GET: Returns an array of all clubs, without their members.
+// Output: application/json
+func allClubs(w http.ResponseWriter, r *http.Request) {
+	// Connects to the database, and finds the clubs.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+	clubs, err := database.FindAllClubs()
+	if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+
+	if clubs == nil {
+		clubs = []mongodb.Club{}
+	}
+	for i := 0; i < len(clubs); i++ {
+		clubs[i].Members = nil
+	}
+	httpjson.Write(w, http.StatusOK, clubs)
+}
+
+// POST: Creates a new club, the logged in user becomes its admin. Returns the clubs ID.
+// Input/Output: application/json
+func insertNewClub(w http.ResponseWriter, r *http.Request) {
+	account, ok := user.Authenticate(r)
+	if !ok {
+		// Sets the header code to 401 (Unauthorized), and returns error message.
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Error: Log in to create clubs, with the 'Authorization: Bearer <token>' header"))
+		return
+	}
+
+	var body newClub
+
+	// Decodes the json and converts it to a struct.
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&body)
+	body.Name = strings.TrimSpace(body.Name)
+	if err != nil || body.Name == "" {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Malformed POST request, should be '{\"name\": \"<name>\"}'"))
+		return
+	}
+
+	// Connects to the database, and adds the club.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+	newID, err := database.InsertClub(mongodb.Club{
+		Name:    body.Name,
+		Members: []mongodb.Member{{UserID: account.ID, Role: mongodb.ClubAdmin}},
+		Created: time.Now().UTC(),
+	})
+	if err == mongodb.ErrClubExists {
+		// Sets header status code to 409 "Conflict", and returns error message.
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("Error: " + err.Error()))
+		return
+	} else if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+	httpjson.Write(w, http.StatusCreated, id{newID})
+}
+
+// HandleClubs - GET:  Returns an array of all clubs.
+// HandleClubs - POST: Creates a new club.
+// Input/Output: application/json
+func HandleClubs(w http.ResponseWriter, r *http.Request) {
+	// Calls functions to handle the GET and POST requests.
+	switch r.Method {
+	case "GET":
+		allClubs(w, r)
+
+	case "POST":
+		insertNewClub(w, r)
+
+	default:
+		// Wrong method.
+		// Sets status code to 400 (Bad request).
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// GetClub - GET: Returns the club with the provided '<id>'. The members are only shown to the members.
+// Output: application/json
+func GetClub(w http.ResponseWriter, r *http.Request) {
+	club, ok := findClub(w, r)
+	if !ok {
+		return
+	}
+
+	if !user.ViewerOf(r).Member(club.ID) {
+		club.Members = nil
+	}
+	httpjson.Write(w, http.StatusOK, club)
+}
+
+// POST: Adds a member to the club, or changes the role of a member, only for the club admins.
+// Input/Output: application/json
+func addMember(w http.ResponseWriter, r *http.Request, club mongodb.Club) {
+	var body newMember
+
+	// Decodes the json and converts it to a struct.
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&body)
+	if body.Role == "" {
+		body.Role = mongodb.ClubMember
+	}
+	if err != nil || body.Username == "" || (body.Role != mongodb.ClubMember && body.Role != mongodb.ClubAdmin) {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Malformed POST request, should be '{\"username\": \"<username>\", \"role\": <member or admin>}'"))
+		return
+	}
+
+	// Finds the user to add.
+	users := mongodb.DatabaseContext(r.Context(), CollectionUser)
+	account, err := users.FindUserByName(body.Username)
+	if err != nil {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: There is no user named " + body.Username))
+		return
+	}
+
+	// Connects to the database. Adds the user, or changes the role if the user is a member.
+	// The updates are atomic, so changes made by other admins at the same time are kept.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+	err = database.AddMember(club.ID, mongodb.Member{UserID: account.ID, Role: body.Role})
+	if err == mongodb.ErrMemberExists {
+		err = database.SetMemberRole(club.ID, account.ID, body.Role)
+	}
+	if err == nil {
+		club, err = database.FindClub(club.ID)
+	}
+	if !memberError(w, r, err) {
+		return
+	}
+	httpjson.Write(w, http.StatusOK, club)
+}
+
+// Writes the response for an error from a change of the members, and returns true if there was no error.
+func memberError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch err {
+	case nil:
+		return true
+	case mongodb.ErrLastAdmin:
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: The club must have an admin"))
+	case mongodb.ErrNotMember:
+		// The user is not a member, or was removed at the same time.
+		// Sets the header code to 404 (Not found).
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+	}
+	return false
+}
+
+// HandleMembers - POST: Adds a member to the club with the provided '<id>', or changes the role of a member.
+// Only for the admins of the club.
+// Input/Output: application/json
+func HandleMembers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		// Wrong method.
+		// Sets status code to 400 (Bad request).
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	club, ok := findClub(w, r)
+	if !ok {
+		return
+	}
+	if !isClubAdmin(r, club) {
+		// Sets the header code to 401 (Unauthorized), and returns error message.
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Error: Only the admins of the club can manage its members"))
+		return
+	}
+	addMember(w, r, club)
+}
+
+// RemoveMember - DELETE: Removes the user with the provided '<user_id>' from the club with the provided '<id>'.
+// Only for the admins of the club, and the member.
+func RemoveMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		// Wrong method.
+		// Sets status code to 400 (Bad request).
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	club, ok := findClub(w, r)
+	if !ok {
+		return
+	}
+
+	var clubID, userID int
+	// Gets the user ID from the URL and converts it to an integer.
+	fmt.Sscanf(r.URL.Path, "/paragliding/api/club/%d/members/%d", &clubID, &userID)
+
+	// Members can leave by themselves.
+	account, loggedIn := user.Authenticate(r)
+	if !isClubAdmin(r, club) && !(loggedIn && account.ID == userID) {
+		// Sets the header code to 401 (Unauthorized), and returns error message.
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Error: Only the admins of the club can manage its members"))
+		return
+	}
+
+	// Connects to the database, and removes the member.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+	if !memberError(w, r, database.RemoveMember(club.ID, userID)) {
+		return
+	}
+
+	// The webhooks the user registered for the club are no longer notified of its tracks.
+	if err := webhook.RemoveClubWebhooks(r.Context(), club.ID, userID); err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+	// Sets the header code to 204 (No content).
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Ranks the owners of the tracks by their total track length, the longest first.
+func leaderboard(tracks []mongodb.Track) []leader {
+	index := map[int]int{}
+	leaders := []leader{}
+	for i := 0; i < len(tracks); i++ {
+		j, ok := index[tracks[i].Owner]
+		if !ok {
+			j = len(leaders)
+			index[tracks[i].Owner] = j
+			leaders = append(leaders, leader{UserID: tracks[i].Owner})
+		}
+		leaders[j].Flights++
+		leaders[j].TotalLength += tracks[i].TrackLength
+		if tracks[i].TrackLength > leaders[j].Longest {
+			leaders[j].Longest = tracks[i].TrackLength
+		}
+	}
+
+	sort.SliceStable(leaders, func(i, j int) bool {
+		if leaders[i].TotalLength != leaders[j].TotalLength {
+			return leaders[i].TotalLength > leaders[j].TotalLength
+		}
+		return leaders[i].UserID < leaders[j].UserID
+	})
+	return leaders
+}
+
+// GetLeaderboard - GET: Returns the pilots of the tracks shared with the club with the provided '<id>', ranked by
+// their total track length. Only for the members of the club.
+// Output: application/json
+func GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	club, ok := findClub(w, r)
+	if !ok {
+		return
+	}
+	if !user.ViewerOf(r).Member(club.ID) {
+		// Sets the header code to 401 (Unauthorized).
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// Connects to the database, and finds the tracks shared with the club.
+	database := mongodb.DatabaseContext(r.Context(), CollectionTrack)
+	tracks, err := database.FindClubTracks(club.ID)
+	if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+
+	// Adds the usernames.
+	leaders := leaderboard(tracks)
+	users := mongodb.DatabaseContext(r.Context(), CollectionUser)
+	for i := 0; i < len(leaders); i++ {
+		if account, err := users.FindUser(leaders[i].UserID); err == nil {
+			leaders[i].Username = account.Username
+		}
+	}
+	httpjson.Write(w, http.StatusOK, leaders)
+}
@@ -0,0 +1,135 @@
+/*
+  File: club_test.go
+  Contains unit tests for club.go
+*/
+
+package club
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/user"
+	"github.com/mats93/paragliding/webhook"
+)
+
+// Function to test: leaderboard().
+// Test that the owners are ranked by their total track length.
+func Test_leaderboard(t *testing.T) {
+	tracks := []mongodb.Track{
+		{ID: 1, Owner: 1, TrackLength: 10},
+		{ID: 2, Owner: 2, TrackLength: 25},
+		{ID: 3, Owner: 1, TrackLength: 20},
+		{ID: 4, Owner: 3, TrackLength: 5},
+	}
+
+	actual := leaderboard(tracks)
+	if len(actual) != 3 {
+		t.Fatalf("Function returned wrong number of pilots: got %d want %d", len(actual), 3)
+	}
+	expected := leader{UserID: 1, Flights: 2, TotalLength: 30, Longest: 20}
+	if actual[0] != expected {
+		t.Errorf("Function returned wrong leader: got %v want %v", actual[0], expected)
+	}
+	if actual[1].UserID != 2 || actual[2].UserID != 3 {
+		t.Errorf("Function returned wrong order: got %v", actual)
+	}
+}
+
+// Function to test: HandleClubs().
+// Test that clubs can not be created without logging in.
+func Test_HandleClubs_POST_Unauthorized(t *testing.T) {
+	// Creates a POST request without a token that is passed to the handler.
+	request, _ := http.NewRequest("POST", "/paragliding/api/club", strings.NewReader("{\"name\": \"club\"}"))
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/club", HandleClubs).Methods("POST")
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (401).
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Handler returned wrong status code: got %v want %v", recorder.Code, http.StatusUnauthorized)
+	}
+}
+
+// Function to test: HandleMembers() and RemoveMember().
+// Test that the admin of a club can add members, that the last admin can not leave,
+// and that the club webhooks of a member that leaves are deleted.
+func Test_HandleMembers(t *testing.T) {
+	// Injects the MongoDB collections to use.
+	Collection = "TestClubs"
+	CollectionUser = "TestUsers"
+	user.Collection = CollectionUser
+	user.CollectionSession = "TestSessions"
+	user.CollectionClub = Collection
+	webhook.CollectionWebhook = "TestWebhooks"
+	defer func() { webhook.CollectionWebhook = "" }()
+
+	// Adds the users and a club with the first user as admin.
+	users := mongodb.DatabaseInit(CollectionUser)
+	adminID, _ := users.InsertUser(mongodb.User{Username: "clubadmin", Created: time.Now()})
+	memberID, _ := users.InsertUser(mongodb.User{Username: "clubmember", Created: time.Now()})
+	clubs := mongodb.DatabaseInit(Collection)
+	clubID, _ := clubs.InsertClub(mongodb.Club{Name: "club", Members: []mongodb.Member{{UserID: adminID, Role: mongodb.ClubAdmin}}})
+	adminToken, _, _ := user.NewToken(adminID)
+	memberToken, _, _ := user.NewToken(memberID)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/paragliding/api/club/{id:[0-9]+}/members", HandleMembers)
+	router.HandleFunc("/paragliding/api/club/{id:[0-9]+}/members/{user_id:[0-9]+}", RemoveMember)
+	request := func(method string, path string, body string, token string) int {
+		r, _ := http.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, r)
+		return recorder.Code
+	}
+	path := "/paragliding/api/club/" + strconv.Itoa(clubID) + "/members"
+
+	// A user that is not an admin of the club can not add members.
+	if code := request("POST", path, "{\"username\": \"clubmember\"}", memberToken); code != http.StatusUnauthorized {
+		t.Errorf("Handler returned wrong status code: got %v want %v", code, http.StatusUnauthorized)
+	}
+	// The admin can.
+	if code := request("POST", path, "{\"username\": \"clubmember\"}", adminToken); code != http.StatusOK {
+		t.Errorf("Handler returned wrong status code: got %v want %v", code, http.StatusOK)
+	}
+	if club, _ := clubs.FindClub(clubID); club.Role(memberID) != mongodb.ClubMember {
+		t.Errorf("Handler did not add the member: got %v", club.Members)
+	}
+	// The last admin can not leave.
+	if code := request("DELETE", path+"/"+strconv.Itoa(adminID), "", adminToken); code != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code: got %v want %v", code, http.StatusBadRequest)
+	}
+	// The member registers a webhook for the club.
+	webhooks := mongodb.DatabaseInit(webhook.CollectionWebhook)
+	hookID, err := webhooks.InsertWebhook(mongodb.Webhook{WebhookURL: "http://club.local", Club: clubID, Owner: memberID})
+	if err != nil {
+		t.Fatalf("Could not insert the webhook: %v", err)
+	}
+	// A member can leave.
+	if code := request("DELETE", path+"/"+strconv.Itoa(memberID), "", memberToken); code != http.StatusNoContent {
+		t.Errorf("Handler returned wrong status code: got %v want %v", code, http.StatusNoContent)
+	}
+	// The webhook of the member is deleted.
+	if _, err := webhooks.FindWebhook(hookID); err == nil {
+		t.Errorf("The club webhook of the member that left was not deleted")
+	}
+
+	// Deletes all from the database.
+	users.DeleteAll()
+	clubs.DeleteAll()
+	webhooks.DeleteAll()
+	sessions := mongodb.DatabaseInit(user.CollectionSession)
+	sessions.DeleteAll()
+}
@@ -0,0 +1,117 @@
+/*
+	File: admin.go
+  Contains the admin command, with the operations of the admin token.
+  The tracks are counted and deleted with the admin API, and corrected with the track routes of the API.
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/mats93/paragliding/client"
+)
+
+// admin - Counts the tracks, or deletes or corrects any track with the admin token.
+func runAdmin(e *env, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	if e.adminToken == "" {
+		return errors.New("the admin commands need the admin token, given with -admin-token or $PARAGLIDE_ADMIN_TOKEN")
+	}
+	admin := *e.client
+	admin.Token = e.adminToken
+
+	switch args[0] {
+	case "count":
+		if len(args) != 1 {
+			return errUsage
+		}
+		count, err := admin.TrackCount(e.ctx)
+		if err != nil {
+			return err
+		}
+		return e.out.line(map[string]int{"tracks": count}, fmt.Sprint(count))
+	case "delete-all":
+		// All tracks are gone, so it is only done with -yes.
+		flags := flag.NewFlagSet("admin delete-all", flag.ContinueOnError)
+		yes := flags.Bool("yes", false, "delete all tracks")
+		if err := parseFlags(e, flags, args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 0 || !*yes {
+			fmt.Fprintln(e.stderr, "paraglide: deletes all tracks, only with -yes")
+			return errUsage
+		}
+		count, err := admin.DeleteAllTracks(e.ctx)
+		if err != nil {
+			return err
+		}
+		return e.out.line(map[string]int{"deleted": count}, fmt.Sprintf("deleted %d", count))
+	case "delete":
+		if len(args) != 2 {
+			return errUsage
+		}
+		id, err := parseID(e, args[1])
+		if err != nil {
+			return err
+		}
+		if err := admin.DeleteTrack(e.ctx, id); err != nil {
+			return err
+		}
+		return e.out.line(map[string]int{"deleted": id}, fmt.Sprintf("deleted %d", id))
+	case "correct":
+		return correctTrack(e, &admin, args[1:])
+	}
+	return errUsage
+}
+
+// Corrects the fields of a track given by the flags, and prints the corrected track.
+func correctTrack(e *env, admin *client.Client, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	id, err := parseID(e, args[0])
+	if err != nil {
+		return err
+	}
+	flags := flag.NewFlagSet("admin correct", flag.ContinueOnError)
+	pilot := flags.String("pilot", "", "name of the pilot")
+	glider := flags.String("glider", "", "name of the glider")
+	gliderID := flags.String("glider-id", "", "ID of the glider")
+	privacy := flags.String("privacy", "", "privacy level, public, club or private")
+	if err := parseFlags(e, flags, args[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errUsage
+	}
+
+	// Only the given flags are corrected.
+	var correction client.Correction
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "pilot":
+			correction.Pilot = pilot
+		case "glider":
+			correction.Glider = glider
+		case "glider-id":
+			correction.GliderID = gliderID
+		case "privacy":
+			correction.Privacy = privacy
+		}
+	})
+	if correction == (client.Correction{}) {
+		fmt.Fprintln(e.stderr, "paraglide: nothing to correct")
+		return errUsage
+	}
+
+	t, err := admin.CorrectTrack(e.ctx, id, correction)
+	if err != nil {
+		return err
+	}
+	return e.out.fields(t, trackFields(t))
+}
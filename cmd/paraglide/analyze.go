@@ -0,0 +1,143 @@
+/*
+	File: analyze.go
+  Contains the analyze command, analyzing local IGC files without the API.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mats93/paragliding/analysis"
+	"github.com/mats93/paragliding/terrain"
+)
+
+// Format for the analysis of a file.
+type analyzed struct {
+	File string `json:"file"`
+	analysis.Analysis
+	Error string `json:"error,omitempty"`
+}
+
+// analyze - Prints the metadata the API would store for local IGC files, with the statistics and scores of
+// the flights. One file is printed as fields, more as a table. The sites are not matched, since they are
+// in the database. With -keep-outliers the outliers are only flagged, not removed. With -dem (or
+// $PARAGLIDE_DEM) the heights above the ground are found from the elevation tiles in the directory.
+func runAnalyze(e *env, args []string) error {
+	flags := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	keepOutliers := flags.Bool("keep-outliers", false, "flag the outliers, without removing them")
+	dem := flags.String("dem", os.Getenv("PARAGLIDE_DEM"), "directory of SRTM/HGT elevation tiles")
+	if err := parseFlags(e, flags, args); err != nil {
+		return err
+	}
+	args = flags.Args()
+	if len(args) == 0 {
+		return errUsage
+	}
+	analysis.Cleaning.RemoveOutliers = !*keepOutliers
+	analysis.GroundElevation = nil
+	if *dem != "" {
+		tiles, err := terrain.Open(*dem)
+		if err != nil {
+			return err
+		}
+		analysis.GroundElevation = tiles.Elevation
+	}
+
+	results := make([]analyzed, len(args))
+	var lastErr error
+	failed := 0
+	for i := 0; i < len(args); i++ {
+		results[i].File = args[i]
+		content, err := os.ReadFile(args[i])
+		if err == nil {
+			results[i].Analysis, err = analysis.Analyze(string(content))
+		}
+		if err != nil {
+			results[i].Error = err.Error()
+			lastErr = fmt.Errorf("%s: %v", args[i], err)
+			failed++
+		}
+	}
+
+	if len(results) == 1 {
+		if lastErr != nil {
+			return lastErr
+		}
+		if err := e.out.fields(results[0], analysisFields(results[0].Analysis)); err != nil {
+			return err
+		}
+	} else {
+		headers := []string{"FILE", "DATE", "PILOT", "GLIDER", "LENGTH (KM)", "DURATION", "MAX ALT (M)", "FREE DIST (KM)", "VALIDATION"}
+		rows := make([][]string, len(results))
+		for i := 0; i < len(results); i++ {
+			a := results[i].Analysis
+			if results[i].Error != "" {
+				// The errors are printed to stderr, so the table stays aligned.
+				fmt.Fprintf(e.stderr, "paraglide: %s: %s\n", results[i].File, results[i].Error)
+				rows[i] = []string{results[i].File, "-", "-", "-", "-", "-", "-", "-", "failed"}
+				continue
+			}
+			rows[i] = []string{
+				results[i].File,
+				a.Track.HDate.Format("2006-01-02"),
+				a.Track.Pilot,
+				a.Track.Glider,
+				fmt.Sprintf("%.1f", a.Track.TrackLength),
+				duration(a.Stats.Duration),
+				fmt.Sprint(a.Stats.MaxAltitude),
+				fmt.Sprintf("%.1f", a.Score.FreeDistance),
+				a.Track.Validation,
+			}
+		}
+		if err := e.out.table(results, headers, rows); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case failed == 0:
+		return nil
+	case failed == len(results):
+		return lastErr
+	}
+	return errPartial
+}
+
+// Returns the fields of the analysis of a file.
+func analysisFields(a analysis.Analysis) [][2]string {
+	return [][2]string{
+		{"date", a.Track.HDate.Format("2006-01-02")},
+		{"pilot", a.Track.Pilot},
+		{"glider", a.Track.Glider},
+		{"glider_id", a.Track.GliderID},
+		{"track_length", fmt.Sprintf("%.3f km", a.Track.TrackLength)},
+		{"track_length_clean", fmt.Sprintf("%.3f km", a.Track.TrackLengthClean)},
+		{"validation", a.Track.Validation},
+		{"checksum", a.Track.Checksum},
+		{"fixes", fmt.Sprint(a.Stats.Fixes)},
+		{"start", a.Stats.Start.Format(time.RFC3339)},
+		{"end", a.Stats.End.Format(time.RFC3339)},
+		{"duration", duration(a.Stats.Duration)},
+		{"min_altitude", fmt.Sprintf("%d m", a.Stats.MinAltitude)},
+		{"max_altitude", fmt.Sprintf("%d m", a.Stats.MaxAltitude)},
+		{"max_pressure_altitude", fmt.Sprintf("%d m", a.Stats.MaxPressureAltitude)},
+		{"altitude_gain", fmt.Sprintf("%d m", a.Stats.AltitudeGain)},
+		{"min_agl", agl(a.Track.MinAGL, a.Track.Terrain)},
+		{"straight_distance", fmt.Sprintf("%.3f km", a.Score.StraightDistance)},
+		{"max_distance", fmt.Sprintf("%.3f km", a.Score.MaxDistance)},
+		{"free_distance", fmt.Sprintf("%.3f km", a.Score.FreeDistance)},
+		{"duplicates", fmt.Sprint(a.Quality.Duplicates)},
+		{"outliers", fmt.Sprint(len(a.Quality.Outliers))},
+		{"removed", fmt.Sprint(a.Quality.Removed)},
+		{"gaps", fmt.Sprint(len(a.Quality.Gaps))},
+	}
+}
+
+// Returns the seconds as a duration, like "1h5m0s".
+func duration(seconds int64) string {
+	return (time.Duration(seconds) * time.Second).String()
+}
@@ -0,0 +1,68 @@
+/*
+	File: analyze_test.go
+  Contains unit tests for analyze.go
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+// The made up flight of the analysis tests.
+var triangle = filepath.Join("..", "..", "analysis", "testdata", "triangle.igc")
+
+// Function to test: runAnalyze().
+// Test that a local file is analyzed without the API, and printed as json.
+func Test_runAnalyze(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"-api", "http://invalid.invalid", "-o", "json", "analyze", triangle}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("paraglide returned wrong exit code: got %d want %d, %s", code, exitOK, stderr.String())
+	}
+
+	var actual analyzed
+	if err := json.Unmarshal(stdout.Bytes(), &actual); err != nil {
+		t.Fatalf("paraglide returned invalid json: %v", err)
+	}
+	if actual.File != triangle || actual.Track.Pilot != "Test Pilot" || actual.Stats.Fixes != 6 {
+		t.Errorf("paraglide returned wrong analysis: got %+v", actual)
+	}
+}
+
+// Function to test: runAnalyze().
+// Test that a file that can not be analyzed exits with exitPartial, and all failing with exitError.
+func Test_runAnalyze_Failed(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	missing := filepath.Join("testdata", "missing.igc")
+
+	code := run(context.Background(), []string{"analyze", triangle, missing}, &stdout, &stderr)
+	if code != exitPartial {
+		t.Errorf("paraglide returned wrong exit code: got %d want %d", code, exitPartial)
+	}
+	code = run(context.Background(), []string{"analyze", missing}, &stdout, &stderr)
+	if code != exitError {
+		t.Errorf("paraglide returned wrong exit code: got %d want %d", code, exitError)
+	}
+}
+
+// Function to test: runAnalyze().
+// Test that a missing directory of elevation tiles is an error, and a directory without the tiles is not.
+func Test_runAnalyze_DEM(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"analyze", "-dem", filepath.Join(t.TempDir(), "missing"), triangle}, &stdout, &stderr)
+	if code != exitError {
+		t.Errorf("paraglide returned wrong exit code: got %d want %d", code, exitError)
+	}
+
+	stdout.Reset()
+	code = run(context.Background(), []string{"-o", "json", "analyze", "-dem", t.TempDir(), triangle}, &stdout, &stderr)
+	var actual analyzed
+	if err := json.Unmarshal(stdout.Bytes(), &actual); code != exitOK || err != nil || actual.Track.Terrain != "missing" {
+		t.Errorf("paraglide returned wrong analysis: got %d, %s", code, stdout.String())
+	}
+}
@@ -0,0 +1,232 @@
+/*
+	File: main.go
+  Contains the paraglide command, a command-line tool for the paragliding API, built on the client package.
+  Usage: paraglide [-api <url>] [-token <token>] [-o table|json] <command> [arguments]
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/mats93/paragliding/client"
+)
+
+// Exit codes, so scripts can tell the failures apart.
+const (
+	exitOK = 0
+	// exitError is any other failure, like a failed connection or a server error.
+	exitError = 1
+	// exitUsage is a wrong command, flag or argument.
+	exitUsage = 2
+	// exitNotFound is a track, job or webhook that does not exist.
+	exitNotFound = 3
+	// exitConflict is a webhook that is registered already.
+	exitConflict = 4
+	// exitRateLimited is a request over the rate limits, it can be retried later.
+	exitRateLimited = 5
+	// exitDenied is a request that needs a token, or a token that is not allowed.
+	exitDenied = 6
+	// exitPartial is an upload where some of the files failed.
+	exitPartial = 7
+)
+
+// The default url of the API.
+const defaultAPI = "https://paragliding-api.herokuapp.com"
+
+// errUsage is returned by the commands for wrong arguments, the usage of the command is printed.
+var errUsage = errors.New("wrong arguments")
+
+// errPartial is returned when some of the tracks of an upload failed, they are in the output.
+var errPartial = errors.New("some of the tracks failed")
+
+// Environment of a command.
+type env struct {
+	ctx    context.Context
+	client *client.Client
+	// The admin token, for the admin commands.
+	adminToken string
+	out        *printer
+	stderr     io.Writer
+}
+
+// A command, with the usage of its arguments.
+type command struct {
+	usage string
+	run   func(e *env, args []string) error
+}
+
+// The commands, by name.
+var commands = map[string]command{
+	"info":    {"", runInfo},
+	"upload":  {"[-privacy <level>] [-clubs <id>,<id>] [-async] [-dry-run] <file or url> ...", runUpload},
+	"list":    {"[-validation <status>]", runList},
+	"search":  {"[-pilot <name>] [-glider <name>] [-glider-id <id>] [-validation <status>] [-from <date>] [-to <date>]", runSearch},
+	"show":    {"<id>", runShow},
+	"field":   {"<id> <field>", runField},
+	"job":     {"<id>", runJob},
+	"tail":    {"[-from <timestamp>] [-interval <duration>] [-tracks]", runTail},
+	"webhook": {"add -url <url> [-min-trigger <n>] [-event <type>] [-club <id>] | get <id> | delete <id>", runWebhook},
+	"analyze": {"[-keep-outliers] [-dem <dir>] <file> ...", runAnalyze},
+	"admin":   {"count | delete-all -yes | delete <id> | correct <id> [-pilot <name>] [-glider <name>] [-glider-id <id>] [-privacy <level>]", runAdmin},
+}
+
+func main() {
+	// Stops the commands on Ctrl-C, like tail.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// Runs the command of the arguments, and returns the exit code.
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("paraglide", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	api := flags.String("api", envOr("PARAGLIDE_API", defaultAPI), "url of the API, or $PARAGLIDE_API")
+	token := flags.String("token", os.Getenv("PARAGLIDE_TOKEN"), "token of the logged in user, or $PARAGLIDE_TOKEN")
+	adminToken := flags.String("admin-token", os.Getenv("PARAGLIDE_ADMIN_TOKEN"), "admin token, or $PARAGLIDE_ADMIN_TOKEN")
+	format := flags.String("o", formatTable, "output format, table or json")
+	flags.Usage = func() { usage(stderr, flags) }
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		usage(stderr, flags)
+		return exitUsage
+	}
+	if *format != formatTable && *format != formatJSON {
+		fmt.Fprintf(stderr, "paraglide: unknown output format %q, should be table or json\n", *format)
+		usage(stderr, flags)
+		return exitUsage
+	}
+
+	name := flags.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "paraglide: unknown command %q\n", name)
+		usage(stderr, flags)
+		return exitUsage
+	}
+
+	c := client.New(*api)
+	c.Token = *token
+	e := &env{ctx, c, *adminToken, &printer{stdout, *format}, stderr}
+	err := cmd.run(e, flags.Args()[1:])
+	if err == errUsage {
+		fmt.Fprintln(stderr, "usage: paraglide "+strings.TrimSpace(name+" "+cmd.usage))
+	} else if err != nil && err != errPartial {
+		fmt.Fprintf(stderr, "paraglide: %v\n", err)
+	}
+	return exitCode(err)
+}
+
+// Returns the exit code of the error of a command.
+func exitCode(err error) int {
+	var apiErr *client.Error
+	switch {
+	case err == nil:
+		return exitOK
+	case err == errUsage:
+		return exitUsage
+	case err == errPartial:
+		return exitPartial
+	case errors.Is(err, client.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, client.ErrConflict):
+		return exitConflict
+	case errors.Is(err, client.ErrRateLimited):
+		return exitRateLimited
+	case errors.As(err, &apiErr) &&
+		(apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden):
+		return exitDenied
+	}
+	return exitError
+}
+
+// Prints the usage of paraglide, and its commands.
+func usage(w io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintln(w, "usage: paraglide [flags] <command> [arguments]")
+	fmt.Fprintln(w, "\nflags:")
+	flags.SetOutput(w)
+	flags.PrintDefaults()
+	fmt.Fprintln(w, "\ncommands:")
+	names := []string{"info", "upload", "list", "search", "show", "field", "job", "tail", "webhook", "admin", "analyze"}
+	for i := 0; i < len(names); i++ {
+		fmt.Fprintln(w, "  "+strings.TrimSpace(names[i]+" "+commands[names[i]].usage))
+	}
+}
+
+// Returns the enviroment var, or the default if it is not set.
+func envOr(name string, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
+
+// Parses the flags of a command, wrong flags are errUsage.
+func parseFlags(e *env, flags *flag.FlagSet, args []string) error {
+	flags.SetOutput(io.Discard)
+	if err := flags.Parse(args); err != nil {
+		fmt.Fprintf(e.stderr, "paraglide: %v\n", err)
+		return errUsage
+	}
+	return nil
+}
+
+// Returns the comma separated IDs, like "1,2".
+func parseIDs(text string) ([]int, error) {
+	var ids []int
+	if text == "" {
+		return ids, nil
+	}
+	parts := strings.Split(text, ",")
+	for i := 0; i < len(parts); i++ {
+		var id int
+		if _, err := fmt.Sscanf(strings.TrimSpace(parts[i]), "%d", &id); err != nil {
+			return nil, fmt.Errorf("%q is not an ID", parts[i])
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Returns the ID of the argument, a wrong ID is errUsage.
+func parseID(e *env, arg string) (int, error) {
+	var id int
+	if _, err := fmt.Sscanf(arg, "%d", &id); err != nil || fmt.Sprint(id) != arg {
+		fmt.Fprintf(e.stderr, "paraglide: %q is not an ID\n", arg)
+		return 0, errUsage
+	}
+	return id, nil
+}
+
+// info - Prints the information about the API.
+func runInfo(e *env, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	info, err := e.client.Info(e.ctx)
+	if err != nil {
+		return err
+	}
+	return e.out.fields(info, [][2]string{
+		{"uptime", info.Uptime},
+		{"info", info.Info},
+		{"version", info.Version},
+		{"commit", info.Commit},
+		{"go_version", info.GoVersion},
+		{"storage", info.Storage},
+		{"tracks", fmt.Sprint(info.Tracks)},
+		{"ticker_cap", fmt.Sprint(info.TickerCap)},
+	})
+}
@@ -0,0 +1,114 @@
+/*
+	File: main_test.go
+  Contains unit tests for main.go, against the router of the API.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mats93/paragliding/client"
+	"github.com/mats93/paragliding/ratelimit"
+	"github.com/mats93/paragliding/server"
+)
+
+// Runs paraglide against the router of the API, wrapped by the middleware if it is not nil,
+// and returns the exit code and the output.
+func runTest(middleware func(http.Handler) http.Handler, args ...string) (int, string, string) {
+	var handler http.Handler = server.Router()
+	if middleware != nil {
+		handler = middleware(handler)
+	}
+	testServer := httptest.NewServer(handler)
+	defer testServer.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), append([]string{"-api", testServer.URL, "-token", ""}, args...), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// Function to test: run().
+// Test that wrong commands, flags and arguments exit with exitUsage, and print the usage.
+func Test_run_Usage(t *testing.T) {
+	tests := [][]string{
+		{},
+		{"fly"},
+		{"-o", "xml", "list"},
+		{"show"},
+		{"show", "twelve"},
+		{"list", "-unknown"},
+		{"search", "-from", "yesterday"},
+		{"webhook", "add"},
+		{"upload", "-dry-run", "http://test.test/track.igc"},
+	}
+
+	for i := 0; i < len(tests); i++ {
+		code, _, stderr := runTest(nil, tests[i]...)
+		if code != exitUsage {
+			t.Errorf("paraglide %v returned wrong exit code: got %d want %d", tests[i], code, exitUsage)
+		}
+		if !strings.Contains(stderr, "usage: paraglide") {
+			t.Errorf("paraglide %v did not print the usage: %s", tests[i], stderr)
+		}
+	}
+}
+
+// Function to test: run().
+// Test that uploading without a token exits with exitDenied, and the error is printed.
+func Test_run_Unauthorized(t *testing.T) {
+	code, stdout, stderr := runTest(nil, "-o", "json", "upload", "http://test.test/track.igc")
+	if code != exitDenied {
+		t.Errorf("paraglide returned wrong exit code: got %d want %d", code, exitDenied)
+	}
+	if !strings.Contains(stdout, `"source": "http://test.test/track.igc"`) || !strings.Contains(stderr, "401") {
+		t.Errorf("paraglide returned wrong output: got %s and %s", stdout, stderr)
+	}
+}
+
+// Function to test: run().
+// Test that a request over the rate limits exits with exitRateLimited.
+func Test_run_RateLimited(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.Config{
+		Ingest:  ratelimit.Limit{Requests: 1, Per: time.Minute},
+		Read:    ratelimit.Limit{Requests: 1, Per: time.Minute},
+		Webhook: ratelimit.Limit{Requests: 1, Per: time.Minute},
+	})
+	runTest(limiter.Middleware, "list", "-validation", "signed")
+	code, _, _ := runTest(limiter.Middleware, "list", "-validation", "signed")
+	if code != exitRateLimited {
+		t.Errorf("paraglide returned wrong exit code: got %d want %d", code, exitRateLimited)
+	}
+}
+
+// Function to test: exitCode().
+func Test_exitCode(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{nil, exitOK},
+		{errUsage, exitUsage},
+		{errPartial, exitPartial},
+		{&client.Error{StatusCode: http.StatusNotFound}, exitNotFound},
+		{&client.Error{StatusCode: http.StatusConflict}, exitConflict},
+		{&client.Error{StatusCode: http.StatusTooManyRequests}, exitRateLimited},
+		{&client.Error{StatusCode: http.StatusUnauthorized}, exitDenied},
+		{&client.Error{StatusCode: http.StatusForbidden}, exitDenied},
+		{&client.Error{StatusCode: http.StatusBadRequest}, exitError},
+		{errors.New("connection refused"), exitError},
+	}
+
+	for i := 0; i < len(tests); i++ {
+		if actual := exitCode(tests[i].err); actual != tests[i].expected {
+			t.Errorf("Function returned wrong exit code for %v: got %d want %d", tests[i].err, actual, tests[i].expected)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+/*
+	File: output.go
+  Contains the printing of the output of the commands, as aligned tables or json.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mats93/paragliding/client"
+)
+
+// Output formats.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// Prints the output of the commands in the format.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+// Prints the value as json, indented.
+func (p *printer) json(value interface{}) error {
+	encoder := json.NewEncoder(p.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// Prints the value as json, or the rows as a table with the headers.
+func (p *printer) table(value interface{}, headers []string, rows [][]string) error {
+	if p.format == formatJSON {
+		return p.json(value)
+	}
+	w := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for i := 0; i < len(rows); i++ {
+		fmt.Fprintln(w, strings.Join(rows[i], "\t"))
+	}
+	return w.Flush()
+}
+
+// Prints the value as json, or the named fields as a table of two columns.
+func (p *printer) fields(value interface{}, fields [][2]string) error {
+	if p.format == formatJSON {
+		return p.json(value)
+	}
+	w := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	for i := 0; i < len(fields); i++ {
+		fmt.Fprintf(w, "%s:\t%s\n", fields[i][0], fields[i][1])
+	}
+	return w.Flush()
+}
+
+// Prints the value as a line of json, or the text. Used for streams, like tail.
+func (p *printer) line(value interface{}, text string) error {
+	if p.format == formatJSON {
+		return json.NewEncoder(p.w).Encode(value)
+	}
+	_, err := fmt.Fprintln(p.w, text)
+	return err
+}
+
+// Headers of the tables of tracks.
+var trackHeaders = []string{"ID", "DATE", "PILOT", "GLIDER", "GLIDER ID", "LENGTH (KM)", "VALIDATION", "PRIVACY"}
+
+// Returns the row of a track, in the table of tracks.
+func trackRow(t client.Track) []string {
+	return []string{
+		fmt.Sprint(t.ID),
+		t.HDate.Format("2006-01-02"),
+		t.Pilot,
+		t.Glider,
+		t.GliderID,
+		fmt.Sprintf("%.1f", t.TrackLength),
+		t.Validation,
+		t.Privacy,
+	}
+}
+
+// Returns the fields of a track, with its metadata and statistics.
+func trackFields(t client.Track) [][2]string {
+	fields := [][2]string{
+		{"id", fmt.Sprint(t.ID)},
+		{"date", t.HDate.Format(time.RFC3339)},
+		{"pilot", t.Pilot},
+		{"glider", t.Glider},
+		{"glider_id", t.GliderID},
+		{"track_length", fmt.Sprintf("%.3f km", t.TrackLength)},
+		{"track_length_clean", fmt.Sprintf("%.3f km", t.TrackLengthClean)},
+		{"takeoff_site", site(t.TakeoffSite)},
+		{"landing_site", site(t.LandingSite)},
+		{"min_agl", agl(t.MinAGL, t.Terrain)},
+		{"validation", t.Validation},
+		{"privacy", t.Privacy},
+		{"owner", fmt.Sprint(t.Owner)},
+		{"track_src_url", t.TrackSrcURL},
+	}
+	if len(t.Clubs) > 0 {
+		fields = append(fields, [2]string{"clubs", strings.Trim(fmt.Sprint(t.Clubs), "[]")})
+	}
+	return fields
+}
+
+// Returns the min AGL as text, with the terrain if the ground is not known under all the fixes.
+// It is "-" if it is unknown.
+func agl(minAGL *float64, terrain string) string {
+	text := "-"
+	if minAGL != nil {
+		text = fmt.Sprintf("%.0f m", *minAGL)
+	}
+	if terrain != "" && terrain != "complete" {
+		text += " (terrain " + terrain + ")"
+	}
+	return text
+}
+
+// Returns the ID of a site as text, "-" if the track is not from or to a known site.
+func site(id int) string {
+	if id == 0 {
+		return "-"
+	}
+	return fmt.Sprint(id)
+}
@@ -0,0 +1,33 @@
+/*
+	File: output_test.go
+  Contains unit tests for output.go
+*/
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Method to test: printer.table().
+// Test that the columns are aligned as a table, and that json prints the value.
+func Test_printer_table(t *testing.T) {
+	var buffer bytes.Buffer
+	p := &printer{&buffer, formatTable}
+	p.table(nil, []string{"ID", "PILOT"}, [][]string{{"1", "Aladin"}, {"12", "Jafar"}})
+
+	expected := "ID  PILOT\n1   Aladin\n12  Jafar\n"
+	if buffer.String() != expected {
+		t.Errorf("Function returned wrong table: got %q want %q", buffer.String(), expected)
+	}
+
+	buffer.Reset()
+	p.format = formatJSON
+	p.table([]int{1, 12}, []string{"ID"}, [][]string{{"1"}, {"12"}})
+
+	expected = "[\n  1,\n  12\n]\n"
+	if buffer.String() != expected {
+		t.Errorf("Function returned wrong json: got %q want %q", buffer.String(), expected)
+	}
+}
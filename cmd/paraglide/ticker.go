@@ -0,0 +1,102 @@
+/*
+	File: ticker.go
+  Contains the tail command, following the ticker of the public tracks.
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/mats93/paragliding/client"
+)
+
+// tail - Prints the public tracks as they are added, until interrupted.
+// Starts after the last track, or after the timestamp given with -from (0 is from the first track).
+func runTail(e *env, args []string) error {
+	flags := flag.NewFlagSet("tail", flag.ContinueOnError)
+	from := flags.Int64("from", -1, "timestamp to start after, 0 for the first track")
+	interval := flags.Duration("interval", 10*time.Second, "time between polls of the ticker")
+	details := flags.Bool("tracks", false, "print the metadata of the tracks, not only the IDs")
+	if err := parseFlags(e, flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 || *interval <= 0 {
+		return errUsage
+	}
+
+	after := *from
+	if after < 0 {
+		latest, err := e.client.TickerLatest(e.ctx)
+		if err != nil && !errors.Is(err, client.ErrNotFound) {
+			return err
+		}
+		// No tracks yet starts from the first track.
+		after = latest
+	}
+
+	for {
+		it := e.client.TickerIterator(e.ctx, after)
+		for it.Next() {
+			if err := printTicked(e, it.ID(), *details); err != nil {
+				return err
+			}
+		}
+		after = it.Timestamp()
+		if err := it.Err(); err != nil {
+			if e.ctx.Err() != nil {
+				// Interrupted.
+				return nil
+			}
+			if !errors.Is(err, client.ErrRateLimited) {
+				return err
+			}
+			// Waits until the rate limit allows more requests.
+			var apiErr *client.Error
+			errors.As(err, &apiErr)
+			if apiErr.RetryAfter > *interval {
+				fmt.Fprintf(e.stderr, "paraglide: rate limited, retrying in %v\n", apiErr.RetryAfter)
+				if !sleep(e, apiErr.RetryAfter) {
+					return nil
+				}
+				continue
+			}
+		}
+		if !sleep(e, *interval) {
+			return nil
+		}
+	}
+}
+
+// Prints a track of the ticker, its ID or its metadata.
+func printTicked(e *env, id int, details bool) error {
+	if !details {
+		return e.out.line(map[string]int{"id": id}, fmt.Sprint(id))
+	}
+	t, err := e.client.Track(e.ctx, id)
+	if errors.Is(err, client.ErrNotFound) {
+		// Deleted since it was added.
+		return nil
+	} else if err != nil {
+		return err
+	}
+	row := trackRow(t)
+	text := row[0]
+	for i := 1; i < len(row); i++ {
+		text += "\t" + row[i]
+	}
+	return e.out.line(t, text)
+}
+
+// Sleeps for the duration, and returns false if interrupted.
+func sleep(e *env, d time.Duration) bool {
+	select {
+	case <-e.ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
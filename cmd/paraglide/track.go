@@ -0,0 +1,320 @@
+/*
+	File: track.go
+  Contains the commands for tracks: upload, list, search, show, field and job.
+*/
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mats93/paragliding/client"
+)
+
+// Format for the result of an uploaded file or url.
+type uploadResult struct {
+	Source    string `json:"source"`
+	ID        int    `json:"id,omitempty"`
+	JobID     int    `json:"job_id,omitempty"`
+	Duplicate bool   `json:"duplicate,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// upload - Uploads IGC files, local files are imported in one zip and urls are added one by one.
+// The exit code is errPartial when some of them failed, and the error when all of them failed.
+func runUpload(e *env, args []string) error {
+	flags := flag.NewFlagSet("upload", flag.ContinueOnError)
+	privacy := flags.String("privacy", "", "privacy level, public, club or private")
+	clubs := flags.String("clubs", "", "IDs of the clubs to share the tracks with")
+	async := flags.Bool("async", false, "queue the urls as jobs, and print the job IDs")
+	dryRun := flags.Bool("dry-run", false, "check the local files, without storing them")
+	if err := parseFlags(e, flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errUsage
+	}
+	clubIDs, err := parseIDs(*clubs)
+	if err != nil {
+		fmt.Fprintf(e.stderr, "paraglide: %v\n", err)
+		return errUsage
+	}
+
+	// Splits the arguments into urls, and local files.
+	var urls []string
+	var files []client.IGCFile
+	for i := 0; i < flags.NArg(); i++ {
+		arg := flags.Arg(i)
+		if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
+			urls = append(urls, arg)
+			continue
+		}
+		data, err := os.ReadFile(arg)
+		if err != nil {
+			return err
+		}
+		files = append(files, client.IGCFile{Name: arg, Data: data})
+	}
+	if *dryRun && len(urls) > 0 {
+		fmt.Fprintln(e.stderr, "paraglide: -dry-run only checks local files")
+		return errUsage
+	}
+
+	var results []uploadResult
+	var lastErr error
+	failed := 0
+
+	// Adds the tracks of the urls.
+	for i := 0; i < len(urls); i++ {
+		result := uploadResult{Source: urls[i]}
+		track := client.NewTrack{URL: urls[i], Privacy: *privacy, Clubs: clubIDs}
+		if *async {
+			result.JobID, err = e.client.AddTrackAsync(e.ctx, track)
+		} else {
+			result.ID, err = e.client.AddTrack(e.ctx, track)
+		}
+		if err != nil {
+			result.Error = err.Error()
+			lastErr = err
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	// Imports the local files.
+	if len(files) > 0 {
+		imported, err := e.client.ImportIGC(e.ctx, files, client.ImportOptions{Privacy: *privacy, Clubs: clubIDs, DryRun: *dryRun})
+		if err != nil {
+			// The request failed, so all the files failed.
+			lastErr = err
+			for i := 0; i < len(files); i++ {
+				results = append(results, uploadResult{Source: files[i].Name, Error: err.Error()})
+				failed++
+			}
+		} else {
+			for i := 0; i < len(imported.Results); i++ {
+				r := imported.Results[i]
+				// The import names the files "zip:<file name>", the results are in the same order as the files.
+				source := r.Source
+				if i < len(files) {
+					source = files[i].Name
+				}
+				results = append(results, uploadResult{Source: source, ID: r.ID, Duplicate: r.Duplicate, Error: r.Error})
+				if r.Error != "" {
+					lastErr = errors.New(r.Error)
+					failed++
+				}
+			}
+		}
+	}
+
+	// Prints the results.
+	rows := make([][]string, len(results))
+	for i := 0; i < len(results); i++ {
+		status := "added"
+		switch {
+		case results[i].Error != "":
+			status = "failed: " + results[i].Error
+		case results[i].JobID != 0:
+			status = fmt.Sprintf("queued as job %d", results[i].JobID)
+		case results[i].Duplicate:
+			status = "duplicate"
+		case *dryRun:
+			status = "ok"
+		}
+		rows[i] = []string{results[i].Source, trackID(results[i].ID), status}
+	}
+	if err := e.out.table(results, []string{"SOURCE", "ID", "STATUS"}, rows); err != nil {
+		return err
+	}
+
+	switch {
+	case failed == 0:
+		return nil
+	case failed == len(results):
+		return lastErr
+	}
+	return errPartial
+}
+
+// Returns the ID of a track as text, "-" if there is none.
+func trackID(id int) string {
+	if id == 0 {
+		return "-"
+	}
+	return fmt.Sprint(id)
+}
+
+// list - Prints the IDs of the tracks.
+func runList(e *env, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	validation := flags.String("validation", "", "only the tracks with the G-record validation status")
+	if err := parseFlags(e, flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errUsage
+	}
+
+	ids, err := e.client.TrackIDs(e.ctx, *validation)
+	if err != nil {
+		return err
+	}
+	rows := make([][]string, len(ids))
+	for i := 0; i < len(ids); i++ {
+		rows[i] = []string{fmt.Sprint(ids[i])}
+	}
+	return e.out.table(ids, []string{"ID"}, rows)
+}
+
+// Criteria of a search, empty criteria match all tracks.
+type criteria struct {
+	pilot      string
+	glider     string
+	gliderID   string
+	from       time.Time
+	to         time.Time
+	validation string
+}
+
+// Checks if the track matches the criteria. Names match if they contain the criteria, ignoring case.
+// The dates are inclusive.
+func (c criteria) match(t client.Track) bool {
+	contains := func(value string, part string) bool {
+		return strings.Contains(strings.ToLower(value), strings.ToLower(part))
+	}
+	if !contains(t.Pilot, c.pilot) || !contains(t.Glider, c.glider) {
+		return false
+	}
+	if c.gliderID != "" && !strings.EqualFold(t.GliderID, c.gliderID) {
+		return false
+	}
+	if !c.from.IsZero() && t.HDate.Before(c.from) {
+		return false
+	}
+	if !c.to.IsZero() && !t.HDate.Before(c.to.AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
+}
+
+// search - Prints the tracks matching the criteria.
+func runSearch(e *env, args []string) error {
+	var c criteria
+	var from, to string
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	flags.StringVar(&c.pilot, "pilot", "", "part of the name of the pilot")
+	flags.StringVar(&c.glider, "glider", "", "part of the name of the glider")
+	flags.StringVar(&c.gliderID, "glider-id", "", "ID of the glider")
+	flags.StringVar(&c.validation, "validation", "", "G-record validation status")
+	flags.StringVar(&from, "from", "", "first date, like 2016-02-19")
+	flags.StringVar(&to, "to", "", "last date, like 2016-02-19")
+	if err := parseFlags(e, flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errUsage
+	}
+	var err error
+	if from != "" {
+		if c.from, err = time.Parse("2006-01-02", from); err != nil {
+			fmt.Fprintf(e.stderr, "paraglide: -from %q is not a date, like 2016-02-19\n", from)
+			return errUsage
+		}
+	}
+	if to != "" {
+		if c.to, err = time.Parse("2006-01-02", to); err != nil {
+			fmt.Fprintf(e.stderr, "paraglide: -to %q is not a date, like 2016-02-19\n", to)
+			return errUsage
+		}
+	}
+
+	// The API filters by validation status, the other criteria are checked on each track.
+	ids, err := e.client.TrackIDs(e.ctx, c.validation)
+	if err != nil {
+		return err
+	}
+	tracks := []client.Track{}
+	for i := 0; i < len(ids); i++ {
+		t, err := e.client.Track(e.ctx, ids[i])
+		if errors.Is(err, client.ErrNotFound) {
+			// Deleted since the IDs were listed.
+			continue
+		} else if err != nil {
+			return err
+		}
+		if c.match(t) {
+			tracks = append(tracks, t)
+		}
+	}
+
+	rows := make([][]string, len(tracks))
+	for i := 0; i < len(tracks); i++ {
+		rows[i] = trackRow(tracks[i])
+	}
+	return e.out.table(tracks, trackHeaders, rows)
+}
+
+// show - Prints the metadata of a track.
+func runShow(e *env, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	id, err := parseID(e, args[0])
+	if err != nil {
+		return err
+	}
+	t, err := e.client.Track(e.ctx, id)
+	if err != nil {
+		return err
+	}
+	return e.out.fields(t, trackFields(t))
+}
+
+// field - Prints a field of a track.
+func runField(e *env, args []string) error {
+	if len(args) != 2 {
+		return errUsage
+	}
+	id, err := parseID(e, args[0])
+	if err != nil {
+		return err
+	}
+	value, err := e.client.TrackField(e.ctx, id, args[1])
+	if err != nil {
+		return err
+	}
+	return e.out.line(map[string]string{"field": args[1], "value": value}, value)
+}
+
+// job - Prints a job adding a track.
+func runJob(e *env, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	id, err := parseID(e, args[0])
+	if err != nil {
+		return err
+	}
+	job, err := e.client.Job(e.ctx, id)
+	if err != nil {
+		return err
+	}
+	fields := [][2]string{
+		{"id", fmt.Sprint(job.ID)},
+		{"url", job.URL},
+		{"status", job.Status},
+		{"track_id", trackID(job.TrackID)},
+		{"created", job.Created.Format(time.RFC3339)},
+		{"updated", job.Updated.Format(time.RFC3339)},
+	}
+	if job.Error != "" {
+		fields = append(fields, [2]string{"error", job.Error})
+	}
+	return e.out.fields(job, fields)
+}
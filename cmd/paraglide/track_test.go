@@ -0,0 +1,46 @@
+/*
+	File: track_test.go
+  Contains unit tests for track.go
+*/
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mats93/paragliding/client"
+)
+
+// Method to test: criteria.match().
+// Test that names match by part ignoring case, and that the dates are inclusive.
+func Test_criteria_match(t *testing.T) {
+	track := client.Track{
+		HDate:    time.Date(2016, 2, 19, 14, 30, 0, 0, time.UTC),
+		Pilot:    "Miguel Angel Gordillo",
+		Glider:   "RV8",
+		GliderID: "EC-XLL",
+	}
+	day := time.Date(2016, 2, 19, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		criteria criteria
+		expected bool
+	}{
+		{criteria{}, true},
+		{criteria{pilot: "miguel"}, true},
+		{criteria{pilot: "gordillo", glider: "rv"}, true},
+		{criteria{pilot: "aladin"}, false},
+		{criteria{gliderID: "ec-xll"}, true},
+		{criteria{gliderID: "EC"}, false},
+		{criteria{from: day, to: day}, true},
+		{criteria{from: day.AddDate(0, 0, 1)}, false},
+		{criteria{to: day.AddDate(0, 0, -1)}, false},
+	}
+
+	for i := 0; i < len(tests); i++ {
+		if actual := tests[i].criteria.match(track); actual != tests[i].expected {
+			t.Errorf("Function returned wrong match for %+v: got %v want %v", tests[i].criteria, actual, tests[i].expected)
+		}
+	}
+}
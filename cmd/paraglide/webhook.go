@@ -0,0 +1,98 @@
+/*
+	File: webhook.go
+  Contains the webhook command, registering, printing and deleting webhooks.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/mats93/paragliding/client"
+)
+
+// Flag that can be given more than once, like -event.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// webhook - Registers, prints or deletes a webhook.
+func runWebhook(e *env, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "add":
+		return addWebhook(e, args[1:])
+	case "get":
+		if len(args) != 2 {
+			return errUsage
+		}
+		hook, err := e.client.Webhook(e.ctx, args[1])
+		if err != nil {
+			return err
+		}
+		return e.out.fields(hook, webhookFields(hook))
+	case "delete":
+		if len(args) != 2 {
+			return errUsage
+		}
+		if err := e.client.DeleteWebhook(e.ctx, args[1]); err != nil {
+			return err
+		}
+		return e.out.line(map[string]string{"deleted": args[1]}, "deleted "+args[1])
+	}
+	return errUsage
+}
+
+// Registers a webhook, and prints its ID.
+func addWebhook(e *env, args []string) error {
+	var hook client.Webhook
+	var events listFlag
+	flags := flag.NewFlagSet("webhook add", flag.ContinueOnError)
+	flags.StringVar(&hook.URL, "url", "", "url to notify")
+	flags.IntVar(&hook.MinTriggerValue, "min-trigger", 0, "number of new tracks before it is notified, 1 if 0")
+	flags.Var(&events, "event", "event type, new_track, track.airspace or track.deleted, can be given more than once")
+	flags.IntVar(&hook.Club, "club", 0, "ID of the club, to only be notified about the tracks shared with it")
+	if err := parseFlags(e, flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 || hook.URL == "" {
+		return errUsage
+	}
+	hook.Events = events
+
+	id, err := e.client.AddWebhook(e.ctx, hook)
+	if err != nil {
+		return err
+	}
+	return e.out.line(map[string]string{"id": id}, id)
+}
+
+// Returns the fields of a webhook.
+func webhookFields(hook client.Webhook) [][2]string {
+	events := strings.Join(hook.Events, ", ")
+	if events == "" {
+		events = client.EventNewTrack
+	}
+	club := "-"
+	if hook.Club != 0 {
+		club = fmt.Sprint(hook.Club)
+	}
+	return [][2]string{
+		{"id", hook.ID},
+		{"url", hook.URL},
+		{"min_trigger", fmt.Sprint(hook.MinTriggerValue)},
+		{"events", events},
+		{"club", club},
+	}
+}
@@ -0,0 +1,115 @@
+/*
+	File: distance.go
+  Contains the distance between two positions, on a sphere (haversine) or on the WGS84 ellipsoid (Vincenty).
+*/
+
+package distance
+
+import (
+	"fmt"
+	"math"
+)
+
+// Model is a model of the earth the distances are calculated on.
+type Model string
+
+// Models of the earth.
+const (
+	// Haversine is a sphere with the mean radius of the earth, the model of goigc.
+	// It is off by up to 0.5 % from the ellipsoid, depending on the latitude and direction.
+	Haversine Model = "haversine"
+	// WGS84 is the ellipsoid of GPS, with the distances calculated by the formula of Vincenty, accurate to
+	// less than a millimeter. Nearly antipodal positions, where it does not converge, fall back to Haversine.
+	WGS84 Model = "wgs84"
+)
+
+// EarthRadius is the mean radius of the earth in km, the same as goigc.
+const EarthRadius = 6371.0
+
+// Parameters of the WGS84 ellipsoid, in meters.
+const (
+	wgs84A = 6378137.0
+	wgs84F = 1 / 298.257223563
+	wgs84B = wgs84A * (1 - wgs84F)
+)
+
+// ParseModel returns the model with the name, haversine or wgs84.
+func ParseModel(name string) (Model, error) {
+	switch Model(name) {
+	case Haversine, WGS84:
+		return Model(name), nil
+	}
+	return "", fmt.Errorf("unknown distance model %q, should be haversine or wgs84", name)
+}
+
+// Distance returns the distance in km between two positions in degrees, on the model.
+func (m Model) Distance(lat1, lng1, lat2, lng2 float64) float64 {
+	if m == WGS84 {
+		if d, ok := Vincenty(lat1, lng1, lat2, lng2); ok {
+			return d
+		}
+	}
+	return HaversineDistance(lat1, lng1, lat2, lng2)
+}
+
+// HaversineDistance returns the great circle distance in km between two positions in degrees.
+func HaversineDistance(lat1, lng1, lat2, lng2 float64) float64 {
+	lat1r, lat2r := radians(lat1), radians(lat2)
+	dLat, dLng := radians(lat2-lat1), radians(lng2-lng1)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1r)*math.Cos(lat2r)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Vincenty returns the distance in km between two positions in degrees on the WGS84 ellipsoid, with the
+// inverse formula of Vincenty. False is returned if it does not converge, for nearly antipodal positions.
+func Vincenty(lat1, lng1, lat2, lng2 float64) (float64, bool) {
+	L := radians(lng2 - lng1)
+	U1 := math.Atan((1 - wgs84F) * math.Tan(radians(lat1)))
+	U2 := math.Atan((1 - wgs84F) * math.Tan(radians(lat2)))
+	sinU1, cosU1 := math.Sincos(U1)
+	sinU2, cosU2 := math.Sincos(U2)
+
+	lambda := L
+	var sinSigma, cosSigma, sigma, cosSqAlpha, cos2SigmaM float64
+	converged := false
+	for i := 0; i < 200; i++ {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		x := cosU2 * sinLambda
+		y := cosU1*sinU2 - sinU1*cosU2*cosLambda
+		sinSigma = math.Sqrt(x*x + y*y)
+		if sinSigma == 0 {
+			// The same position.
+			return 0, true
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha = 1 - sinAlpha*sinAlpha
+		cos2SigmaM = 0
+		if cosSqAlpha != 0 {
+			// Both positions are not on the equator.
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		}
+		C := wgs84F / 16 * cosSqAlpha * (4 + wgs84F*(4-3*cosSqAlpha))
+		previous := lambda
+		lambda = L + (1-C)*wgs84F*sinAlpha*(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-previous) < 1e-12 {
+			converged = true
+			break
+		}
+	}
+	if !converged {
+		return 0, false
+	}
+
+	u2 := cosSqAlpha * (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+	A := 1 + u2/16384*(4096+u2*(-768+u2*(320-175*u2)))
+	B := u2 / 1024 * (256 + u2*(-128+u2*(74-47*u2)))
+	dSigma := B * sinSigma * (cos2SigmaM + B/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-B/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+	return wgs84B * A * (sigma - dSigma) / 1000, true
+}
+
+// Converts degrees to radians.
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
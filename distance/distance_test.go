@@ -0,0 +1,67 @@
+/*
+	File: distance_test.go
+  Contains unit tests for distance.go
+*/
+
+package distance
+
+import (
+	"math"
+	"testing"
+)
+
+// Function to test: Vincenty().
+// Test with the example of Vincenty's paper, from Flinders Peak to Buninyong, 54972.271 m.
+func Test_Vincenty(t *testing.T) {
+	lat1 := -(37 + 57/60.0 + 3.72030/3600)
+	lng1 := 144 + 25/60.0 + 29.52440/3600
+	lat2 := -(37 + 39/60.0 + 10.15610/3600)
+	lng2 := 143 + 55/60.0 + 35.38390/3600
+
+	actual, ok := Vincenty(lat1, lng1, lat2, lng2)
+	if !ok || math.Abs(actual-54.972271) > 0.000001 {
+		t.Errorf("Function returned wrong distance: got %v, %v want %v", actual, ok, 54.972271)
+	}
+	if actual, ok := Vincenty(60, 6, 60, 6); !ok || actual != 0 {
+		t.Errorf("Function returned wrong distance for the same position: got %v, %v", actual, ok)
+	}
+	// Nearly antipodal positions do not converge.
+	if _, ok := Vincenty(0, 0, 0.5, 179.7); ok {
+		t.Error("Function converged for nearly antipodal positions")
+	}
+}
+
+// Method to test: Model.Distance().
+// Test the models along a meridian, where the ellipsoid differs the most from the sphere.
+func Test_Model_Distance(t *testing.T) {
+	// A degree of latitude is 111.195 km on the sphere, and 111.412 km at 60 degrees on the ellipsoid.
+	tests := []struct {
+		model    Model
+		expected float64
+	}{
+		{Haversine, 111.195},
+		{WGS84, 111.412},
+	}
+
+	for i := 0; i < len(tests); i++ {
+		actual := tests[i].model.Distance(59.5, 6, 60.5, 6)
+		if math.Abs(actual-tests[i].expected) > 0.001 {
+			t.Errorf("Function returned wrong distance for %s: got %v want %v", tests[i].model, actual, tests[i].expected)
+		}
+	}
+
+	// Nearly antipodal positions fall back to the sphere.
+	if actual := WGS84.Distance(0, 0, 0.5, 179.7); actual != HaversineDistance(0, 0, 0.5, 179.7) {
+		t.Errorf("Function did not fall back to haversine: got %v", actual)
+	}
+}
+
+// Function to test: ParseModel().
+func Test_ParseModel(t *testing.T) {
+	if model, err := ParseModel("wgs84"); err != nil || model != WGS84 {
+		t.Errorf("Function returned wrong model: got %v, %v", model, err)
+	}
+	if _, err := ParseModel("flat"); err == nil {
+		t.Error("Function returned no error for an unknown model")
+	}
+}
@@ -0,0 +1,99 @@
+/*
+	File: length.go
+  Contains the length of a track, optionally trimmed to the flight and with the GPS jitter filtered out.
+*/
+
+package distance
+
+import (
+	"time"
+
+	igc "github.com/marni/goigc"
+)
+
+// Options of the length of a track.
+type Options struct {
+	Model Model
+	// TrimToFlight leaves out the fixes on the ground before the takeoff and after the landing.
+	TrimToFlight bool
+	// MinStep is the distance in meters a fix must be from the last counted fix to be counted, so the GPS
+	// jitter of a logger standing still is not added up. 0 counts all fixes.
+	MinStep float64
+}
+
+// Raw is the length as it has always been stored as track_length: all fixes, on a sphere.
+var Raw = Options{Model: Haversine}
+
+// Clean is the length stored as track_length_clean: the flight only, on the WGS84 ellipsoid, and without
+// the steps shorter than 5 m.
+var Clean = Options{Model: WGS84, TrimToFlight: true, MinStep: 5}
+
+// The takeoff and landing detection: the pilot is flying when the speed over a window of 30 seconds
+// is more than 15 km/h, faster than walking and slower than any paraglider flies.
+const (
+	FlightSpeed  = 15.0
+	FlightWindow = 30 * time.Second
+)
+
+// Length returns the length of a track in km, with the options.
+func Length(points []igc.Point, options Options) float64 {
+	if options.TrimToFlight {
+		if first, last, ok := Flight(points); ok {
+			points = points[first : last+1]
+		}
+	}
+
+	var sum float64
+	last := 0
+	for i := 1; i < len(points); i++ {
+		d := between(options.Model, points[last], points[i])
+		if d*1000 < options.MinStep {
+			// Jitter, the next fix is measured from the last counted fix.
+			continue
+		}
+		sum += d
+		last = i
+	}
+	return sum
+}
+
+// Flight returns the indexes of the fixes of the takeoff and the landing: the first and the last fix moving
+// faster than FlightSpeed over FlightWindow. False is returned if the track never moves that fast.
+func Flight(points []igc.Point) (first int, last int, ok bool) {
+	first = -1
+	for i := 0; i < len(points); i++ {
+		j, flying := moving(points, i)
+		if !flying {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		last = j
+	}
+	if first < 0 {
+		return 0, len(points) - 1, false
+	}
+	return first, last, true
+}
+
+// Returns the first fix at least FlightWindow after fix i, and if the speed between them is flying speed.
+func moving(points []igc.Point, i int) (int, bool) {
+	for j := i + 1; j < len(points); j++ {
+		elapsed := points[j].Time.Sub(points[i].Time)
+		if elapsed < 0 {
+			// The fixes only have the time of day, the flight went past midnight.
+			elapsed += 24 * time.Hour
+		}
+		if elapsed >= FlightWindow {
+			speed := between(Haversine, points[i], points[j]) / elapsed.Hours()
+			return j, speed > FlightSpeed
+		}
+	}
+	return i, false
+}
+
+// Returns the distance in km between two fixes on the model.
+func between(model Model, a igc.Point, b igc.Point) float64 {
+	return model.Distance(a.Lat.Degrees(), a.Lng.Degrees(), b.Lat.Degrees(), b.Lng.Degrees())
+}
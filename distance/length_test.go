@@ -0,0 +1,96 @@
+/*
+	File: length_test.go
+  Contains unit tests for length.go
+*/
+
+package distance
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	igc "github.com/marni/goigc"
+)
+
+// Returns a fix at the position, the seconds after noon.
+func fix(lat float64, lng float64, seconds int) igc.Point {
+	p := igc.NewPointFromLatLng(lat, lng)
+	p.Time = time.Date(0, 1, 1, 12, 0, seconds, 0, time.UTC)
+	return p
+}
+
+// A track with 2 minutes standing on the ground with jitter of about 2 m, a flight of 6 minutes 0.1 degree
+// north (11.1 km), and 2 minutes on the ground at the landing.
+func testTrack() []igc.Point {
+	var points []igc.Point
+	jitter := 0.00002
+	for s := 0; s < 120; s += 10 {
+		points = append(points, fix(60+jitter*float64(s/10%2), 6, s))
+	}
+	for s := 0; s <= 360; s += 10 {
+		points = append(points, fix(60+0.1*float64(s)/360, 6, 120+s))
+	}
+	for s := 10; s <= 120; s += 10 {
+		points = append(points, fix(60.1+jitter*float64(s/10%2), 6, 480+s))
+	}
+	return points
+}
+
+// Function to test: Length().
+// Test that the raw length counts the jitter, and the clean length only the flight.
+func Test_Length(t *testing.T) {
+	points := testTrack()
+	flight := HaversineDistance(60, 6, 60.1, 6)
+	step := HaversineDistance(60, 6, 60.00002, 6)
+
+	raw := Length(points, Raw)
+	// 12 steps of jitter before the takeoff, and 12 after the landing.
+	if math.Abs(raw-(flight+24*step)) > 0.0001 {
+		t.Errorf("Function returned wrong raw length: got %v want %v", raw, flight+24*step)
+	}
+
+	trimmed := Length(points, Options{Model: Haversine, TrimToFlight: true})
+	// The flight starts and ends within a window of the takeoff and landing, 2 steps of jitter each.
+	if math.Abs(trimmed-(flight+4*step)) > 0.0001 {
+		t.Errorf("Function returned wrong trimmed length: got %v want %v", trimmed, flight+4*step)
+	}
+
+	filtered := Length(points, Options{Model: Haversine, MinStep: 5})
+	if math.Abs(filtered-flight) > 0.0001 {
+		t.Errorf("Function returned wrong filtered length: got %v want %v", filtered, flight)
+	}
+
+	clean := Length(points, Clean)
+	if math.Abs(clean-WGS84.Distance(60, 6, 60.1, 6)) > 0.0001 {
+		t.Errorf("Function returned wrong clean length: got %v want %v", clean, WGS84.Distance(60, 6, 60.1, 6))
+	}
+}
+
+// Function to test: Length().
+// Test that the raw length is the length goigc has always calculated.
+func Test_Length_Raw(t *testing.T) {
+	points := testTrack()
+	var expected float64
+	for i := 0; i < len(points)-1; i++ {
+		expected += points[i].Distance(points[i+1])
+	}
+	if actual := Length(points, Raw); math.Abs(actual-expected) > 1e-9 {
+		t.Errorf("Function returned wrong length: got %v want %v", actual, expected)
+	}
+}
+
+// Function to test: Flight().
+func Test_Flight(t *testing.T) {
+	points := testTrack()
+	first, last, ok := Flight(points)
+	// The takeoff is at fix 12 and the landing at fix 48, and the window
+	// of 30 seconds before the takeoff and after the landing is included.
+	if !ok || first != 10 || last != 50 {
+		t.Errorf("Function returned wrong flight: got %d to %d, %v want %d to %d", first, last, ok, 10, 50)
+	}
+
+	if _, _, ok := Flight(points[:12]); ok {
+		t.Error("Function found a flight on the ground")
+	}
+}
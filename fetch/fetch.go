@@ -0,0 +1,325 @@
+/*
+	File: fetch.go
+  Fetches remote files from user supplied URLs, with a policy that blocks internal addresses and limits
+  redirects, body size and time.
+*/
+
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	igc "github.com/marni/goigc"
+	"github.com/mats93/paragliding/metrics"
+)
+
+// Error codes, one for each way a fetch can fail.
+const (
+	CodeInvalidURL       = "invalid_url"
+	CodeSchemeNotAllowed = "scheme_not_allowed"
+	CodeBlockedAddress   = "blocked_address"
+	CodeTooManyRedirects = "too_many_redirects"
+	CodeBodyTooLarge     = "body_too_large"
+	CodeTimeout          = "timeout"
+	CodeConnectionFailed = "connection_failed"
+	CodeBadStatus        = "bad_status"
+	CodeResolutionFailed = "resolution_failed"
+)
+
+// Error is a failed fetch, the code tells why it failed.
+type Error struct {
+	Code string
+	Err  error
+}
+
+// Error returns the code and the cause of the error.
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Err.Error()
+}
+
+// Policy is the limits of a fetch.
+type Policy struct {
+	// Schemes are the allowed URL schemes.
+	Schemes []string
+	// AllowPrivate allows private, loopback and link-local addresses, used by tests and trusted setups.
+	AllowPrivate bool
+	// MaxRedirects is the number of redirects followed.
+	MaxRedirects int
+	// MaxBodySize is the maximum size of the body in bytes.
+	MaxBodySize int64
+	// ConnectTimeout is the time allowed to connect to the host.
+	ConnectTimeout time.Duration
+	// ReadTimeout is the time allowed for the whole request, including reading the body.
+	ReadTimeout time.Duration
+}
+
+// DefaultPolicy is the policy used for IGC files and webhooks.
+var DefaultPolicy = Policy{
+	Schemes:        []string{"http", "https"},
+	MaxRedirects:   5,
+	MaxBodySize:    10 << 20,
+	ConnectTimeout: 5 * time.Second,
+	ReadTimeout:    30 * time.Second,
+}
+
+// The address ranges that are blocked, unless private addresses are allowed.
+var blockedNetworks []*net.IPNet
+
+func init() {
+	for _, cidr := range []string{
+		"0.0.0.0/8",      // "This" network.
+		"10.0.0.0/8",     // Private.
+		"100.64.0.0/10",  // Carrier-grade NAT.
+		"127.0.0.0/8",    // Loopback.
+		"169.254.0.0/16", // Link-local, includes cloud metadata services.
+		"172.16.0.0/12",  // Private.
+		"192.0.0.0/24",   // IETF protocol assignments.
+		"192.168.0.0/16", // Private.
+		"198.18.0.0/15",  // Benchmarking.
+		"224.0.0.0/4",    // Multicast.
+		"240.0.0.0/4",    // Reserved and broadcast.
+		"::/128",         // Unspecified.
+		"::1/128",        // Loopback.
+		"64:ff9b::/96",   // IPv4/IPv6 translation, can reach the IPv4 ranges above.
+		"fc00::/7",       // Unique local.
+		"fe80::/10",      // Link-local.
+		"ff00::/8",       // Multicast.
+	} {
+		_, network, _ := net.ParseCIDR(cidr)
+		blockedNetworks = append(blockedNetworks, network)
+	}
+}
+
+// Blocked checks if an address is in a private, loopback, link-local or reserved range.
+func Blocked(ip net.IP) bool {
+	// IPv4 addresses mapped to IPv6 are checked as IPv4.
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for i := 0; i < len(blockedNetworks); i++ {
+		if blockedNetworks[i].Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks that the URL is absolute and has an allowed scheme.
+func (p Policy) parseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, &Error{CodeInvalidURL, err}
+	}
+	if u.Scheme == "" {
+		return nil, &Error{CodeInvalidURL, fmt.Errorf("%q is not an absolute url", raw)}
+	}
+
+	allowed := false
+	for i := 0; i < len(p.Schemes); i++ {
+		if strings.EqualFold(u.Scheme, p.Schemes[i]) {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return nil, &Error{CodeSchemeNotAllowed, fmt.Errorf("scheme %q is not allowed", u.Scheme)}
+	}
+
+	if u.Hostname() == "" {
+		return nil, &Error{CodeInvalidURL, fmt.Errorf("%q has no host", raw)}
+	}
+	return u, nil
+}
+
+// CheckURL checks that the URL is allowed by the policy, and that its host does not resolve to a blocked
+// address. The addresses are checked again when connecting, since DNS answers can change.
+func (p Policy) CheckURL(raw string) error {
+	u, err := p.parseURL(raw)
+	if err != nil {
+		return err
+	}
+	if p.AllowPrivate {
+		return nil
+	}
+
+	addresses, err := net.LookupIP(u.Hostname())
+	if err != nil {
+		return &Error{CodeResolutionFailed, err}
+	}
+	for i := 0; i < len(addresses); i++ {
+		if Blocked(addresses[i]) {
+			return &Error{CodeBlockedAddress, fmt.Errorf("%s resolves to %s", u.Hostname(), addresses[i])}
+		}
+	}
+	return nil
+}
+
+// IdleConnTimeout is how long an idle connection of a client is kept open for reuse.
+const IdleConnTimeout = 90 * time.Second
+
+// The clients of the policies, each policy has one client so the connections are reused.
+var clients = struct {
+	sync.Mutex
+	byPolicy map[string]*http.Client
+}{byPolicy: map[string]*http.Client{}}
+
+// Returns a key that is the same for equal policies.
+func (p Policy) key() string {
+	return fmt.Sprintf("%s|%t|%d|%d|%v|%v", strings.Join(p.Schemes, ","), p.AllowPrivate, p.MaxRedirects,
+		p.MaxBodySize, p.ConnectTimeout, p.ReadTimeout)
+}
+
+// Client returns the HTTP client that follows the policy, it is made the first time the policy is used.
+// The address is checked after it is resolved, right before connecting.
+func (p Policy) Client() *http.Client {
+	clients.Lock()
+	defer clients.Unlock()
+	client, ok := clients.byPolicy[p.key()]
+	if !ok {
+		client = p.newClient()
+		clients.byPolicy[p.key()] = client
+	}
+	return client
+}
+
+// Makes a HTTP client that follows the policy.
+func (p Policy) newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: p.ConnectTimeout,
+		Control: func(network string, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); !p.AllowPrivate && (ip == nil || Blocked(ip)) {
+				return &Error{CodeBlockedAddress, fmt.Errorf("address %s is blocked", host)}
+			}
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		// No proxy, it would connect to the proxy instead of the checked address.
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   p.ConnectTimeout,
+		ResponseHeaderTimeout: p.ReadTimeout,
+		IdleConnTimeout:       IdleConnTimeout,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   p.ReadTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > p.MaxRedirects {
+				return &Error{CodeTooManyRedirects, fmt.Errorf("stopped after %d redirects", p.MaxRedirects)}
+			}
+			// The redirect has to follow the policy too.
+			_, err := p.parseURL(req.URL.String())
+			return err
+		},
+	}
+}
+
+// Converts an error from the client to a fetch error.
+func clientError(err error) error {
+	var fetchErr *Error
+	if errors.As(err, &fetchErr) {
+		return fetchErr
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &Error{CodeTimeout, err}
+	}
+	return &Error{CodeConnectionFailed, err}
+}
+
+// Get fetches the URL, and returns the body. The time it took is observed by the result, which is the
+// error code if it failed.
+// All errors are of type *Error.
+func (p Policy) Get(raw string) ([]byte, error) {
+	start := time.Now()
+	body, err := p.get(raw)
+
+	result := metrics.ResultSuccess
+	if err != nil {
+		result = Code(err)
+	}
+	metrics.FetchDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+	return body, err
+}
+
+// Fetches the URL, and returns the body.
+func (p Policy) get(raw string) ([]byte, error) {
+	u, err := p.parseURL(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.Client().Get(u.String())
+	if err != nil {
+		return nil, clientError(err)
+	}
+	// Drains what is left of the body before closing it, so the connection can be reused.
+	defer func() {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, p.MaxBodySize))
+		resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &Error{CodeBadStatus, fmt.Errorf("got status %s", resp.Status)}
+	}
+	if resp.ContentLength > p.MaxBodySize {
+		return nil, &Error{CodeBodyTooLarge, fmt.Errorf("body is %d bytes, the limit is %d", resp.ContentLength, p.MaxBodySize)}
+	}
+
+	// Reads one byte more than the limit, to know if the body is too large.
+	body, err := io.ReadAll(io.LimitReader(resp.Body, p.MaxBodySize+1))
+	if err != nil {
+		return nil, clientError(err)
+	}
+	if int64(len(body)) > p.MaxBodySize {
+		return nil, &Error{CodeBodyTooLarge, fmt.Errorf("body is larger than %d bytes", p.MaxBodySize)}
+	}
+	return body, nil
+}
+
+// Get fetches the URL with the default policy.
+func Get(raw string) ([]byte, error) {
+	return DefaultPolicy.Get(raw)
+}
+
+// IGC fetches an IGC file with the default policy, and returns its content and the parsed track.
+// The content is kept, since it is needed to verify the G-record.
+func IGC(raw string) (string, igc.Track, error) {
+	content, err := Get(raw)
+	if err != nil {
+		return "", igc.Track{}, err
+	}
+
+	track, err := igc.Parse(string(content))
+	return string(content), track, err
+}
+
+// CheckURL checks the URL with the default policy.
+func CheckURL(raw string) error {
+	return DefaultPolicy.CheckURL(raw)
+}
+
+// Code returns the code of a fetch error, or an empty string for other errors.
+func Code(err error) string {
+	var fetchErr *Error
+	if errors.As(err, &fetchErr) {
+		return fetchErr.Code
+	}
+	return ""
+}
@@ -0,0 +1,121 @@
+/*
+  File: fetch_test.go
+  Contains unit tests for fetch.go
+*/
+
+package fetch
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A policy that allows the test server on the loopback address.
+var testPolicy = Policy{
+	Schemes:        []string{"http"},
+	AllowPrivate:   true,
+	MaxRedirects:   2,
+	MaxBodySize:    10,
+	ConnectTimeout: time.Second,
+	ReadTimeout:    200 * time.Millisecond,
+}
+
+// Function to test: Blocked().
+func Test_Blocked(t *testing.T) {
+	blocked := []string{"127.0.0.1", "10.1.2.3", "172.20.0.1", "192.168.1.1", "169.254.169.254", "::1", "fd00::1", "::ffff:127.0.0.1"}
+	for i := 0; i < len(blocked); i++ {
+		if !Blocked(net.ParseIP(blocked[i])) {
+			t.Errorf("Function did not block %s", blocked[i])
+		}
+	}
+
+	allowed := []string{"8.8.8.8", "151.101.1.1", "2a00:1450:4001::1"}
+	for i := 0; i < len(allowed); i++ {
+		if Blocked(net.ParseIP(allowed[i])) {
+			t.Errorf("Function blocked %s", allowed[i])
+		}
+	}
+}
+
+// Method to test: Get().
+// Test the error codes of each way a fetch can fail.
+func Test_Get(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("igc"))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", 100)))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// A successful fetch.
+	body, err := testPolicy.Get(server.URL + "/ok")
+	if err != nil || string(body) != "igc" {
+		t.Errorf("Method returned wrong body: got %q (%v) want %q", body, err, "igc")
+	}
+
+	tests := []struct {
+		policy   Policy
+		url      string
+		expected string
+	}{
+		{testPolicy, "wrong", CodeInvalidURL},
+		{testPolicy, "file:///etc/passwd", CodeSchemeNotAllowed},
+		{DefaultPolicy, server.URL + "/ok", CodeBlockedAddress},
+		{testPolicy, server.URL + "/loop", CodeTooManyRedirects},
+		{testPolicy, server.URL + "/large", CodeBodyTooLarge},
+		{testPolicy, server.URL + "/slow", CodeTimeout},
+		{testPolicy, server.URL + "/missing", CodeBadStatus},
+	}
+	for i := 0; i < len(tests); i++ {
+		_, err := tests[i].policy.Get(tests[i].url)
+		if actual := Code(err); actual != tests[i].expected {
+			t.Errorf("Method returned wrong error code for %s: got %q (%v) want %q",
+				tests[i].url, actual, err, tests[i].expected)
+		}
+	}
+}
+
+// Method to test: CheckURL().
+func Test_CheckURL(t *testing.T) {
+	if err := DefaultPolicy.CheckURL("http://127.0.0.1:8080/hook"); Code(err) != CodeBlockedAddress {
+		t.Errorf("Method returned wrong error for a loopback address: got %v", err)
+	}
+	if err := DefaultPolicy.CheckURL("ftp://example.com"); Code(err) != CodeSchemeNotAllowed {
+		t.Errorf("Method returned wrong error for a ftp url: got %v", err)
+	}
+	if err := testPolicy.CheckURL("http://127.0.0.1:8080/hook"); err != nil {
+		t.Errorf("Method returned unexpected error when private addresses are allowed: %v", err)
+	}
+}
+
+// Method to test: Client().
+// Test that a policy has one client, so the connections are reused, and a changed policy has its own.
+func Test_Client(t *testing.T) {
+	if testPolicy.Client() != testPolicy.Client() {
+		t.Error("Method returned a new client for the same policy")
+	}
+	blocking := testPolicy
+	blocking.AllowPrivate = false
+	if blocking.Client() == testPolicy.Client() {
+		t.Error("Method returned the same client for a different policy")
+	}
+	if transport := testPolicy.Client().Transport.(*http.Transport); transport.IdleConnTimeout != IdleConnTimeout {
+		t.Errorf("Method returned a client without an idle timeout: got %v", transport.IdleConnTimeout)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+	File: grecord.go
+  Validates the security record (G-record) of IGC files, with verifiers for each logger manufacturer.
+*/
+
+package grecord
+
+import (
+	"strings"
+	"sync"
+
+	igc "github.com/marni/goigc"
+)
+
+// Validation statuses of a track.
+const (
+	// StatusValid is a G-record that was verified by the verifier for the logger.
+	StatusValid = "valid"
+	// StatusInvalid is a G-record that did not match the content, the file has been changed.
+	StatusInvalid = "invalid"
+	// StatusUnsigned is a file without a G-record.
+	StatusUnsigned = "unsigned"
+	// StatusUnknownLogger is a G-record from a logger that has no verifier.
+	StatusUnknownLogger = "unknown-logger"
+)
+
+// Statuses are all the validation statuses.
+var Statuses = []string{StatusValid, StatusInvalid, StatusUnsigned, StatusUnknownLogger}
+
+// Verifier verifies the G-record of the IGC files from a logger manufacturer.
+// The content is the full IGC file, and the signature the joined G-record lines.
+type Verifier interface {
+	Verify(content string, signature string) bool
+}
+
+// VerifierFunc is a function used as a Verifier.
+type VerifierFunc func(content string, signature string) bool
+
+// Verify calls the function.
+func (f VerifierFunc) Verify(content string, signature string) bool {
+	return f(content, signature)
+}
+
+// The registered verifiers, by the three character manufacturer code of the A-record.
+var verifiers = map[string]Verifier{}
+var lock sync.RWMutex
+
+// Register adds the verifier for a manufacturer code (e.g. "XCT"), replacing any earlier verifier.
+func Register(manufacturer string, verifier Verifier) {
+	lock.Lock()
+	defer lock.Unlock()
+	verifiers[strings.ToUpper(manufacturer)] = verifier
+}
+
+// Unregister removes the verifier for a manufacturer code.
+func Unregister(manufacturer string) {
+	lock.Lock()
+	defer lock.Unlock()
+	delete(verifiers, strings.ToUpper(manufacturer))
+}
+
+// Check returns the validation status of an IGC file, the track is the parsed content.
+func Check(content string, track igc.Track) string {
+	if track.Signature == "" {
+		return StatusUnsigned
+	}
+
+	lock.RLock()
+	verifier, found := verifiers[strings.ToUpper(track.Manufacturer)]
+	lock.RUnlock()
+	if !found {
+		return StatusUnknownLogger
+	}
+
+	if verifier.Verify(content, track.Signature) {
+		return StatusValid
+	}
+	return StatusInvalid
+}
+
+// ValidStatus checks if the status is one of the validation statuses.
+func ValidStatus(status string) bool {
+	for i := 0; i < len(Statuses); i++ {
+		if status == Statuses[i] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+/*
+  File: grecord_test.go
+  Contains unit tests for grecord.go
+*/
+
+package grecord
+
+import (
+	"strings"
+	"testing"
+
+	igc "github.com/marni/goigc"
+)
+
+// A short IGC file from a test logger, without the G-record.
+const testContent = "AXTS001\r\n" +
+	"HFDTE201018\r\n" +
+	"HFPLTPILOTINCHARGE:Test Pilot\r\n" +
+	"B1200006039000N00625000EA0100001000\r\n" +
+	"B1201006039100N00625100EA0110001100\r\n"
+
+// Function to test: Check().
+// Test the status of unsigned files, unknown loggers, and valid and invalid signatures.
+func Test_Check(t *testing.T) {
+	// Signs the content by its length, the verifier checks it.
+	signed := testContent + "G" + strings.Repeat("A", 10) + "\r\n"
+	verifier := VerifierFunc(func(content string, signature string) bool {
+		return strings.HasPrefix(content, testContent) && signature == strings.Repeat("A", 10)
+	})
+
+	tests := []struct {
+		name     string
+		content  string
+		register bool
+		expected string
+	}{
+		{"unsigned", testContent, true, StatusUnsigned},
+		{"unknown logger", signed, false, StatusUnknownLogger},
+		{"valid", signed, true, StatusValid},
+		{"invalid", strings.Replace(signed, "Test Pilot", "Other Pilot", 1), true, StatusInvalid},
+	}
+
+	for i := 0; i < len(tests); i++ {
+		Unregister("XTS")
+		if tests[i].register {
+			Register("xts", verifier)
+		}
+
+		track, err := igc.Parse(tests[i].content)
+		if err != nil {
+			t.Fatalf("%s: could not parse the test file: %v", tests[i].name, err)
+		}
+		if actual := Check(tests[i].content, track); actual != tests[i].expected {
+			t.Errorf("%s: Function returned wrong status: got %s want %s", tests[i].name, actual, tests[i].expected)
+		}
+	}
+	Unregister("XTS")
+}
+
+// Function to test: ValidStatus().
+func Test_ValidStatus(t *testing.T) {
+	if !ValidStatus(StatusUnknownLogger) {
+		t.Errorf("Function returned false for %s", StatusUnknownLogger)
+	}
+	if ValidStatus("signed") {
+		t.Error("Function returned true for an unknown status")
+	}
+}
@@ -0,0 +1,73 @@
+/*
+	File: health.go
+  Contains the liveness and readiness endpoints for orchestrators.
+*/
+
+package health
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/mats93/paragliding/internal/httpjson"
+)
+
+// Check is a dependency the service needs to serve requests, Check returns an error when it is not usable.
+type Check struct {
+	Name  string
+	Check func() error
+}
+
+// Checks are the checks of the readiness endpoint. Gets injected from main or test.
+var Checks []Check
+
+// Set when the service is shutting down.
+var shuttingDown int32
+
+// Format of the readiness status, with the result of each check.
+type status struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// SetShuttingDown makes the readiness endpoint fail, so no new requests are sent to the service while
+// it shuts down.
+func SetShuttingDown() {
+	atomic.StoreInt32(&shuttingDown, 1)
+}
+
+// ShuttingDown checks if the service is shutting down.
+func ShuttingDown() bool {
+	return atomic.LoadInt32(&shuttingDown) == 1
+}
+
+// Healthz - GET: Returns 200 (OK) while the process is alive.
+// Output: application/json
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	httpjson.Write(w, http.StatusOK, status{Status: "ok"})
+}
+
+// Readyz - GET: Returns 200 (OK) when all the checks pass, and 503 (Service unavailable) with the failed
+// checks when not, or when the service is shutting down.
+// Output: application/json
+func Readyz(w http.ResponseWriter, r *http.Request) {
+	result := status{Status: "ready", Checks: map[string]string{}}
+	code := http.StatusOK
+
+	if ShuttingDown() {
+		result.Status = "shutting down"
+		code = http.StatusServiceUnavailable
+	}
+	for i := 0; i < len(Checks); i++ {
+		if err := Checks[i].Check(); err != nil {
+			result.Checks[Checks[i].Name] = err.Error()
+			if code == http.StatusOK {
+				result.Status = "not ready"
+				code = http.StatusServiceUnavailable
+			}
+		} else {
+			result.Checks[Checks[i].Name] = "ok"
+		}
+	}
+	httpjson.Write(w, code, result)
+}
@@ -0,0 +1,61 @@
+/*
+  File: health_test.go
+  Contains unit tests for health.go
+*/
+
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// Returns the status code and status of the handler.
+func serve(handler http.HandlerFunc) (int, status) {
+	request, _ := http.NewRequest("GET", "/readyz", nil)
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+
+	var actual status
+	json.NewDecoder(recorder.Body).Decode(&actual)
+	return recorder.Code, actual
+}
+
+// Function to test: Healthz().
+func Test_Healthz(t *testing.T) {
+	if code, actual := serve(Healthz); code != http.StatusOK || actual.Status != "ok" {
+		t.Errorf("Handler returned wrong status: got %v %v", code, actual)
+	}
+}
+
+// Function to test: Readyz().
+// Test that the service is ready only when all checks pass, and not while shutting down.
+func Test_Readyz(t *testing.T) {
+	var failing error
+	Checks = []Check{
+		{"database", func() error { return nil }},
+		{"webhook_queue", func() error { return failing }},
+	}
+	defer func() { Checks = nil }()
+
+	if code, actual := serve(Readyz); code != http.StatusOK || actual.Checks["webhook_queue"] != "ok" {
+		t.Errorf("Handler returned wrong status: got %v %v", code, actual)
+	}
+
+	failing = errors.New("the webhook queue is stuck")
+	code, actual := serve(Readyz)
+	if code != http.StatusServiceUnavailable || actual.Checks["webhook_queue"] != failing.Error() || actual.Checks["database"] != "ok" {
+		t.Errorf("Handler returned wrong status for a failed check: got %v %v", code, actual)
+	}
+
+	failing = nil
+	SetShuttingDown()
+	defer atomic.StoreInt32(&shuttingDown, 0)
+	if code, actual := serve(Readyz); code != http.StatusServiceUnavailable || actual.Status != "shutting down" {
+		t.Errorf("Handler returned wrong status while shutting down: got %v %v", code, actual)
+	}
+}
@@ -0,0 +1,27 @@
+/*
+	File: httpjson.go
+  Contains the writing of json responses, shared by the handlers of all packages.
+*/
+
+package httpjson
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// Write writes the value as json, with the given status code.
+func Write(w http.ResponseWriter, status int, value interface{}) {
+	json, err := json.Marshal(value)
+	if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	// Sets header content-type to application/json and the status code.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(json)
+}
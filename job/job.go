@@ -0,0 +1,222 @@
+/*
+	File: job.go
+  Contains the workers that ingest tracks in the background, and functions used by API calls to the "Job paths".
+*/
+
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/mats93/paragliding/admin"
+	"github.com/mats93/paragliding/internal/httpjson"
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/user"
+)
+
+// QueueSize is the number of jobs that can wait for a worker.
+const QueueSize = 100
+
+// Process ingests the track at the url for the owner, shared with the clubs, and returns the tracks ID.
+// The context has the logger of the job. Gets injected from main.
+var Process func(ctx context.Context, url string, owner int, privacy string, clubs []int) (int, error)
+
+// Collection is the MongoDB collection to use for jobs. Gets injected from main or test.
+var Collection string
+
+// The IDs of the jobs waiting for a worker, nil until the workers are started and after they are stopped.
+var queue chan int
+
+// Guards the queue, so no job is queued after it is stopped.
+var queueLock sync.RWMutex
+
+// Closed when the workers are stopped, they then finish the job they are running.
+var stopped chan struct{}
+
+// Done when the workers have finished their jobs after they are stopped.
+var workers sync.WaitGroup
+
+// Start starts the workers, and queues the jobs that were unfinished when the server stopped.
+// Jobs that were running are run again from the start.
+func Start(n int) error {
+	queueLock.Lock()
+	queue = make(chan int, QueueSize)
+	stopped = make(chan struct{})
+	for i := 0; i < n; i++ {
+		workers.Add(1)
+		go worker(queue, stopped)
+	}
+	jobQueue, jobsStopped := queue, stopped
+	queueLock.Unlock()
+
+	// Connects to the database, and finds the unfinished jobs.
+	database := mongodb.DatabaseInit(Collection)
+	jobs, err := database.FindUnfinishedJobs()
+	if err != nil {
+		return err
+	}
+
+	// Queues them in the background, there may be more than fits in the queue.
+	go func() {
+		for i := 0; i < len(jobs); i++ {
+			select {
+			case jobQueue <- jobs[i].ID:
+			case <-jobsStopped:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops queueing jobs, and waits for the workers to finish the jobs they are running, or for the
+// context to be done. The queued jobs are left unfinished in the database, and are run when the workers
+// are started again.
+func Stop(ctx context.Context) error {
+	queueLock.Lock()
+	if queue != nil {
+		close(stopped)
+		queue = nil
+	}
+	queueLock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Enqueue stores a new job for the url, and queues it for a worker. Returns the jobs ID.
+// The track gets the owner and privacy level, and is shared with the clubs.
+// The job keeps the request ID of the context, and logs with it when it runs.
+func Enqueue(ctx context.Context, url string, owner int, privacy string, clubs []int) (int, error) {
+	queueLock.RLock()
+	defer queueLock.RUnlock()
+	if queue == nil {
+		return 0, errors.New("the job workers are not started")
+	}
+
+	// Connects to the database, and stores the job.
+	database := mongodb.DatabaseContext(ctx, Collection)
+	job, err := database.InsertJob(url, owner, privacy, clubs, logging.RequestID(ctx))
+	if err != nil {
+		return 0, err
+	}
+
+	select {
+	case queue <- job.ID:
+		return job.ID, nil
+	default:
+		// The queue is full, the job fails right away.
+		job.Status = mongodb.JobFailed
+		job.Error = "the job queue is full"
+		database.UpdateJob(job)
+		return 0, errors.New(job.Error)
+	}
+}
+
+// Runs the queued jobs, until the workers are stopped.
+func worker(queue chan int, stopped chan struct{}) {
+	defer workers.Done()
+	for {
+		// Checks for the stop first, since select picks any of the ready cases.
+		select {
+		case <-stopped:
+			return
+		default:
+		}
+		select {
+		case id := <-queue:
+			run(id)
+		case <-stopped:
+			return
+		}
+	}
+}
+
+// Runs a job, and stores the result.
+func run(id int) {
+	// Connects to the database.
+	database := mongodb.DatabaseInit(Collection)
+
+	job, err := database.FindJob(id)
+	if err != nil {
+		slog.Error(err.Error(), "job_id", id)
+		return
+	}
+
+	// Logs with the request ID of the request that queued the job, and the jobs ID.
+	ctx := context.Background()
+	if job.RequestID != "" {
+		ctx = logging.WithRequestID(ctx, job.RequestID)
+	}
+	ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("job_id", job.ID))
+	logger := logging.FromContext(ctx)
+	database = mongodb.DatabaseContext(ctx, Collection)
+
+	// Marks the job as running.
+	job.Status = mongodb.JobRunning
+	if err := database.UpdateJob(job); err != nil {
+		logger.Error(err.Error())
+		return
+	}
+
+	// Ingests the track, and stores the result.
+	trackID, err := Process(ctx, job.URL, job.Owner, job.Privacy, job.Clubs)
+	if err != nil {
+		job.Status = mongodb.JobFailed
+		job.Error = err.Error()
+		logger.Warn("job failed", "error", err)
+	} else {
+		job.Status = mongodb.JobSucceeded
+		job.TrackID = trackID
+		logger.Info("job succeeded", "track_id", trackID)
+	}
+	if err := database.UpdateJob(job); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+// CanView checks if the request may see the job, only the owner of the job and admins can.
+// The job has the url and privacy level of the track, which may be private.
+func CanView(r *http.Request, job mongodb.Job) bool {
+	if admin.IsAdmin(r) {
+		return true
+	}
+	owner, ok := user.Authenticate(r)
+	return ok && owner.ID == job.Owner
+}
+
+// GetJob - GET: Returns the status of the job with the provided '<id>', with the track ID or error when finished.
+// Only the owner of the job and admins can see it.
+// Output: application/json
+func GetJob(w http.ResponseWriter, r *http.Request) {
+	// Connects to the database.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+
+	var id int
+	// Gets the ID from the URL and converts it to an integer.
+	fmt.Sscanf(r.URL.Path, "/paragliding/api/job/%d", &id)
+
+	job, err := database.FindJob(id)
+	if err != nil || !CanView(r, job) {
+		// A job with the given ID does not exist, or is hidden from the request.
+		// Sets the header code to 404 (Not found).
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	httpjson.Write(w, http.StatusOK, job)
+}
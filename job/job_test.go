@@ -0,0 +1,129 @@
+/*
+  File: job_test.go
+  Contains unit tests for job.go
+*/
+
+package job
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mats93/paragliding/admin"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/user"
+)
+
+// Function to test: Enqueue().
+// Test that jobs are not accepted before the workers are started.
+func Test_Enqueue_NotStarted(t *testing.T) {
+	queue = nil
+
+	if _, err := Enqueue(context.Background(), "http://test.test/track.igc", 1, mongodb.PrivacyPublic, nil); err == nil {
+		t.Error("Function did not return error when the workers are not started")
+	}
+}
+
+// Function to test: Stop().
+// Test that the workers stop, and jobs are not accepted after they are stopped.
+func Test_Stop(t *testing.T) {
+	// Starts workers without the database, with no jobs queued.
+	queue = make(chan int, QueueSize)
+	stopped = make(chan struct{})
+	for i := 0; i < 2; i++ {
+		workers.Add(1)
+		go worker(queue, stopped)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := Stop(ctx); err != nil {
+		t.Fatalf("Function returned unexpected error: %v", err)
+	}
+	if _, err := Enqueue(context.Background(), "http://test.test/track.igc", 1, mongodb.PrivacyPublic, nil); err == nil {
+		t.Error("Function did not return error when the workers are stopped")
+	}
+	// Stopping again does nothing.
+	if err := Stop(ctx); err != nil {
+		t.Errorf("Function returned unexpected error when stopped again: %v", err)
+	}
+}
+
+// Function to test: run().
+// Test that the result of the processing is stored in the job.
+func Test_run(t *testing.T) {
+	// Injects the MongoDB collection to use.
+	Collection = "TestJobs"
+	database := mongodb.DatabaseInit(Collection)
+
+	// Processes the urls without fetching them.
+	Process = func(ctx context.Context, url string, owner int, privacy string, clubs []int) (int, error) {
+		if url == "http://test.test/bad.igc" {
+			return 0, errors.New("could not parse the IGC data")
+		}
+		return 7, nil
+	}
+
+	good, _ := database.InsertJob("http://test.test/good.igc", 1, mongodb.PrivacyPublic, nil, "")
+	bad, _ := database.InsertJob("http://test.test/bad.igc", 1, mongodb.PrivacyPublic, nil, "")
+	run(good.ID)
+	run(bad.ID)
+
+	actual, _ := database.FindJob(good.ID)
+	if actual.Status != mongodb.JobSucceeded || actual.TrackID != 7 {
+		t.Errorf("Function stored wrong result: got %v", actual)
+	}
+	actual, _ = database.FindJob(bad.ID)
+	if actual.Status != mongodb.JobFailed || actual.Error != "could not parse the IGC data" {
+		t.Errorf("Function stored wrong result: got %v", actual)
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+}
+
+// Function to test: GetJob().
+// Test that only the owner of a job and admins can see it.
+func Test_GetJob(t *testing.T) {
+	// Injects the MongoDB collections to use.
+	Collection = "TestJobs"
+	user.Collection = "TestUsers"
+	user.CollectionSession = "TestSessions"
+	admin.Token = "secret"
+	defer func() { admin.Token = "" }()
+
+	// Adds the owner and another user, and a job of the owner.
+	users := mongodb.DatabaseInit(user.Collection)
+	ownerID, _ := users.InsertUser(mongodb.User{Username: "jobowner", Created: time.Now()})
+	otherID, _ := users.InsertUser(mongodb.User{Username: "jobother", Created: time.Now()})
+	ownerToken, _, _ := user.NewToken(ownerID)
+	otherToken, _, _ := user.NewToken(otherID)
+	database := mongodb.DatabaseInit(Collection)
+	job, _ := database.InsertJob("http://test.test/private.igc", ownerID, mongodb.PrivacyPrivate, nil, "")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/paragliding/api/job/{id:[0-9]+}", GetJob)
+	tokens := map[string]int{"": http.StatusNotFound, otherToken: http.StatusNotFound,
+		ownerToken: http.StatusOK, "secret": http.StatusOK}
+	for token, expected := range tokens {
+		request, _ := http.NewRequest("GET", "/paragliding/api/job/"+strconv.Itoa(job.ID), nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		if recorder.Code != expected {
+			t.Errorf("Handler returned wrong status code: got %v want %v", recorder.Code, expected)
+		}
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+	users.DeleteAll()
+	sessions := mongodb.DatabaseInit(user.CollectionSession)
+	sessions.DeleteAll()
+}
@@ -0,0 +1,166 @@
+/*
+	File: logging.go
+  Contains the structured (JSON) logger, the request IDs, and the middleware that writes the access log.
+  Each request gets a logger with its request ID in the context, so everything logged while handling it,
+  also by the webhooks and jobs it starts, can be traced back to it.
+*/
+
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// HeaderRequestID is the header the request ID is read from, and returned in.
+const HeaderRequestID = "X-Request-ID"
+
+// Longest request ID taken from a client, longer IDs are replaced.
+const maxRequestIDLength = 128
+
+// Keys of the values stored in the context.
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+)
+
+// ParseLevel parses a log level: debug, info, warn or error. An empty level is info.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	err := level.UnmarshalText([]byte(s))
+	return level, err
+}
+
+// Setup makes the default logger write JSON to w, leaving out the entries below the level.
+// The standard log package writes to the default logger too.
+func Setup(w io.Writer, level slog.Level) {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})))
+}
+
+// NewRequestID returns a new random request ID.
+func NewRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// Checks that a request ID from a client is short and printable, so it is safe to log and return.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// WithRequestID returns a context with the request ID, and a logger that adds it to all entries.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey, id)
+	return WithLogger(ctx, FromContext(ctx).With("request_id", id))
+}
+
+// RequestID returns the request ID of the context, or an empty string if it has none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithLogger returns a context with the logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// FromContext returns the logger of the context, or the default logger if it has none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+// Remembers the status code and the number of bytes written by a handler.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+// WriteHeader remembers the status code, and writes it.
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write counts the bytes, and writes them.
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap returns the writer, so streaming handlers can flush it with http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Route returns the path template of the route of the router the request matches, or "unmatched".
+// Templates are logged instead of paths, so the entries of a route can be found together.
+func Route(router *mux.Router, r *http.Request) string {
+	var match mux.RouteMatch
+	if router != nil && router.Match(r, &match) && match.Route != nil {
+		if template, err := match.Route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unmatched"
+}
+
+// Middleware gives each request to the handler a request ID, taken from the X-Request-ID header if the client
+// sent one, and returns it in the same header. The request gets a logger with the ID in its context, and an
+// access log entry with the method, the route template of the router, status, bytes and duration is written.
+// It should be the outermost handler, so the requests stopped by other middleware are logged too.
+func Middleware(router *mux.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := strings.TrimSpace(r.Header.Get(HeaderRequestID))
+		if !validRequestID(id) {
+			id = NewRequestID()
+		}
+		w.Header().Set(HeaderRequestID, id)
+		r = r.WithContext(WithRequestID(r.Context(), id))
+
+		recorder := &responseRecorder{w, http.StatusOK, 0}
+		next.ServeHTTP(recorder, r)
+
+		level := slog.LevelInfo
+		if recorder.status >= 500 {
+			level = slog.LevelError
+		}
+		FromContext(r.Context()).Log(r.Context(), level, "request",
+			"method", r.Method,
+			"route", Route(router, r),
+			"status", recorder.status,
+			"bytes", recorder.bytes,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000)
+	})
+}
@@ -0,0 +1,88 @@
+/*
+  File: logging_test.go
+  Contains unit tests for logging.go
+*/
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// Function to test: ParseLevel().
+func Test_ParseLevel(t *testing.T) {
+	tests := []struct {
+		level    string
+		expected slog.Level
+	}{
+		{"", slog.LevelInfo},
+		{"debug", slog.LevelDebug},
+		{"WARN", slog.LevelWarn},
+		{"error", slog.LevelError},
+	}
+	for i := 0; i < len(tests); i++ {
+		if actual, err := ParseLevel(tests[i].level); err != nil || actual != tests[i].expected {
+			t.Errorf("Function returned wrong level for %q: got %v (%v) want %v", tests[i].level, actual, err, tests[i].expected)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("Function did not return error for an unknown level")
+	}
+}
+
+// Function to test: Middleware().
+// Test that the request ID is propagated to the handlers logger, returned, and logged with the route template.
+func Test_Middleware(t *testing.T) {
+	var buffer bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	Setup(&buffer, slog.LevelInfo)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/paragliding/api/track/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("handled")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	})
+	handler := Middleware(router, router)
+
+	// The request ID of the client is used.
+	request, _ := http.NewRequest("GET", "/paragliding/api/track/12", nil)
+	request.Header.Set(HeaderRequestID, "client-id")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if id := recorder.Header().Get(HeaderRequestID); id != "client-id" {
+		t.Errorf("Middleware returned wrong request ID: got %q want %q", id, "client-id")
+	}
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Middleware wrote wrong number of entries: got %d want %d", len(lines), 2)
+	}
+	var handled, access map[string]interface{}
+	json.Unmarshal([]byte(lines[0]), &handled)
+	json.Unmarshal([]byte(lines[1]), &access)
+	if handled["request_id"] != "client-id" {
+		t.Errorf("Handler logged without the request ID: got %v", handled)
+	}
+	if access["request_id"] != "client-id" || access["route"] != "/paragliding/api/track/{id:[0-9]+}" ||
+		access["status"] != float64(http.StatusNotFound) || access["bytes"] != float64(len("not found")) {
+		t.Errorf("Middleware wrote wrong access log: got %v", access)
+	}
+
+	// A new request ID is made when the client sent none, or one that is not safe to log.
+	request, _ = http.NewRequest("GET", "/unknown", nil)
+	request.Header.Set(HeaderRequestID, "bad id\n")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if id := recorder.Header().Get(HeaderRequestID); len(id) != 32 {
+		t.Errorf("Middleware did not make a new request ID: got %q", id)
+	}
+}
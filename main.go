@@ -12,60 +12,134 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"runtime/debug"
+	"syscall"
 	"time"
 
-	"github.com/gorilla/mux"
 	"github.com/mats93/paragliding/admin"
+	"github.com/mats93/paragliding/airspace"
+	"github.com/mats93/paragliding/analysis"
+	"github.com/mats93/paragliding/club"
+	"github.com/mats93/paragliding/health"
+	"github.com/mats93/paragliding/job"
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/ratelimit"
+	"github.com/mats93/paragliding/server"
+	"github.com/mats93/paragliding/site"
+	"github.com/mats93/paragliding/task"
+	"github.com/mats93/paragliding/terrain"
 	"github.com/mats93/paragliding/ticker"
 	"github.com/mats93/paragliding/track"
+	"github.com/mats93/paragliding/user"
+	"github.com/mats93/paragliding/waypoint"
 	"github.com/mats93/paragliding/webhook"
 )
 
 // COLLECTION is the collection to be used when the main app is running.
 const COLLECTION = "Tracks"
 
+// JOBWORKERS is the number of workers ingesting tracks in the background.
+const JOBWORKERS = 4
+
+// WEBHOOKWORKERS is the number of workers sending webhook messages in the background.
+const WEBHOOKWORKERS = 4
+
+// commit is the commit the app was built from, set with '-ldflags "-X main.commit=<commit>"'.
+// If not set, it is read from the version control information Go adds to the build.
+var commit string
+
 // StartTime is the start time for the API service.
 var startTime = time.Now()
 
 func main() {
-	// Injects the startime to the track package.
+	// Logs as JSON, the level is read from the enviroment var LOG_LEVEL (debug, info, warn or error).
+	level, err := logging.ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	logging.Setup(os.Stdout, level)
+
+	// Injects the startime and the commit to the track package.
 	track.StartTime = startTime
+	track.Commit = buildCommit()
 
 	// Injects the MongoDB collection to use.
 	track.Collection = COLLECTION
+	track.CollectionAudit = "Audit"
 	ticker.Collection = COLLECTION
 	webhook.CollectionTrack = COLLECTION
 	webhook.CollectionWebhook = "Webhooks"
 	admin.Collection = COLLECTION
+	task.Collection = "Tasks"
+	task.CollectionResult = "TaskResults"
+	waypoint.Collection = "Waypoints"
+	site.Collection = "Sites"
+	site.CollectionTrack = COLLECTION
+	user.Collection = "Users"
+	user.CollectionSession = "Sessions"
+	user.CollectionTrack = COLLECTION
+	user.CollectionClub = "Clubs"
+	club.Collection = "Clubs"
+	club.CollectionTrack = COLLECTION
+	club.CollectionUser = "Users"
+
+	// The outliers in the fixes of new tracks are removed, unless the enviroment var KEEP_OUTLIERS is "true".
+	analysis.Cleaning.RemoveOutliers = os.Getenv("KEEP_OUTLIERS") != "true"
+
+	// Injects the admin token from the enviroment var, admins can delete and correct all tracks.
+	admin.Token = os.Getenv("ADMIN_TOKEN")
+
+	// Finds the heights above the ground from the SRTM/HGT elevation tiles in the directory given by the
+	// enviroment var, if any. They are used for the min AGL of new tracks, the detection of the takeoff and
+	// landing, and the AGL limits of airspaces.
+	if dir := os.Getenv("DEM_DIR"); dir != "" {
+		tiles, err := terrain.Open(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		analysis.GroundElevation = tiles.Elevation
+		site.GroundElevation = tiles.Elevation
+		airspace.GroundElevation = tiles.Elevation
+	}
+
+	// Loads the airspaces from the OpenAir file given by the enviroment var, if any.
+	airspace.Collection = COLLECTION
+	if file := os.Getenv("AIRSPACE_FILE"); file != "" {
+		if err := airspace.Load(file); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	// Uses mux for regex matching on the HandleFunc paths.
-	router := mux.NewRouter()
+	// Connects to the database once, the requests and jobs use copies of the session.
+	if err := mongodb.Dial(); err != nil {
+		log.Fatal(err)
+	}
 
-	// Functions to handle the URL paths.
-	// Track:
-	router.HandleFunc("/paragliding/", track.RedirectToInfo)
-	router.HandleFunc("/paragliding/api", track.GetAPIInfo)
-	router.HandleFunc("/paragliding/api/track", track.HandleTracks)
-	router.HandleFunc("/paragliding/api/track/{id:[0-9]+}", track.GetTrackByID)
-	router.HandleFunc("/paragliding/api/track/{id:[0-9]+}/{field:[a-z-A-Z-_]+}", track.GetDetailedTrack)
+	// Starts the workers that ingest tracks posted with '?async=true', and resumes the unfinished jobs.
+	job.Collection = "Jobs"
+	job.Process = track.Ingest
+	if err := job.Start(JOBWORKERS); err != nil {
+		log.Fatal(err)
+	}
 
-	// Ticker:
-	router.HandleFunc("/paragliding/api/ticker/latest", ticker.GetLastTimestamp)
-	router.HandleFunc("/paragliding/api/ticker/", ticker.GetTimestamps)
-	router.HandleFunc("/paragliding/api/ticker/{timestamp:[0-9]+}", ticker.GetTimestampsNewerThen)
+	// Starts the workers that send the webhook messages.
+	webhook.StartDeliveries(WEBHOOKWORKERS)
 
-	// Webhook:
-	router.HandleFunc("/paragliding/api/webhook/new_track/", webhook.NewWebhook)
-	router.HandleFunc("/paragliding/api/webhook/new_track/{id:[a-z-A-Z-0-9]+}", webhook.HandleWebhooks)
+	// The service is ready when the database is reachable, and the webhook messages are being sent.
+	health.Checks = []health.Check{
+		{Name: "database", Check: mongodb.Ping},
+		{Name: "webhook_queue", Check: webhook.CheckQueue},
+	}
 
-	// Admin:
-	/* Did not have time to implemet authentication, admin API is turned of.
-	router.HandleFunc("/paragliding/admin/api/tracks_count", admin.GetTrackCount)
-	router.HandleFunc("/paragliding/admin/api/tracks", admin.DeleteAllTracks)
-	*/
+	// The routes of the API.
+	router := server.Router()
 
 	// Gets the port from enviroment var.
 	port := os.Getenv("PORT")
@@ -74,8 +148,61 @@ func main() {
 		port = "8080"
 	}
 
-	// Starts the API.
-	if err := http.ListenAndServe(":"+port, router); err != nil {
+	// Rate limits for each client address and API key. The limits are read from the enviroment vars
+	// RATE_LIMIT_INGEST, RATE_LIMIT_READ and RATE_LIMIT_WEBHOOK if set, like "30/1m".
+	limits := ratelimit.DefaultConfig
+	limits.TrustProxy = os.Getenv("TRUST_PROXY") == "true"
+	for env, limit := range map[string]*ratelimit.Limit{
+		"RATE_LIMIT_INGEST":  &limits.Ingest,
+		"RATE_LIMIT_READ":    &limits.Read,
+		"RATE_LIMIT_WEBHOOK": &limits.Webhook,
+	} {
+		if value := os.Getenv(env); value != "" {
+			parsed, err := ratelimit.ParseLimit(value)
+			if err != nil {
+				log.Fatal(err)
+			}
+			*limit = parsed
+		}
+	}
+	limiter := ratelimit.New(limits)
+
+	// Starts the API. Each request gets a request ID and is logged, also when it is rate limited.
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		log.Fatal(err)
+	}
+	api := server.New(":"+port, logging.Middleware(router, limiter.Middleware(router)))
+
+	// The time to keep serving after the readiness endpoint fails, from the enviroment var SHUTDOWN_DRAIN_DELAY
+	// if set, like "5s".
+	if value := os.Getenv("SHUTDOWN_DRAIN_DELAY"); value != "" {
+		delay, err := time.ParseDuration(value)
+		if err != nil || delay < 0 {
+			log.Fatal("invalid SHUTDOWN_DRAIN_DELAY: ", value)
+		}
+		server.DrainDelay = delay
+	}
+
+	// Serves until SIGTERM or SIGINT, then lets the requests and webhook messages in flight finish.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	if err := server.Serve(ctx, api, listener, server.ShutdownTimeout); err != nil && err != http.ErrServerClosed {
 		log.Fatal(err)
 	}
 }
+
+// Returns the commit the app was built from, or "unknown".
+func buildCommit() string {
+	if commit != "" {
+		return commit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for i := 0; i < len(info.Settings); i++ {
+			if info.Settings[i].Key == "vcs.revision" {
+				return info.Settings[i].Value
+			}
+		}
+	}
+	return "unknown"
+}
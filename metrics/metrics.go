@@ -0,0 +1,146 @@
+/*
+	File: metrics.go
+  Contains the Prometheus metrics of the service, and the middleware that counts the requests of each route.
+*/
+
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// RouteUnmatched is the route label of requests that matched no route.
+const RouteUnmatched = "unmatched"
+
+// Results of fetches and webhook deliveries.
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// RequestsTotal counts the requests of each route, by method and status code.
+var RequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "paragliding_http_requests_total",
+	Help: "Number of HTTP requests, by route template, method and status code.",
+}, []string{"route", "method", "code"})
+
+// RequestDuration is the time the requests of each route took, by method and status code.
+var RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "paragliding_http_request_duration_seconds",
+	Help:    "Time spent handling HTTP requests, by route template, method and status code.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"route", "method", "code"})
+
+// TracksIngested counts the tracks stored, from single posts, batches and jobs.
+var TracksIngested = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "paragliding_tracks_ingested_total",
+	Help: "Number of new tracks stored.",
+})
+
+// ParseFailures counts the IGC files that could not be parsed.
+var ParseFailures = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "paragliding_track_parse_failures_total",
+	Help: "Number of IGC files that could not be parsed.",
+})
+
+// FetchDuration is the time fetching IGC files took, by result. Failed fetches have the error code as result.
+var FetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "paragliding_igc_fetch_duration_seconds",
+	Help:    "Time spent fetching IGC files, by result.",
+	Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+}, []string{"result"})
+
+// WebhookDeliveries counts the messages sent to webhooks, by result after all attempts.
+var WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "paragliding_webhook_deliveries_total",
+	Help: "Number of webhook deliveries, by result after all attempts.",
+}, []string{"result"})
+
+// WebhookRetries counts the attempts to deliver webhook messages after the first.
+var WebhookRetries = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "paragliding_webhook_retries_total",
+	Help: "Number of webhook delivery attempts after the first.",
+})
+
+// MongoDuration is the time the database operations took, by operation.
+var MongoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "paragliding_mongodb_operation_duration_seconds",
+	Help:    "Time spent on MongoDB operations, by operation.",
+	Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+}, []string{"operation"})
+
+// ObserveMongo starts timing a database operation, the returned function stops it.
+// Used as 'defer metrics.ObserveMongo("Insert")()'.
+func ObserveMongo(operation string) func() {
+	start := time.Now()
+	return func() {
+		MongoDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Remembers the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader remembers the status code, and writes it.
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap returns the writer, so streaming handlers can flush it with http.ResponseController.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Returns the path template of the route the request matched, which keeps the number of labels low.
+func route(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return RouteUnmatched
+}
+
+// MethodOther is the method label of requests with a non-standard method.
+const MethodOther = "other"
+
+// Returns the method of the request, or MethodOther if it is not a standard method, which keeps
+// clients from making new labels with made up methods.
+func method(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return r.Method
+	}
+	return MethodOther
+}
+
+// Middleware counts and times the requests to the handler. It is used with router.Use, so the matched
+// route is known, and as the routers NotFoundHandler for the requests that matched no route.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{w, http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		labels := prometheus.Labels{"route": route(r), "method": method(r), "code": strconv.Itoa(recorder.status)}
+		RequestsTotal.With(labels).Inc()
+		RequestDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,82 @@
+/*
+  File: metrics_test.go
+  Contains unit tests for metrics.go
+*/
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// Function to test: Middleware().
+// Test that the requests are counted by the route template, not the raw URL.
+func Test_Middleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Middleware)
+	router.NotFoundHandler = Middleware(http.NotFoundHandler())
+	router.HandleFunc("/paragliding/api/track/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	paths := []string{"/paragliding/api/track/1", "/paragliding/api/track/2", "/unknown"}
+	for i := 0; i < len(paths); i++ {
+		request, _ := http.NewRequest("GET", paths[i], nil)
+		router.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	counter := RequestsTotal.WithLabelValues("/paragliding/api/track/{id:[0-9]+}", "GET", "404")
+	if count := testutil.ToFloat64(counter); count != 2 {
+		t.Errorf("Middleware counted wrong for the route: got %v want %v", count, 2)
+	}
+	counter = RequestsTotal.WithLabelValues(RouteUnmatched, "GET", "404")
+	if count := testutil.ToFloat64(counter); count != 1 {
+		t.Errorf("Middleware counted wrong for unmatched requests: got %v want %v", count, 1)
+	}
+}
+
+// Function to test: Middleware().
+// Test that requests with non-standard methods are counted with the method "other".
+func Test_Middleware_Method(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Middleware)
+	router.HandleFunc("/paragliding/api", func(w http.ResponseWriter, r *http.Request) {})
+
+	methods := []string{"FOO", "BAR", "POST"}
+	for i := 0; i < len(methods); i++ {
+		request, _ := http.NewRequest(methods[i], "/paragliding/api", nil)
+		router.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	counter := RequestsTotal.WithLabelValues("/paragliding/api", MethodOther, "200")
+	if count := testutil.ToFloat64(counter); count != 2 {
+		t.Errorf("Middleware counted wrong for non-standard methods: got %v want %v", count, 2)
+	}
+	counter = RequestsTotal.WithLabelValues("/paragliding/api", "POST", "200")
+	if count := testutil.ToFloat64(counter); count != 1 {
+		t.Errorf("Middleware counted wrong for POST: got %v want %v", count, 1)
+	}
+}
+
+// Function to test: Handler().
+// Test that the metrics are served in the text format.
+func Test_Handler(t *testing.T) {
+	TracksIngested.Inc()
+
+	request, _ := http.NewRequest("GET", "/metrics", nil)
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+	}
+	if !strings.Contains(recorder.Body.String(), "paragliding_tracks_ingested_total 1") {
+		t.Errorf("Handler did not return the metrics: got %s", recorder.Body.String())
+	}
+}
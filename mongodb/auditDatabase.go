@@ -0,0 +1,55 @@
+/*
+	File: auditDatabase.go
+  Handles the mongoDB operations for the audit log of changes to tracks.
+*/
+
+package mongodb
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// Audit actions.
+const (
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// Change is the old and new value of a changed field.
+type Change struct {
+	From string `bson:"from" json:"from"`
+	To   string `bson:"to"   json:"to"`
+}
+
+// AuditEntry is a change to a track, who made it and when.
+// Changes holds the changed fields of an update, by their json name.
+type AuditEntry struct {
+	TrackID int               `bson:"track_id" json:"track_id"`
+	Actor   string            `bson:"actor"    json:"actor"`
+	Action  string            `bson:"action"   json:"action"`
+	Changes map[string]Change `bson:"changes"  json:"changes,omitempty"`
+	Time    time.Time         `bson:"time"     json:"time"`
+}
+
+// InsertAudit adds an entry to the audit log.
+func (m *MongoDB) InsertAudit(a AuditEntry) error {
+	defer m.observe("InsertAudit")()
+	coll, done := m.collection()
+	defer done()
+	return coll.Insert(&a)
+}
+
+// FindAudit finds the audit log of the track with the given ID, oldest first.
+func (m *MongoDB) FindAudit(trackID int) ([]AuditEntry, error) {
+	defer m.observe("FindAudit")()
+	coll, done := m.collection()
+	defer done()
+	var results []AuditEntry
+
+	// Queries the database.
+	err := coll.Find(bson.M{"track_id": trackID}).Sort("time").All(&results)
+
+	return results, err
+}
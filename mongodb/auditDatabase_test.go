@@ -0,0 +1,38 @@
+/*
+  File: auditDatabase_test.go
+  Contains unit tests for auditDatabase.go
+*/
+
+package mongodb
+
+import (
+	"testing"
+	"time"
+)
+
+// Method to test: InsertAudit() and FindAudit().
+// Test that the audit log of a track is returned in order.
+func Test_FindAudit(t *testing.T) {
+	// Connects to the database.
+	database := DatabaseInit("TestAudit")
+
+	now := time.Now()
+	database.InsertAudit(AuditEntry{TrackID: 1, Actor: "admin", Action: AuditDelete, Time: now.Add(time.Minute)})
+	database.InsertAudit(AuditEntry{TrackID: 1, Actor: "admin", Action: AuditUpdate, Time: now,
+		Changes: map[string]Change{"pilot": {"Pilot", "Pilot Name"}}})
+	database.InsertAudit(AuditEntry{TrackID: 2, Actor: "admin", Action: AuditDelete, Time: now})
+
+	entries, err := database.FindAudit(1)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Method returned wrong entries or error: got %v and %v", entries, err)
+	}
+	if entries[0].Action != AuditUpdate || entries[0].Changes["pilot"].To != "Pilot Name" {
+		t.Errorf("Method returned wrong entry: got %v", entries[0])
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+
+	// Closes the database session.
+	defer Close()
+}
@@ -0,0 +1,205 @@
+/*
+	File: clubDatabase.go
+  Handles the mongoDB operations for clubs and their members.
+*/
+
+package mongodb
+
+import (
+	"errors"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+// Roles of the members of a club. Club admins manage the members.
+const (
+	ClubAdmin  = "admin"
+	ClubMember = "member"
+)
+
+// Member is a user in a club.
+type Member struct {
+	UserID int    `bson:"user_id" json:"user_id"`
+	Role   string `bson:"role"    json:"role"`
+}
+
+// Club is a group of users that share tracks.
+type Club struct {
+	ID      int       `bson:"id"      json:"id"`
+	Name    string    `bson:"name"    json:"name"`
+	Members []Member  `bson:"members" json:"members,omitempty"`
+	Created time.Time `bson:"created" json:"created"`
+}
+
+// ErrClubExists is returned when a club with the same name is created.
+var ErrClubExists = errors.New("the club already exists")
+
+// ErrMemberExists is returned when a user that is a member of the club is added.
+var ErrMemberExists = errors.New("the user is a member of the club")
+
+// ErrNotMember is returned when the role of a user that is not a member of the club is changed, or the user is removed.
+var ErrNotMember = errors.New("the user is not a member of the club")
+
+// ErrLastAdmin is returned when the last admin of the club would be removed or lose the role.
+var ErrLastAdmin = errors.New("the club must have an admin")
+
+// Role returns the role of the user in the club, or an empty string if the user is not a member.
+func (c Club) Role(userID int) string {
+	for i := 0; i < len(c.Members); i++ {
+		if c.Members[i].UserID == userID {
+			return c.Members[i].Role
+		}
+	}
+	return ""
+}
+
+// AdminCount returns the number of admins of the club.
+func (c Club) AdminCount() int {
+	count := 0
+	for i := 0; i < len(c.Members); i++ {
+		if c.Members[i].Role == ClubAdmin {
+			count++
+		}
+	}
+	return count
+}
+
+// InsertClub inserts a new club into the database, and returns its ID.
+// The unique index on the name rejects a taken name, also when two clubs are created at the same time.
+func (m *MongoDB) InsertClub(c Club) (int, error) {
+	defer m.observe("InsertClub")()
+	coll, done := m.collection()
+	defer done()
+	if err := m.ensureUnique("id", "name"); err != nil {
+		return 0, err
+	}
+
+	id, err := m.GetNewClubID()
+	if err != nil {
+		return 0, err
+	}
+	c.ID = id
+	err = coll.Insert(&c)
+	if mgo.IsDup(err) {
+		return 0, ErrClubExists
+	}
+	return c.ID, err
+}
+
+// AddMember adds the member to the club with the given ID.
+// The update only matches the club if the user is not a member, so a user is never added twice.
+func (m *MongoDB) AddMember(clubID int, member Member) error {
+	defer m.observe("AddMember")()
+	coll, done := m.collection()
+	defer done()
+	query := bson.M{"id": clubID, "members.user_id": bson.M{"$ne": member.UserID}}
+	err := coll.Update(query, bson.M{"$push": bson.M{"members": member}})
+	if err != mgo.ErrNotFound {
+		return err
+	}
+
+	// The club does not exist, or the user is a member.
+	if _, err := m.FindClub(clubID); err != nil {
+		return err
+	}
+	return ErrMemberExists
+}
+
+// SetMemberRole changes the role of the member of the club with the given ID.
+// The update only matches the club if the user is a member, and an admin only loses the role if the club
+// has another admin. Changes made by others at the same time are kept.
+func (m *MongoDB) SetMemberRole(clubID int, userID int, role string) error {
+	defer m.observe("SetMemberRole")()
+	coll, done := m.collection()
+	defer done()
+	query := bson.M{"id": clubID, "members.user_id": userID}
+	if role != ClubAdmin {
+		// The check for another admin is negated twice, so the positional operator of the update
+		// refers to the member, and not to the other admin.
+		query["$nor"] = []bson.M{{"members": bson.M{"$not": otherAdmin(userID)}}}
+	}
+	err := coll.Update(query, bson.M{"$set": bson.M{"members.$.role": role}})
+	if err != mgo.ErrNotFound {
+		return err
+	}
+	return m.memberError(clubID, userID)
+}
+
+// RemoveMember removes the user from the club with the given ID.
+// The update only matches the club if the user is a member, and the club has another admin.
+func (m *MongoDB) RemoveMember(clubID int, userID int) error {
+	defer m.observe("RemoveMember")()
+	coll, done := m.collection()
+	defer done()
+	query := bson.M{"id": clubID, "members.user_id": userID, "members": otherAdmin(userID)}
+	err := coll.Update(query, bson.M{"$pull": bson.M{"members": bson.M{"user_id": userID}}})
+	if err != mgo.ErrNotFound {
+		return err
+	}
+	return m.memberError(clubID, userID)
+}
+
+// Matches a club with an admin other than the user with the given ID.
+// A club always has an admin, so this only fails for the last admin.
+func otherAdmin(userID int) bson.M {
+	return bson.M{"$elemMatch": bson.M{"role": ClubAdmin, "user_id": bson.M{"$ne": userID}}}
+}
+
+// Finds out why an update of a member did not match the club.
+func (m *MongoDB) memberError(clubID int, userID int) error {
+	club, err := m.FindClub(clubID)
+	if err != nil {
+		return err
+	}
+	if club.Role(userID) == "" {
+		return ErrNotMember
+	}
+	return ErrLastAdmin
+}
+
+// FindClub finds a club by ID.
+func (m *MongoDB) FindClub(id int) (Club, error) {
+	defer m.observe("FindClub")()
+	coll, done := m.collection()
+	defer done()
+	var result Club
+
+	// Queries the database, returns an error if there is no such club.
+	err := coll.Find(bson.M{"id": id}).One(&result)
+
+	return result, err
+}
+
+// FindAllClubs finds all clubs.
+func (m *MongoDB) FindAllClubs() ([]Club, error) {
+	defer m.observe("FindAllClubs")()
+	coll, done := m.collection()
+	defer done()
+	var results []Club
+
+	// Queries the database.
+	err := coll.Find(bson.M{}).Sort("id").All(&results)
+
+	return results, err
+}
+
+// FindClubsByMember finds all clubs the user with the given ID is a member of.
+func (m *MongoDB) FindClubsByMember(userID int) ([]Club, error) {
+	defer m.observe("FindClubsByMember")()
+	coll, done := m.collection()
+	defer done()
+	var results []Club
+
+	// Queries the database.
+	err := coll.Find(bson.M{"members.user_id": userID}).All(&results)
+
+	return results, err
+}
+
+// GetNewClubID returns a new ID that will be used in the Club, from the atomic counter of the collection.
+func (m *MongoDB) GetNewClubID() (int, error) {
+	defer m.observe("GetNewClubID")()
+	return m.newID()
+}
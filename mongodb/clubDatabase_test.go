@@ -0,0 +1,104 @@
+/*
+  File: clubDatabase_test.go
+  Contains unit tests for clubDatabase.go
+*/
+
+package mongodb
+
+import (
+	"testing"
+)
+
+// Method to test: Role() and AdminCount().
+func Test_Club_Role(t *testing.T) {
+	club := Club{Members: []Member{{1, ClubAdmin}, {2, ClubMember}}}
+
+	if role := club.Role(2); role != ClubMember {
+		t.Errorf("Method returned wrong role: got %q want %q", role, ClubMember)
+	}
+	if role := club.Role(3); role != "" {
+		t.Errorf("Method returned a role for a user that is not a member: got %q", role)
+	}
+	if count := club.AdminCount(); count != 1 {
+		t.Errorf("Method counted wrong: got %d want %d", count, 1)
+	}
+}
+
+// Method to test: InsertClub(), AddMember() and FindClubsByMember().
+// Test that the name can not be taken twice, and that the clubs of a member are found.
+func Test_InsertClub(t *testing.T) {
+	// Connects to the database.
+	database := DatabaseInit("TestClubs")
+
+	id, err := database.InsertClub(Club{Name: "club1", Members: []Member{{1, ClubAdmin}}})
+	if err != nil || id != 1 {
+		t.Errorf("Method returned wrong ID or error: got %d and %v", id, err)
+	}
+	if _, err := database.InsertClub(Club{Name: "club1"}); err != ErrClubExists {
+		t.Errorf("Method returned wrong error for a taken name: got %v want %v", err, ErrClubExists)
+	}
+	database.InsertClub(Club{Name: "club2", Members: []Member{{2, ClubAdmin}}})
+
+	// Adds user 2 to the first club.
+	if err := database.AddMember(1, Member{2, ClubMember}); err != nil {
+		t.Errorf("Method returned unexpected error: %v", err)
+	}
+	if err := database.AddMember(1, Member{2, ClubAdmin}); err != ErrMemberExists {
+		t.Errorf("Method returned wrong error for a member: got %v want %v", err, ErrMemberExists)
+	}
+
+	if clubs, _ := database.FindClubsByMember(2); len(clubs) != 2 {
+		t.Errorf("Method queried the DB wrong: got %d length want %d length of slice", len(clubs), 2)
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+
+	// Closes the database session.
+	defer Close()
+}
+
+// Method to test: SetMemberRole() and RemoveMember().
+// Test that the last admin can not lose the role or be removed, and that a removed member is not changed.
+func Test_SetMemberRole(t *testing.T) {
+	// Connects to the database.
+	database := DatabaseInit("TestClubs")
+
+	id, _ := database.InsertClub(Club{Name: "club", Members: []Member{{1, ClubAdmin}, {2, ClubMember}}})
+
+	if err := database.SetMemberRole(id, 1, ClubMember); err != ErrLastAdmin {
+		t.Errorf("Method returned wrong error for the last admin: got %v want %v", err, ErrLastAdmin)
+	}
+	if err := database.RemoveMember(id, 1); err != ErrLastAdmin {
+		t.Errorf("Method returned wrong error for the last admin: got %v want %v", err, ErrLastAdmin)
+	}
+
+	// With a second admin, the first can lose the role.
+	if err := database.SetMemberRole(id, 2, ClubAdmin); err != nil {
+		t.Errorf("Method returned unexpected error: %v", err)
+	}
+	if err := database.SetMemberRole(id, 1, ClubMember); err != nil {
+		t.Errorf("Method returned unexpected error: %v", err)
+	}
+	club, _ := database.FindClub(id)
+	if club.Role(1) != ClubMember || club.Role(2) != ClubAdmin {
+		t.Errorf("Method changed the wrong roles: got %v", club.Members)
+	}
+
+	// A removed member does not come back when the role is changed.
+	if err := database.RemoveMember(id, 1); err != nil {
+		t.Errorf("Method returned unexpected error: %v", err)
+	}
+	if err := database.SetMemberRole(id, 1, ClubAdmin); err != ErrNotMember {
+		t.Errorf("Method returned wrong error for a removed member: got %v want %v", err, ErrNotMember)
+	}
+	if club, _ := database.FindClub(id); len(club.Members) != 1 {
+		t.Errorf("Method returned wrong members: got %v", club.Members)
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+
+	// Closes the database session.
+	defer Close()
+}
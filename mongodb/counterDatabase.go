@@ -0,0 +1,70 @@
+/*
+	File: counterDatabase.go
+  Handles the counters that new IDs are taken from, and the unique indexes that keep the IDs unique.
+*/
+
+package mongodb
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+// CollectionCounter is the MongoDB collection of the counters that new IDs are taken from.
+const CollectionCounter = "Counters"
+
+// Format for a counter, the ID is the collection it counts for.
+type counter struct {
+	ID    string `bson:"_id"`
+	Value int    `bson:"value"`
+}
+
+// Format for the ID of a document.
+type document struct {
+	ID int `bson:"id"`
+}
+
+// Returns a new ID for a document of the collection. The IDs are taken from a counter that is increased
+// atomically, so documents inserted at the same time get different IDs. The counter starts at the highest
+// ID of the stored documents.
+func (m *MongoDB) newID() (int, error) {
+	coll, done := m.collection()
+	defer done()
+	var result []document
+
+	// Gets the document with the highest ID.
+	if err := coll.Find(bson.M{}).Select(bson.M{"id": 1}).Sort("-id").Limit(1).All(&result); err != nil {
+		return 0, err
+	}
+	highest := 0
+	if result != nil {
+		highest = result[0].ID
+	}
+
+	// Creates the counter, or moves it past documents stored without it. $max never lowers it.
+	counters := coll.Database.C(CollectionCounter)
+	if _, err := counters.UpsertId(m.Collection, bson.M{"$max": bson.M{"value": highest}}); err != nil {
+		return 0, err
+	}
+
+	// Increases the counter, and returns the new value.
+	var c counter
+	change := mgo.Change{Update: bson.M{"$inc": bson.M{"value": 1}}, ReturnNew: true}
+	if _, err := counters.FindId(m.Collection).Apply(change, &c); err != nil {
+		return 0, err
+	}
+	return c.Value, nil
+}
+
+// Makes sure the collection has a unique index on each of the keys, so a second document with the same
+// value is rejected with a duplicate key error.
+func (m *MongoDB) ensureUnique(keys ...string) error {
+	coll, done := m.collection()
+	defer done()
+	for i := 0; i < len(keys); i++ {
+		if err := coll.EnsureIndex(mgo.Index{Key: []string{keys[i]}, Unique: true}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+	File: jobDatabase.go
+  Handles the mongoDB operations for track ingestion jobs.
+*/
+
+package mongodb
+
+import (
+	"errors"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// Job statuses.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Job is the ingestion of a track from an url, done in the background.
+// TrackID is set when the job succeeded, and Error when it failed.
+// Owner, Privacy and Clubs are given to the track. RequestID is the ID of the request that queued the job,
+// so what the job logs can be traced back to it.
+type Job struct {
+	ID        int       `bson:"id"         json:"id"`
+	URL       string    `bson:"url"        json:"url"`
+	Owner     int       `bson:"owner"      json:"owner"`
+	Privacy   string    `bson:"privacy"    json:"privacy"`
+	Clubs     []int     `bson:"clubs"      json:"clubs,omitempty"`
+	Status    string    `bson:"status"     json:"status"`
+	TrackID   int       `bson:"track_id"   json:"track_id,omitempty"`
+	Error     string    `bson:"error"      json:"error,omitempty"`
+	Created   time.Time `bson:"created"    json:"created"`
+	Updated   time.Time `bson:"updated"    json:"updated"`
+	RequestID string    `bson:"request_id" json:"-"`
+}
+
+// InsertJob inserts a new queued job into the database, and returns it with its ID.
+func (m *MongoDB) InsertJob(url string, owner int, privacy string, clubs []int, requestID string) (Job, error) {
+	defer m.observe("InsertJob")()
+	coll, done := m.collection()
+	defer done()
+
+	// The IDs are unique, also if two jobs get the same ID by mistake.
+	if err := m.ensureUnique("id"); err != nil {
+		return Job{}, err
+	}
+	id, err := m.GetNewJobID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	now := time.Now().UTC()
+	job := Job{ID: id, URL: url, Owner: owner, Privacy: privacy, Clubs: clubs, Status: JobQueued,
+		Created: now, Updated: now, RequestID: requestID}
+	err = coll.Insert(&job)
+	return job, err
+}
+
+// UpdateJob replaces the stored job with the same ID.
+func (m *MongoDB) UpdateJob(j Job) error {
+	defer m.observe("UpdateJob")()
+	coll, done := m.collection()
+	defer done()
+	j.Updated = time.Now().UTC()
+	return coll.Update(bson.M{"id": j.ID}, &j)
+}
+
+// FindJob finds a job by ID.
+func (m *MongoDB) FindJob(id int) (Job, error) {
+	defer m.observe("FindJob")()
+	coll, done := m.collection()
+	defer done()
+	var result []Job
+
+	// Find job with given 'id'.
+	err := coll.Find(bson.M{"id": id}).All(&result)
+
+	// Generate error if the job with given ID was not found.
+	if result == nil {
+		return Job{}, errors.New("not found")
+	}
+	// Returns the struct, and error if any.
+	return result[0], err
+}
+
+// FindUnfinishedJobs finds the jobs that are queued or running, oldest first.
+func (m *MongoDB) FindUnfinishedJobs() ([]Job, error) {
+	defer m.observe("FindUnfinishedJobs")()
+	coll, done := m.collection()
+	defer done()
+	var results []Job
+
+	// Queries the database.
+	query := bson.M{"status": bson.M{"$in": []string{JobQueued, JobRunning}}}
+	err := coll.Find(query).Sort("id").All(&results)
+
+	return results, err
+}
+
+// GetNewJobID returns a new ID that will be used in the Job, from the atomic counter of the collection.
+func (m *MongoDB) GetNewJobID() (int, error) {
+	defer m.observe("GetNewJobID")()
+	return m.newID()
+}
@@ -0,0 +1,101 @@
+/*
+  File: jobDatabase_test.go
+  Contains unit tests for jobDatabase.go
+*/
+
+package mongodb
+
+import (
+	"sync"
+	"testing"
+)
+
+// Method to test: InsertJob(), UpdateJob() and FindJob().
+// Test that a job can be inserted and its status updated.
+func Test_InsertJob(t *testing.T) {
+	// Connects to the database.
+	database := DatabaseInit("TestJobs")
+
+	// Check to see if insert generates error.
+	job, err := database.InsertJob("http://test.test/track.igc", 1, PrivacyPublic, nil, "")
+	if err != nil || job.ID != 1 || job.Status != JobQueued {
+		t.Errorf("Method returned wrong job or error: got %v and %v", job, err)
+	}
+
+	// The job succeeds.
+	job.Status = JobSucceeded
+	job.TrackID = 3
+	if err := database.UpdateJob(job); err != nil {
+		t.Errorf("Method returned an unexpected error: %v", err)
+	}
+
+	actual, _ := database.FindJob(job.ID)
+	if actual.Status != JobSucceeded || actual.TrackID != 3 {
+		t.Errorf("Method returned wrong job: got %v", actual)
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+
+	// Closes the database session.
+	defer Close()
+}
+
+// Method to test: FindUnfinishedJobs().
+// Test that only queued and running jobs are returned.
+func Test_FindUnfinishedJobs(t *testing.T) {
+	// Connects to the database.
+	database := DatabaseInit("TestJobs")
+
+	database.InsertJob("http://test1.test", 1, PrivacyPublic, nil, "")
+	running, _ := database.InsertJob("http://test2.test", 1, PrivacyPublic, nil, "")
+	done, _ := database.InsertJob("http://test3.test", 1, PrivacyPublic, nil, "")
+	running.Status = JobRunning
+	database.UpdateJob(running)
+	done.Status = JobFailed
+	database.UpdateJob(done)
+
+	jobs, _ := database.FindUnfinishedJobs()
+	if len(jobs) != 2 || jobs[0].ID != 1 || jobs[1].ID != 2 {
+		t.Errorf("Method returned wrong jobs: got %v", jobs)
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+
+	// Closes the database session.
+	defer Close()
+}
+
+// Method to test: GetNewJobID().
+// Test that jobs inserted at the same time get different IDs.
+func Test_GetNewJobID(t *testing.T) {
+	// Connects to the database.
+	database := DatabaseInit("TestJobs")
+
+	var wg sync.WaitGroup
+	ids := make([]int, 10)
+	for i := 0; i < len(ids); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ids[i], _ = database.GetNewJobID()
+		}(i)
+	}
+	wg.Wait()
+
+	seen := map[int]bool{}
+	for i := 0; i < len(ids); i++ {
+		if ids[i] < 1 || ids[i] > len(ids) || seen[ids[i]] {
+			t.Errorf("Method returned a duplicate or wrong ID: got %v", ids)
+			break
+		}
+		seen[ids[i]] = true
+	}
+
+	// Deletes all from the database, and the counter.
+	database.DeleteAll()
+
+	// Closes the database session.
+	defer Close()
+}
@@ -0,0 +1,89 @@
+/*
+	File: siteDatabase.go
+  Handles the mongoDB operations for takeoff and landing sites.
+*/
+
+package mongodb
+
+import (
+	"errors"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// Site is a takeoff or landing site, the radius is in meters.
+// Unnamed sites are created from takeoffs, Takeoffs is the number of takeoffs that made up its position.
+type Site struct {
+	ID        int     `bson:"id"        json:"id"`
+	Name      string  `bson:"name"      json:"name"`
+	Latitude  float64 `bson:"latitude"  json:"latitude"`
+	Longitude float64 `bson:"longitude" json:"longitude"`
+	Radius    float64 `bson:"radius"    json:"radius"`
+	Takeoffs  int     `bson:"takeoffs"  json:"-"`
+}
+
+// InsertSite inserts a new site into the database, and returns its ID.
+func (m *MongoDB) InsertSite(s Site) (int, error) {
+	defer m.observe("InsertSite")()
+	coll, done := m.collection()
+	defer done()
+	s.ID = m.GetNewSiteID()
+	err := coll.Insert(&s)
+	return s.ID, err
+}
+
+// UpdateSite replaces the stored site with the same ID.
+func (m *MongoDB) UpdateSite(s Site) error {
+	defer m.observe("UpdateSite")()
+	coll, done := m.collection()
+	defer done()
+	return coll.Update(bson.M{"id": s.ID}, &s)
+}
+
+// FindAllSites finds all sites in the collection.
+func (m *MongoDB) FindAllSites() ([]Site, error) {
+	defer m.observe("FindAllSites")()
+	coll, done := m.collection()
+	defer done()
+	var results []Site
+
+	// Find all sites in the collection.
+	err := coll.Find(bson.M{}).Sort("id").All(&results)
+
+	// Returns the slice, and error if any.
+	return results, err
+}
+
+// FindSite finds a site by ID.
+func (m *MongoDB) FindSite(id int) (Site, error) {
+	defer m.observe("FindSite")()
+	coll, done := m.collection()
+	defer done()
+	var result []Site
+
+	// Find site with given 'id'.
+	err := coll.Find(bson.M{"id": id}).All(&result)
+
+	// Generate error if the site with given ID was not found.
+	if result == nil {
+		return Site{}, errors.New("not found")
+	}
+	// Returns the struct, and error if any.
+	return result[0], err
+}
+
+// GetNewSiteID returns a new ID that will be used in the Site.
+func (m *MongoDB) GetNewSiteID() int {
+	defer m.observe("GetNewSiteID")()
+	coll, done := m.collection()
+	defer done()
+	var result []Site
+
+	// Gets the site with the highest ID.
+	err := coll.Find(bson.M{}).Sort("-id").Limit(1).All(&result)
+	if err != nil || result == nil {
+		// If there are no sites. The new ID is 1.
+		return 1
+	}
+	return result[0].ID + 1
+}
@@ -0,0 +1,61 @@
+/*
+  File: siteDatabase_test.go
+  Contains unit tests for siteDatabase.go
+*/
+
+package mongodb
+
+import (
+	"testing"
+	"time"
+)
+
+// Method to test: InsertSite(), UpdateSite() and FindSite().
+// Test that a site can be inserted and updated.
+func Test_InsertSite(t *testing.T) {
+	// Connects to the database.
+	database := DatabaseInit("TestSites")
+
+	// Check to see if insert generates error.
+	id, err := database.InsertSite(Site{Latitude: 60.65, Longitude: 6.425, Radius: 500, Takeoffs: 1})
+	if err != nil || id != 1 {
+		t.Errorf("Method returned wrong ID or error: got %d and %v", id, err)
+	}
+
+	// Names the site.
+	site, _ := database.FindSite(id)
+	site.Name = "Hanguren"
+	if err := database.UpdateSite(site); err != nil {
+		t.Errorf("Method returned an unexpected error: %v", err)
+	}
+
+	actual, _ := database.FindSite(id)
+	if actual.Name != "Hanguren" {
+		t.Errorf("Method returned wrong Site: got %v", actual)
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+
+	// Closes the database session.
+	defer Close()
+}
+
+// Method to test: FindTracksBySite().
+// Test that tracks that took off or landed at the site are found.
+func Test_FindTracksBySite(t *testing.T) {
+	// Connects to the database.
+	database := DatabaseInit("TestTracks")
+
+	database.Insert(Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", TakeoffSite: 1, LandingSite: 2})
+	database.Insert(Track{ID: 2, Timestamp: 12, HDate: time.Now(), Pilot: "pilot2", TakeoffSite: 3, LandingSite: 1})
+	database.Insert(Track{ID: 3, Timestamp: 13, HDate: time.Now(), Pilot: "pilot3", TakeoffSite: 2, LandingSite: 2})
+
+	actual, _ := database.FindTracksBySite(1)
+	if len(actual) != 2 {
+		t.Errorf("Method returned wrong number of tracks: got %d want %d", len(actual), 2)
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+}
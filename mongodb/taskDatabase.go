@@ -0,0 +1,171 @@
+/*
+	File: taskDatabase.go
+  Handles the mongoDB operations for competition tasks and task results.
+*/
+
+package mongodb
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// Turnpoint types used in a task.
+const (
+	TurnpointTakeoff = "takeoff"
+	TurnpointSSS     = "sss"
+	TurnpointNormal  = "turnpoint"
+	TurnpointESS     = "ess"
+	TurnpointGoal    = "goal"
+)
+
+// Goal types used in a task.
+const (
+	GoalCylinder = "cylinder"
+	GoalLine     = "line"
+)
+
+// Turnpoint is a cylinder the pilot has to reach, radius is in meters.
+// The position can be given by the ID of a stored waypoint instead of coordinates.
+type Turnpoint struct {
+	Name       string  `bson:"name"                  json:"name"`
+	WaypointID int     `bson:"waypoint_id,omitempty" json:"waypoint_id,omitempty"`
+	Latitude   float64 `bson:"latitude"              json:"latitude"`
+	Longitude  float64 `bson:"longitude"             json:"longitude"`
+	Radius     float64 `bson:"radius"                json:"radius"`
+	Type       string  `bson:"type"                  json:"type"`
+}
+
+// Task is a competition task that tracks can be validated against.
+type Task struct {
+	ID             int         `bson:"id"               json:"id"`
+	Name           string      `bson:"name"             json:"name"`
+	StartGate      time.Time   `bson:"start_gate"       json:"start_gate"`
+	GoalType       string      `bson:"goal_type"        json:"goal_type"`
+	GoalLineLength float64     `bson:"goal_line_length" json:"goal_line_length"`
+	Turnpoints     []Turnpoint `bson:"turnpoints"       json:"turnpoints"`
+	RequireValid   bool        `bson:"require_valid"    json:"require_valid"`
+	Owner          int         `bson:"owner"            json:"owner"`
+}
+
+// TaskResult is the outcome of validating a track against a task.
+type TaskResult struct {
+	TaskID            int       `bson:"task_id"            json:"-"`
+	Rank              int       `bson:"-"                  json:"rank"`
+	TrackID           int       `bson:"track_id"           json:"track_id"`
+	Pilot             string    `bson:"pilot"              json:"pilot"`
+	TurnpointsReached int       `bson:"turnpoints_reached" json:"turnpoints_reached"`
+	StartTime         time.Time `bson:"start_time"         json:"start_time"`
+	ESSTime           time.Time `bson:"ess_time"           json:"ess_time"`
+	SpeedSectionTime  int64     `bson:"speed_section_time" json:"speed_section_time"`
+	MadeGoal          bool      `bson:"made_goal"          json:"made_goal"`
+	Validation        string    `bson:"validation"         json:"validation"`
+}
+
+// InsertTask inserts a new task into the database.
+func (m *MongoDB) InsertTask(t Task) error {
+	defer m.observe("InsertTask")()
+	coll, done := m.collection()
+	defer done()
+
+	// The IDs are unique, also if two tasks get the same ID by mistake.
+	if err := m.ensureUnique("id"); err != nil {
+		return err
+	}
+	err := coll.Insert(&t)
+	return err
+}
+
+// FindAllTasks finds all tasks in the collection.
+func (m *MongoDB) FindAllTasks() ([]Task, error) {
+	defer m.observe("FindAllTasks")()
+	coll, done := m.collection()
+	defer done()
+	var results []Task
+
+	// Find all tasks in the collection.
+	err := coll.Find(bson.M{}).All(&results)
+
+	// Returns the slice, and error if any.
+	return results, err
+}
+
+// FindTask finds a task by ID.
+func (m *MongoDB) FindTask(id int) (Task, error) {
+	defer m.observe("FindTask")()
+	coll, done := m.collection()
+	defer done()
+	var result []Task
+
+	// Find task with given 'id'.
+	err := coll.Find(bson.M{"id": id}).All(&result)
+
+	// Generate error if the task with given ID was not found.
+	if result == nil {
+		return Task{}, errors.New("not found")
+	}
+	// Returns the struct, and error if any.
+	return result[0], err
+}
+
+// GetNewTaskID returns a new ID that will be used in the Task, from the atomic counter of the collection.
+func (m *MongoDB) GetNewTaskID() (int, error) {
+	defer m.observe("GetNewTaskID")()
+	return m.newID()
+}
+
+// UpsertTaskResult inserts a task result, or replaces the result a track already has for the task.
+func (m *MongoDB) UpsertTaskResult(r TaskResult) error {
+	defer m.observe("UpsertTaskResult")()
+	coll, done := m.collection()
+	defer done()
+	_, err := coll.Upsert(bson.M{"task_id": r.TaskID, "track_id": r.TrackID}, &r)
+	return err
+}
+
+// FindTaskResults finds all results for a task, ranked from best to worst.
+func (m *MongoDB) FindTaskResults(taskID int) ([]TaskResult, error) {
+	defer m.observe("FindTaskResults")()
+	coll, done := m.collection()
+	defer done()
+	var results []TaskResult
+
+	// Find all results for the given task.
+	err := coll.Find(bson.M{"task_id": taskID}).All(&results)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ranks the results and returns them.
+	return RankTaskResults(results), nil
+}
+
+// RankTaskResults sorts the results and sets the rank of each result.
+// Pilots that made goal are ranked by speed section time, the rest by turnpoints reached.
+func RankTaskResults(results []TaskResult) []TaskResult {
+	// The function works on a buffer.
+	buffer := append([]TaskResult(nil), results...)
+
+	sort.SliceStable(buffer, func(i, j int) bool {
+		a, b := buffer[i], buffer[j]
+		if a.MadeGoal != b.MadeGoal {
+			return a.MadeGoal
+		}
+		if a.MadeGoal {
+			return a.SpeedSectionTime < b.SpeedSectionTime
+		}
+		if a.TurnpointsReached != b.TurnpointsReached {
+			return a.TurnpointsReached > b.TurnpointsReached
+		}
+		return a.TrackID < b.TrackID
+	})
+
+	// Sets the rank, starting on 1.
+	for i := 0; i < len(buffer); i++ {
+		buffer[i].Rank = i + 1
+	}
+	return buffer
+}
@@ -0,0 +1,70 @@
+/*
+  File: taskDatabase_test.go
+  Contains unit tests for taskDatabase.go
+*/
+
+package mongodb
+
+import (
+	"testing"
+)
+
+// Method to test: InsertTask(), FindTask() and GetNewTaskID().
+// Test if the correct task is inserted into the database, and the next ID is generated.
+func Test_InsertTask(t *testing.T) {
+	// Connects to the database.
+	database := DatabaseInit("TestTasks")
+
+	expected := Task{ID: 1, Name: "task1", GoalType: GoalCylinder,
+		Turnpoints: []Turnpoint{{Name: "goal", Latitude: 60, Longitude: 10, Radius: 400, Type: TurnpointGoal}}}
+
+	// Check to see if insert generates error.
+	err := database.InsertTask(expected)
+	if err != nil {
+		t.Errorf("Method returned an unexpected error: %v", err)
+	}
+
+	// Check if the same task was returned.
+	actual, err := database.FindTask(1)
+	if err != nil || actual.Name != expected.Name {
+		t.Errorf("Method returned wrong Task: got %v want %v", actual, expected)
+	}
+
+	// Check if the next ID is 2.
+	if id, err := database.GetNewTaskID(); err != nil || id != 2 {
+		t.Errorf("Method returned wrong ID: got %d, %v want %d", id, err, 2)
+	}
+
+	// Check that a second task with the same ID is rejected.
+	if err := database.InsertTask(expected); err == nil {
+		t.Error("Method inserted a task with an ID in use")
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+
+	// Closes the database session.
+	defer Close()
+}
+
+// Function to test: RankTaskResults().
+// Test that pilots in goal are ranked first by speed, then the rest by turnpoints reached.
+func Test_RankTaskResults(t *testing.T) {
+	results := []TaskResult{
+		{TrackID: 1, TurnpointsReached: 2},
+		{TrackID: 2, TurnpointsReached: 4, MadeGoal: true, SpeedSectionTime: 3000},
+		{TrackID: 3, TurnpointsReached: 3},
+		{TrackID: 4, TurnpointsReached: 4, MadeGoal: true, SpeedSectionTime: 2000},
+	}
+
+	// Expected order of the track IDs.
+	expected := []int{4, 2, 3, 1}
+
+	actual := RankTaskResults(results)
+	for i := 0; i < len(expected); i++ {
+		if actual[i].TrackID != expected[i] || actual[i].Rank != i+1 {
+			t.Errorf("Function returned wrong ranking at %d: got track %d (rank %d) want track %d (rank %d)",
+				i, actual[i].TrackID, actual[i].Rank, expected[i], i+1)
+		}
+	}
+}
@@ -6,24 +6,128 @@
 package mongodb
 
 import (
+	"context"
 	"errors"
+	"log/slog"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/metrics"
 )
 
 // Track is the metadata about the track that will be stored in the database.
+// MinAGL is the lowest height in meters above the ground during the flight, nil if the ground is unknown, and
+// Terrain how much of the ground under the track is known (see the terrain package). Both are empty for tracks
+// stored without elevation tiles.
 type Track struct {
-	ID          int       `json:"-"`
-	Timestamp   int64     `bson:"timestamp"     json:"-"`
-	HDate       time.Time `bson:"H_date"        json:"H_date"`
-	Pilot       string    `bson:"pilot"         json:"pilot"`
-	Glider      string    `bson:"glider"        json:"glider"`
-	GliderID    string    `bson:"glider_id"     json:"glider_id"`
-	TrackLength float64   `bson:"track_length"  json:"track_length"`
-	TrackSrcURL string    `bson:"track_src_url" json:"track_src_url"`
+	ID               int       `json:"-"`
+	Timestamp        int64     `bson:"timestamp"          json:"-"`
+	HDate            time.Time `bson:"H_date"             json:"H_date"`
+	Pilot            string    `bson:"pilot"              json:"pilot"`
+	Glider           string    `bson:"glider"             json:"glider"`
+	GliderID         string    `bson:"glider_id"          json:"glider_id"`
+	TrackLength      float64   `bson:"track_length"       json:"track_length"`
+	TrackLengthClean float64   `bson:"track_length_clean" json:"track_length_clean"`
+	TrackSrcURL      string    `bson:"track_src_url"      json:"track_src_url"`
+	TakeoffSite      int       `bson:"takeoff_site"       json:"takeoff_site"`
+	LandingSite      int       `bson:"landing_site"       json:"landing_site"`
+	MinAGL           *float64  `bson:"min_agl,omitempty"  json:"min_agl"`
+	Terrain          string    `bson:"terrain,omitempty"  json:"terrain"`
+	Validation       string    `bson:"validation"         json:"validation"`
+	Checksum         string    `bson:"checksum"           json:"-"`
+	Deleted          bool      `bson:"deleted"            json:"-"`
+	Owner            int       `bson:"owner"              json:"owner"`
+	Privacy          string    `bson:"privacy"            json:"privacy"`
+	Clubs            []int     `bson:"clubs"              json:"clubs"`
+	Quality          *Quality  `bson:"quality,omitempty"  json:"-"`
+	Airspace         *Airspace `bson:"airspace,omitempty" json:"-"`
+}
+
+// Quality is the data-quality report of the fixes of a track, made when it is stored. Fixes is the number of
+// fixes in the file, and Removed the number left out of the metadata: the duplicates, and the outliers if they
+// were removed. Tracks stored before the reports have none.
+type Quality struct {
+	Fixes      int       `bson:"fixes"      json:"fixes"`
+	Duplicates int       `bson:"duplicates" json:"duplicates"`
+	Removed    int       `bson:"removed"    json:"removed"`
+	Outliers   []Outlier `bson:"outliers"   json:"outliers"`
+	Gaps       []Gap     `bson:"gaps"       json:"gaps"`
+}
+
+// Outlier is a fix that jumped impossibly far from the fix before it. Index is its index in the file, and
+// Speed the speed in km/h of the jump. Reason is "speed" or "acceleration".
+type Outlier struct {
+	Index  int       `bson:"index"  json:"index"`
+	Time   time.Time `bson:"time"   json:"time"`
+	Reason string    `bson:"reason" json:"reason"`
+	Speed  float64   `bson:"speed"  json:"speed"`
+}
+
+// Gap is a time without fixes, like from GPS loss. Duration is in seconds.
+type Gap struct {
+	Start    time.Time `bson:"start"    json:"start"`
+	End      time.Time `bson:"end"      json:"end"`
+	Duration int64     `bson:"duration" json:"duration"`
+}
+
+// Airspace is the check of the fixes of a track against the airspaces loaded when it was stored. Tracks stored
+// before the checks, or without an airspace file, have none.
+type Airspace struct {
+	Infringements []Infringement `bson:"infringements" json:"infringements"`
+}
+
+// Infringement is a continuous period a track was inside an airspace.
+// The margins are how far inside the airspace the track was at most, in meters.
+// Approximate is set if an AGL limit was checked without knowing the ground elevation.
+type Infringement struct {
+	Airspace         string           `bson:"airspace"          json:"airspace"`
+	Class            string           `bson:"class"             json:"class"`
+	Start            time.Time        `bson:"start"             json:"start"`
+	End              time.Time        `bson:"end"               json:"end"`
+	Floor            AirspaceAltitude `bson:"floor"             json:"floor"`
+	Ceiling          AirspaceAltitude `bson:"ceiling"           json:"ceiling"`
+	VerticalMargin   float64          `bson:"vertical_margin"   json:"vertical_margin"`
+	HorizontalMargin float64          `bson:"horizontal_margin" json:"horizontal_margin"`
+	Approximate      bool             `bson:"approximate"       json:"approximate"`
+}
+
+// AirspaceAltitude is a floor or ceiling of an airspace, the value is in meters.
+// Flight levels are stored in meters of standard pressure altitude.
+type AirspaceAltitude struct {
+	Value     float64 `bson:"value"     json:"value"`
+	Reference string  `bson:"reference" json:"reference"`
+	Unlimited bool    `bson:"unlimited" json:"unlimited,omitempty"`
+}
+
+// Public checks if everyone can see the track, tracks without a privacy level are public.
+func (t Track) Public() bool {
+	return t.Privacy == "" || t.Privacy == PrivacyPublic
+}
+
+// Adds the condition that leaves out deleted tracks to a query.
+// Deleted tracks are kept in the database, so their IDs are not used again.
+func notDeleted(query bson.M) bson.M {
+	query["deleted"] = bson.M{"$ne": true}
+	return query
+}
+
+// Adds the condition that leaves out the tracks that are not public to a query.
+// Tracks stored before privacy levels existed have no level, and are public.
+func public(query bson.M) bson.M {
+	query["privacy"] = bson.M{"$nin": []string{PrivacyClub, PrivacyPrivate}}
+	return query
+}
+
+// Adds the condition that leaves out the tracks not shared with the club to a query.
+// Private tracks are left out, even if they are shared.
+func sharedWith(club int, query bson.M) bson.M {
+	query["clubs"] = club
+	query["privacy"] = bson.M{"$ne": PrivacyPrivate}
+	return query
 }
 
 // MongoDB - holds the database information.
@@ -33,42 +137,125 @@ type MongoDB struct {
 	Collection string
 	Username   string
 	Password   string
+	// The logger of the request or job using the database.
+	logger *slog.Logger
 }
 
-// MDB is the database that connected.
+// MDB is the database of the root session. It is dialed once, and each operation uses a copy of the session.
 var MDB *mgo.Database
 
-// Connect to the database.
+// Locks MDB, since the database is used by many requests at the same time.
+var mdbLock sync.Mutex
+
+// Connect to the database, unless the root session is dialed.
 func (m *MongoDB) Connect() {
+	// An error in the mgo.Dial leaves the database unconnected, and wil create a Panic(err) in the operations.
+	m.dial()
+}
+
+// Dials the root session, unless it is dialed.
+func (m *MongoDB) dial() error {
+	mdbLock.Lock()
+	defer mdbLock.Unlock()
+	if MDB != nil {
+		return nil
+	}
+
 	// Creates the Database URL.
 	dbURL := string(m.Username + ":" + m.Password + "@" + m.Server + "/" + m.Database)
 
 	// Starts the session.
-	// An error in the mgo.Dial wil create a Panic(err) in the mgo package.
-	session, _ := mgo.Dial(dbURL)
+	session, err := mgo.Dial(dbURL)
+	if err != nil {
+		return err
+	}
 
-	// Saves the name of the Database that was connected.
+	// Saves the Database that was connected.
 	MDB = session.DB(m.Database)
+	return nil
+}
+
+// Dial connects to the database at startup. All requests and jobs share the one root session.
+func Dial() error {
+	database := newDatabase("")
+	return database.dial()
+}
+
+// Returns the collection of m on a copy of the root session, so each operation gets a connection of its own.
+// The returned function closes the copy. Used as 'coll, done := m.collection()' and 'defer done()'.
+func (m *MongoDB) collection() (*mgo.Collection, func()) {
+	mdbLock.Lock()
+	database := MDB
+	mdbLock.Unlock()
+	session := database.Session.Copy()
+	return database.With(session).C(m.Collection), session.Close
+}
+
+// WithCollection returns the database using another collection.
+func (m MongoDB) WithCollection(coll string) MongoDB {
+	m.Collection = coll
+	return m
+}
+
+// Close closes the root session. The next DatabaseInit dials it again.
+func Close() {
+	mdbLock.Lock()
+	defer mdbLock.Unlock()
+	if MDB != nil && MDB.Session != nil {
+		MDB.Session.Close()
+	}
+	MDB = nil
+}
+
+// PingTimeout is the time Ping waits for the database.
+var PingTimeout = 2 * time.Second
+
+// Ping checks that the database of the root session is reachable.
+func Ping() error {
+	mdbLock.Lock()
+	database := MDB
+	mdbLock.Unlock()
+	if database == nil || database.Session == nil {
+		return errors.New("the database is not connected")
+	}
+	// Uses a copy of the session, so the timeouts do not change the sessions of the requests.
+	session := database.Session.Copy()
+	defer session.Close()
+	session.SetSyncTimeout(PingTimeout)
+	session.SetSocketTimeout(PingTimeout)
+	return session.Ping()
 }
 
 // Insert a new Struct into the database.
 func (m *MongoDB) Insert(t Track) error {
-	err := MDB.C(m.Collection).Insert(&t)
+	defer m.observe("Insert")()
+	coll, done := m.collection()
+	defer done()
+	err := coll.Insert(&t)
 	return err
 }
 
-// DeleteAll deletes all entries in the database collection.
+// DeleteAll deletes all entries in the database collection, and the counter of its IDs if it has one.
 func (m *MongoDB) DeleteAll() error {
-	_, err := MDB.C(m.Collection).RemoveAll(bson.M{})
+	defer m.observe("DeleteAll")()
+	coll, done := m.collection()
+	defer done()
+	if _, err := coll.RemoveAll(bson.M{}); err != nil {
+		return err
+	}
+	_, err := coll.Database.C(CollectionCounter).RemoveAll(bson.M{"_id": m.Collection})
 	return err
 }
 
 // FindAll finds all entries in the collection.
 func (m *MongoDB) FindAll() ([]Track, error) {
+	defer m.observe("FindAll")()
+	coll, done := m.collection()
+	defer done()
 	var results []Track
 
-	// Find all tracks in the collection.
-	err := MDB.C(m.Collection).Find(bson.M{}).All(&results)
+	// Find all tracks in the collection, that are not deleted.
+	err := coll.Find(notDeleted(bson.M{})).All(&results)
 
 	// Returns the struct, and error if any.
 	return results, err
@@ -76,10 +263,13 @@ func (m *MongoDB) FindAll() ([]Track, error) {
 
 // FindByID finds entry by ID.
 func (m *MongoDB) FindByID(id int) ([]Track, error) {
+	defer m.observe("FindByID")()
+	coll, done := m.collection()
+	defer done()
 	var result []Track
 
 	// Find track with given 'id'.
-	err := MDB.C(m.Collection).Find(bson.M{"id": id}).All(&result)
+	err := coll.Find(notDeleted(bson.M{"id": id})).All(&result)
 
 	// Generate error if track with given ID was not found.
 	if result == nil {
@@ -91,7 +281,10 @@ func (m *MongoDB) FindByID(id int) ([]Track, error) {
 
 // GetCount gets the count of all tracks in the database.
 func (m *MongoDB) GetCount() (int, error) {
-	count, err := MDB.C(m.Collection).Count()
+	defer m.observe("GetCount")()
+	coll, done := m.collection()
+	defer done()
+	count, err := coll.Find(notDeleted(bson.M{})).Count()
 	if err != nil {
 		return 0, err
 	}
@@ -101,10 +294,14 @@ func (m *MongoDB) GetCount() (int, error) {
 
 // GetNewID returns a new ID that wil be used in the Track.
 func (m *MongoDB) GetNewID() int {
+	defer m.observe("GetNewID")()
+	coll, done := m.collection()
+	defer done()
 	// For readability, mongoDB`s ID wil not be used.
 
-	// Gets all tracks from the DB.
-	tracks, err := m.FindAll()
+	// Gets all tracks from the DB, including the deleted tracks.
+	var tracks []Track
+	err := coll.Find(bson.M{}).All(&tracks)
 	if err != nil || tracks == nil {
 		// If there are no tracks. The new ID is 1.
 		return 1
@@ -118,29 +315,183 @@ func (m *MongoDB) GetNewID() int {
 
 // FindTrackHigherThen finds all entries that have a higher timestamp than the parameter.
 func (m *MongoDB) FindTrackHigherThen(ts int64) ([]Track, error) {
+	defer m.observe("FindTrackHigherThen")()
+	coll, done := m.collection()
+	defer done()
+	var results []Track
+
+	// Queries the database.
+	err := coll.Find(notDeleted(bson.M{"timestamp": bson.M{"$gt": ts}})).All(&results)
+
+	return results, err
+}
+
+// FindTracksBySite finds all tracks that took off or landed at the site with the given ID.
+func (m *MongoDB) FindTracksBySite(siteID int) ([]Track, error) {
+	defer m.observe("FindTracksBySite")()
+	coll, done := m.collection()
+	defer done()
+	var results []Track
+
+	// Queries the database.
+	query := bson.M{"$or": []bson.M{{"takeoff_site": siteID}, {"landing_site": siteID}}}
+	err := coll.Find(notDeleted(query)).All(&results)
+
+	return results, err
+}
+
+// FindTracksByValidation finds all tracks with the given G-record validation status.
+func (m *MongoDB) FindTracksByValidation(status string) ([]Track, error) {
+	defer m.observe("FindTracksByValidation")()
+	coll, done := m.collection()
+	defer done()
 	var results []Track
 
 	// Queries the database.
-	err := MDB.C(m.Collection).Find(bson.M{"timestamp": bson.M{"$gt": ts}}).All(&results)
+	err := coll.Find(notDeleted(bson.M{"validation": status})).All(&results)
 
 	return results, err
 }
 
-// DatabaseInit Initialises the database, and connects to it.
+// FindTrackByChecksum finds the track with the given checksum of its IGC file.
+func (m *MongoDB) FindTrackByChecksum(checksum string) (Track, error) {
+	defer m.observe("FindTrackByChecksum")()
+	coll, done := m.collection()
+	defer done()
+	var result Track
+
+	// Queries the database, returns an error if there is no such track.
+	err := coll.Find(notDeleted(bson.M{"checksum": checksum})).One(&result)
+
+	return result, err
+}
+
+// UpdateTrack replaces the stored track with the same ID.
+func (m *MongoDB) UpdateTrack(t Track) error {
+	defer m.observe("UpdateTrack")()
+	coll, done := m.collection()
+	defer done()
+	return coll.Update(bson.M{"id": t.ID}, &t)
+}
+
+// SoftDeleteTrack marks the track with the given ID as deleted, it is left out of all queries.
+func (m *MongoDB) SoftDeleteTrack(id int) error {
+	defer m.observe("SoftDeleteTrack")()
+	coll, done := m.collection()
+	defer done()
+	return coll.Update(notDeleted(bson.M{"id": id}), bson.M{"$set": bson.M{"deleted": true}})
+}
+
+// FindTracksByOwner finds all tracks of the user with the given ID.
+func (m *MongoDB) FindTracksByOwner(owner int) ([]Track, error) {
+	defer m.observe("FindTracksByOwner")()
+	coll, done := m.collection()
+	defer done()
+	var results []Track
+
+	// Queries the database.
+	err := coll.Find(notDeleted(bson.M{"owner": owner})).Sort("id").All(&results)
+
+	return results, err
+}
+
+// FindAllPublic finds all public tracks, used where no user is known, like tickers and webhooks.
+func (m *MongoDB) FindAllPublic() ([]Track, error) {
+	defer m.observe("FindAllPublic")()
+	coll, done := m.collection()
+	defer done()
+	var results []Track
+
+	// Queries the database.
+	err := coll.Find(notDeleted(public(bson.M{}))).All(&results)
+
+	return results, err
+}
+
+// FindPublicTrackHigherThen finds all public tracks with a higher timestamp then the provided one.
+func (m *MongoDB) FindPublicTrackHigherThen(ts int64) ([]Track, error) {
+	defer m.observe("FindPublicTrackHigherThen")()
+	coll, done := m.collection()
+	defer done()
+	var results []Track
+
+	// Queries the database.
+	err := coll.Find(notDeleted(public(bson.M{"timestamp": bson.M{"$gt": ts}}))).All(&results)
+
+	return results, err
+}
+
+// FindClubTracks finds all tracks shared with the club with the given ID.
+func (m *MongoDB) FindClubTracks(club int) ([]Track, error) {
+	defer m.observe("FindClubTracks")()
+	coll, done := m.collection()
+	defer done()
+	var results []Track
+
+	// Queries the database.
+	err := coll.Find(notDeleted(sharedWith(club, bson.M{}))).All(&results)
+
+	return results, err
+}
+
+// FindClubTrackHigherThen finds all tracks shared with the club with a higher timestamp then the provided one.
+func (m *MongoDB) FindClubTrackHigherThen(club int, ts int64) ([]Track, error) {
+	defer m.observe("FindClubTrackHigherThen")()
+	coll, done := m.collection()
+	defer done()
+	var results []Track
+
+	// Queries the database.
+	err := coll.Find(notDeleted(sharedWith(club, bson.M{"timestamp": bson.M{"$gt": ts}}))).All(&results)
+
+	return results, err
+}
+
+// DatabaseInit Initialises the database, and connects to it if the root session is not dialed.
 // The collection to use is given by the parameter.
 func DatabaseInit(coll string) MongoDB {
-	database := MongoDB{
+	database := newDatabase(coll)
+	// Connects to the database and returns the struct.
+	database.Connect()
+	return database
+}
+
+// Returns the database information, without connecting.
+func newDatabase(coll string) MongoDB {
+	return MongoDB{
 		"ds233763.mlab.com:33763",
 		"paragliding_db",
 		coll,
 		"paragliderAPI",
 		"6oLKQOFcxMDCZyd",
+		nil,
 	}
-	// Connects to the database and returns the struct.
-	database.Connect()
+}
+
+// DatabaseContext initialises the database like DatabaseInit, and logs the operations with the logger of the
+// context, so they can be traced back to the request or job.
+func DatabaseContext(ctx context.Context, coll string) MongoDB {
+	database := DatabaseInit(coll)
+	database.logger = logging.FromContext(ctx)
 	return database
 }
 
+// Starts timing an operation, the returned function stops it, and logs it at the debug level.
+// Used as 'defer m.observe("Insert")()'.
+func (m *MongoDB) observe(operation string) func() {
+	start := time.Now()
+	done := metrics.ObserveMongo(operation)
+	return func() {
+		done()
+		logger := m.logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Debug("mongodb operation", "operation", operation, "collection", m.Collection,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000)
+	}
+}
+
 // SortTrackByTimestamp takes a slice of Tracks, sorts them from newest to oldest (increasing), returns the sortet slice.
 func SortTrackByTimestamp(track []Track) []Track {
 	// The function works on a buffer.
@@ -16,7 +16,7 @@ func Test_Insert(t *testing.T) {
 	// Connects to the database.
 	database := DatabaseInit("TestTracks")
 
-	expected := Track{100, 10, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.test"}
+	expected := Track{ID: 100, Timestamp: 10, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.test"}
 
 	// Check to see if insert generates error.
 	err := database.Insert(expected)
@@ -35,7 +35,7 @@ func Test_Insert(t *testing.T) {
 	database.DeleteAll()
 
 	// Closes the database session.
-	defer MDB.Session.Close()
+	defer Close()
 }
 
 // Method to test: DeleteAll().
@@ -48,11 +48,11 @@ func Test_DeleteAll(t *testing.T) {
 	expected := 0
 
 	// Inserts 5 tracks to the database.
-	database.Insert(Track{1, 11, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.test"})
-	database.Insert(Track{2, 12, time.Now(), "pilot2", "glider2", "glider_id2", 20.2, "http://test2.test"})
-	database.Insert(Track{3, 13, time.Now(), "pilot3", "glider3", "glider_id3", 20.3, "http://test3.test"})
-	database.Insert(Track{4, 14, time.Now(), "pilot4", "glider4", "glider_id4", 20.4, "http://test4.test"})
-	database.Insert(Track{5, 15, time.Now(), "pilot5", "glider5", "glider_id5", 20.5, "http://test5.test"})
+	database.Insert(Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.test"})
+	database.Insert(Track{ID: 2, Timestamp: 12, HDate: time.Now(), Pilot: "pilot2", Glider: "glider2", GliderID: "glider_id2", TrackLength: 20.2, TrackSrcURL: "http://test2.test"})
+	database.Insert(Track{ID: 3, Timestamp: 13, HDate: time.Now(), Pilot: "pilot3", Glider: "glider3", GliderID: "glider_id3", TrackLength: 20.3, TrackSrcURL: "http://test3.test"})
+	database.Insert(Track{ID: 4, Timestamp: 14, HDate: time.Now(), Pilot: "pilot4", Glider: "glider4", GliderID: "glider_id4", TrackLength: 20.4, TrackSrcURL: "http://test4.test"})
+	database.Insert(Track{ID: 5, Timestamp: 15, HDate: time.Now(), Pilot: "pilot5", Glider: "glider5", GliderID: "glider_id5", TrackLength: 20.5, TrackSrcURL: "http://test5.test"})
 
 	// Check for errors when deleting.
 	err := database.DeleteAll()
@@ -83,7 +83,7 @@ func Test_FindAll_Empty(t *testing.T) {
 	}
 
 	// Closes the database session.
-	defer MDB.Session.Close()
+	defer Close()
 }
 
 // Method to test: FindAll().
@@ -95,12 +95,12 @@ func Test_FindAll(t *testing.T) {
 	// Expected results from the database.
 	var expected []Track
 	expected = append(expected,
-		Track{1, 11, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.test"},
-		Track{2, 12, time.Now(), "pilot2", "glider2", "glider_id2", 20.2, "http://test2.test"})
+		Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.test"},
+		Track{ID: 2, Timestamp: 12, HDate: time.Now(), Pilot: "pilot2", Glider: "glider2", GliderID: "glider_id2", TrackLength: 20.2, TrackSrcURL: "http://test2.test"})
 
 	// Test if correct track slice is retunred when collection has data.
-	database.Insert(Track{1, 11, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.test"})
-	database.Insert(Track{2, 12, time.Now(), "pilot2", "glider2", "glider_id2", 20.2, "http://test2.test"})
+	database.Insert(Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.test"})
+	database.Insert(Track{ID: 2, Timestamp: 12, HDate: time.Now(), Pilot: "pilot2", Glider: "glider2", GliderID: "glider_id2", TrackLength: 20.2, TrackSrcURL: "http://test2.test"})
 	actual, _ := database.FindAll()
 
 	// Check if method did not return an emtpy slice.
@@ -148,7 +148,7 @@ func Test_FindAll(t *testing.T) {
 	database.DeleteAll()
 
 	// Closes the database session.
-	defer MDB.Session.Close()
+	defer Close()
 }
 
 // Method to test: FindByID().
@@ -164,7 +164,7 @@ func Test_FindByID_Empty(t *testing.T) {
 	}
 
 	// Closes the database session.
-	defer MDB.Session.Close()
+	defer Close()
 }
 
 // Method to test: FindByID().
@@ -176,10 +176,10 @@ func Test_FindByID(t *testing.T) {
 	// Expected results from the database.
 	var expected []Track
 	expected = append(expected,
-		Track{1, 11, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.test"})
+		Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.test"})
 
 	// Test data.
-	database.Insert(Track{1, 11, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.test"})
+	database.Insert(Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.test"})
 
 	// Check if correct track was returned, when given the ID 1.
 	actual, _ := database.FindByID(1)
@@ -193,7 +193,7 @@ func Test_FindByID(t *testing.T) {
 	database.DeleteAll()
 
 	// Closes the database session.
-	defer MDB.Session.Close()
+	defer Close()
 }
 
 // Method to test: GetCount().
@@ -216,7 +216,7 @@ func Test_GetCount_Empty(t *testing.T) {
 	}
 
 	// Closes the database session.
-	defer MDB.Session.Close()
+	defer Close()
 }
 
 // Method to test: GetCount().
@@ -228,7 +228,7 @@ func Test_GetCount(t *testing.T) {
 	expected := 1
 
 	// Test data.
-	database.Insert(Track{1, 11, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.test"})
+	database.Insert(Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.test"})
 
 	actual, err := database.GetCount()
 	if err != nil {
@@ -245,7 +245,7 @@ func Test_GetCount(t *testing.T) {
 	database.DeleteAll()
 
 	// Closes the database session.
-	defer MDB.Session.Close()
+	defer Close()
 }
 
 // Method to test: GetNewID().
@@ -266,7 +266,7 @@ func Test_GetNewID_Empty(t *testing.T) {
 	}
 
 	// Closes the database session.
-	defer MDB.Session.Close()
+	defer Close()
 }
 
 // Method to test: GetNewID().
@@ -275,11 +275,11 @@ func Test_GetNewID(t *testing.T) {
 	database := DatabaseInit("TestTracks")
 
 	// Inserts 5 tracks to the database.
-	database.Insert(Track{1, 11, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.test"})
-	database.Insert(Track{2, 12, time.Now(), "pilot2", "glider2", "glider_id2", 20.2, "http://test2.test"})
-	database.Insert(Track{3, 13, time.Now(), "pilot3", "glider3", "glider_id3", 20.3, "http://test3.test"})
-	database.Insert(Track{4, 14, time.Now(), "pilot4", "glider4", "glider_id4", 20.4, "http://test4.test"})
-	database.Insert(Track{5, 15, time.Now(), "pilot5", "glider5", "glider_id5", 20.5, "http://test5.test"})
+	database.Insert(Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.test"})
+	database.Insert(Track{ID: 2, Timestamp: 12, HDate: time.Now(), Pilot: "pilot2", Glider: "glider2", GliderID: "glider_id2", TrackLength: 20.2, TrackSrcURL: "http://test2.test"})
+	database.Insert(Track{ID: 3, Timestamp: 13, HDate: time.Now(), Pilot: "pilot3", Glider: "glider3", GliderID: "glider_id3", TrackLength: 20.3, TrackSrcURL: "http://test3.test"})
+	database.Insert(Track{ID: 4, Timestamp: 14, HDate: time.Now(), Pilot: "pilot4", Glider: "glider4", GliderID: "glider_id4", TrackLength: 20.4, TrackSrcURL: "http://test4.test"})
+	database.Insert(Track{ID: 5, Timestamp: 15, HDate: time.Now(), Pilot: "pilot5", Glider: "glider5", GliderID: "glider_id5", TrackLength: 20.5, TrackSrcURL: "http://test5.test"})
 
 	// The expected ID to be generated after inserting 5 tracks with ID 1-5.
 	expected := 6
@@ -296,7 +296,7 @@ func Test_GetNewID(t *testing.T) {
 	database.DeleteAll()
 
 	// Closes the database session.
-	defer MDB.Session.Close()
+	defer Close()
 }
 
 // Method to test: FindTrackHigherThen().
@@ -306,11 +306,11 @@ func Test_FindTrackHigherThen(t *testing.T) {
 	database := DatabaseInit("TestTracks")
 
 	// Inserts 5 tracks to the database.
-	database.Insert(Track{1, 11, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.test"})
-	database.Insert(Track{2, 12, time.Now(), "pilot2", "glider2", "glider_id2", 20.2, "http://test2.test"})
-	database.Insert(Track{3, 13, time.Now(), "pilot3", "glider3", "glider_id3", 20.3, "http://test3.test"})
-	database.Insert(Track{4, 14, time.Now(), "pilot4", "glider4", "glider_id4", 20.4, "http://test4.test"})
-	database.Insert(Track{5, 15, time.Now(), "pilot5", "glider5", "glider_id5", 20.5, "http://test5.test"})
+	database.Insert(Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.test"})
+	database.Insert(Track{ID: 2, Timestamp: 12, HDate: time.Now(), Pilot: "pilot2", Glider: "glider2", GliderID: "glider_id2", TrackLength: 20.2, TrackSrcURL: "http://test2.test"})
+	database.Insert(Track{ID: 3, Timestamp: 13, HDate: time.Now(), Pilot: "pilot3", Glider: "glider3", GliderID: "glider_id3", TrackLength: 20.3, TrackSrcURL: "http://test3.test"})
+	database.Insert(Track{ID: 4, Timestamp: 14, HDate: time.Now(), Pilot: "pilot4", Glider: "glider4", GliderID: "glider_id4", TrackLength: 20.4, TrackSrcURL: "http://test4.test"})
+	database.Insert(Track{ID: 5, Timestamp: 15, HDate: time.Now(), Pilot: "pilot5", Glider: "glider5", GliderID: "glider_id5", TrackLength: 20.5, TrackSrcURL: "http://test5.test"})
 
 	// The expected slice lenth to be returned, when querieng for
 	// timestamps higher then 13.
@@ -340,7 +340,114 @@ func Test_FindTrackHigherThen(t *testing.T) {
 	database.DeleteAll()
 
 	// Closes the database session.
-	defer MDB.Session.Close()
+	defer Close()
+}
+
+// Method to test: FindTracksByValidation().
+// Test if only the tracks with the given status are returned.
+func Test_FindTracksByValidation(t *testing.T) {
+	// Connects to the database.
+	database := DatabaseInit("TestTracks")
+
+	// Inserts 3 tracks to the database.
+	database.Insert(Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", TrackSrcURL: "http://test1.test", Validation: "valid"})
+	database.Insert(Track{ID: 2, Timestamp: 12, HDate: time.Now(), Pilot: "pilot2", TrackSrcURL: "http://test2.test", Validation: "unsigned"})
+	database.Insert(Track{ID: 3, Timestamp: 13, HDate: time.Now(), Pilot: "pilot3", TrackSrcURL: "http://test3.test", Validation: "valid"})
+
+	querie, _ := database.FindTracksByValidation("valid")
+	if len(querie) != 2 {
+		t.Errorf("Method queried the DB wrong: got %d length want %d length of slice", len(querie), 2)
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+
+	// Closes the database session.
+	defer Close()
+}
+
+// Method to test: SoftDeleteTrack().
+// Test that a deleted track is left out of the queries, and its ID is not used again.
+func Test_SoftDeleteTrack(t *testing.T) {
+	// Connects to the database.
+	database := DatabaseInit("TestTracks")
+
+	// Inserts 2 tracks to the database.
+	database.Insert(Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", TrackSrcURL: "http://test1.test"})
+	database.Insert(Track{ID: 2, Timestamp: 12, HDate: time.Now(), Pilot: "pilot2", TrackSrcURL: "http://test2.test"})
+
+	if err := database.SoftDeleteTrack(2); err != nil {
+		t.Errorf("Method returned unexpected error: %v", err)
+	}
+
+	if _, err := database.FindByID(2); err == nil {
+		t.Error("Method did not leave out the deleted track")
+	}
+	if count, _ := database.GetCount(); count != 1 {
+		t.Errorf("Method counted wrong: got %d want %d", count, 1)
+	}
+	if id := database.GetNewID(); id != 3 {
+		t.Errorf("Method returned wrong ID: got %d want %d", id, 3)
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+
+	// Closes the database session.
+	defer Close()
+}
+
+// Method to test: FindAllPublic(), FindPublicTrackHigherThen() and FindTracksByOwner().
+// Test that club and private tracks are left out, and that the owner finds all of their tracks.
+func Test_FindAllPublic(t *testing.T) {
+	// Connects to the database.
+	database := DatabaseInit("TestTracks")
+
+	// Inserts 3 tracks to the database, the first has no privacy level.
+	database.Insert(Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", TrackSrcURL: "http://test1.test"})
+	database.Insert(Track{ID: 2, Timestamp: 12, HDate: time.Now(), Pilot: "pilot2", TrackSrcURL: "http://test2.test", Owner: 7, Privacy: PrivacyPrivate})
+	database.Insert(Track{ID: 3, Timestamp: 13, HDate: time.Now(), Pilot: "pilot3", TrackSrcURL: "http://test3.test", Owner: 7, Privacy: PrivacyPublic})
+
+	if querie, _ := database.FindAllPublic(); len(querie) != 2 {
+		t.Errorf("Method queried the DB wrong: got %d length want %d length of slice", len(querie), 2)
+	}
+	if querie, _ := database.FindPublicTrackHigherThen(11); len(querie) != 1 {
+		t.Errorf("Method queried the DB wrong: got %d length want %d length of slice", len(querie), 1)
+	}
+	if querie, _ := database.FindTracksByOwner(7); len(querie) != 2 {
+		t.Errorf("Method queried the DB wrong: got %d length want %d length of slice", len(querie), 2)
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+
+	// Closes the database session.
+	defer Close()
+}
+
+// Method to test: FindClubTracks() and FindClubTrackHigherThen().
+// Test that only the tracks shared with the club are returned, and not the private ones.
+func Test_FindClubTracks(t *testing.T) {
+	// Connects to the database.
+	database := DatabaseInit("TestTracks")
+
+	// Inserts 3 tracks to the database, shared with club 1 and 2.
+	database.Insert(Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", TrackSrcURL: "http://test1.test", Privacy: PrivacyClub, Clubs: []int{1, 2}})
+	database.Insert(Track{ID: 2, Timestamp: 12, HDate: time.Now(), Pilot: "pilot2", TrackSrcURL: "http://test2.test", Privacy: PrivacyPublic, Clubs: []int{2}})
+	database.Insert(Track{ID: 3, Timestamp: 13, HDate: time.Now(), Pilot: "pilot3", TrackSrcURL: "http://test3.test", Privacy: PrivacyPrivate, Clubs: []int{2}})
+
+	if querie, _ := database.FindClubTracks(2); len(querie) != 2 {
+		t.Errorf("Method queried the DB wrong: got %d length want %d length of slice", len(querie), 2)
+	}
+	if querie, _ := database.FindClubTrackHigherThen(2, 11); len(querie) != 1 {
+		t.Errorf("Method queried the DB wrong: got %d length want %d length of slice", len(querie), 1)
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+
+	// Closes the database session.
+	defer Close()
 }
 
 // Function to test: SortTrackByTimestamp().
@@ -349,9 +456,9 @@ func Test_SortTrackByTimestamp(t *testing.T) {
 	// Connects the the database and inserts 3 tracks.
 	// The last inserted has the highest timestamp.
 	database := DatabaseInit("TestTracks")
-	database.Insert(Track{1, 111, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.test"})
-	database.Insert(Track{2, 222, time.Now(), "pilot2", "glider2", "glider_id2", 20.2, "http://test2.test"})
-	database.Insert(Track{3, 333, time.Now(), "pilot3", "glider3", "glider_id3", 20.3, "http://test3.test"})
+	database.Insert(Track{ID: 1, Timestamp: 111, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.test"})
+	database.Insert(Track{ID: 2, Timestamp: 222, HDate: time.Now(), Pilot: "pilot2", Glider: "glider2", GliderID: "glider_id2", TrackLength: 20.2, TrackSrcURL: "http://test2.test"})
+	database.Insert(Track{ID: 3, Timestamp: 333, HDate: time.Now(), Pilot: "pilot3", Glider: "glider3", GliderID: "glider_id3", TrackLength: 20.3, TrackSrcURL: "http://test3.test"})
 
 	// Returns all tracks from the DB.
 	tracks, _ := database.FindAll()
@@ -369,7 +476,7 @@ func Test_SortTrackByTimestamp(t *testing.T) {
 	database.DeleteAll()
 
 	// Closes the database session.
-	defer MDB.Session.Close()
+	defer Close()
 }
 
 // Function to test: GenerateTimestamp().
@@ -0,0 +1,125 @@
+/*
+	File: userDatabase.go
+  Handles the mongoDB operations for users and their login sessions.
+*/
+
+package mongodb
+
+import (
+	"errors"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+// Privacy levels of a track. Tracks without a privacy level are public.
+const (
+	PrivacyPublic  = "public"
+	PrivacyClub    = "club"
+	PrivacyPrivate = "private"
+)
+
+// User is a registered user, the password is stored as a hash from a key derivation function.
+type User struct {
+	ID           int       `bson:"id"            json:"id"`
+	Username     string    `bson:"username"      json:"username"`
+	PasswordHash string    `bson:"password_hash" json:"-"`
+	Created      time.Time `bson:"created"       json:"created"`
+}
+
+// Session is a login of a user. Only the hash of the token is stored.
+type Session struct {
+	TokenHash string    `bson:"token_hash"`
+	UserID    int       `bson:"user_id"`
+	Expires   time.Time `bson:"expires"`
+}
+
+// ErrUserExists is returned when a user with the same username is registered.
+var ErrUserExists = errors.New("the user already exists")
+
+// InsertUser inserts a new user into the database, and returns its ID.
+// The unique index on the username rejects a taken username, also when two users register at the same time.
+func (m *MongoDB) InsertUser(u User) (int, error) {
+	defer m.observe("InsertUser")()
+	coll, done := m.collection()
+	defer done()
+	if err := m.ensureUnique("id", "username"); err != nil {
+		return 0, err
+	}
+
+	id, err := m.GetNewUserID()
+	if err != nil {
+		return 0, err
+	}
+	u.ID = id
+	err = coll.Insert(&u)
+	if mgo.IsDup(err) {
+		return 0, ErrUserExists
+	}
+	return u.ID, err
+}
+
+// FindUser finds a user by ID.
+func (m *MongoDB) FindUser(id int) (User, error) {
+	defer m.observe("FindUser")()
+	coll, done := m.collection()
+	defer done()
+	var result User
+
+	// Queries the database, returns an error if there is no such user.
+	err := coll.Find(bson.M{"id": id}).One(&result)
+
+	return result, err
+}
+
+// FindUserByName finds a user by username.
+func (m *MongoDB) FindUserByName(username string) (User, error) {
+	defer m.observe("FindUserByName")()
+	coll, done := m.collection()
+	defer done()
+	var result User
+
+	// Queries the database, returns an error if there is no such user.
+	err := coll.Find(bson.M{"username": username}).One(&result)
+
+	return result, err
+}
+
+// GetNewUserID returns a new ID that will be used in the User, from the atomic counter of the collection.
+func (m *MongoDB) GetNewUserID() (int, error) {
+	defer m.observe("GetNewUserID")()
+	return m.newID()
+}
+
+// InsertSession inserts a new session into the database.
+func (m *MongoDB) InsertSession(s Session) error {
+	defer m.observe("InsertSession")()
+	coll, done := m.collection()
+	defer done()
+	return coll.Insert(&s)
+}
+
+// FindSession finds the session with the given token hash, that has not expired.
+func (m *MongoDB) FindSession(tokenHash string) (Session, error) {
+	defer m.observe("FindSession")()
+	coll, done := m.collection()
+	defer done()
+	var result Session
+
+	// Queries the database, returns an error if there is no such session.
+	query := bson.M{"token_hash": tokenHash, "expires": bson.M{"$gt": time.Now()}}
+	err := coll.Find(query).One(&result)
+
+	return result, err
+}
+
+// DeleteSession deletes the session with the given token hash, and the expired sessions.
+func (m *MongoDB) DeleteSession(tokenHash string) error {
+	defer m.observe("DeleteSession")()
+	coll, done := m.collection()
+	defer done()
+	query := bson.M{"$or": []bson.M{{"token_hash": tokenHash}, {"expires": bson.M{"$lte": time.Now()}}}}
+	_, err := coll.RemoveAll(query)
+	return err
+}
@@ -0,0 +1,70 @@
+/*
+  File: userDatabase_test.go
+  Contains unit tests for userDatabase.go
+*/
+
+package mongodb
+
+import (
+	"testing"
+	"time"
+)
+
+// Method to test: InsertUser(), FindUserByName() and GetNewUserID().
+// Test that a user can be inserted, and the username can not be taken twice.
+func Test_InsertUser(t *testing.T) {
+	// Connects to the database.
+	database := DatabaseInit("TestUsers")
+
+	id, err := database.InsertUser(User{Username: "pilot", PasswordHash: "hash"})
+	if err != nil || id != 1 {
+		t.Errorf("Method returned wrong ID or error: got %d and %v", id, err)
+	}
+	if _, err := database.InsertUser(User{Username: "pilot", PasswordHash: "other"}); err != ErrUserExists {
+		t.Errorf("Method returned wrong error for a taken username: got %v want %v", err, ErrUserExists)
+	}
+
+	actual, err := database.FindUserByName("pilot")
+	if err != nil || actual.ID != 1 || actual.PasswordHash != "hash" {
+		t.Errorf("Method returned wrong user: got %v and %v", actual, err)
+	}
+
+	// The rejected user took an ID from the counter, so the next ID is 3.
+	if id, err := database.GetNewUserID(); err != nil || id != 3 {
+		t.Errorf("Method returned wrong ID or error: got %d and %v want 3", id, err)
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+
+	// Closes the database session.
+	defer Close()
+}
+
+// Method to test: InsertSession(), FindSession() and DeleteSession().
+// Test that expired and deleted sessions are not found.
+func Test_FindSession(t *testing.T) {
+	// Connects to the database.
+	database := DatabaseInit("TestSessions")
+
+	database.InsertSession(Session{TokenHash: "valid", UserID: 1, Expires: time.Now().Add(time.Hour)})
+	database.InsertSession(Session{TokenHash: "expired", UserID: 1, Expires: time.Now().Add(-time.Hour)})
+
+	if session, err := database.FindSession("valid"); err != nil || session.UserID != 1 {
+		t.Errorf("Method returned wrong session: got %v and %v", session, err)
+	}
+	if _, err := database.FindSession("expired"); err == nil {
+		t.Error("Method returned an expired session")
+	}
+
+	database.DeleteSession("valid")
+	if _, err := database.FindSession("valid"); err == nil {
+		t.Error("Method returned a deleted session")
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+
+	// Closes the database session.
+	defer Close()
+}
@@ -0,0 +1,96 @@
+/*
+	File: waypointDatabase.go
+  Handles the mongoDB operations for waypoints.
+*/
+
+package mongodb
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// Waypoint is a named position that can be used as a turnpoint. Elevation is in meters.
+type Waypoint struct {
+	ID          int     `bson:"id"          json:"id"`
+	Name        string  `bson:"name"        json:"name"`
+	Code        string  `bson:"code"        json:"code"`
+	Latitude    float64 `bson:"latitude"    json:"latitude"`
+	Longitude   float64 `bson:"longitude"   json:"longitude"`
+	Elevation   float64 `bson:"elevation"   json:"elevation"`
+	Description string  `bson:"description" json:"description"`
+}
+
+// InsertWaypoints inserts new waypoints into the database, and returns their IDs.
+func (m *MongoDB) InsertWaypoints(waypoints []Waypoint) ([]int, error) {
+	defer m.observe("InsertWaypoints")()
+	coll, done := m.collection()
+	defer done()
+	var ids []int
+
+	// The first ID to use.
+	newID := m.GetNewWaypointID()
+
+	for i := 0; i < len(waypoints); i++ {
+		waypoints[i].ID = newID + i
+		if err := coll.Insert(&waypoints[i]); err != nil {
+			return ids, err
+		}
+		ids = append(ids, waypoints[i].ID)
+	}
+	return ids, nil
+}
+
+// FindWaypoint finds a waypoint by ID.
+func (m *MongoDB) FindWaypoint(id int) (Waypoint, error) {
+	defer m.observe("FindWaypoint")()
+	coll, done := m.collection()
+	defer done()
+	var result []Waypoint
+
+	// Find waypoint with given 'id'.
+	err := coll.Find(bson.M{"id": id}).All(&result)
+
+	// Generate error if the waypoint with given ID was not found.
+	if result == nil {
+		return Waypoint{}, errors.New("not found")
+	}
+	// Returns the struct, and error if any.
+	return result[0], err
+}
+
+// FindWaypointsByName finds all waypoints with a name or code containing the given text.
+// The search is case insensitive, an empty text finds all waypoints.
+func (m *MongoDB) FindWaypointsByName(name string) ([]Waypoint, error) {
+	defer m.observe("FindWaypointsByName")()
+	coll, done := m.collection()
+	defer done()
+	var results []Waypoint
+
+	// Matches the text anywhere in the name or code.
+	pattern := bson.RegEx{Pattern: regexp.QuoteMeta(name), Options: "i"}
+	query := bson.M{"$or": []bson.M{{"name": pattern}, {"code": pattern}}}
+
+	err := coll.Find(query).Sort("id").All(&results)
+
+	// Returns the slice, and error if any.
+	return results, err
+}
+
+// GetNewWaypointID returns a new ID that will be used in the Waypoint.
+func (m *MongoDB) GetNewWaypointID() int {
+	defer m.observe("GetNewWaypointID")()
+	coll, done := m.collection()
+	defer done()
+	var result []Waypoint
+
+	// Gets the waypoint with the highest ID.
+	err := coll.Find(bson.M{}).Sort("-id").Limit(1).All(&result)
+	if err != nil || result == nil {
+		// If there are no waypoints. The new ID is 1.
+		return 1
+	}
+	return result[0].ID + 1
+}
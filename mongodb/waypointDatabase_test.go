@@ -0,0 +1,49 @@
+/*
+  File: waypointDatabase_test.go
+  Contains unit tests for waypointDatabase.go
+*/
+
+package mongodb
+
+import (
+	"testing"
+)
+
+// Method to test: InsertWaypoints(), FindWaypoint() and FindWaypointsByName().
+// Test that the waypoints get increasing IDs, and can be found by ID and name.
+func Test_InsertWaypoints(t *testing.T) {
+	// Connects to the database.
+	database := DatabaseInit("TestWaypoints")
+
+	waypoints := []Waypoint{
+		{Name: "Voss Hanguren", Code: "HANG", Latitude: 60.65, Longitude: 6.425},
+		{Name: "Bomoen", Code: "BOMO", Latitude: 60.625, Longitude: 6.3375},
+	}
+
+	// Check to see if insert generates error, and the IDs returned.
+	ids, err := database.InsertWaypoints(waypoints)
+	if err != nil {
+		t.Errorf("Method returned an unexpected error: %v", err)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Errorf("Method returned wrong IDs: got %v want %v", ids, []int{1, 2})
+	}
+
+	// Check if the waypoint is found by ID.
+	actual, err := database.FindWaypoint(2)
+	if err != nil || actual.Name != "Bomoen" {
+		t.Errorf("Method returned wrong Waypoint: got %v", actual)
+	}
+
+	// Check if the waypoint is found by its code.
+	found, _ := database.FindWaypointsByName("hang")
+	if len(found) != 1 || found[0].ID != 1 {
+		t.Errorf("Method returned wrong Waypoints: got %v", found)
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+
+	// Closes the database session.
+	defer Close()
+}
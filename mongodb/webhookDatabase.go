@@ -13,12 +13,39 @@ import (
 )
 
 // Webhook struct.
+// Events are the event types the webhook subscribes to, no events means only new tracks.
+// Club is the ID of the club the webhook is scoped to, it is then only notified of the tracks shared with the club.
+// Owner is the ID of the user that registered the webhook, 0 if registered before it was stored.
 type Webhook struct {
-	WebhookURL         string `bson:"webhookURL"         json:"webhookURL"`
-	MinTriggerValue    int    `bson:"minTriggerValue"    json:"minTriggerValue"`
-	NumberOfNewInserts int    `bson:"numberOfNewInserts" json:"-"`
+	WebhookURL         string   `bson:"webhookURL"         json:"webhookURL"`
+	MinTriggerValue    int      `bson:"minTriggerValue"    json:"minTriggerValue"`
+	NumberOfNewInserts int      `bson:"numberOfNewInserts" json:"-"`
+	Events             []string `bson:"events,omitempty"   json:"events,omitempty"`
+	Club               int      `bson:"club,omitempty"     json:"club,omitempty"`
+	Owner              int      `bson:"owner,omitempty"    json:"-"`
 }
 
+// EventNewTrack is the event type of webhooks notified about new tracks.
+const EventNewTrack = "new_track"
+
+// Query for the webhooks of a club subscribing to an event type, club 0 are the webhooks not scoped to a club.
+func eventQuery(club int, event string) bson.M {
+	query := bson.M{"events": event}
+	if event == EventNewTrack {
+		// Webhooks without events only subscribe to new tracks.
+		query = bson.M{"$or": []bson.M{{"events": bson.M{"$exists": false}}, {"events": event}}}
+	}
+	if club == 0 {
+		query["club"] = bson.M{"$exists": false}
+	} else {
+		query["club"] = club
+	}
+	return query
+}
+
+// ErrWebhookExists is returned when a webhook with the same url is registered.
+var ErrWebhookExists = errors.New("the webhook allready exists")
+
 // ID of MongoDB webhook object.
 type bsonID struct {
 	ID bson.ObjectId `json:"id" bson:"_id,omitempty"`
@@ -26,10 +53,13 @@ type bsonID struct {
 
 // InsertWebhook inserts a new webhook to the database.
 func (m *MongoDB) InsertWebhook(hook Webhook) (string, error) {
+	defer m.observe("InsertWebhook")()
+	coll, done := m.collection()
+	defer done()
 	var id bsonID
 
 	// Check if the webhook allready exists.
-	count, err := MDB.C(m.Collection).Find(bson.M{"webhookURL": hook.WebhookURL}).Count()
+	count, err := coll.Find(bson.M{"webhookURL": hook.WebhookURL}).Count()
 
 	if err != nil {
 		// There was an error in searching the DB.
@@ -41,7 +71,7 @@ func (m *MongoDB) InsertWebhook(hook Webhook) (string, error) {
 		// The webhook does not exist.
 
 		// Inserts the webhook to the database.
-		err = MDB.C(m.Collection).Insert(&hook)
+		err = coll.Insert(&hook)
 
 		if err != nil {
 			// There was an error in inserting the webhook to the DB.
@@ -50,7 +80,7 @@ func (m *MongoDB) InsertWebhook(hook Webhook) (string, error) {
 		// The insertion was sucessful.
 
 		// Queries the DB for the URL.
-		err := MDB.C(m.Collection).Find(bson.M{"webhookURL": hook.WebhookURL}).One(&id)
+		err := coll.Find(bson.M{"webhookURL": hook.WebhookURL}).One(&id)
 		if err != nil {
 			// There was an error in finding the ID.
 			return "", err
@@ -60,17 +90,31 @@ func (m *MongoDB) InsertWebhook(hook Webhook) (string, error) {
 
 	}
 	// The webhook allready exists, return error.
-	return "", errors.New("the webhook allready exists")
+	return "", ErrWebhookExists
 }
 
 // InvokeWebhooks invokes all webhooks that meet the criteria.
 // This method is called everytime a new track is inserted.
 func (m *MongoDB) InvokeWebhooks() ([]Webhook, error) {
+	return m.InvokeWebhooksN(1)
+}
+
+// InvokeWebhooksN invokes all webhooks that meet the criteria, after n new tracks are inserted at once.
+// The returned webhooks have 'NumberOfNewInserts' as it was before the n new tracks.
+func (m *MongoDB) InvokeWebhooksN(n int) ([]Webhook, error) {
+	return m.InvokeClubWebhooksN(0, n)
+}
+
+// InvokeClubWebhooksN invokes the webhooks of the club that meet the criteria, after n new tracks are shared with it.
+func (m *MongoDB) InvokeClubWebhooksN(club int, n int) ([]Webhook, error) {
+	defer m.observe("InvokeClubWebhooksN")()
+	coll, done := m.collection()
+	defer done()
 	var results []Webhook
 	var returnedHooks []Webhook
 
-	// Find all webhook in the collection.
-	err := MDB.C(m.Collection).Find(bson.M{}).All(&results)
+	// Find all webhooks of the club in the collection subscribing to new tracks.
+	err := coll.Find(eventQuery(club, EventNewTrack)).All(&results)
 
 	if err != nil {
 		// Logs error.
@@ -79,21 +123,21 @@ func (m *MongoDB) InvokeWebhooks() ([]Webhook, error) {
 	// Loops through all webhooks.
 	for i := 0; i < len(results); i++ {
 
-		// Updates all 'NumberOfNewInserts' by 1.
-		err = MDB.C(m.Collection).Update(bson.M{"webhookURL": results[i].WebhookURL},
-			bson.M{"$set": bson.M{"numberOfNewInserts": results[i].NumberOfNewInserts + 1}})
+		// Updates all 'NumberOfNewInserts' by n.
+		err = coll.Update(bson.M{"webhookURL": results[i].WebhookURL},
+			bson.M{"$set": bson.M{"numberOfNewInserts": results[i].NumberOfNewInserts + n}})
 		if err != nil {
 			// Returns error.
 			return nil, err
 		}
 
 		// Check if the subscriber should be notified.
-		if results[i].NumberOfNewInserts+1 >= results[i].MinTriggerValue {
+		if results[i].NumberOfNewInserts+n >= results[i].MinTriggerValue {
 			// Adds the webhook that should be notified to a new slice.
 			returnedHooks = append(returnedHooks, results[i])
 
 			// Sets the 'newInserts' value back to 0.
-			err = MDB.C(m.Collection).Update(bson.M{"webhookURL": results[i].WebhookURL},
+			err = coll.Update(bson.M{"webhookURL": results[i].WebhookURL},
 				bson.M{"$set": bson.M{"numberOfNewInserts": 0}})
 			if err != nil {
 				// Returns error.
@@ -105,6 +149,19 @@ func (m *MongoDB) InvokeWebhooks() ([]Webhook, error) {
 	return returnedHooks, nil
 }
 
+// FindWebhooksByEvent finds all webhooks subscribing to the given event type, that are not scoped to a club.
+func (m *MongoDB) FindWebhooksByEvent(event string) ([]Webhook, error) {
+	defer m.observe("FindWebhooksByEvent")()
+	coll, done := m.collection()
+	defer done()
+	var results []Webhook
+
+	// Queries the database.
+	err := coll.Find(eventQuery(0, event)).All(&results)
+
+	return results, err
+}
+
 // IsObjectIDHex checks if an ID can be a mongodb ID.
 func IsObjectIDHex(s string) bool {
 	if len(s) != 24 {
@@ -116,13 +173,16 @@ func IsObjectIDHex(s string) bool {
 
 // FindWebhook finds a webhook by ID.
 func (m *MongoDB) FindWebhook(id string) (Webhook, error) {
+	defer m.observe("FindWebhook")()
+	coll, done := m.collection()
+	defer done()
 	var result []Webhook
 	errorMessage := "not found"
 
 	// Check if the ID can be a mongodb ID.
 	if IsObjectIDHex(id) {
 		// True, find track with given 'id'.
-		err := MDB.C(m.Collection).Find(bson.M{"_id": bson.ObjectIdHex(id)}).All(&result)
+		err := coll.Find(bson.M{"_id": bson.ObjectIdHex(id)}).All(&result)
 
 		// Generate error if webhook with given ID was not found.
 		if result == nil {
@@ -141,13 +201,16 @@ func (m *MongoDB) FindWebhook(id string) (Webhook, error) {
 
 // DeleteWebhook deletes a webhook with a given ID.
 func (m *MongoDB) DeleteWebhook(id string) (Webhook, error) {
+	defer m.observe("DeleteWebhook")()
+	coll, done := m.collection()
+	defer done()
 	var result []Webhook
 	errorMessage := "not found"
 
 	// Check if the ID can be a mongodb ID.
 	if IsObjectIDHex(id) {
 		// True, get the webhook that should be deleted.
-		err := MDB.C(m.Collection).Find(bson.M{"_id": bson.ObjectIdHex(id)}).All(&result)
+		err := coll.Find(bson.M{"_id": bson.ObjectIdHex(id)}).All(&result)
 
 		// Generate error if webhook with given ID was not found.
 		if result == nil {
@@ -157,7 +220,7 @@ func (m *MongoDB) DeleteWebhook(id string) (Webhook, error) {
 		}
 
 		// Delete the webhook from the database.
-		err = MDB.C(m.Collection).Remove(bson.M{"_id": bson.ObjectIdHex(id)})
+		err = coll.Remove(bson.M{"_id": bson.ObjectIdHex(id)})
 		if err != nil {
 			// Error in removing document, returns the error.
 			return Webhook{}, err
@@ -169,3 +232,15 @@ func (m *MongoDB) DeleteWebhook(id string) (Webhook, error) {
 	err := errors.New(errorMessage)
 	return Webhook{}, err
 }
+
+// DeleteClubWebhooks deletes the webhooks of the owner scoped to the club, and returns how many were deleted.
+func (m *MongoDB) DeleteClubWebhooks(club int, owner int) (int, error) {
+	defer m.observe("DeleteClubWebhooks")()
+	coll, done := m.collection()
+	defer done()
+	info, err := coll.RemoveAll(bson.M{"club": club, "owner": owner})
+	if err != nil {
+		return 0, err
+	}
+	return info.Removed, nil
+}
@@ -17,7 +17,7 @@ func Test_InsertWebhook(t *testing.T) {
 	database := DatabaseInit("TestWebhooks")
 
 	// Creates a webhook to test.
-	hook := Webhook{"http://test.com", 1, 0}
+	hook := Webhook{WebhookURL: "http://test.com", MinTriggerValue: 1, NumberOfNewInserts: 0}
 
 	// Check if insertions of new unused Webhook works.
 	id, err := database.InsertWebhook(hook)
@@ -52,7 +52,7 @@ func Test_InsertWebhook(t *testing.T) {
 	database.DeleteAll()
 
 	// Closes the database session.
-	defer MDB.Session.Close()
+	defer Close()
 }
 
 // Method to test: InvokeWebhooks().
@@ -60,14 +60,14 @@ func Test_InsertWebhook(t *testing.T) {
 func Test_InvokeWebhooks(t *testing.T) {
 	// Connects to database and insert webhooks to test.
 	database := DatabaseInit("TestWebhooks")
-	id, _ := database.InsertWebhook(Webhook{"http://webhook.local", 2, 0})
-	database.InsertWebhook(Webhook{"http://webhook2.local", 99, 0})
-	defer MDB.Session.Close()
+	id, _ := database.InsertWebhook(Webhook{WebhookURL: "http://webhook.local", MinTriggerValue: 2, NumberOfNewInserts: 0})
+	database.InsertWebhook(Webhook{WebhookURL: "http://webhook2.local", MinTriggerValue: 99, NumberOfNewInserts: 0})
+	defer Close()
 
 	// Connects to database and inserts a track.
 	databaseTracks := DatabaseInit("TestTracks")
-	databaseTracks.Insert(Track{1, 111, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.local"})
-	defer MDB.Session.Close()
+	databaseTracks.Insert(Track{ID: 1, Timestamp: 111, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.local"})
+	defer Close()
 
 	// Test the method.
 	database = DatabaseInit("TestWebhooks")
@@ -92,8 +92,8 @@ func Test_InvokeWebhooks(t *testing.T) {
 
 	// Connects to database and inserts a new track.
 	databaseTracks = DatabaseInit("TestTracks")
-	databaseTracks.Insert(Track{2, 222, time.Now(), "pilot2", "glider2", "glider_id2", 20.2, "http://test2.local"})
-	defer MDB.Session.Close()
+	databaseTracks.Insert(Track{ID: 2, Timestamp: 222, HDate: time.Now(), Pilot: "pilot2", Glider: "glider2", GliderID: "glider_id2", TrackLength: 20.2, TrackSrcURL: "http://test2.local"})
+	defer Close()
 
 	// Test the method again.
 	database = DatabaseInit("TestWebhooks")
@@ -109,7 +109,7 @@ func Test_InvokeWebhooks(t *testing.T) {
 	databaseTracks.DeleteAll()
 
 	// Closes the database session.
-	defer MDB.Session.Close()
+	defer Close()
 }
 
 // Method to test: FindWebhook().
@@ -117,9 +117,9 @@ func Test_InvokeWebhooks(t *testing.T) {
 func Test_FindWebhook(t *testing.T) {
 	// Connects to database and insert webhooks to test.
 	database := DatabaseInit("TestWebhooks")
-	database.InsertWebhook(Webhook{"http://webhook.local", 1, 0})
-	id, _ := database.InsertWebhook(Webhook{"http://webhook2.local", 2, 0})
-	database.InsertWebhook(Webhook{"http://webhook3.local", 3, 0})
+	database.InsertWebhook(Webhook{WebhookURL: "http://webhook.local", MinTriggerValue: 1, NumberOfNewInserts: 0})
+	id, _ := database.InsertWebhook(Webhook{WebhookURL: "http://webhook2.local", MinTriggerValue: 2, NumberOfNewInserts: 0})
+	database.InsertWebhook(Webhook{WebhookURL: "http://webhook3.local", MinTriggerValue: 3, NumberOfNewInserts: 0})
 
 	// Expected error message.
 	errorMessage := "not found"
@@ -147,7 +147,7 @@ func Test_FindWebhook(t *testing.T) {
 	database.DeleteAll()
 
 	// Closes the database session.
-	defer MDB.Session.Close()
+	defer Close()
 }
 
 // Method to test: DeleteWebhook().
@@ -155,9 +155,9 @@ func Test_FindWebhook(t *testing.T) {
 func Test_DeleteWebhook(t *testing.T) {
 	// Connects to database and insert webhooks to test.
 	database := DatabaseInit("TestWebhooks")
-	database.InsertWebhook(Webhook{"http://webhook.local", 1, 0})
-	id, _ := database.InsertWebhook(Webhook{"http://webhook2.local", 2, 0})
-	database.InsertWebhook(Webhook{"http://webhook3.local", 3, 0})
+	database.InsertWebhook(Webhook{WebhookURL: "http://webhook.local", MinTriggerValue: 1, NumberOfNewInserts: 0})
+	id, _ := database.InsertWebhook(Webhook{WebhookURL: "http://webhook2.local", MinTriggerValue: 2, NumberOfNewInserts: 0})
+	database.InsertWebhook(Webhook{WebhookURL: "http://webhook3.local", MinTriggerValue: 3, NumberOfNewInserts: 0})
 
 	// Expected error message.
 	errorMessage := "not found"
@@ -194,5 +194,74 @@ func Test_DeleteWebhook(t *testing.T) {
 	database.DeleteAll()
 
 	// Closes the database session.
-	defer MDB.Session.Close()
+	defer Close()
+}
+
+// Method to test: FindWebhooksByEvent().
+// Test that webhooks without events only subscribe to new tracks.
+func Test_FindWebhooksByEvent(t *testing.T) {
+	// Connects to database and insert webhooks to test.
+	database := DatabaseInit("TestWebhooks")
+	database.InsertWebhook(Webhook{WebhookURL: "http://webhook.local", MinTriggerValue: 1})
+	database.InsertWebhook(Webhook{WebhookURL: "http://webhook2.local", MinTriggerValue: 1, Events: []string{"track.airspace"}})
+	defer Close()
+
+	// Both webhooks are found for their own event.
+	hooks, err := database.FindWebhooksByEvent(EventNewTrack)
+	if err != nil || len(hooks) != 1 || hooks[0].WebhookURL != "http://webhook.local" {
+		t.Errorf("Method returned wrong webhooks for new tracks: got %v (%v)", hooks, err)
+	}
+	hooks, err = database.FindWebhooksByEvent("track.airspace")
+	if err != nil || len(hooks) != 1 || hooks[0].WebhookURL != "http://webhook2.local" {
+		t.Errorf("Method returned wrong webhooks for airspace: got %v (%v)", hooks, err)
+	}
+
+	// Deletes all webhooks from the database.
+	database.DeleteAll()
+}
+
+// Method to test: InvokeWebhooksN().
+// Test that many new tracks at once are counted, and notify the webhook once.
+func Test_InvokeWebhooksN(t *testing.T) {
+	// Connects to database and insert a webhook to test.
+	database := DatabaseInit("TestWebhooks")
+	database.InsertWebhook(Webhook{WebhookURL: "http://webhook.local", MinTriggerValue: 5})
+	defer Close()
+
+	// Three tracks are not enough.
+	hooks, _ := database.InvokeWebhooksN(3)
+	if hooks != nil {
+		t.Errorf("Method returned a webhook before it should be notified: got %v", hooks)
+	}
+
+	// Three more are.
+	hooks, _ = database.InvokeWebhooksN(3)
+	if len(hooks) != 1 || hooks[0].NumberOfNewInserts != 3 {
+		t.Errorf("Method returned wrong webhooks: got %v", hooks)
+	}
+
+	// Deletes all webhooks from the database.
+	database.DeleteAll()
+}
+
+// Method to test: InvokeClubWebhooksN().
+// Test that the webhooks of a club are only invoked by the tracks shared with the club.
+func Test_InvokeClubWebhooksN(t *testing.T) {
+	// Connects to database and insert webhooks to test.
+	database := DatabaseInit("TestWebhooks")
+	database.InsertWebhook(Webhook{WebhookURL: "http://webhook.local", MinTriggerValue: 1})
+	database.InsertWebhook(Webhook{WebhookURL: "http://club.local", MinTriggerValue: 1, Club: 1})
+	defer Close()
+
+	hooks, _ := database.InvokeClubWebhooksN(1, 1)
+	if len(hooks) != 1 || hooks[0].WebhookURL != "http://club.local" {
+		t.Errorf("Method returned wrong webhooks for the club: got %v", hooks)
+	}
+	hooks, _ = database.InvokeWebhooksN(1)
+	if len(hooks) != 1 || hooks[0].WebhookURL != "http://webhook.local" {
+		t.Errorf("Method returned wrong webhooks without a club: got %v", hooks)
+	}
+
+	// Deletes all webhooks from the database.
+	database.DeleteAll()
 }
@@ -0,0 +1,273 @@
+/*
+	File: openapi.go
+  Contains the OpenAPI document of the API, the handler serving it, and the validation of request bodies
+  against its schemas.
+*/
+
+package openapi
+
+import (
+	"bytes"
+	_ "embed" // Embeds the OpenAPI document.
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The OpenAPI 3 document, kept in sync with the router by the tests of the server package.
+//
+//go:embed openapi.json
+var document []byte
+
+// The parts of the document used for validation.
+type spec struct {
+	Paths      map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// The subset of JSON schema used by the request bodies.
+// AdditionalProperties is either false, or the schema of the values of a map.
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	MinLength            *int               `json:"minLength"`
+	Pattern              string             `json:"pattern"`
+}
+
+// Prefix of the references to the schemas of the document.
+const schemaRef = "#/components/schemas/"
+
+// The parsed document, the embedded file is checked by the tests so it always parses.
+var parsed = mustParse(document)
+
+// Parses the document.
+func mustParse(doc []byte) spec {
+	var s spec
+	if err := json.Unmarshal(doc, &s); err != nil {
+		panic("openapi: the document is not valid json: " + err.Error())
+	}
+	return s
+}
+
+// GetSpec - GET: Returns the OpenAPI document.
+// Output: application/json
+func GetSpec(w http.ResponseWriter, r *http.Request) {
+	// Sets header content-type to application/json and status code to 200 (OK).
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(document)
+}
+
+// Paths returns the paths of the document, sorted.
+func Paths() []string {
+	paths := make([]string, 0, len(parsed.Paths))
+	for path := range parsed.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Methods returns the methods of the operations of a path of the document, in upper case and sorted.
+func Methods(path string) []string {
+	var operations map[string]json.RawMessage
+	json.Unmarshal(parsed.Paths[path], &operations)
+
+	methods := []string{}
+	for name := range operations {
+		// The parameters shared by the operations are not an operation.
+		if name != "parameters" {
+			methods = append(methods, strings.ToUpper(name))
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// Matches the regular expressions of the variables of mux path templates, e.g. '{id:[0-9]+}'.
+var muxVariable = regexp.MustCompile(`\{([^{}:]+):[^{}]*\}`)
+
+// Path converts a mux path template to an OpenAPI path, by removing the regular expressions of the variables.
+func Path(template string) string {
+	return muxVariable.ReplaceAllString(template, "{$1}")
+}
+
+// Validate validates a json request body against the named schema of the document.
+// The error tells which field is invalid, and why.
+func Validate(name string, body []byte) error {
+	s, ok := parsed.Components.Schemas[name]
+	if !ok {
+		return fmt.Errorf("unknown schema %q", name)
+	}
+
+	// Decodes numbers as json.Number, so integers can be told apart from decimals.
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return errors.New("the body is not valid json")
+	}
+	return validate(value, s, "body")
+}
+
+// Validates a decoded json value against a schema, the path is the name of the value in the errors.
+func validate(value interface{}, s *schema, path string) error {
+	if s.Ref != "" {
+		ref, ok := parsed.Components.Schemas[strings.TrimPrefix(s.Ref, schemaRef)]
+		if !ok {
+			return fmt.Errorf("unknown schema %q", s.Ref)
+		}
+		return validate(value, ref, path)
+	}
+
+	if len(s.Enum) > 0 && !inEnum(value, s.Enum) {
+		return fmt.Errorf("%s should be one of %s", path, enumString(s.Enum))
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s should be an object", path)
+		}
+		return validateObject(object, s, path)
+
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s should be an array", path)
+		}
+		if s.Items == nil {
+			return nil
+		}
+		for i := 0; i < len(array); i++ {
+			if err := validate(array[i], s.Items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s should be a string", path)
+		}
+		if s.MinLength != nil && len(str) < *s.MinLength {
+			return fmt.Errorf("%s should be at least %d characters", path, *s.MinLength)
+		}
+		if s.Pattern != "" {
+			if matched, err := regexp.MatchString(s.Pattern, str); err != nil || !matched {
+				return fmt.Errorf("%s should match %s", path, s.Pattern)
+			}
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return fmt.Errorf("%s should be a RFC 3339 date-time", path)
+			}
+		}
+
+	case "integer", "number":
+		kind := "a number"
+		if s.Type == "integer" {
+			kind = "an integer"
+		}
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s should be %s", path, kind)
+		}
+		if _, err := number.Int64(); s.Type == "integer" && err != nil {
+			return fmt.Errorf("%s should be %s", path, kind)
+		}
+		f, err := number.Float64()
+		if err != nil {
+			return fmt.Errorf("%s should be %s", path, kind)
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			return fmt.Errorf("%s should be at least %v", path, *s.Minimum)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s should be a boolean", path)
+		}
+	}
+	return nil
+}
+
+// Validates the properties of a json object.
+func validateObject(object map[string]interface{}, s *schema, path string) error {
+	for i := 0; i < len(s.Required); i++ {
+		if _, ok := object[s.Required[i]]; !ok {
+			return fmt.Errorf("%s.%s is required", path, s.Required[i])
+		}
+	}
+
+	// Checks the fields in order, so the same body always gives the same error.
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// The schema of the fields that are not properties, nil if they are not allowed.
+	var additional *schema
+	allowAdditional := true
+	if len(s.AdditionalProperties) > 0 {
+		var allowed bool
+		if err := json.Unmarshal(s.AdditionalProperties, &allowed); err == nil {
+			allowAdditional = allowed
+		} else if err := json.Unmarshal(s.AdditionalProperties, &additional); err != nil {
+			return fmt.Errorf("invalid additionalProperties of %s", path)
+		}
+	}
+
+	for i := 0; i < len(names); i++ {
+		name := names[i]
+		property, ok := s.Properties[name]
+		if !ok {
+			property = additional
+		}
+		if property == nil {
+			if !allowAdditional {
+				return fmt.Errorf("%s.%s is not a known field", path, name)
+			}
+			continue
+		}
+		if err := validate(object[name], property, path+"."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Checks if a value is one of the values of an enum.
+func inEnum(value interface{}, enum []interface{}) bool {
+	for i := 0; i < len(enum); i++ {
+		if fmt.Sprint(enum[i]) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the values of an enum as a comma separated string.
+func enumString(enum []interface{}) string {
+	values := make([]string, len(enum))
+	for i := 0; i < len(enum); i++ {
+		values[i] = fmt.Sprint(enum[i])
+	}
+	return strings.Join(values, ", ")
+}
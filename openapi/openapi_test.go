@@ -0,0 +1,113 @@
+/*
+  File: openapi_test.go
+  Contains unit tests for openapi.go
+*/
+
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Function to test: GetSpec().
+func Test_GetSpec(t *testing.T) {
+	request, _ := http.NewRequest("GET", "/paragliding/api/openapi.json", nil)
+	recorder := httptest.NewRecorder()
+	GetSpec(recorder, request)
+
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Handler returned wrong status or content-type: got %v %s", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &doc); err != nil || !strings.HasPrefix(doc["openapi"].(string), "3.") {
+		t.Errorf("Handler returned an invalid OpenAPI 3 document: %v", err)
+	}
+}
+
+// Function to test: Path().
+func Test_Path(t *testing.T) {
+	tests := map[string]string{
+		"/paragliding/api/track":                                  "/paragliding/api/track",
+		"/paragliding/api/track/{id:[0-9]+}":                      "/paragliding/api/track/{id}",
+		"/paragliding/api/track/{id:[0-9]+}/{field:[a-z-A-Z-_]+}": "/paragliding/api/track/{id}/{field}",
+		"/paragliding/api/webhook/new_track/{id}":                 "/paragliding/api/webhook/new_track/{id}",
+	}
+	for template, expected := range tests {
+		if actual := Path(template); actual != expected {
+			t.Errorf("Function returned wrong path for %s: got %s want %s", template, actual, expected)
+		}
+	}
+}
+
+// Test that all the references of the document point to a schema, so no body fails to validate because of the document.
+func Test_References(t *testing.T) {
+	for name, s := range parsed.Components.Schemas {
+		checkReferences(t, name, s)
+	}
+}
+
+// Checks the references of a schema and its subschemas.
+func checkReferences(t *testing.T, name string, s *schema) {
+	if s == nil {
+		return
+	}
+	if s.Ref != "" {
+		if _, ok := parsed.Components.Schemas[strings.TrimPrefix(s.Ref, schemaRef)]; !ok {
+			t.Errorf("Schema %s references unknown schema %s", name, s.Ref)
+		}
+	}
+	for property, p := range s.Properties {
+		checkReferences(t, name+"."+property, p)
+	}
+	checkReferences(t, name+"[]", s.Items)
+}
+
+// Function to test: Validate().
+func Test_Validate(t *testing.T) {
+	tests := []struct {
+		schema string
+		body   string
+		err    string
+	}{
+		{"WebhookRegistration", `{"webhookURL": "http://test.local", "minTriggerValue": 3}`, ""},
+		{"WebhookRegistration", `{"webhookURL": "http://test.local", "events": ["new_track", "track.deleted"], "club": 2}`, ""},
+		{"WebhookRegistration", `{"minTriggerValue": 3}`, "body.webhookURL is required"},
+		{"WebhookRegistration", `{"webhookURL": "http://test.local", "minTriggerValue": "3"}`, "body.minTriggerValue should be an integer"},
+		{"WebhookRegistration", `{"webhookURL": "http://test.local", "minTriggerValue": 1.5}`, "body.minTriggerValue should be an integer"},
+		{"WebhookRegistration", `{"webhookURL": "http://test.local", "minTriggerValue": -1}`, "body.minTriggerValue should be at least 0"},
+		{"WebhookRegistration", `{"webhookURL": "http://test.local", "events": ["track.unknown"]}`, "body.events[0] should be one of new_track, track.airspace, track.deleted"},
+		{"WebhookRegistration", `{"webhookURL": "http://test.local", "minTrigger": 3}`, "body.minTrigger is not a known field"},
+		{"WebhookRegistration", `["http://test.local"]`, "body should be an object"},
+		{"WebhookRegistration", `{"webhookURL": `, "the body is not valid json"},
+		{"TrackRegistration", `{"url": "http://test.local/track.igc", "privacy": "club", "clubs": [1, 2]}`, ""},
+		{"TrackRegistration", `{"url": "http://test.local/track.igc", "privacy": "friends"}`, "body.privacy should be one of public, club, private"},
+		{"TaskRegistration", `{"name": "Task 1", "start_gate": "2018-10-18T12:00:00Z", "turnpoints": [{"type": "sss"}]}`, ""},
+		{"TaskRegistration", `{"name": "Task 1", "start_gate": "noon"}`, "body.start_gate should be a RFC 3339 date-time"},
+		{"TaskRegistration", `{"turnpoints": [{"name": "Start"}]}`, "body.turnpoints[0].type is required"},
+		{"Unknown", `{}`, "unknown schema \"Unknown\""},
+	}
+	for i := 0; i < len(tests); i++ {
+		err := Validate(tests[i].schema, []byte(tests[i].body))
+		actual := ""
+		if err != nil {
+			actual = err.Error()
+		}
+		if actual != tests[i].err {
+			t.Errorf("Function returned wrong error for %s %s: got %q want %q", tests[i].schema, tests[i].body, actual, tests[i].err)
+		}
+	}
+}
+
+// Function to test: Methods().
+func Test_Methods(t *testing.T) {
+	if actual := strings.Join(Methods("/paragliding/api/track/{id}"), ","); actual != "DELETE,GET,PATCH" {
+		t.Errorf("Function returned wrong methods: got %s want %s", actual, "DELETE,GET,PATCH")
+	}
+	if actual := Methods("/unknown"); len(actual) != 0 {
+		t.Errorf("Function returned methods of an unknown path: %v", actual)
+	}
+}
@@ -0,0 +1,161 @@
+/*
+	File: quality.go
+  Contains the cleaning of the fixes of a track: duplicate timestamps are dropped, impossible jumps are flagged
+  and optionally removed, and the gaps from GPS loss are detected. The result is a data-quality report.
+*/
+
+package quality
+
+import (
+	"math"
+	"time"
+
+	igc "github.com/marni/goigc"
+	"github.com/mats93/paragliding/distance"
+	"github.com/mats93/paragliding/mongodb"
+)
+
+// Options of the cleaning.
+type Options struct {
+	// MaxSpeed is the speed in km/h between two fixes, above which the second fix is an outlier.
+	MaxSpeed float64
+	// MaxAcceleration is the change of speed in m/s² between two steps, above which the fix is an outlier.
+	MaxAcceleration float64
+	// MaxGap is the time between two fixes, above which it is a gap.
+	MaxGap time.Duration
+	// RemoveOutliers removes the outliers from the fixes, else they are only flagged in the report.
+	RemoveOutliers bool
+}
+
+// Default is the cleaning of the tracks when they are stored. The limits are far above what any glider flies,
+// so only the spikes of the logger are caught.
+var Default = Options{MaxSpeed: 400, MaxAcceleration: 30, MaxGap: time.Minute, RemoveOutliers: true}
+
+// MaxOutlierRun is the number of outliers in a row, after which the fix they are measured from is taken to be
+// the spike, and the next fix is accepted again. Without it, a spike at the start would make the whole track
+// outliers.
+const MaxOutlierRun = 10
+
+// Reasons of the flagged fixes.
+const (
+	ReasonSpeed        = "speed"
+	ReasonAcceleration = "acceleration"
+)
+
+// Clean cleans the fixes of a flight with the options, and returns the cleaned fixes and the report.
+// The date is the flight date, since the fixes only contain the time of day.
+func Clean(date time.Time, points []igc.Point, options Options) ([]igc.Point, mongodb.Quality) {
+	report := mongodb.Quality{Fixes: len(points), Outliers: []mongodb.Outlier{}, Gaps: []mongodb.Gap{}}
+	if len(points) == 0 {
+		return points, report
+	}
+
+	// A spike at the start: the first fix is an outlier when the jump to the next fix is too fast, and
+	// the jump on from that fix is not.
+	first := 0
+	for first+2 < len(points) && speed(points[first], points[first+1]) > options.MaxSpeed &&
+		speed(points[first+1], points[first+2]) <= options.MaxSpeed {
+		report.Outliers = append(report.Outliers, mongodb.Outlier{
+			Index:  first,
+			Time:   at(date, points[0], points[first]),
+			Reason: ReasonSpeed,
+			Speed:  speed(points[first], points[first+1]),
+		})
+		first++
+	}
+	var cleaned []igc.Point
+	if !options.RemoveOutliers {
+		cleaned = append(cleaned, points[:first]...)
+	}
+	cleaned = append(cleaned, points[first])
+
+	// The last fix the next fix is measured from, the outliers are never used.
+	last := first
+	// The speed in km/h of the last step, the first step has none.
+	var lastSpeed float64
+	hasSpeed := false
+	run := 0
+	for i := first + 1; i < len(points); i++ {
+		elapsed := seconds(points[last], points[i])
+		if elapsed <= 0 {
+			// The same time as the last fix, or back in time: the fix is dropped.
+			report.Duplicates++
+			continue
+		}
+
+		// The speed from the last fix, and the change in m/s² from the last step.
+		stepSpeed := speed(points[last], points[i])
+		reason := ""
+		if stepSpeed > options.MaxSpeed {
+			reason = ReasonSpeed
+		} else if hasSpeed && math.Abs(stepSpeed-lastSpeed)/3.6/elapsed > options.MaxAcceleration {
+			reason = ReasonAcceleration
+		}
+
+		if reason != "" && run < MaxOutlierRun {
+			run++
+			report.Outliers = append(report.Outliers, mongodb.Outlier{
+				Index:  i,
+				Time:   at(date, points[0], points[i]),
+				Reason: reason,
+				Speed:  stepSpeed,
+			})
+			if !options.RemoveOutliers {
+				cleaned = append(cleaned, points[i])
+			}
+			continue
+		}
+		if reason != "" {
+			// The fix measured from was the spike, the speed is measured again from here.
+			hasSpeed = false
+		} else {
+			lastSpeed, hasSpeed = stepSpeed, true
+		}
+		run = 0
+		last = i
+		cleaned = append(cleaned, points[i])
+	}
+
+	// The gaps are found in the cleaned fixes, removed outliers are no fixes.
+	for i := 1; i < len(cleaned); i++ {
+		elapsed := seconds(cleaned[i-1], cleaned[i])
+		if elapsed > options.MaxGap.Seconds() {
+			report.Gaps = append(report.Gaps, mongodb.Gap{
+				Start:    at(date, points[0], cleaned[i-1]),
+				End:      at(date, points[0], cleaned[i]),
+				Duration: int64(elapsed),
+			})
+		}
+	}
+	report.Removed = len(points) - len(cleaned)
+	return cleaned, report
+}
+
+// Returns the speed in km/h from fix a to fix b, 0 if b is not after a.
+func speed(a igc.Point, b igc.Point) float64 {
+	elapsed := seconds(a, b)
+	if elapsed <= 0 {
+		return 0
+	}
+	return distance.HaversineDistance(a.Lat.Degrees(), a.Lng.Degrees(), b.Lat.Degrees(), b.Lng.Degrees()) / elapsed * 3600
+}
+
+// Returns the seconds from fix a to fix b. A flight past midnight goes back to the start of the day, so
+// going back more than 12 hours is taken as the next day.
+func seconds(a igc.Point, b igc.Point) float64 {
+	elapsed := b.Time.Sub(a.Time)
+	if elapsed < -12*time.Hour {
+		elapsed += 24 * time.Hour
+	}
+	return elapsed.Seconds()
+}
+
+// Returns the time of the fix on the date, the next day if it is before the first fix of the flight.
+func at(date time.Time, first igc.Point, p igc.Point) time.Time {
+	t := time.Date(date.Year(), date.Month(), date.Day(), p.Time.Hour(), p.Time.Minute(), p.Time.Second(),
+		p.Time.Nanosecond(), time.UTC)
+	if p.Time.Before(first.Time) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
@@ -0,0 +1,126 @@
+/*
+	File: quality_test.go
+  Contains unit tests for quality.go
+*/
+
+package quality
+
+import (
+	"testing"
+	"time"
+
+	igc "github.com/marni/goigc"
+)
+
+// The date of the test flights.
+var date = time.Date(2018, 10, 20, 0, 0, 0, 0, time.UTC)
+
+// Returns a fix at the position, the seconds after noon.
+func fix(lat float64, lng float64, seconds int) igc.Point {
+	p := igc.NewPointFromLatLng(lat, lng)
+	p.Time = time.Date(0, 1, 1, 12, 0, seconds, 0, time.UTC)
+	return p
+}
+
+// Returns a flight north at 36 km/h (0.0009 degrees in 10 seconds), with a fix every 10 seconds.
+func flight(fixes int) []igc.Point {
+	points := make([]igc.Point, fixes)
+	for i := 0; i < fixes; i++ {
+		points[i] = fix(60+0.0009*float64(i), 6, 10*i)
+	}
+	return points
+}
+
+// Function to test: Clean().
+// Test that a clean flight is left as it is.
+func Test_Clean(t *testing.T) {
+	points := flight(10)
+	cleaned, report := Clean(date, points, Default)
+	if len(cleaned) != 10 || report.Fixes != 10 || report.Removed != 0 || len(report.Outliers) != 0 || len(report.Gaps) != 0 {
+		t.Errorf("Function changed a clean flight: got %d fixes, %+v", len(cleaned), report)
+	}
+}
+
+// Function to test: Clean().
+// Test that a spike is removed, or only flagged.
+func Test_Clean_Spike(t *testing.T) {
+	points := flight(10)
+	// A jump of 1 degree, 111 km, in 10 seconds.
+	points[4] = fix(61, 6, 40)
+
+	cleaned, report := Clean(date, points, Default)
+	if len(cleaned) != 9 || report.Removed != 1 || len(report.Outliers) != 1 {
+		t.Fatalf("Function did not remove the spike: got %d fixes, %+v", len(cleaned), report)
+	}
+	outlier := report.Outliers[0]
+	if outlier.Index != 4 || outlier.Reason != ReasonSpeed || !outlier.Time.Equal(date.Add(12*time.Hour+40*time.Second)) {
+		t.Errorf("Function returned wrong outlier: got %+v", outlier)
+	}
+
+	options := Default
+	options.RemoveOutliers = false
+	cleaned, report = Clean(date, points, options)
+	if len(cleaned) != 10 || report.Removed != 0 || len(report.Outliers) != 1 {
+		t.Errorf("Function removed the spike: got %d fixes, %+v", len(cleaned), report)
+	}
+}
+
+// Function to test: Clean().
+// Test that a spike at the first fix is found, not the fixes after it.
+func Test_Clean_FirstSpike(t *testing.T) {
+	points := flight(10)
+	points[0] = fix(0, 0, 0)
+
+	cleaned, report := Clean(date, points, Default)
+	if len(cleaned) != 9 || len(report.Outliers) != 1 || report.Outliers[0].Index != 0 {
+		t.Errorf("Function did not remove the first fix: got %d fixes, %+v", len(cleaned), report)
+	}
+}
+
+// Function to test: Clean().
+// Test that a sudden change of speed is an outlier, even below the max speed.
+func Test_Clean_Acceleration(t *testing.T) {
+	points := flight(10)
+	// 5.6 km in 10 seconds after 36 km/h, a change of 546 m/s in 10 seconds.
+	points[5] = fix(points[4].Lat.Degrees()+0.05, 6, 50)
+	options := Default
+	options.MaxSpeed = 5000
+
+	_, report := Clean(date, points, options)
+	if len(report.Outliers) != 1 || report.Outliers[0].Index != 5 || report.Outliers[0].Reason != ReasonAcceleration {
+		t.Errorf("Function returned wrong outliers: got %+v", report.Outliers)
+	}
+}
+
+// Function to test: Clean().
+// Test that fixes with the same time as the fix before, or back in time, are dropped.
+func Test_Clean_Duplicates(t *testing.T) {
+	points := flight(5)
+	points = append(points[:3], append([]igc.Point{points[2], fix(60, 6, 5)}, points[3:]...)...)
+
+	cleaned, report := Clean(date, points, Default)
+	if len(cleaned) != 5 || report.Duplicates != 2 || report.Removed != 2 || len(report.Outliers) != 0 {
+		t.Errorf("Function did not drop the duplicates: got %d fixes, %+v", len(cleaned), report)
+	}
+}
+
+// Function to test: Clean().
+// Test that the gaps are found, also past midnight.
+func Test_Clean_Gaps(t *testing.T) {
+	points := flight(4)
+	// 5 minutes without fixes, moving at 36 km/h.
+	points = append(points, fix(60+0.0009*33, 6, 330))
+
+	_, report := Clean(date, points, Default)
+	if len(report.Gaps) != 1 || report.Gaps[0].Duration != 300 || !report.Gaps[0].Start.Equal(date.Add(12*time.Hour+30*time.Second)) {
+		t.Errorf("Function returned wrong gaps: got %+v", report.Gaps)
+	}
+
+	// From 23:59 to 00:01 the next day, the fixes only have the time of day.
+	points = []igc.Point{fix(60, 6, 12*3600-60), fix(60, 6, 0)}
+	points[1].Time = time.Date(0, 1, 1, 0, 1, 0, 0, time.UTC)
+	_, report = Clean(date, points, Default)
+	if len(report.Gaps) != 1 || report.Gaps[0].Duration != 120 || !report.Gaps[0].End.Equal(date.AddDate(0, 0, 1).Add(time.Minute)) {
+		t.Errorf("Function returned wrong gaps past midnight: got %+v", report.Gaps)
+	}
+}
@@ -0,0 +1,273 @@
+/*
+	File: ratelimit.go
+  Contains the rate limiting middleware, token buckets for each client IP and API key, with separate budgets
+  for ingesting tracks, reads and webhook registration.
+*/
+
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mats93/paragliding/logging"
+)
+
+// Budgets, each class of requests has its own buckets.
+const (
+	ClassIngest  = "ingest"
+	ClassRead    = "read"
+	ClassWebhook = "webhook"
+)
+
+// Limit is the number of requests allowed in a period. Requests can be made in a burst of up to
+// Requests, and the bucket is refilled evenly over the period.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Result is the state of a bucket after a request is taken from it.
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket.
+	Limit int
+	// Remaining is the number of requests left in the bucket.
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, when it was not allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. The memory store keeps them in the process, a shared backend can implement
+// Store to share the limits between instances.
+type Store interface {
+	// Take takes a request from the bucket with the key, if there is one left.
+	Take(key string, limit Limit, now time.Time) (Result, error)
+}
+
+// Config is the limits of each class, and where the client address is read from.
+type Config struct {
+	Ingest  Limit
+	Read    Limit
+	Webhook Limit
+	// TrustProxy reads the client address from the X-Forwarded-For header, set when behind a proxy.
+	TrustProxy bool
+}
+
+// DefaultConfig is the limits used when nothing else is configured.
+var DefaultConfig = Config{
+	Ingest:  Limit{30, time.Minute},
+	Read:    Limit{300, time.Minute},
+	Webhook: Limit{10, time.Hour},
+}
+
+// ParseLimit parses a limit written as '<requests>/<period>', like '30/1m'.
+func ParseLimit(s string) (Limit, error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) != 2 {
+		return Limit{}, fmt.Errorf("limit %q should be '<requests>/<period>'", s)
+	}
+	requests, err := strconv.Atoi(parts[0])
+	if err != nil || requests < 1 {
+		return Limit{}, fmt.Errorf("limit %q should have a positive number of requests", s)
+	}
+	per, err := time.ParseDuration(parts[1])
+	if err != nil || per <= 0 {
+		return Limit{}, fmt.Errorf("limit %q should have a positive period, like 1m", s)
+	}
+	return Limit{requests, per}, nil
+}
+
+// A token bucket, tokens is the number of requests left at the time 'updated'.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// Checks if the bucket is full at the time, it is then the same as a new bucket.
+func (b *bucket) full(now time.Time) bool {
+	rate := float64(b.limit.Requests) / b.limit.Per.Seconds()
+	return b.tokens+now.Sub(b.updated).Seconds()*rate >= float64(b.limit.Requests)
+}
+
+// MemoryStore keeps the buckets in the process.
+type MemoryStore struct {
+	lock    sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// NewMemoryStore returns an empty memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}}
+}
+
+// How often full buckets are removed from the memory store.
+const sweepInterval = time.Minute
+
+// Take takes a request from the bucket with the key, if there is one left.
+func (m *MemoryStore) Take(key string, limit Limit, now time.Time) (Result, error) {
+	if limit.Requests < 1 || limit.Per <= 0 {
+		return Result{}, errors.New("the limit must allow at least one request in a positive period")
+	}
+	rate := float64(limit.Requests) / limit.Per.Seconds()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	// Buckets that are full are the same as no bucket, so they are removed to save memory.
+	if now.Sub(m.swept) > sweepInterval {
+		for k, b := range m.buckets {
+			if b.full(now) {
+				delete(m.buckets, k)
+			}
+		}
+		m.swept = now
+	}
+
+	b, ok := m.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{float64(limit.Requests), now, limit}
+		m.buckets[key] = b
+	}
+
+	// Refills the bucket for the time since it was last used.
+	b.tokens = math.Min(float64(limit.Requests), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	result := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((float64(limit.Requests) - b.tokens) / rate)
+	return result, nil
+}
+
+// Converts seconds to a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Classify returns the class of a request: posting tracks is ingest, registering webhooks is webhook,
+// and the rest is read.
+func Classify(r *http.Request) string {
+	if r.Method == "POST" {
+		switch {
+		case r.URL.Path == "/paragliding/api/track" || r.URL.Path == "/paragliding/api/track/batch" ||
+			r.URL.Path == "/paragliding/api/v2/track":
+			return ClassIngest
+		case strings.HasPrefix(r.URL.Path, "/paragliding/api/webhook/") || r.URL.Path == "/paragliding/api/v2/webhook":
+			return ClassWebhook
+		}
+	}
+	return ClassRead
+}
+
+// Limiter is the rate limiting middleware.
+type Limiter struct {
+	Config Config
+	Store  Store
+	// Now returns the current time, replaced by tests.
+	Now func() time.Time
+}
+
+// New returns a limiter with the config, that keeps the buckets in the process.
+func New(config Config) *Limiter {
+	return &Limiter{config, NewMemoryStore(), time.Now}
+}
+
+// Returns the limit of the class.
+func (l *Limiter) limit(class string) Limit {
+	switch class {
+	case ClassIngest:
+		return l.Config.Ingest
+	case ClassWebhook:
+		return l.Config.Webhook
+	}
+	return l.Config.Read
+}
+
+// Returns the address of the client.
+func (l *Limiter) clientIP(r *http.Request) string {
+	if l.Config.TrustProxy {
+		// The proxy adds the address it got the request from last.
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			addresses := strings.Split(forwarded, ",")
+			return strings.TrimSpace(addresses[len(addresses)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Returns the keys of the buckets of the request: the client address, and the API key if there is one.
+// Both are limited, so new API keys can not be used to get around the limit of an address.
+// Only a hash of the API key is used.
+func (l *Limiter) keys(r *http.Request, class string) []string {
+	keys := []string{class + ":ip:" + l.clientIP(r)}
+	if token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "); token != "" {
+		sum := sha256.Sum256([]byte(token))
+		keys = append(keys, class+":key:"+hex.EncodeToString(sum[:]))
+	}
+	return keys
+}
+
+// Middleware limits the requests to the handler, and sets the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers. Requests over the limit get 429 (Too many requests) with a Retry-After header.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := Classify(r)
+		limit := l.limit(class)
+		now := l.Now()
+
+		// Takes from all the buckets of the request, the one with the fewest requests left is reported.
+		var report Result
+		reported := false
+		keys := l.keys(r, class)
+		for i := 0; i < len(keys); i++ {
+			result, err := l.Store.Take(keys[i], limit, now)
+			if err != nil {
+				// The requests are allowed when the store fails, the service should not stop with it.
+				logging.FromContext(r.Context()).Error(err.Error())
+				continue
+			}
+			if !reported || !result.Allowed || (report.Allowed && result.Remaining < report.Remaining) {
+				report = result
+				reported = true
+			}
+		}
+
+		if reported {
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(report.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(report.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(report.Reset.Seconds()))))
+			if !report.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(report.RetryAfter.Seconds()))))
+				// Sets the header code to 429 (Too many requests), and returns error message.
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write([]byte("Error: Too many requests, try again later"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,140 @@
+/*
+  File: ratelimit_test.go
+  Contains unit tests for ratelimit.go
+*/
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Function to test: ParseLimit().
+func Test_ParseLimit(t *testing.T) {
+	limit, err := ParseLimit("30/1m")
+	if err != nil || limit != (Limit{30, time.Minute}) {
+		t.Errorf("Function returned wrong limit: got %v (%v) want %v", limit, err, Limit{30, time.Minute})
+	}
+
+	wrong := []string{"30", "a/1m", "0/1m", "30/minute", "30/-1m"}
+	for i := 0; i < len(wrong); i++ {
+		if _, err := ParseLimit(wrong[i]); err == nil {
+			t.Errorf("Function did not return error for %q", wrong[i])
+		}
+	}
+}
+
+// Method to test: MemoryStore.Take().
+// Test that the bucket allows a burst, is empty after it, and is refilled over the period.
+func Test_MemoryStore_Take(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{3, 3 * time.Second}
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		result, _ := store.Take("key", limit, now)
+		if !result.Allowed || result.Remaining != 2-i {
+			t.Errorf("Method returned wrong result for request %d: got %v", i, result)
+		}
+	}
+
+	// The bucket is empty, the next request is allowed after a second.
+	result, _ := store.Take("key", limit, now)
+	if result.Allowed || result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+		t.Errorf("Method returned wrong result for an empty bucket: got %v", result)
+	}
+	if result, _ := store.Take("key", limit, now.Add(time.Second)); !result.Allowed {
+		t.Errorf("Method did not refill the bucket: got %v", result)
+	}
+
+	// Other keys have their own buckets.
+	if result, _ := store.Take("other", limit, now); !result.Allowed {
+		t.Errorf("Method did not allow another key: got %v", result)
+	}
+}
+
+// Function to test: Classify().
+func Test_Classify(t *testing.T) {
+	tests := []struct {
+		method   string
+		path     string
+		expected string
+	}{
+		{"POST", "/paragliding/api/track", ClassIngest},
+		{"POST", "/paragliding/api/track/batch", ClassIngest},
+		{"GET", "/paragliding/api/track", ClassRead},
+		{"POST", "/paragliding/api/webhook/new_track/", ClassWebhook},
+		{"GET", "/paragliding/api/webhook/new_track/1", ClassRead},
+		{"POST", "/paragliding/api/v2/track", ClassIngest},
+		{"GET", "/paragliding/api/v2/track", ClassRead},
+		{"POST", "/paragliding/api/v2/webhook", ClassWebhook},
+	}
+
+	for i := 0; i < len(tests); i++ {
+		request, _ := http.NewRequest(tests[i].method, tests[i].path, nil)
+		if actual := Classify(request); actual != tests[i].expected {
+			t.Errorf("Function returned wrong class for %s %s: got %s want %s",
+				tests[i].method, tests[i].path, actual, tests[i].expected)
+		}
+	}
+}
+
+// Method to test: Middleware().
+// Test the headers, and that the requests over the limit of an address get 429, also with new API keys.
+func Test_Middleware(t *testing.T) {
+	now := time.Now()
+	limiter := New(Config{Ingest: Limit{2, time.Minute}, Read: Limit{10, time.Minute}, Webhook: Limit{1, time.Hour}})
+	limiter.Now = func() time.Time { return now }
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	post := func(token string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("POST", "/paragliding/api/track", strings.NewReader("{}"))
+		request.RemoteAddr = "192.0.2.1:1234"
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := post("key1")
+	if recorder.Code != http.StatusOK || recorder.Header().Get("RateLimit-Limit") != "2" ||
+		recorder.Header().Get("RateLimit-Remaining") != "1" || recorder.Header().Get("RateLimit-Reset") != "30" {
+		t.Errorf("Middleware returned wrong status or headers: got %v %v", recorder.Code, recorder.Header())
+	}
+	post("key2")
+
+	// The address has used its budget, a new key does not help.
+	recorder = post("key3")
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") != "30" {
+		t.Errorf("Middleware returned wrong status or headers: got %v %v", recorder.Code, recorder.Header())
+	}
+
+	// Reads have their own budget.
+	request, _ := http.NewRequest("GET", "/paragliding/api/track", nil)
+	request.RemoteAddr = "192.0.2.1:1234"
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Middleware returned wrong status code for a read: got %v want %v", recorder.Code, http.StatusOK)
+	}
+}
+
+// Method to test: clientIP().
+func Test_clientIP(t *testing.T) {
+	request, _ := http.NewRequest("GET", "/paragliding/api", nil)
+	request.RemoteAddr = "192.0.2.1:1234"
+	request.Header.Set("X-Forwarded-For", "198.51.100.7, 203.0.113.9")
+
+	if actual := New(Config{}).clientIP(request); actual != "192.0.2.1" {
+		t.Errorf("Method returned wrong address without a proxy: got %s want %s", actual, "192.0.2.1")
+	}
+	if actual := New(Config{TrustProxy: true}).clientIP(request); actual != "203.0.113.9" {
+		t.Errorf("Method returned wrong address behind a proxy: got %s want %s", actual, "203.0.113.9")
+	}
+}
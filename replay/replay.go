@@ -0,0 +1,204 @@
+/*
+	File: replay.go
+  Contains the API call for the replay of several flights, aligned in time and streamed as NDJSON.
+*/
+
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mats93/paragliding/analysis"
+	"github.com/mats93/paragliding/fetch"
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/track"
+	"github.com/mats93/paragliding/user"
+)
+
+// MaxTracks is the number of tracks that can be replayed together.
+const MaxTracks = 20
+
+// DefaultStep is the time between the frames, if no step is given.
+const DefaultStep = 5 * time.Second
+
+// MaxStep is the longest time between the frames.
+const MaxStep = 5 * time.Minute
+
+// FlushFrames is the number of frames written between each flush of the stream.
+const FlushFrames = 100
+
+// WriteTimeout is the time allowed to fetch the tracks, and to write the frames between two flushes.
+// The write deadline of the server is moved forward by it, so long replays are not cut off.
+const WriteTimeout = 2 * time.Minute
+
+// Header is the first line of the replay. Tracks are the tracks in the frames, NotOverlapping the tracks that
+// do not overlap in time with any of the others, which are left out of the frames. Step is in seconds, and
+// Frames the number of times of the grid from Start.
+type Header struct {
+	Type           string       `json:"type"`
+	Start          time.Time    `json:"start"`
+	Step           float64      `json:"step"`
+	Frames         int          `json:"frames"`
+	Tracks         []TrackRange `json:"tracks"`
+	NotOverlapping []TrackRange `json:"not_overlapping"`
+}
+
+// TrackRange is a track of the replay, with the times of its first and last fix. The times are left out if the
+// track has no fixes.
+type TrackRange struct {
+	ID    int        `json:"id"`
+	Pilot string     `json:"pilot"`
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// Frame is a line of the replay, the samples of the tracks at a time of the grid. The tracks without fixes
+// around the time are left out, and frames without any are not written.
+type Frame struct {
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	Tracks []Sample  `json:"tracks"`
+}
+
+// Parses the step of the grid, like "1s" or "5". It must be whole seconds up to MaxStep.
+func parseStep(text string) (time.Duration, error) {
+	if text == "" {
+		return DefaultStep, nil
+	}
+	step, err := time.ParseDuration(text)
+	if err != nil {
+		var seconds int
+		if _, scanErr := fmt.Sscanf(text, "%d", &seconds); scanErr != nil || fmt.Sprint(seconds) != text {
+			return 0, fmt.Errorf("%q is not a duration", text)
+		}
+		step = time.Duration(seconds) * time.Second
+	}
+	if step < time.Second || step > MaxStep || step%time.Second != 0 {
+		return 0, fmt.Errorf("the step must be whole seconds from 1s to %v", MaxStep)
+	}
+	return step, nil
+}
+
+// Parses the comma separated IDs of the tracks, like "1,2,3". Each track is only replayed once.
+func parseIDs(text string) ([]int, error) {
+	var ids []int
+	seen := map[int]bool{}
+	parts := strings.Split(text, ",")
+	for i := 0; i < len(parts); i++ {
+		var id int
+		if _, err := fmt.Sscanf(parts[i], "%d", &id); err != nil || fmt.Sprint(id) != parts[i] || id < 1 {
+			return nil, fmt.Errorf("%q is not the ID of a track", parts[i])
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > MaxTracks {
+		return nil, fmt.Errorf("at most %d tracks can be replayed together", MaxTracks)
+	}
+	return ids, nil
+}
+
+// Returns the range of a flight.
+func rangeOf(flight Flight) TrackRange {
+	r := TrackRange{ID: flight.ID, Pilot: flight.Pilot}
+	if len(flight.Times) > 0 {
+		start, end := flight.Start(), flight.End()
+		r.Start, r.End = &start, &end
+	}
+	return r
+}
+
+// GET: Returns the replay of the tracks with the provided '?ids=<id>,<id>', resampled every '&step=<duration>'
+// (5s if not given). The first line is the header, and the rest the frames, each a json object.
+// Output: application/x-ndjson
+func GetReplay(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseIDs(r.URL.Query().Get("ids"))
+	if err != nil {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Malformed ids, should be '?ids=<id>,<id>', " + err.Error()))
+		return
+	}
+	step, err := parseStep(r.URL.Query().Get("step"))
+	if err != nil {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Malformed step, " + err.Error()))
+		return
+	}
+
+	// The server deadline is for short requests, the replay sets its own. Not all writers support it.
+	controller := http.NewResponseController(w)
+	controller.SetWriteDeadline(time.Now().Add(WriteTimeout))
+
+	// Finds and parses the IGC file of each track, the fixes are cleaned like when they were stored.
+	flights := make([]Flight, len(ids))
+	for i := 0; i < len(ids); i++ {
+		rTrack, err := track.Find(r.Context(), user.ViewerOf(r), ids[i])
+		if err != nil {
+			// A track with the given ID does not exist, or is hidden from the request.
+			// Sets the header code to 404 (Not found), and returns error message.
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(fmt.Sprintf("Error: Track %d was not found", ids[i])))
+			return
+		}
+		_, trackFile, err := fetch.IGC(rTrack.TrackSrcURL)
+		if err != nil {
+			// Sets header status code to 502 "Bad gateway", and returns error message.
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(fmt.Sprintf("Error: Could not parse the IGC data of track %d", ids[i])))
+			return
+		}
+		trackFile, _ = analysis.Clean(trackFile)
+		flights[i] = NewFlight(ids[i], rTrack.Pilot, rTrack.HDate, trackFile.Points)
+	}
+
+	// The tracks that do not overlap with any other are reported in the header, not padded.
+	overlapping, alone := Overlapping(flights)
+	start, frames := Grid(overlapping, step)
+	header := Header{Type: "header", Start: start, Step: step.Seconds(), Frames: frames,
+		Tracks: []TrackRange{}, NotOverlapping: []TrackRange{}}
+	for i := 0; i < len(overlapping); i++ {
+		header.Tracks = append(header.Tracks, rangeOf(overlapping[i]))
+	}
+	for i := 0; i < len(alone); i++ {
+		header.NotOverlapping = append(header.NotOverlapping, rangeOf(alone[i]))
+	}
+
+	// Sets header content-type to application/x-ndjson and status code to 200 (OK), and streams the lines.
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(header); err != nil {
+		return
+	}
+	for i := 0; i < frames; i++ {
+		if r.Context().Err() != nil {
+			// The client went away.
+			return
+		}
+		frame := Frame{Type: "frame", Time: start.Add(time.Duration(i) * step)}
+		for j := 0; j < len(overlapping); j++ {
+			if sample, ok := overlapping[j].At(frame.Time, analysis.Cleaning.MaxGap); ok {
+				frame.Tracks = append(frame.Tracks, sample)
+			}
+		}
+		if len(frame.Tracks) == 0 {
+			continue
+		}
+		if err := encoder.Encode(frame); err != nil {
+			logging.FromContext(r.Context()).Error(err.Error())
+			return
+		}
+		if i%FlushFrames == 0 {
+			controller.Flush()
+			controller.SetWriteDeadline(time.Now().Add(WriteTimeout))
+		}
+	}
+}
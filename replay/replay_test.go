@@ -0,0 +1,57 @@
+/*
+	File: replay_test.go
+  Contains unit tests for replay.go
+*/
+
+package replay
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Function to test: parseStep().
+func Test_parseStep(t *testing.T) {
+	valid := map[string]time.Duration{"": DefaultStep, "1s": time.Second, "5": 5 * time.Second, "1m": time.Minute}
+	for text, expected := range valid {
+		if actual, err := parseStep(text); err != nil || actual != expected {
+			t.Errorf("Function returned wrong step for %q: got %v, %v want %v", text, actual, err, expected)
+		}
+	}
+	invalid := []string{"0", "500ms", "1.5s", "10m", "-1s", "5x"}
+	for i := 0; i < len(invalid); i++ {
+		if _, err := parseStep(invalid[i]); err == nil {
+			t.Errorf("Function returned no error for %q", invalid[i])
+		}
+	}
+}
+
+// Function to test: parseIDs().
+func Test_parseIDs(t *testing.T) {
+	ids, err := parseIDs("3,1,3")
+	if err != nil || len(ids) != 2 || ids[0] != 3 || ids[1] != 1 {
+		t.Errorf("Function returned wrong IDs: got %v, %v", ids, err)
+	}
+	invalid := []string{"", "1,,2", "1,a", "0", "1 ,2", "1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21"}
+	for i := 0; i < len(invalid); i++ {
+		if _, err := parseIDs(invalid[i]); err == nil {
+			t.Errorf("Function returned no error for %q", invalid[i])
+		}
+	}
+}
+
+// Function to test: GetReplay().
+// Test that malformed IDs and steps are answered with 400, before the database is used.
+func Test_GetReplay_BadRequest(t *testing.T) {
+	paths := []string{"/paragliding/api/replay", "/paragliding/api/replay?ids=1,x", "/paragliding/api/replay?ids=1,2&step=0.5s"}
+	for i := 0; i < len(paths); i++ {
+		request, _ := http.NewRequest("GET", paths[i], nil)
+		recorder := httptest.NewRecorder()
+		GetReplay(recorder, request)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Handler returned wrong status code for %s: got %v want %v", paths[i], recorder.Code, http.StatusBadRequest)
+		}
+	}
+}
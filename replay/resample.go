@@ -0,0 +1,155 @@
+/*
+	File: resample.go
+  Contains the resampling of the fixes of flights to a common time grid, with interpolated positions and altitudes.
+*/
+
+package replay
+
+import (
+	"sort"
+	"time"
+
+	igc "github.com/marni/goigc"
+)
+
+// Flight is the fixes of a track with their times on the flight date.
+type Flight struct {
+	ID     int
+	Pilot  string
+	Times  []time.Time
+	Points []igc.Point
+}
+
+// Sample is the interpolated position and altitudes in meters of a track at a time of the grid.
+type Sample struct {
+	ID               int     `json:"id"`
+	Lat              float64 `json:"lat"`
+	Lng              float64 `json:"lng"`
+	GNSSAltitude     float64 `json:"gnss_altitude"`
+	PressureAltitude float64 `json:"pressure_altitude"`
+}
+
+// NewFlight returns the flight of the track with the ID, from its fixes. The date is the flight date, since the
+// fixes only contain the time of day. Fixes that are not after the fix before are left out, and the times of a
+// flight past midnight go on to the next day.
+func NewFlight(id int, pilot string, date time.Time, points []igc.Point) Flight {
+	flight := Flight{ID: id, Pilot: pilot}
+	day := date
+	for i := 0; i < len(points); i++ {
+		t := time.Date(day.Year(), day.Month(), day.Day(), points[i].Time.Hour(), points[i].Time.Minute(),
+			points[i].Time.Second(), points[i].Time.Nanosecond(), time.UTC)
+		if n := len(flight.Times); n > 0 && t.Before(flight.Times[n-1].Add(-12*time.Hour)) {
+			// The flight went past midnight UTC.
+			day = day.AddDate(0, 0, 1)
+			t = t.AddDate(0, 0, 1)
+		}
+		if n := len(flight.Times); n > 0 && !t.After(flight.Times[n-1]) {
+			continue
+		}
+		flight.Times = append(flight.Times, t)
+		flight.Points = append(flight.Points, points[i])
+	}
+	return flight
+}
+
+// Start returns the time of the first fix of the flight.
+func (f Flight) Start() time.Time {
+	return f.Times[0]
+}
+
+// End returns the time of the last fix of the flight.
+func (f Flight) End() time.Time {
+	return f.Times[len(f.Times)-1]
+}
+
+// At returns the sample of the flight at the time, interpolated between the fixes before and after it.
+// False is returned before the first and after the last fix, and in gaps longer than maxGap between the fixes,
+// the flight is not padded.
+func (f Flight) At(t time.Time, maxGap time.Duration) (Sample, bool) {
+	if len(f.Times) == 0 || t.Before(f.Start()) || t.After(f.End()) {
+		return Sample{}, false
+	}
+	// The first fix at or after the time.
+	i := sort.Search(len(f.Times), func(i int) bool { return !f.Times[i].Before(t) })
+	if f.Times[i].Equal(t) {
+		return f.sample(i, i, 0), true
+	}
+	gap := f.Times[i].Sub(f.Times[i-1])
+	if gap > maxGap {
+		return Sample{}, false
+	}
+	return f.sample(i-1, i, float64(t.Sub(f.Times[i-1]))/float64(gap)), true
+}
+
+// Returns the sample the fraction of the way from fix a to fix b.
+func (f Flight) sample(a int, b int, fraction float64) Sample {
+	p, q := f.Points[a], f.Points[b]
+	lng := q.Lng.Degrees()
+	// Across the antimeridian, the shorter way is interpolated.
+	if lng-p.Lng.Degrees() > 180 {
+		lng -= 360
+	} else if lng-p.Lng.Degrees() < -180 {
+		lng += 360
+	}
+	sample := Sample{
+		ID:               f.ID,
+		Lat:              between(p.Lat.Degrees(), q.Lat.Degrees(), fraction),
+		Lng:              between(p.Lng.Degrees(), lng, fraction),
+		GNSSAltitude:     between(float64(p.GNSSAltitude), float64(q.GNSSAltitude), fraction),
+		PressureAltitude: between(float64(p.PressureAltitude), float64(q.PressureAltitude), fraction),
+	}
+	if sample.Lng > 180 {
+		sample.Lng -= 360
+	} else if sample.Lng < -180 {
+		sample.Lng += 360
+	}
+	return sample
+}
+
+// Returns the value the fraction of the way from a to b.
+func between(a float64, b float64, fraction float64) float64 {
+	return a + (b-a)*fraction
+}
+
+// Overlapping splits the flights into those that overlap in time with at least one of the others, and those that
+// overlap with none of them. A flight alone overlaps, it is replayed by itself. Flights without fixes overlap none.
+func Overlapping(flights []Flight) (overlapping []Flight, alone []Flight) {
+	for i := 0; i < len(flights); i++ {
+		overlaps := len(flights[i].Times) > 0 && len(flights) == 1
+		for j := 0; j < len(flights) && !overlaps && len(flights[i].Times) > 0; j++ {
+			overlaps = i != j && len(flights[j].Times) > 0 &&
+				!flights[i].Start().After(flights[j].End()) && !flights[j].Start().After(flights[i].End())
+		}
+		if overlaps {
+			overlapping = append(overlapping, flights[i])
+		} else {
+			alone = append(alone, flights[i])
+		}
+	}
+	return overlapping, alone
+}
+
+// Grid returns the first time and the number of times of the grid every step, from the first start to the last
+// end of the flights. The first time is rounded up to a whole step, so the grids of the same flights match.
+func Grid(flights []Flight, step time.Duration) (time.Time, int) {
+	if len(flights) == 0 {
+		return time.Time{}, 0
+	}
+	start, end := flights[0].Start(), flights[0].End()
+	for i := 1; i < len(flights); i++ {
+		if flights[i].Start().Before(start) {
+			start = flights[i].Start()
+		}
+		if flights[i].End().After(end) {
+			end = flights[i].End()
+		}
+	}
+	first := start.Truncate(step)
+	if first.Before(start) {
+		first = first.Add(step)
+	}
+	if first.After(end) {
+		return first, 0
+	}
+	return first, int(end.Sub(first)/step) + 1
+}
@@ -0,0 +1,119 @@
+/*
+	File: resample_test.go
+  Contains unit tests for resample.go
+*/
+
+package replay
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	igc "github.com/marni/goigc"
+)
+
+// The date of the test flights.
+var date = time.Date(2018, 10, 20, 0, 0, 0, 0, time.UTC)
+
+// Returns a fix at the position and GNSS altitude, at the time of day.
+func fix(lat float64, lng float64, altitude int64, hour int, min int, sec int) igc.Point {
+	p := igc.NewPointFromLatLng(lat, lng)
+	p.Time = time.Date(0, 1, 1, hour, min, sec, 0, time.UTC)
+	p.GNSSAltitude, p.PressureAltitude = altitude, altitude-10
+	return p
+}
+
+// Returns the time of day on the date of the test flights.
+func at(hour int, min int, sec int) time.Time {
+	return time.Date(2018, 10, 20, hour, min, sec, 0, time.UTC)
+}
+
+// Function to test: NewFlight().
+// Test that fixes not after the fix before are left out, and a flight past midnight goes on to the next day.
+func Test_NewFlight(t *testing.T) {
+	points := []igc.Point{
+		fix(60, 6, 1000, 23, 59, 50),
+		fix(60, 6, 1000, 23, 59, 50),
+		fix(60, 6, 1000, 23, 59, 40),
+		fix(60, 6, 1000, 0, 0, 10),
+	}
+	flight := NewFlight(1, "Pilot", date, points)
+	if len(flight.Times) != 2 || !flight.Start().Equal(at(23, 59, 50)) || !flight.End().Equal(at(0, 0, 10).AddDate(0, 0, 1)) {
+		t.Errorf("Function returned wrong times: got %v", flight.Times)
+	}
+}
+
+// Method to test: Flight.At().
+// Test the interpolation between the fixes, and that there are no samples outside the flight or in gaps.
+func Test_Flight_At(t *testing.T) {
+	flight := NewFlight(1, "Pilot", date, []igc.Point{
+		fix(60, 6, 1000, 12, 0, 0),
+		fix(60.1, 6.2, 1200, 12, 0, 10),
+		// A gap of 5 minutes.
+		fix(60.2, 6.2, 1200, 12, 5, 10),
+	})
+
+	sample, ok := flight.At(at(12, 0, 5), time.Minute)
+	if !ok || math.Abs(sample.Lat-60.05) > 1e-9 || math.Abs(sample.Lng-6.1) > 1e-9 || sample.GNSSAltitude != 1100 || sample.PressureAltitude != 1090 {
+		t.Errorf("Function returned wrong sample: got %+v, %v", sample, ok)
+	}
+	if sample, ok := flight.At(at(12, 0, 10), time.Minute); !ok || math.Abs(sample.Lat-60.1) > 1e-9 {
+		t.Errorf("Function returned wrong sample at a fix: got %+v, %v", sample, ok)
+	}
+
+	missing := []time.Time{at(11, 59, 59), at(12, 2, 0), at(12, 5, 11)}
+	for i := 0; i < len(missing); i++ {
+		if sample, ok := flight.At(missing[i], time.Minute); ok {
+			t.Errorf("Function returned a sample at %v: got %+v", missing[i], sample)
+		}
+	}
+}
+
+// Method to test: Flight.At().
+// Test that the longitude is interpolated the short way across the antimeridian.
+func Test_Flight_At_Antimeridian(t *testing.T) {
+	flight := NewFlight(1, "Pilot", date, []igc.Point{fix(-40, 179.9, 0, 12, 0, 0), fix(-40, -179.9, 0, 12, 0, 10)})
+	if sample, _ := flight.At(at(12, 0, 5), time.Minute); math.Abs(math.Abs(sample.Lng)-180) > 1e-9 {
+		t.Errorf("Function returned wrong longitude: got %v want %v", sample.Lng, 180)
+	}
+}
+
+// Function to test: Overlapping().
+func Test_Overlapping(t *testing.T) {
+	flights := []Flight{
+		NewFlight(1, "A", date, []igc.Point{fix(60, 6, 0, 12, 0, 0), fix(60, 6, 0, 13, 0, 0)}),
+		NewFlight(2, "B", date, []igc.Point{fix(60, 6, 0, 12, 30, 0), fix(60, 6, 0, 14, 0, 0)}),
+		NewFlight(3, "C", date, []igc.Point{fix(60, 6, 0, 15, 0, 0), fix(60, 6, 0, 16, 0, 0)}),
+		NewFlight(4, "D", date, nil),
+	}
+	overlapping, alone := Overlapping(flights)
+	if len(overlapping) != 2 || overlapping[0].ID != 1 || overlapping[1].ID != 2 {
+		t.Errorf("Function returned wrong overlapping flights: got %v", overlapping)
+	}
+	if len(alone) != 2 || alone[0].ID != 3 || alone[1].ID != 4 {
+		t.Errorf("Function returned wrong flights overlapping none: got %v", alone)
+	}
+
+	// A flight by itself is replayed.
+	if overlapping, _ := Overlapping(flights[2:3]); len(overlapping) != 1 {
+		t.Errorf("Function did not replay a flight by itself: got %v", overlapping)
+	}
+}
+
+// Function to test: Grid().
+// Test that the grid starts at a whole step, and spans all the flights.
+func Test_Grid(t *testing.T) {
+	flights := []Flight{
+		NewFlight(1, "A", date, []igc.Point{fix(60, 6, 0, 12, 0, 3), fix(60, 6, 0, 12, 1, 0)}),
+		NewFlight(2, "B", date, []igc.Point{fix(60, 6, 0, 12, 0, 30), fix(60, 6, 0, 12, 2, 2)}),
+	}
+	start, frames := Grid(flights, 5*time.Second)
+	// From 12:00:05 to 12:02:00.
+	if !start.Equal(at(12, 0, 5)) || frames != 24 {
+		t.Errorf("Function returned wrong grid: got %v and %d frames", start, frames)
+	}
+	if _, frames := Grid(nil, time.Second); frames != 0 {
+		t.Errorf("Function returned frames without flights: got %d", frames)
+	}
+}
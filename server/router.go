@@ -0,0 +1,127 @@
+/*
+	File: router.go
+  Contains the routes of the API.
+*/
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mats93/paragliding/admin"
+	"github.com/mats93/paragliding/airspace"
+	"github.com/mats93/paragliding/apiv2"
+	"github.com/mats93/paragliding/club"
+	"github.com/mats93/paragliding/health"
+	"github.com/mats93/paragliding/job"
+	"github.com/mats93/paragliding/metrics"
+	"github.com/mats93/paragliding/openapi"
+	"github.com/mats93/paragliding/replay"
+	"github.com/mats93/paragliding/site"
+	"github.com/mats93/paragliding/task"
+	"github.com/mats93/paragliding/ticker"
+	"github.com/mats93/paragliding/track"
+	"github.com/mats93/paragliding/user"
+	"github.com/mats93/paragliding/waypoint"
+	"github.com/mats93/paragliding/webhook"
+)
+
+// Router returns the router with all the routes of the API.
+func Router() *mux.Router {
+	// Uses mux for regex matching on the HandleFunc paths.
+	router := mux.NewRouter()
+
+	// Counts and times the requests by route, also those that matched no route.
+	router.Use(metrics.Middleware)
+	router.NotFoundHandler = metrics.Middleware(http.NotFoundHandler())
+	// Only the v2 routes are limited to methods, the v1 handlers answer other methods themselves.
+	router.MethodNotAllowedHandler = metrics.Middleware(http.HandlerFunc(apiv2.MethodNotAllowed))
+
+	// Functions to handle the URL paths.
+	// Track:
+	router.HandleFunc("/paragliding/", track.RedirectToInfo)
+	router.HandleFunc("/paragliding/api", track.GetAPIInfo)
+	router.HandleFunc("/paragliding/api/openapi.json", openapi.GetSpec)
+	router.HandleFunc("/paragliding/api/track", track.HandleTracks)
+	router.HandleFunc("/paragliding/api/track/batch", track.HandleBatch)
+	router.HandleFunc("/paragliding/api/track/{id:[0-9]+}", track.HandleTrack)
+	router.HandleFunc("/paragliding/api/track/{id:[0-9]+}/airspace", airspace.GetTrackAirspace)
+	router.HandleFunc("/paragliding/api/track/{id:[0-9]+}/audit", track.GetTrackAudit)
+	router.HandleFunc("/paragliding/api/track/{id:[0-9]+}/quality", track.GetTrackQuality)
+	router.HandleFunc("/paragliding/api/track/{id:[0-9]+}/{field:[a-z-A-Z-_]+}", track.GetDetailedTrack)
+
+	// Replay:
+	router.HandleFunc("/paragliding/api/replay", replay.GetReplay)
+
+	// Job:
+	router.HandleFunc("/paragliding/api/job/{id:[0-9]+}", job.GetJob)
+
+	// Ticker:
+	router.HandleFunc("/paragliding/api/ticker/latest", ticker.GetLastTimestamp)
+	router.HandleFunc("/paragliding/api/ticker/", ticker.GetTimestamps)
+	router.HandleFunc("/paragliding/api/ticker/{timestamp:[0-9]+}", ticker.GetTimestampsNewerThen)
+
+	// Webhook:
+	router.HandleFunc("/paragliding/api/webhook/new_track/", webhook.NewWebhook)
+	router.HandleFunc("/paragliding/api/webhook/new_track/{id:[a-z-A-Z-0-9]+}", webhook.HandleWebhooks)
+
+	// Task:
+	router.HandleFunc("/paragliding/api/task", task.HandleTasks)
+	router.HandleFunc("/paragliding/api/task/{id:[0-9]+}", task.GetTaskByID)
+	router.HandleFunc("/paragliding/api/task/{id:[0-9]+}/results", task.HandleResults)
+
+	// Waypoint:
+	router.HandleFunc("/paragliding/api/waypoint", waypoint.HandleWaypoints)
+	router.HandleFunc("/paragliding/api/waypoint/{id:[0-9]+}", waypoint.GetWaypointByID)
+
+	// User:
+	router.HandleFunc("/paragliding/api/user", user.Register)
+	router.HandleFunc("/paragliding/api/user/login", user.Login)
+	router.HandleFunc("/paragliding/api/user/logout", user.Logout)
+	router.HandleFunc("/paragliding/api/user/me", user.GetMe)
+	router.HandleFunc("/paragliding/api/user/me/tracks", user.GetMyTracks)
+
+	// Club:
+	router.HandleFunc("/paragliding/api/club", club.HandleClubs)
+	router.HandleFunc("/paragliding/api/club/{id:[0-9]+}", club.GetClub)
+	router.HandleFunc("/paragliding/api/club/{id:[0-9]+}/members", club.HandleMembers)
+	router.HandleFunc("/paragliding/api/club/{id:[0-9]+}/members/{user_id:[0-9]+}", club.RemoveMember)
+	router.HandleFunc("/paragliding/api/club/{id:[0-9]+}/leaderboard", club.GetLeaderboard)
+	router.HandleFunc("/paragliding/api/club/{id:[0-9]+}/ticker", ticker.GetClubTimestamps)
+	router.HandleFunc("/paragliding/api/club/{id:[0-9]+}/ticker/latest", ticker.GetClubLastTimestamp)
+	router.HandleFunc("/paragliding/api/club/{id:[0-9]+}/ticker/{timestamp:[0-9]+}", ticker.GetClubTimestampsNewerThen)
+
+	// Site:
+	router.HandleFunc("/paragliding/api/site", site.HandleSites)
+	router.HandleFunc("/paragliding/api/site/{id:[0-9]+}", site.HandleSite)
+
+	// Version 2, with JSON envelopes:
+	router.HandleFunc("/paragliding/api/v2", apiv2.GetInfo).Methods("GET")
+	router.HandleFunc("/paragliding/api/v2/track", apiv2.ListTracks).Methods("GET")
+	router.HandleFunc("/paragliding/api/v2/track", apiv2.AddTrack).Methods("POST")
+	router.HandleFunc("/paragliding/api/v2/track/{id:[0-9]+}", apiv2.GetTrack).Methods("GET")
+	router.HandleFunc("/paragliding/api/v2/track/{id:[0-9]+}", apiv2.UpdateTrack).Methods("PATCH")
+	router.HandleFunc("/paragliding/api/v2/track/{id:[0-9]+}", apiv2.DeleteTrack).Methods("DELETE")
+	router.HandleFunc("/paragliding/api/v2/track/{id:[0-9]+}/{field:[a-z-A-Z-_]+}", apiv2.GetTrackField).Methods("GET")
+	router.HandleFunc("/paragliding/api/v2/job/{id:[0-9]+}", apiv2.GetJob).Methods("GET")
+	router.HandleFunc("/paragliding/api/v2/ticker", apiv2.GetTicker).Methods("GET")
+	router.HandleFunc("/paragliding/api/v2/ticker/latest", apiv2.GetTickerLatest).Methods("GET")
+	router.HandleFunc("/paragliding/api/v2/ticker/{timestamp:[0-9]+}", apiv2.GetTickerAfter).Methods("GET")
+	router.HandleFunc("/paragliding/api/v2/webhook", apiv2.AddWebhook).Methods("POST")
+	router.HandleFunc("/paragliding/api/v2/webhook/{id:[a-z-A-Z-0-9]+}", apiv2.GetWebhook).Methods("GET")
+	router.HandleFunc("/paragliding/api/v2/webhook/{id:[a-z-A-Z-0-9]+}", apiv2.DeleteWebhook).Methods("DELETE")
+
+	// Metrics:
+	router.Handle("/metrics", metrics.Handler())
+
+	// Health:
+	router.HandleFunc("/healthz", health.Healthz)
+	router.HandleFunc("/readyz", health.Readyz)
+
+	// Admin, only with the admin token:
+	router.HandleFunc("/paragliding/admin/api/tracks_count", admin.Only(admin.GetTrackCount))
+	router.HandleFunc("/paragliding/admin/api/tracks", admin.Only(admin.DeleteAllTracks))
+
+	return router
+}
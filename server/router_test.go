@@ -0,0 +1,83 @@
+/*
+  File: router_test.go
+  Contains unit tests for router.go
+*/
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mats93/paragliding/openapi"
+)
+
+// Function to test: Router().
+// Test that the OpenAPI document has the same paths as the router, and the operations of the routes limited to
+// methods, so it is updated with the routes.
+func Test_Router_OpenAPI(t *testing.T) {
+	documented := map[string]bool{}
+	paths := openapi.Paths()
+	for i := 0; i < len(paths); i++ {
+		documented[paths[i]] = true
+	}
+
+	registered := map[string]bool{}
+	err := Router().Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		path := openapi.Path(template)
+		registered[path] = true
+		if !documented[path] {
+			t.Errorf("Route %s is missing from the OpenAPI document", template)
+			return nil
+		}
+
+		// The routes limited to methods must have an operation for each of them.
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		operations := strings.Join(openapi.Methods(path), ",")
+		for i := 0; i < len(methods); i++ {
+			if !strings.Contains(","+operations+",", ","+methods[i]+",") {
+				t.Errorf("Route %s %s is missing from the OpenAPI document", methods[i], template)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < len(paths); i++ {
+		if !registered[paths[i]] {
+			t.Errorf("Path %s of the OpenAPI document has no route", paths[i])
+		}
+	}
+}
+
+// Function to test: Router().
+// Test that the v2 routes answer other methods with 405 in an envelope, and v1 routes are left to their handlers.
+func Test_Router_MethodNotAllowed(t *testing.T) {
+	router := Router()
+
+	request, _ := http.NewRequest("PUT", "/paragliding/api/v2/track", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusMethodNotAllowed || !strings.Contains(recorder.Body.String(), `"code":"method_not_allowed"`) {
+		t.Errorf("Router returned wrong response for a v2 route: got %v %s", recorder.Code, recorder.Body.String())
+	}
+
+	request, _ = http.NewRequest("PUT", "/paragliding/api/webhook/new_track/", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Router returned wrong status code for a v1 route: got %v want %v", recorder.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+	File: server.go
+  Contains the HTTP server of the API, with timeouts, and the graceful shutdown that lets the requests,
+  jobs and webhook messages in flight finish.
+*/
+
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/mats93/paragliding/health"
+	"github.com/mats93/paragliding/job"
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/webhook"
+)
+
+// Limits of the server.
+const (
+	// ReadHeaderTimeout is the time allowed to read the headers of a request.
+	ReadHeaderTimeout = 10 * time.Second
+	// ReadTimeout is the time allowed to read a whole request, zip files of batches can be large.
+	ReadTimeout = time.Minute
+	// WriteTimeout is the time allowed to handle a request. The batch import and the replay stream set
+	// longer deadlines of their own.
+	WriteTimeout = 2 * time.Minute
+	// IdleTimeout is the time a keep-alive connection is kept open between requests.
+	IdleTimeout = 2 * time.Minute
+	// MaxHeaderBytes is the maximum size of the headers of a request.
+	MaxHeaderBytes = 1 << 16
+)
+
+// ShutdownTimeout is the time the requests and webhook messages in flight get to finish when shutting down.
+const ShutdownTimeout = 30 * time.Second
+
+// DrainDelay is the time the server keeps accepting requests after the readiness endpoint starts failing,
+// so load balancers can stop sending requests to it first. Gets injected from main, 0 shuts down right away.
+var DrainDelay time.Duration
+
+// New returns a server for the handler at the address, with the limits above.
+func New(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: ReadHeaderTimeout,
+		ReadTimeout:       ReadTimeout,
+		WriteTimeout:      WriteTimeout,
+		IdleTimeout:       IdleTimeout,
+		MaxHeaderBytes:    MaxHeaderBytes,
+	}
+}
+
+// Serve serves requests on the listener until the context is done, then shuts down: the readiness endpoint
+// fails, after the DrainDelay no new requests are accepted, the requests in flight, the running jobs and the
+// queued webhook messages get until the timeout to finish, and the database session is closed.
+func Serve(ctx context.Context, server *http.Server, listener net.Listener, timeout time.Duration) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		// The server stopped by itself.
+		return err
+	case <-ctx.Done():
+	}
+
+	logger := logging.FromContext(ctx)
+	logger.Info("shutting down", "timeout", timeout.String(), "drain_delay", DrainDelay.String())
+	health.SetShuttingDown()
+
+	// Keeps serving while the load balancers see the failing readiness endpoint.
+	if DrainDelay > 0 {
+		select {
+		case err := <-serveErr:
+			return err
+		case <-time.After(DrainDelay):
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Stops accepting requests, and waits for the ones in flight.
+	err := server.Shutdown(shutdownCtx)
+	if err != nil {
+		logger.Error("requests were still in flight", "error", err)
+	}
+	// Lets the job workers finish the jobs they are running, before the database session is closed.
+	if stopErr := job.Stop(shutdownCtx); stopErr != nil {
+		logger.Error("jobs were still running", "error", stopErr)
+		if err == nil {
+			err = stopErr
+		}
+	}
+	// Sends the queued webhook messages, also those queued by the last requests, and by the jobs.
+	if stopErr := webhook.StopDeliveries(shutdownCtx); stopErr != nil {
+		logger.Error("webhook messages were still queued", "error", stopErr)
+		if err == nil {
+			err = stopErr
+		}
+	}
+	mongodb.Close()
+
+	logger.Info("shut down")
+	return err
+}
@@ -0,0 +1,130 @@
+/*
+  File: server_test.go
+  Contains unit tests for server.go
+*/
+
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mats93/paragliding/health"
+)
+
+// Function to test: Serve().
+// Test that the server lets a request in flight finish when it shuts down, and stops accepting new ones.
+func Test_Serve(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := "http://" + listener.Addr().String()
+
+	// The handler waits until it is released.
+	started := make(chan bool)
+	release := make(chan bool)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- true
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ctx, stop := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, New("", handler), listener, 5*time.Second)
+	}()
+
+	// Starts a request, and shuts down while it is in flight.
+	responses := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(address)
+		if err != nil {
+			responses <- 0
+			return
+		}
+		resp.Body.Close()
+		responses <- resp.StatusCode
+	}()
+	<-started
+	stop()
+
+	// The server waits for the request.
+	select {
+	case err := <-served:
+		t.Fatalf("Function returned before the request finished: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if !health.ShuttingDown() {
+		t.Error("Function did not make the readiness fail")
+	}
+
+	close(release)
+	if code := <-responses; code != http.StatusOK {
+		t.Errorf("Request in flight got wrong status code: got %v want %v", code, http.StatusOK)
+	}
+	if err := <-served; err != nil {
+		t.Errorf("Function returned unexpected error: %v", err)
+	}
+
+	// New requests are not accepted.
+	if resp, err := http.Get(address); err == nil {
+		resp.Body.Close()
+		t.Error("Server accepted a request after shutting down")
+	}
+}
+
+// Function to test: New().
+func Test_New(t *testing.T) {
+	server := New(":8080", http.NotFoundHandler())
+	if server.ReadTimeout == 0 || server.WriteTimeout == 0 || server.IdleTimeout == 0 || server.MaxHeaderBytes == 0 {
+		t.Errorf("Function returned a server without limits: %+v", server)
+	}
+}
+
+// Function to test: Serve().
+// Test that the server keeps accepting requests during the drain delay, after the readiness fails.
+func Test_Serve_DrainDelay(t *testing.T) {
+	DrainDelay = 500 * time.Millisecond
+	defer func() { DrainDelay = 0 }()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := "http://" + listener.Addr().String()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ctx, stop := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, New("", handler), listener, 5*time.Second)
+	}()
+	stop()
+
+	// A request during the delay is served.
+	time.Sleep(100 * time.Millisecond)
+	if !health.ShuttingDown() {
+		t.Error("Function did not make the readiness fail")
+	}
+	resp, err := http.Get(address)
+	if err != nil {
+		t.Fatalf("Server did not accept a request during the drain delay: %v", err)
+	}
+	resp.Body.Close()
+
+	// Then it shuts down.
+	if err := <-served; err != nil {
+		t.Errorf("Function returned unexpected error: %v", err)
+	}
+	if resp, err := http.Get(address); err == nil {
+		resp.Body.Close()
+		t.Error("Server accepted a request after shutting down")
+	}
+}
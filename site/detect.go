@@ -0,0 +1,105 @@
+/*
+	File: detect.go
+  Detects the takeoff and landing of a flight from the fixes of a track.
+*/
+
+package site
+
+import (
+	"math"
+
+	igc "github.com/marni/goigc"
+	"github.com/mats93/paragliding/terrain"
+)
+
+// MinGroundSpeed is the ground speed in km/h above which a glider is considered flying.
+const MinGroundSpeed = 10.0
+
+// MinVerticalSpeed is the climb or sink rate in m/s above which a glider is considered flying.
+const MinVerticalSpeed = 1.0
+
+// MinFlyingTime is how many seconds a glider has to be flying for it to count as a takeoff.
+const MinFlyingTime = 60.0
+
+// MinFlyingHeight is the height in meters above the ground, above which a glider is flying however slow it moves.
+const MinFlyingHeight = 50.0
+
+// MaxGroundHeight is the height in meters above the ground, below which a glider is on the ground however fast
+// it moves, like in a car.
+const MaxGroundHeight = 10.0
+
+// GroundElevation returns the ground elevation in meters at a position, used to detect the flight by the height
+// above the ground. If it is nil or returns false, only the speeds are used.
+var GroundElevation func(lat float64, lng float64) (float64, bool)
+
+// Checks if the glider is flying between two fixes.
+func flying(a, b igc.Point) bool {
+	seconds := b.Time.Sub(a.Time).Seconds()
+	if seconds <= 0 {
+		return false
+	}
+
+	// Ground speed in km/h, and vertical speed in m/s from the GNSS altitude.
+	groundSpeed := a.Distance(b) / (seconds / 3600)
+	verticalSpeed := math.Abs(float64(b.GNSSAltitude-a.GNSSAltitude)) / seconds
+
+	return groundSpeed > MinGroundSpeed || verticalSpeed > MinVerticalSpeed
+}
+
+// Checks if the glider is flying between the fixes j and j+1, by the height above the ground of fix j+1 if it
+// is known and above MinFlyingHeight or below MaxGroundHeight, else by the speeds.
+func flyingAt(points []igc.Point, heights []terrain.Height, j int) bool {
+	if heights != nil && heights[j+1].Known {
+		if heights[j+1].AGL > MinFlyingHeight {
+			return true
+		}
+		if heights[j+1].AGL < MaxGroundHeight {
+			return false
+		}
+	}
+	return flying(points[j], points[j+1])
+}
+
+// DetectFlight finds the index of the takeoff and landing fixes.
+// The takeoff is the first fix where the glider keeps flying for MinFlyingTime,
+// the landing is the last fix where it was flying. If no flight was found ok is false.
+// The height above the ground is used if GroundElevation is set.
+func DetectFlight(points []igc.Point) (takeoff int, landing int, ok bool) {
+	var heights []terrain.Height
+	if GroundElevation != nil {
+		heights = terrain.Heights(points, GroundElevation)
+	}
+	return DetectFlightAbove(points, heights)
+}
+
+// DetectFlightAbove finds the index of the takeoff and landing fixes like DetectFlight, with the heights above
+// the ground of the fixes. If heights is nil, only the speeds are used.
+func DetectFlightAbove(points []igc.Point, heights []terrain.Height) (takeoff int, landing int, ok bool) {
+	takeoff = -1
+	for i := 0; i < len(points)-1 && takeoff == -1; i++ {
+		// Checks that every segment in the following MinFlyingTime seconds is flying.
+		sustained := false
+		for j := i; j < len(points)-1 && flyingAt(points, heights, j); j++ {
+			if points[j+1].Time.Sub(points[i].Time).Seconds() >= MinFlyingTime {
+				sustained = true
+				break
+			}
+		}
+		if sustained {
+			takeoff = i
+		}
+	}
+	if takeoff == -1 {
+		return 0, 0, false
+	}
+
+	// The landing is at the end of the last flying segment.
+	landing = len(points) - 1
+	for j := len(points) - 1; j > takeoff; j-- {
+		if flyingAt(points, heights, j-1) {
+			landing = j
+			break
+		}
+	}
+	return takeoff, landing, true
+}
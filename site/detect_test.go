@@ -0,0 +1,108 @@
+/*
+  File: detect_test.go
+  Contains unit tests for detect.go
+*/
+
+package site
+
+import (
+	"testing"
+	"time"
+
+	igc "github.com/marni/goigc"
+)
+
+// Creates a fix at the given position and GNSS altitude, seconds after 12:00.
+func testPoint(lat, lng float64, altitude int64, seconds int) igc.Point {
+	p := igc.NewPointFromLatLng(lat, lng)
+	p.Time = time.Date(0, 1, 1, 12, 0, seconds, 0, time.UTC)
+	p.GNSSAltitude = altitude
+	return p
+}
+
+// Function to test: DetectFlight().
+// Test that ground time before the takeoff and after the landing is excluded.
+func Test_DetectFlight(t *testing.T) {
+	points := []igc.Point{
+		// Standing at takeoff.
+		testPoint(60.650, 6.425, 820, 0),
+		testPoint(60.650, 6.425, 820, 20),
+		// Flying north, ~22 m per second.
+		testPoint(60.650, 6.425, 820, 40),
+		testPoint(60.654, 6.425, 800, 60),
+		testPoint(60.658, 6.425, 780, 80),
+		testPoint(60.662, 6.425, 760, 100),
+		testPoint(60.666, 6.425, 740, 120),
+		// Landed.
+		testPoint(60.670, 6.425, 100, 140),
+		testPoint(60.670, 6.425, 100, 160),
+		testPoint(60.670, 6.425, 100, 180),
+	}
+
+	takeoff, landing, ok := DetectFlight(points)
+	if !ok {
+		t.Fatal("Function did not detect the flight")
+	}
+	if takeoff != 2 {
+		t.Errorf("Function returned wrong takeoff: got %d want %d", takeoff, 2)
+	}
+	if landing != 7 {
+		t.Errorf("Function returned wrong landing: got %d want %d", landing, 7)
+	}
+}
+
+// Function to test: DetectFlight().
+// Test that a short hop is not detected as a flight.
+func Test_DetectFlight_NoFlight(t *testing.T) {
+	points := []igc.Point{
+		testPoint(60.650, 6.425, 820, 0),
+		testPoint(60.651, 6.425, 820, 20),
+		testPoint(60.651, 6.425, 820, 40),
+		testPoint(60.651, 6.425, 820, 60),
+		testPoint(60.651, 6.425, 820, 80),
+	}
+
+	if _, _, ok := DetectFlight(points); ok {
+		t.Error("Function detected a flight when the glider did not fly")
+	}
+}
+
+// Function to test: DetectFlight().
+// Test that with the ground elevation, driving to the takeoff is not flying, and hovering high above the ground is.
+func Test_DetectFlight_Terrain(t *testing.T) {
+	points := []igc.Point{
+		// Driving north at ~80 km/h on the ground.
+		testPoint(60.650, 6.425, 500, 0),
+		testPoint(60.654, 6.425, 500, 20),
+		testPoint(60.658, 6.425, 500, 40),
+		testPoint(60.662, 6.425, 500, 60),
+		testPoint(60.666, 6.425, 500, 80),
+		// Standing at takeoff.
+		testPoint(60.666, 6.425, 500, 100),
+		// Flying north 100 m above the ground.
+		testPoint(60.670, 6.425, 600, 120),
+		testPoint(60.674, 6.425, 600, 140),
+		testPoint(60.678, 6.425, 600, 160),
+		// Hovering in the wind.
+		testPoint(60.678, 6.425, 600, 180),
+		testPoint(60.678, 6.425, 600, 200),
+		// Landed.
+		testPoint(60.682, 6.425, 500, 220),
+		testPoint(60.682, 6.425, 500, 240),
+	}
+
+	// Only by the speeds, the drive is a flight, and the hovering is not.
+	GroundElevation = nil
+	if takeoff, landing, _ := DetectFlight(points); takeoff != 0 || landing != 11 {
+		t.Errorf("Function returned wrong flight without the ground: got %d to %d want %d to %d", takeoff, landing, 0, 11)
+	}
+
+	// The ground is at 500 m.
+	GroundElevation = func(lat float64, lng float64) (float64, bool) {
+		return 500, true
+	}
+	defer func() { GroundElevation = nil }()
+	if takeoff, landing, _ := DetectFlight(points); takeoff != 5 || landing != 10 {
+		t.Errorf("Function returned wrong flight with the ground: got %d to %d want %d to %d", takeoff, landing, 5, 10)
+	}
+}
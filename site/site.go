@@ -0,0 +1,425 @@
+/*
+	File: site.go
+  Contains functions used by API calls to the "Site paths", and matching of takeoffs and landings to sites.
+*/
+
+package site
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	igc "github.com/marni/goigc"
+	"github.com/mats93/paragliding/admin"
+	"github.com/mats93/paragliding/internal/httpjson"
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/user"
+	"github.com/mats93/paragliding/waypoint"
+)
+
+// DefaultRadius is the radius in meters of sites created from takeoffs, or registered without one.
+const DefaultRadius = 500
+
+// MaxRadius is the largest radius in meters a site can have.
+const MaxRadius = 10000
+
+// MinSeasonShare is the share of a site's flights a month needs to be part of the typical season.
+const MinSeasonShare = 0.1
+
+// Format for a flight from or to a site.
+type flight struct {
+	ID          int       `json:"id"`
+	Pilot       string    `json:"pilot"`
+	HDate       time.Time `json:"H_date"`
+	TrackLength float64   `json:"track_length"`
+	Takeoff     bool      `json:"takeoff"`
+	Landing     bool      `json:"landing"`
+}
+
+// Format for the statistics of a site.
+type stats struct {
+	Flights      int      `json:"flights"`
+	Takeoffs     int      `json:"takeoffs"`
+	Landings     int      `json:"landings"`
+	BestDistance float64  `json:"best_distance"`
+	BestTrack    int      `json:"best_track"`
+	Months       [12]int  `json:"months"`
+	Season       []string `json:"season"`
+}
+
+// Format for the details of a site.
+type siteDetails struct {
+	Site    mongodb.Site `json:"site"`
+	Flights []flight     `json:"flights"`
+	Stats   stats        `json:"stats"`
+}
+
+// Format for the body of a new site. The position is given by "latitude" and "longitude",
+// or by the ID of an imported waypoint.
+type siteBody struct {
+	Name       string   `json:"name"`
+	WaypointID int      `json:"waypoint_id"`
+	Latitude   *float64 `json:"latitude"`
+	Longitude  *float64 `json:"longitude"`
+	Radius     float64  `json:"radius"`
+}
+
+// Format for the id to be returned when a new site is posted.
+type id struct {
+	ID int `json:"id"`
+}
+
+// Collection is the MongoDB collection to use for sites. Gets injected from main or test.
+var Collection string
+
+// CollectionTrack is the MongoDB collection to use for tracks. Gets injected from main or test.
+var CollectionTrack string
+
+// Locks the changes to the sites, since tracks are matched by the job and batch workers too,
+// and clustering a takeoff reads and writes the site.
+var lock sync.Mutex
+
+// Finds the closest site that contains the point, returns false if there is none.
+func closestSite(sites []mongodb.Site, p igc.Point) (mongodb.Site, bool) {
+	var closest mongodb.Site
+	found := false
+	best := 0.0
+
+	for i := 0; i < len(sites); i++ {
+		// Point.Distance returns kilometers.
+		distance := p.Distance(igc.NewPointFromLatLng(sites[i].Latitude, sites[i].Longitude)) * 1000
+		if distance <= sites[i].Radius && (!found || distance < best) {
+			closest, found, best = sites[i], true, distance
+		}
+	}
+	return closest, found
+}
+
+// Matches a position to a site, and returns the ID of the site (0 if none matched).
+// For takeoffs an unnamed site is created if none matched, and the position of a matched
+// unnamed site is moved to the average of all the takeoffs from it.
+func match(database mongodb.MongoDB, p igc.Point, takeoff bool) (int, error) {
+	sites, err := database.FindAllSites()
+	if err != nil {
+		return 0, err
+	}
+
+	site, found := closestSite(sites, p)
+	if !found {
+		if !takeoff {
+			return 0, nil
+		}
+		// Creates a new unnamed site at the takeoff.
+		return database.InsertSite(mongodb.Site{
+			Latitude:  p.Lat.Degrees(),
+			Longitude: p.Lng.Degrees(),
+			Radius:    DefaultRadius,
+			Takeoffs:  1,
+		})
+	}
+
+	// Clusters the takeoffs from unnamed sites.
+	if takeoff && site.Name == "" {
+		n := float64(site.Takeoffs)
+		site.Latitude = (site.Latitude*n + p.Lat.Degrees()) / (n + 1)
+		site.Longitude = (site.Longitude*n + p.Lng.Degrees()) / (n + 1)
+		site.Takeoffs++
+		if err := database.UpdateSite(site); err != nil {
+			return 0, err
+		}
+	}
+	return site.ID, nil
+}
+
+// MatchFlight detects the takeoff and landing of a flight, and returns the IDs
+// of the sites they were at. An ID is 0 if no flight or site was found.
+// The sites are read from the collection of the database given, so the caller's session is used.
+func MatchFlight(database mongodb.MongoDB, points []igc.Point) (takeoffSite int, landingSite int, err error) {
+	takeoff, landing, ok := DetectFlight(points)
+	if !ok {
+		return 0, 0, nil
+	}
+
+	// This part is in critical sector, the sites are read and changed.
+	lock.Lock()
+	defer lock.Unlock()
+
+	if takeoffSite, err = match(database, points[takeoff], true); err != nil {
+		return 0, 0, err
+	}
+	if landingSite, err = match(database, points[landing], false); err != nil {
+		return 0, 0, err
+	}
+	return takeoffSite, landingSite, nil
+}
+
+// Calculates the statistics of the flights from and to a site.
+func siteStats(flights []flight) stats {
+	var s stats
+	s.Season = []string{}
+
+	for i := 0; i < len(flights); i++ {
+		s.Flights++
+		if flights[i].Takeoff {
+			s.Takeoffs++
+		}
+		if flights[i].Landing {
+			s.Landings++
+		}
+		if flights[i].TrackLength > s.BestDistance {
+			s.BestDistance = flights[i].TrackLength
+			s.BestTrack = flights[i].ID
+		}
+		s.Months[flights[i].HDate.Month()-1]++
+	}
+
+	// The typical season is the months with at least MinSeasonShare of the flights.
+	for month := 0; month < 12; month++ {
+		if s.Months[month] > 0 && float64(s.Months[month]) >= MinSeasonShare*float64(s.Flights) {
+			s.Season = append(s.Season, time.Month(month+1).String())
+		}
+	}
+	return s
+}
+
+// GET: Returns all sites.
+// Output: application/json
+func allSites(w http.ResponseWriter, r *http.Request) {
+	// Connects to the database.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+
+	sites, err := database.FindAllSites()
+	if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+	if sites == nil {
+		sites = []mongodb.Site{}
+	}
+	httpjson.Write(w, http.StatusOK, sites)
+}
+
+// POST: Registers a new named site, at a position or at an imported waypoint.
+// Input/Output: application/json
+func insertNewSite(w http.ResponseWriter, r *http.Request) {
+	var body siteBody
+
+	// Decodes the json and converts it to a struct.
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&body)
+	positioned := body.WaypointID != 0 || (body.Latitude != nil && body.Longitude != nil)
+	if err != nil || body.Name == "" || body.Radius < 0 || body.Radius > MaxRadius || !positioned {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Malformed POST request, should be '{\"name\": <name>, \"latitude\": <lat>, \"longitude\": <lon>, [optional: \"radius\": <meters>]}', or with '\"waypoint_id\": <id>' instead of the position"))
+		return
+	}
+	newSite := mongodb.Site{Name: body.Name, Radius: body.Radius}
+
+	// Sets the position of the waypoint, if the site refers to one.
+	if body.WaypointID != 0 {
+		found, err := waypoint.Find(body.WaypointID)
+		if err != nil {
+			// Sets header status code to 400 "Bad request", and returns error message.
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf("Error: Waypoint %d was not found", body.WaypointID)))
+			return
+		}
+		newSite.Latitude, newSite.Longitude = found.Latitude, found.Longitude
+	} else {
+		newSite.Latitude, newSite.Longitude = *body.Latitude, *body.Longitude
+	}
+	if newSite.Latitude < -90 || newSite.Latitude > 90 || newSite.Longitude < -180 || newSite.Longitude > 180 {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: The latitude must be from -90 to 90, and the longitude from -180 to 180"))
+		return
+	}
+	if newSite.Radius == 0 {
+		newSite.Radius = DefaultRadius
+	}
+
+	// Connects to the database, and adds the site.
+	lock.Lock()
+	defer lock.Unlock()
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+	newID, err := database.InsertSite(newSite)
+	if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+	httpjson.Write(w, http.StatusOK, id{newID})
+}
+
+// HandleSites - GET:  Returns all sites.
+// HandleSites - POST: Registers a new named site, only for admins.
+// Input/Output: application/json
+func HandleSites(w http.ResponseWriter, r *http.Request) {
+	// Calls functions to handle the GET and POST requests.
+	switch r.Method {
+	case "GET":
+		allSites(w, r)
+
+	case "POST":
+		if !admin.IsAdmin(r) {
+			// Sets the header code to 401 (Unauthorized).
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		insertNewSite(w, r)
+
+	default:
+		// Wrong method.
+		// Sets status code to 400 (Bad request).
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// GET: Returns a site with the flights from and to it, and statistics.
+// The flights can be filtered by date with 'from' and 'to' (YYYY-MM-DD, inclusive),
+// and by 'role' (takeoff or landing).
+// Output: application/json
+func getSiteDetails(w http.ResponseWriter, r *http.Request, site mongodb.Site) {
+	query := r.URL.Query()
+
+	// Reads the filters.
+	var from, to time.Time
+	var errFrom, errTo error
+	if query.Get("from") != "" {
+		from, errFrom = time.Parse("2006-01-02", query.Get("from"))
+	}
+	if query.Get("to") != "" {
+		to, errTo = time.Parse("2006-01-02", query.Get("to"))
+		to = to.AddDate(0, 0, 1)
+	}
+	role := query.Get("role")
+	if errFrom != nil || errTo != nil || (role != "" && role != "takeoff" && role != "landing") {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: 'from' and 'to' must be dates (YYYY-MM-DD), and 'role' takeoff or landing"))
+		return
+	}
+
+	// Finds the tracks from and to the site.
+	database := mongodb.DatabaseContext(r.Context(), CollectionTrack)
+	tracks, err := database.FindTracksBySite(site.ID)
+	if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+
+	details := siteDetails{Site: site, Flights: []flight{}}
+	viewer := user.ViewerOf(r)
+	for i := 0; i < len(tracks); i++ {
+		// Leaves out the flights hidden from the request.
+		if !viewer.CanView(tracks[i]) {
+			continue
+		}
+		f := flight{tracks[i].ID, tracks[i].Pilot, tracks[i].HDate, tracks[i].TrackLength,
+			tracks[i].TakeoffSite == site.ID, tracks[i].LandingSite == site.ID}
+
+		// Skips the flights that do not match the filters.
+		if (!from.IsZero() && f.HDate.Before(from)) || (!to.IsZero() && !f.HDate.Before(to)) ||
+			(role == "takeoff" && !f.Takeoff) || (role == "landing" && !f.Landing) {
+			continue
+		}
+		details.Flights = append(details.Flights, f)
+	}
+	details.Stats = siteStats(details.Flights)
+
+	httpjson.Write(w, http.StatusOK, details)
+}
+
+// PATCH: Updates the name and radius of a site, used to name sites created from takeoffs.
+// Input/Output: application/json
+func updateSite(w http.ResponseWriter, r *http.Request, id int) {
+	var update struct {
+		Name   *string  `json:"name"`
+		Radius *float64 `json:"radius"`
+	}
+
+	// Decodes the json and converts it to a struct.
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&update)
+	if err != nil || (update.Name != nil && *update.Name == "") ||
+		(update.Radius != nil && (*update.Radius <= 0 || *update.Radius > MaxRadius)) {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("Error: Malformed PATCH request, should be '{\"name\": <name>, \"radius\": <meters, at most %d>}'", MaxRadius)))
+		return
+	}
+
+	// Reads the site again in the critical sector, so a takeoff clustered meanwhile is not lost.
+	lock.Lock()
+	defer lock.Unlock()
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+	site, err := database.FindSite(id)
+	if err != nil {
+		// Sets the header code to 404 (Not found).
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if update.Name != nil {
+		site.Name = *update.Name
+	}
+	if update.Radius != nil {
+		site.Radius = *update.Radius
+	}
+
+	// Updates the site.
+	if err := database.UpdateSite(site); err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+	httpjson.Write(w, http.StatusOK, site)
+}
+
+// HandleSite - GET:   Returns the site with the provided '<id>', its flights and statistics.
+// HandleSite - PATCH: Updates the name and radius of the site with the provided '<id>', only for admins.
+// Input/Output: application/json
+func HandleSite(w http.ResponseWriter, r *http.Request) {
+	// Connects to the database.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+
+	var id int
+	// Gets the ID from the URL and converts it to an integer.
+	fmt.Sscanf(r.URL.Path, "/paragliding/api/site/%d", &id)
+
+	site, err := database.FindSite(id)
+	if err != nil {
+		// A site with the given ID does not exist.
+		// Sets the header code to 404 (Not found).
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Calls functions to handle the GET and PATCH requests.
+	switch r.Method {
+	case "GET":
+		getSiteDetails(w, r, site)
+
+	case "PATCH":
+		if !admin.IsAdmin(r) {
+			// Sets the header code to 401 (Unauthorized).
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		updateSite(w, r, site.ID)
+
+	default:
+		// Wrong method.
+		// Sets status code to 400 (Bad request).
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,168 @@
+/*
+  File: site_test.go
+  Contains unit tests for site.go
+*/
+
+package site
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	igc "github.com/marni/goigc"
+	"github.com/mats93/paragliding/admin"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/waypoint"
+)
+
+// Function to test: closestSite().
+// Test that the closest site containing the point is found.
+func Test_closestSite(t *testing.T) {
+	sites := []mongodb.Site{
+		{ID: 1, Name: "Hanguren", Latitude: 60.650, Longitude: 6.425, Radius: 500},
+		{ID: 2, Name: "Big", Latitude: 60.652, Longitude: 6.425, Radius: 5000},
+		{ID: 3, Name: "Far", Latitude: 61.000, Longitude: 7.000, Radius: 500},
+	}
+
+	// Inside both site 1 and 2, site 1 is closest.
+	site, found := closestSite(sites, igc.NewPointFromLatLng(60.6505, 6.425))
+	if !found || site.ID != 1 {
+		t.Errorf("Function returned wrong site: got %v want %d", site, 1)
+	}
+
+	// Outside every site.
+	if _, found := closestSite(sites, igc.NewPointFromLatLng(59.0, 5.0)); found {
+		t.Error("Function found a site when the point is outside every site")
+	}
+}
+
+// Function to test: siteStats().
+// Test the best distance, counts and typical season of the flights.
+func Test_siteStats(t *testing.T) {
+	may := time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC)
+	aug := time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC)
+	flights := []flight{
+		{ID: 1, HDate: may, TrackLength: 20, Takeoff: true},
+		{ID: 2, HDate: may, TrackLength: 80, Takeoff: true},
+		{ID: 3, HDate: aug, TrackLength: 50, Takeoff: true, Landing: true},
+	}
+
+	actual := siteStats(flights)
+	if actual.Flights != 3 || actual.Takeoffs != 3 || actual.Landings != 1 {
+		t.Errorf("Function returned wrong counts: got %v", actual)
+	}
+	if actual.BestDistance != 80 || actual.BestTrack != 2 {
+		t.Errorf("Function returned wrong best distance: got %v (track %d) want %v (track %d)",
+			actual.BestDistance, actual.BestTrack, 80.0, 2)
+	}
+	if strings.Join(actual.Season, ",") != "May,August" {
+		t.Errorf("Function returned wrong season: got %v want %v", actual.Season, "May,August")
+	}
+}
+
+// Function to test: HandleSites() and HandleSite().
+// Test that a site can be registered, and takeoffs are matched to it.
+func Test_HandleSites(t *testing.T) {
+	// Injects the MongoDB collections to use.
+	Collection = "TestSites"
+	CollectionTrack = "TestTracks"
+	database := mongodb.DatabaseInit(Collection)
+	admin.Token = "secret"
+	defer func() { admin.Token = "" }()
+
+	// Creates the router.
+	router := mux.NewRouter()
+	router.HandleFunc("/paragliding/api/site", HandleSites)
+	router.HandleFunc("/paragliding/api/site/{id:[0-9]+}", HandleSite)
+
+	// Registers a site.
+	body := "{\"name\": \"Hanguren\", \"latitude\": 60.65, \"longitude\": 6.425}"
+	request, _ := http.NewRequest("POST", "/paragliding/api/site", strings.NewReader(body))
+	request.Header.Set("Authorization", "Bearer secret")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	expected := "{\"id\":1}"
+	if actual := recorder.Body.String(); actual != expected {
+		t.Errorf("Handler returned wrong data: got %v want %v", actual, expected)
+	}
+
+	// Registers a site at an imported waypoint.
+	waypoint.Collection = "TestWaypoints"
+	waypoints := mongodb.DatabaseInit(waypoint.Collection)
+	waypointIDs, _ := waypoints.InsertWaypoints([]mongodb.Waypoint{{Name: "Bulken", Latitude: 60.63, Longitude: 6.28}})
+	body = fmt.Sprintf("{\"name\": \"Bulken\", \"waypoint_id\": %d}", waypointIDs[0])
+	request, _ = http.NewRequest("POST", "/paragliding/api/site", strings.NewReader(body))
+	request.Header.Set("Authorization", "Bearer secret")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+	}
+	if bulken, err := database.FindSite(2); err != nil || bulken.Latitude != 60.63 || bulken.Longitude != 6.28 {
+		t.Errorf("Handler registered the site at the wrong position: got %+v", bulken)
+	}
+
+	// A takeoff at the site is matched to it, a takeoff elsewhere creates a new site.
+	matched, err := match(database, igc.NewPointFromLatLng(60.651, 6.425), true)
+	if err != nil || matched != 1 {
+		t.Errorf("Function returned wrong site: got %d want %d", matched, 1)
+	}
+	created, err := match(database, igc.NewPointFromLatLng(61.0, 7.0), true)
+	if err != nil || created != 3 {
+		t.Errorf("Function returned wrong site: got %d want %d", created, 3)
+	}
+
+	// Gets the site, it has no flights.
+	request, _ = http.NewRequest("GET", "/paragliding/api/site/1", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var details siteDetails
+	json.NewDecoder(recorder.Body).Decode(&details)
+	if details.Site.Name != "Hanguren" || len(details.Flights) != 0 {
+		t.Errorf("Handler returned wrong site: got %v", details)
+	}
+
+	// Removes the test data.
+	database.DeleteAll()
+	waypoints.DeleteAll()
+}
+
+// Function to test: HandleSites().
+// Test that only admins can register sites, and a site without a name or a valid position is a bad request.
+func Test_HandleSites_Malformed(t *testing.T) {
+	admin.Token = "secret"
+	defer func() { admin.Token = "" }()
+
+	request, _ := http.NewRequest("POST", "/paragliding/api/site", strings.NewReader("{\"name\": \"Hanguren\", \"latitude\": 60.65, \"longitude\": 6.425}"))
+	recorder := httptest.NewRecorder()
+	HandleSites(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Handler returned wrong status code: got %v want %v", recorder.Code, http.StatusUnauthorized)
+	}
+
+	bodies := []string{
+		"{\"latitude\": 60.65, \"longitude\": 6.425}",
+		"{\"name\": \"Hanguren\", \"latitude\": 60.65}",
+		"{\"name\": \"Hanguren\"}",
+		"{\"name\": \"Hanguren\", \"latitude\": 91, \"longitude\": 6.425}",
+		"{\"name\": \"Hanguren\", \"latitude\": 60.65, \"longitude\": -181}",
+		"{\"name\": \"Hanguren\", \"latitude\": 60.65, \"longitude\": 6.425, \"radius\": 100000}",
+	}
+	for i := 0; i < len(bodies); i++ {
+		request, _ := http.NewRequest("POST", "/paragliding/api/site", strings.NewReader(bodies[i]))
+		request.Header.Set("Authorization", "Bearer secret")
+		recorder := httptest.NewRecorder()
+		HandleSites(recorder, request)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Handler returned wrong status code for %s: got %v want %v", bodies[i], recorder.Code, http.StatusBadRequest)
+		}
+	}
+}
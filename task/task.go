@@ -0,0 +1,383 @@
+/*
+	File: task.go
+  Contains functions used by API calls to the "Task paths".
+*/
+
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	igc "github.com/marni/goigc"
+	"github.com/mats93/paragliding/fetch"
+	"github.com/mats93/paragliding/grecord"
+	"github.com/mats93/paragliding/internal/httpjson"
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/track"
+	"github.com/mats93/paragliding/user"
+	"github.com/mats93/paragliding/waypoint"
+)
+
+// Format for the body of a new task.
+// Either a full task, or the ID of a track with a declared task.
+type taskBody struct {
+	mongodb.Task
+	TrackID int `json:"track_id"`
+}
+
+// Format for the body when validating a track against a task.
+type trackID struct {
+	TrackID int `json:"track_id"`
+}
+
+// Format for the id to be returned when a new task is posted.
+type id struct {
+	ID int `json:"id"`
+}
+
+// Collection is the MongoDB collection to use for tasks. Gets injected from main or test.
+var Collection string
+
+// CollectionResult is the MongoDB collection to use for task results. Gets injected from main or test.
+var CollectionResult string
+
+// Checks that the turnpoints of a task are in a valid order, and fills in defaults.
+func checkTask(task *mongodb.Task) error {
+	if len(task.Turnpoints) == 0 {
+		return fmt.Errorf("the task has no turnpoints")
+	}
+
+	// The goal must be the last turnpoint.
+	last := len(task.Turnpoints) - 1
+	if task.Turnpoints[last].Type != mongodb.TurnpointGoal {
+		return fmt.Errorf("the last turnpoint must be of type %q", mongodb.TurnpointGoal)
+	}
+
+	// Checks the type of each turnpoint, and the order of the SSS and ESS.
+	seenSSS, seenESS := false, false
+	for i := 0; i < len(task.Turnpoints); i++ {
+		tp := &task.Turnpoints[i]
+		switch tp.Type {
+		case mongodb.TurnpointTakeoff:
+			if i != 0 {
+				return fmt.Errorf("turnpoint %d: the takeoff must be the first turnpoint", i)
+			}
+		case mongodb.TurnpointSSS:
+			if seenSSS || seenESS {
+				return fmt.Errorf("turnpoint %d: only one SSS is allowed, before the ESS", i)
+			}
+			seenSSS = true
+		case mongodb.TurnpointESS:
+			if seenESS {
+				return fmt.Errorf("turnpoint %d: only one ESS is allowed", i)
+			}
+			seenESS = true
+		case mongodb.TurnpointNormal:
+		case mongodb.TurnpointGoal:
+			if i != last {
+				return fmt.Errorf("turnpoint %d: only the last turnpoint can be the goal", i)
+			}
+		default:
+			return fmt.Errorf("turnpoint %d: unknown type %q", i, tp.Type)
+		}
+		if tp.Radius < 0 {
+			return fmt.Errorf("turnpoint %d: the radius can not be negative", i)
+		}
+		if tp.Radius == 0 {
+			tp.Radius = DefaultRadius
+		}
+	}
+
+	// Sets the goal type.
+	switch task.GoalType {
+	case "":
+		task.GoalType = mongodb.GoalCylinder
+	case mongodb.GoalCylinder, mongodb.GoalLine:
+	default:
+		return fmt.Errorf("unknown goal type %q", task.GoalType)
+	}
+	return nil
+}
+
+// Creates a task from the task declared in an IGC file (C-records).
+func declaredTask(track igc.Track) (mongodb.Task, error) {
+	declared := track.Task
+	if len(declared.Turnpoints) == 0 && declared.Start.LatLng == declared.Finish.LatLng {
+		return mongodb.Task{}, fmt.Errorf("the track has no declared task")
+	}
+
+	// Converts a declared point to a turnpoint.
+	turnpoint := func(p igc.Point, tpType string) mongodb.Turnpoint {
+		return mongodb.Turnpoint{
+			Name:      p.Description,
+			Latitude:  p.Lat.Degrees(),
+			Longitude: p.Lng.Degrees(),
+			Radius:    DefaultRadius,
+			Type:      tpType,
+		}
+	}
+
+	// Takeoff, start, turnpoints and finish. The landing is not part of the task.
+	task := mongodb.Task{Name: declared.Description, GoalType: mongodb.GoalCylinder}
+	task.Turnpoints = append(task.Turnpoints, turnpoint(declared.Takeoff, mongodb.TurnpointTakeoff))
+	task.Turnpoints = append(task.Turnpoints, turnpoint(declared.Start, mongodb.TurnpointSSS))
+	for i := 0; i < len(declared.Turnpoints); i++ {
+		task.Turnpoints = append(task.Turnpoints, turnpoint(declared.Turnpoints[i], mongodb.TurnpointNormal))
+	}
+	task.Turnpoints = append(task.Turnpoints, turnpoint(declared.Finish, mongodb.TurnpointGoal))
+	return task, nil
+}
+
+// Finds a stored track the user of the request can view, and parses its IGC file.
+// A track hidden from the user is not found, like a track that does not exist.
+func parseTrack(r *http.Request, trackID int) (mongodb.Track, igc.Track, error) {
+	stored, err := track.Find(r.Context(), user.ViewerOf(r), trackID)
+	if err != nil {
+		return mongodb.Track{}, igc.Track{}, fmt.Errorf("track %d was not found", trackID)
+	}
+
+	_, trackFile, err := fetch.IGC(stored.TrackSrcURL)
+	if err != nil {
+		return mongodb.Track{}, igc.Track{}, fmt.Errorf("could not parse the IGC data of track %d", trackID)
+	}
+	return stored, trackFile, nil
+}
+
+// GET: Returns an array of all task IDs.
+// Output: application/json
+func allTaskIDs(w http.ResponseWriter, r *http.Request) {
+	// Connects to the database.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+
+	tasks, err := database.FindAllTasks()
+	if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+
+	// Adds the IDs to a slice, an empty array is returned if there are no tasks.
+	idSlice := []int{}
+	for i := 0; i < len(tasks); i++ {
+		idSlice = append(idSlice, tasks[i].ID)
+	}
+	httpjson.Write(w, http.StatusOK, idSlice)
+}
+
+// POST: Takes the task as json, or the ID of a track with a declared task, and inserts a new task
+// owned by the logged in user.
+// Input/Output: application/json
+func insertNewTask(w http.ResponseWriter, r *http.Request) {
+	// Every task has an owner.
+	account, ok := user.Authenticate(r)
+	if !ok {
+		// Sets the header code to 401 (Unauthorized), and returns error message.
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Error: Log in to add tasks, with the 'Authorization: Bearer <token>' header"))
+		return
+	}
+
+	var body taskBody
+
+	// Decodes the json and converts it to a struct.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&body); err != nil {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Malformed POST request, should be a task or '{\"track_id\": <id>}'"))
+		return
+	}
+
+	newTask := body.Task
+	if body.TrackID != 0 {
+		// Creates the task from the task declared in the track.
+		_, trackFile, err := parseTrack(r, body.TrackID)
+		if err == nil {
+			newTask, err = declaredTask(trackFile)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("Error: " + err.Error()))
+			return
+		}
+	}
+
+	// Sets the position of the turnpoints given by waypoint IDs, and checks the task.
+	err := waypoint.ResolveTurnpoints(newTask.Turnpoints)
+	if err == nil {
+		err = checkTask(&newTask)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: " + err.Error()))
+		return
+	}
+
+	// Connects to the database, and adds the task.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+	newTask.Owner = account.ID
+	newTask.ID, err = database.GetNewTaskID()
+	if err == nil {
+		err = database.InsertTask(newTask)
+	}
+	if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+
+	// Returns the ID of the new task.
+	httpjson.Write(w, http.StatusOK, id{newTask.ID})
+}
+
+// HandleTasks - GET:  Returns an array of all task IDs.
+// HandleTasks - POST: Task registration.
+// Input/Output: application/json
+func HandleTasks(w http.ResponseWriter, r *http.Request) {
+	// Calls functions to handle the GET and POST requests.
+	switch r.Method {
+	case "GET":
+		allTaskIDs(w, r)
+
+	case "POST":
+		insertNewTask(w, r)
+
+	default:
+		// Wrong method.
+		// Sets status code to 400 (Bad request).
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// GetTaskByID - GET: Returns the task with the provided '<id>'.
+// Output: application/json
+func GetTaskByID(w http.ResponseWriter, r *http.Request) {
+	// Connects to the database.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+
+	var id int
+	// Gets the ID from the URL and converts it to an integer.
+	fmt.Sscanf(r.URL.Path, "/paragliding/api/task/%d", &id)
+
+	task, err := database.FindTask(id)
+	if err != nil {
+		// A task with the given ID does not exist.
+		// Sets the header code to 404 (Not found).
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	httpjson.Write(w, http.StatusOK, task)
+}
+
+// GET: Returns the ranked results of a task.
+// Output: application/json
+func getResults(w http.ResponseWriter, r *http.Request, task mongodb.Task) {
+	// Connects to the database.
+	database := mongodb.DatabaseContext(r.Context(), CollectionResult)
+
+	results, err := database.FindTaskResults(task.ID)
+	if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+	if task.RequireValid {
+		// Only tracks with a valid G-record are eligible, the rest are left out of the ranking.
+		results = eligibleResults(results)
+	}
+	if results == nil {
+		results = []mongodb.TaskResult{}
+	}
+	httpjson.Write(w, http.StatusOK, results)
+}
+
+// Returns the results of the tracks with a valid G-record, ranked among themselves.
+func eligibleResults(results []mongodb.TaskResult) []mongodb.TaskResult {
+	var eligible []mongodb.TaskResult
+	for i := 0; i < len(results); i++ {
+		if results[i].Validation == grecord.StatusValid {
+			eligible = append(eligible, results[i])
+		}
+	}
+	return mongodb.RankTaskResults(eligible)
+}
+
+// POST: Validates a track against a task, stores and returns the result.
+// Input/Output: application/json
+func validateTrack(w http.ResponseWriter, r *http.Request, task mongodb.Task) {
+	var body trackID
+
+	// Decodes the json and converts it to a struct.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&body); err != nil || body.TrackID == 0 {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Malformed POST request, should be '{\"track_id\": <id>}'"))
+		return
+	}
+
+	// Finds and parses the track.
+	stored, trackFile, err := parseTrack(r, body.TrackID)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: " + err.Error()))
+		return
+	}
+
+	// Validates the track against the task.
+	result := Validate(task, stored.HDate, trackFile.Points)
+	result.TaskID = task.ID
+	result.TrackID = stored.ID
+	result.Pilot = stored.Pilot
+	result.Validation = stored.Validation
+
+	// Stores the result.
+	database := mongodb.DatabaseContext(r.Context(), CollectionResult)
+	if err := database.UpsertTaskResult(result); err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+	httpjson.Write(w, http.StatusOK, result)
+}
+
+// HandleResults - GET:  Returns the ranked results of the task with the provided '<id>'.
+// HandleResults - POST: Validates a track against the task with the provided '<id>'.
+// Input/Output: application/json
+func HandleResults(w http.ResponseWriter, r *http.Request) {
+	// Connects to the database.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+
+	var id int
+	// Gets the ID from the URL and converts it to an integer.
+	fmt.Sscanf(r.URL.Path, "/paragliding/api/task/%d/results", &id)
+
+	task, err := database.FindTask(id)
+	if err != nil {
+		// A task with the given ID does not exist.
+		// Sets the header code to 404 (Not found).
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Calls functions to handle the GET and POST requests.
+	switch r.Method {
+	case "GET":
+		getResults(w, r, task)
+
+	case "POST":
+		validateTrack(w, r, task)
+
+	default:
+		// Wrong method.
+		// Sets status code to 400 (Bad request).
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,209 @@
+/*
+  File: task_test.go
+  Contains unit tests for task.go
+*/
+
+package task
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/track"
+	"github.com/mats93/paragliding/user"
+)
+
+// Returns a token of a test user, for the requests that need a login.
+func testToken() string {
+	// Injects the MongoDB collections to use.
+	user.Collection = "TestUsers"
+	user.CollectionSession = "TestSessions"
+
+	users := mongodb.DatabaseInit(user.Collection)
+	pilot, err := users.FindUserByName("testpilot")
+	if err != nil {
+		pilot.ID, _ = users.InsertUser(mongodb.User{Username: "testpilot", Created: time.Now()})
+	}
+	token, _, _ := user.NewToken(pilot.ID)
+	return token
+}
+
+// Function to test: checkTask().
+// Test that tasks with turnpoints in the wrong order are rejected.
+func Test_checkTask(t *testing.T) {
+	// A valid task, gets default values filled in.
+	valid := testTask("")
+	valid.Turnpoints[2].Radius = 0
+	if err := checkTask(&valid); err != nil {
+		t.Errorf("Function returned unexpected error: %v", err)
+	}
+	if valid.GoalType != mongodb.GoalCylinder || valid.Turnpoints[2].Radius != DefaultRadius {
+		t.Errorf("Function did not set the defaults: got %s and %v", valid.GoalType, valid.Turnpoints[2].Radius)
+	}
+
+	// The goal is not the last turnpoint.
+	noGoal := testTask("")
+	noGoal.Turnpoints = noGoal.Turnpoints[:4]
+	if err := checkTask(&noGoal); err == nil {
+		t.Error("Function did not return error when the goal is missing")
+	}
+
+	// The ESS comes before the SSS.
+	wrongOrder := testTask("")
+	wrongOrder.Turnpoints[1].Type, wrongOrder.Turnpoints[3].Type = mongodb.TurnpointESS, mongodb.TurnpointSSS
+	if err := checkTask(&wrongOrder); err == nil {
+		t.Error("Function did not return error when the ESS is before the SSS")
+	}
+}
+
+// Function to test: eligibleResults().
+// Test that only the results of tracks with a valid G-record are ranked.
+func Test_eligibleResults(t *testing.T) {
+	results := mongodb.RankTaskResults([]mongodb.TaskResult{
+		{TrackID: 1, MadeGoal: true, SpeedSectionTime: 3600, Validation: "unsigned"},
+		{TrackID: 2, MadeGoal: true, SpeedSectionTime: 4000, Validation: "valid"},
+		{TrackID: 3, TurnpointsReached: 2, Validation: "valid"},
+	})
+
+	actual := eligibleResults(results)
+	if len(actual) != 2 || actual[0].TrackID != 2 || actual[0].Rank != 1 || actual[1].Rank != 2 {
+		t.Errorf("Function returned wrong results: got %v", actual)
+	}
+}
+
+// Function to test: HandleTasks().
+// Test that tasks can only be added when logged in.
+func Test_HandleTasks_POST_Unauthorized(t *testing.T) {
+	body, _ := json.Marshal(testTask(""))
+	request, _ := http.NewRequest("POST", "/paragliding/api/task", strings.NewReader(string(body)))
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/task", HandleTasks).Methods("POST")
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (401).
+	if status := recorder.Code; status != http.StatusUnauthorized {
+		t.Errorf("Handler returned wrong status code: got %v want %v",
+			status, http.StatusUnauthorized)
+	}
+}
+
+// Function to test: HandleTasks().
+// Test to check the returned status code and data when the POST request has wrong json format.
+func Test_HandleTasks_POST_MalformedPost(t *testing.T) {
+	// Creates a malformed (wrong json format) POST request that is passed to the handler.
+	request, _ := http.NewRequest("POST", "/paragliding/api/task", strings.NewReader("wrong"))
+	request.Header.Set("Authorization", "Bearer "+testToken())
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/task", HandleTasks).Methods("POST")
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (400).
+	status := recorder.Code
+	if status != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
+
+// Function to test: HandleTasks().
+// Test that a task without a goal is rejected.
+func Test_HandleTasks_POST_InvalidTask(t *testing.T) {
+	// POST data, a task without a goal.
+	task := testTask("")
+	task.Turnpoints = task.Turnpoints[:2]
+	body, _ := json.Marshal(task)
+
+	// Creates a POST request that is passed to the handler.
+	request, _ := http.NewRequest("POST", "/paragliding/api/task", strings.NewReader(string(body)))
+	request.Header.Set("Authorization", "Bearer "+testToken())
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/task", HandleTasks).Methods("POST")
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (400).
+	status := recorder.Code
+	if status != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+
+	// Check the response body is what we expect, an error.
+	expected := "Error: the last turnpoint must be of type \"goal\""
+	actual := recorder.Body.String()
+	if actual != expected {
+		t.Errorf("Handler returned wrong data: got \"%v\" want \"%v\"",
+			actual, expected)
+	}
+}
+
+// Function to test: HandleTasks(), GetTaskByID() and HandleResults().
+// Test that a task can be inserted, retrieved, and has no results.
+func Test_HandleTasks_POST(t *testing.T) {
+	// Injects the MongoDB collections to use.
+	Collection = "TestTasks"
+	track.Collection = "TestTracks"
+	CollectionResult = "TestTaskResults"
+	database := mongodb.DatabaseInit(Collection)
+
+	// Creates the router.
+	router := mux.NewRouter()
+	router.HandleFunc("/paragliding/api/task", HandleTasks)
+	router.HandleFunc("/paragliding/api/task/{id:[0-9]+}", GetTaskByID)
+	router.HandleFunc("/paragliding/api/task/{id:[0-9]+}/results", HandleResults)
+
+	// Inserts a new task.
+	body, _ := json.Marshal(testTask(""))
+	request, _ := http.NewRequest("POST", "/paragliding/api/task", strings.NewReader(string(body)))
+	request.Header.Set("Authorization", "Bearer "+testToken())
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	expected := "{\"id\":1}"
+	if actual := recorder.Body.String(); actual != expected {
+		t.Errorf("Handler returned wrong data: got %v want %v", actual, expected)
+	}
+
+	// Retrieves the task.
+	request, _ = http.NewRequest("GET", "/paragliding/api/task/1", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var actual mongodb.Task
+	json.NewDecoder(recorder.Body).Decode(&actual)
+	if actual.ID != 1 || len(actual.Turnpoints) != 5 || actual.Owner == 0 {
+		t.Errorf("Handler returned wrong task: got %v", actual)
+	}
+
+	// The task has no results yet.
+	request, _ = http.NewRequest("GET", "/paragliding/api/task/1/results", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	if actual := recorder.Body.String(); actual != "[]" {
+		t.Errorf("Handler returned wrong data: got %v want %v", actual, "[]")
+	}
+
+	// Removes the test data.
+	database.DeleteAll()
+}
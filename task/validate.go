@@ -0,0 +1,156 @@
+/*
+	File: validate.go
+  Validates the fixes of a track against a competition task.
+*/
+
+package task
+
+import (
+	"math"
+	"time"
+
+	igc "github.com/marni/goigc"
+	"github.com/mats93/paragliding/mongodb"
+)
+
+// DefaultRadius is the cylinder radius in meters used when none is given.
+const DefaultRadius = 400
+
+// Validate checks the fixes of a track against the task, and returns the result.
+// The date is the flight date, since the fixes only contain the time of day.
+//
+// The takeoff is informational and is not required to be reached. The SSS is an
+// exit cylinder, the pilot starts when leaving it after the start gate opens, and
+// may restart until the next turnpoint is reached. If the task has no SSS, the speed
+// section starts at the first fix, and if it has no ESS, the goal ends it.
+func Validate(task mongodb.Task, date time.Time, points []igc.Point) mongodb.TaskResult {
+	var result mongodb.TaskResult
+
+	// The turnpoints that must be reached, in order.
+	var sequence []mongodb.Turnpoint
+	for i := 0; i < len(task.Turnpoints); i++ {
+		if task.Turnpoints[i].Type != mongodb.TurnpointTakeoff {
+			sequence = append(sequence, task.Turnpoints[i])
+		}
+	}
+	if len(sequence) == 0 || len(points) == 0 {
+		return result
+	}
+
+	// Index of the next turnpoint to reach, and of the SSS (-1 if there is none).
+	next := 0
+	sss := -1
+	for i := 0; i < len(sequence); i++ {
+		if sequence[i].Type == mongodb.TurnpointSSS {
+			sss = i
+			break
+		}
+	}
+	if sss == -1 {
+		result.StartTime = fixTime(date, points[0].Time)
+	}
+
+	wasInsideSSS := false
+	for i := 0; i < len(points) && next < len(sequence); i++ {
+		at := fixTime(date, points[i].Time)
+
+		// The SSS can be restarted until the turnpoint after it is reached.
+		if sss != -1 && (next == sss || next == sss+1) {
+			inside := insideCylinder(points[i], sequence[sss])
+			if wasInsideSSS && !inside && !at.Before(task.StartGate) {
+				result.StartTime = at
+				next = sss + 1
+			}
+			wasInsideSSS = inside
+			if next == sss {
+				continue
+			}
+		}
+
+		tp := sequence[next]
+		reached := false
+		if tp.Type == mongodb.TurnpointGoal && task.GoalType == mongodb.GoalLine && next > 0 && i > 0 {
+			reached = crossesGoalLine(points[i-1], points[i], sequence[next-1], tp, task.GoalLineLength)
+		} else {
+			reached = insideCylinder(points[i], tp)
+		}
+		if !reached {
+			continue
+		}
+
+		// The turnpoint was reached.
+		next++
+		if tp.Type == mongodb.TurnpointESS {
+			result.ESSTime = at
+		}
+		if tp.Type == mongodb.TurnpointGoal {
+			result.MadeGoal = true
+			if result.ESSTime.IsZero() {
+				result.ESSTime = at
+			}
+		}
+	}
+
+	// Counts the reached turnpoints, and calculates the speed section time.
+	result.TurnpointsReached = next
+	if !result.ESSTime.IsZero() && !result.StartTime.IsZero() {
+		result.SpeedSectionTime = int64(result.ESSTime.Sub(result.StartTime).Seconds())
+	}
+	return result
+}
+
+// Combines the flight date with the time of day of a fix.
+func fixTime(date time.Time, t time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(),
+		t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// Checks if a fix is inside the cylinder of a turnpoint.
+func insideCylinder(p igc.Point, tp mongodb.Turnpoint) bool {
+	radius := tp.Radius
+	if radius <= 0 {
+		radius = DefaultRadius
+	}
+	center := igc.NewPointFromLatLng(tp.Latitude, tp.Longitude)
+
+	// Point.Distance returns kilometers.
+	return p.Distance(center)*1000 <= radius
+}
+
+// Checks if the line between two fixes crosses the goal line.
+// The goal line is perpendicular to the last leg, centered on the goal.
+func crossesGoalLine(a, b igc.Point, previous, goal mongodb.Turnpoint, length float64) bool {
+	if length <= 0 {
+		length = 2 * DefaultRadius
+	}
+
+	// Projects the points to a local plane in meters, with the goal as origin.
+	project := func(lat, lng float64) (float64, float64) {
+		x := (lng - goal.Longitude) * math.Pi / 180 * igc.EarthRadius * 1000 * math.Cos(goal.Latitude*math.Pi/180)
+		y := (lat - goal.Latitude) * math.Pi / 180 * igc.EarthRadius * 1000
+		return x, y
+	}
+	px, py := project(previous.Latitude, previous.Longitude)
+	ax, ay := project(a.Lat.Degrees(), a.Lng.Degrees())
+	bx, by := project(b.Lat.Degrees(), b.Lng.Degrees())
+
+	// Unit vector of the last leg, pointing towards the goal.
+	legLength := math.Hypot(px, py)
+	if legLength == 0 {
+		return false
+	}
+	ux, uy := -px/legLength, -py/legLength
+
+	// Distance along the leg relative to the line, the line is crossed when it changes sign.
+	sa := ax*ux + ay*uy
+	sb := bx*ux + by*uy
+	if !(sa < 0 && sb >= 0) {
+		return false
+	}
+
+	// The offset along the line where it was crossed must be within half the line length.
+	f := sa / (sa - sb)
+	cx, cy := ax+(bx-ax)*f, ay+(by-ay)*f
+	offset := math.Abs(cx*-uy + cy*ux)
+	return offset <= length/2
+}
@@ -0,0 +1,133 @@
+/*
+  File: validate_test.go
+  Contains unit tests for validate.go
+*/
+
+package task
+
+import (
+	"testing"
+	"time"
+
+	igc "github.com/marni/goigc"
+	"github.com/mats93/paragliding/mongodb"
+)
+
+// The flight date used in the tests.
+var testDate = time.Date(2018, 10, 20, 0, 0, 0, 0, time.UTC)
+
+// Creates a fix at the given position, seconds after 12:00.
+func testPoint(lat, lng float64, seconds int) igc.Point {
+	p := igc.NewPointFromLatLng(lat, lng)
+	p.Time = time.Date(0, 1, 1, 12, 0, seconds, 0, time.UTC)
+	return p
+}
+
+// A task going north along longitude 10, every turnpoint is 0.1 degrees (~11 km) apart.
+func testTask(goalType string) mongodb.Task {
+	return mongodb.Task{
+		ID:        1,
+		StartGate: testDate.Add(12 * time.Hour),
+		GoalType:  goalType,
+		Turnpoints: []mongodb.Turnpoint{
+			{Name: "TO", Latitude: 60.0, Longitude: 10, Radius: 400, Type: mongodb.TurnpointTakeoff},
+			{Name: "SSS", Latitude: 60.0, Longitude: 10, Radius: 2000, Type: mongodb.TurnpointSSS},
+			{Name: "TP1", Latitude: 60.1, Longitude: 10, Radius: 400, Type: mongodb.TurnpointNormal},
+			{Name: "ESS", Latitude: 60.2, Longitude: 10, Radius: 400, Type: mongodb.TurnpointESS},
+			{Name: "Goal", Latitude: 60.3, Longitude: 10, Radius: 400, Type: mongodb.TurnpointGoal},
+		},
+	}
+}
+
+// Function to test: Validate().
+// Test a flight that reaches every turnpoint and makes goal.
+func Test_Validate_MadeGoal(t *testing.T) {
+	points := []igc.Point{
+		testPoint(60.0, 10, 0),
+		testPoint(60.05, 10, 600),
+		testPoint(60.1, 10, 1200),
+		testPoint(60.2, 10, 1800),
+		testPoint(60.3, 10, 2400),
+	}
+
+	result := Validate(testTask(mongodb.GoalCylinder), testDate, points)
+
+	if !result.MadeGoal {
+		t.Error("Function did not report that goal was made")
+	}
+	if result.TurnpointsReached != 4 {
+		t.Errorf("Function returned wrong turnpoints reached: got %d want %d", result.TurnpointsReached, 4)
+	}
+
+	// Started when leaving the SSS at 12:10, and reached the ESS at 12:30.
+	expectedStart := testDate.Add(12*time.Hour + 10*time.Minute)
+	if !result.StartTime.Equal(expectedStart) {
+		t.Errorf("Function returned wrong start time: got %v want %v", result.StartTime, expectedStart)
+	}
+	if result.SpeedSectionTime != 1200 {
+		t.Errorf("Function returned wrong speed section time: got %d want %d", result.SpeedSectionTime, 1200)
+	}
+}
+
+// Function to test: Validate().
+// Test a flight that leaves the SSS before the start gate opens, and lands before the ESS.
+func Test_Validate_EarlyStartAndLanded(t *testing.T) {
+	task := testTask(mongodb.GoalCylinder)
+	task.StartGate = testDate.Add(13 * time.Hour)
+
+	// Leaves the SSS before the start gate, goes back and leaves it again after.
+	points := []igc.Point{
+		testPoint(60.0, 10, 0),
+		testPoint(60.05, 10, 600),
+		testPoint(60.0, 10, 3000),
+		testPoint(60.05, 10, 3900),
+		testPoint(60.1, 10, 4500),
+		testPoint(60.15, 10, 5100),
+	}
+
+	result := Validate(task, testDate, points)
+
+	if result.MadeGoal {
+		t.Error("Function reported goal when the pilot landed before it")
+	}
+	if result.TurnpointsReached != 2 {
+		t.Errorf("Function returned wrong turnpoints reached: got %d want %d", result.TurnpointsReached, 2)
+	}
+	expectedStart := testDate.Add(13*time.Hour + 5*time.Minute)
+	if !result.StartTime.Equal(expectedStart) {
+		t.Errorf("Function returned wrong start time: got %v want %v", result.StartTime, expectedStart)
+	}
+	if result.SpeedSectionTime != 0 {
+		t.Errorf("Function returned a speed section time without reaching the ESS: got %d", result.SpeedSectionTime)
+	}
+}
+
+// Function to test: Validate().
+// Test that a goal line is crossed, even if no fix is inside the goal cylinder.
+func Test_Validate_GoalLine(t *testing.T) {
+	task := testTask(mongodb.GoalLine)
+	task.GoalLineLength = 1000
+
+	points := []igc.Point{
+		testPoint(60.0, 10, 0),
+		testPoint(60.05, 10, 600),
+		testPoint(60.1, 10, 1200),
+		testPoint(60.2, 10, 1800),
+		testPoint(60.29, 10.003, 2300),
+		testPoint(60.31, 10.003, 2400),
+	}
+
+	result := Validate(task, testDate, points)
+	if !result.MadeGoal {
+		t.Error("Function did not detect the goal line crossing")
+	}
+
+	// Crossing outside the line does not count.
+	points[4] = testPoint(60.29, 10.02, 2300)
+	points[5] = testPoint(60.31, 10.02, 2400)
+
+	result = Validate(task, testDate, points)
+	if result.MadeGoal {
+		t.Error("Function reported goal when the line was passed outside its length")
+	}
+}
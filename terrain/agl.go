@@ -0,0 +1,74 @@
+/*
+	File: agl.go
+  Contains the height above the ground of the fixes of a track.
+*/
+
+package terrain
+
+import (
+	igc "github.com/marni/goigc"
+)
+
+// Coverage of the ground elevation under the fixes of a track, stored as the terrain of the track.
+// Tracks stored without elevation tiles have none.
+const (
+	// Complete - the ground is known under all the fixes.
+	Complete = "complete"
+	// Partial - tiles are missing under some of the fixes, the min AGL is of the others.
+	Partial = "partial"
+	// Missing - tiles are missing under all the fixes, there is no min AGL.
+	Missing = "missing"
+)
+
+// Height is the height above the ground in meters of a fix, Known is false if the ground under it is unknown.
+type Height struct {
+	AGL   float64
+	Known bool
+}
+
+// Heights returns the heights above the ground of the fixes, with the ground elevation function.
+// The GNSS altitude is used, or the pressure altitude if the fix has none.
+func Heights(points []igc.Point, ground func(lat float64, lng float64) (float64, bool)) []Height {
+	heights := make([]Height, len(points))
+	for i := 0; i < len(points); i++ {
+		elevation, ok := ground(points[i].Lat.Degrees(), points[i].Lng.Degrees())
+		if !ok {
+			continue
+		}
+		altitude := points[i].GNSSAltitude
+		if altitude == 0 {
+			altitude = points[i].PressureAltitude
+		}
+		heights[i] = Height{AGL: float64(altitude) - elevation, Known: true}
+	}
+	return heights
+}
+
+// Coverage returns the coverage of the ground under the fixes with the heights.
+func Coverage(heights []Height) string {
+	known := 0
+	for i := 0; i < len(heights); i++ {
+		if heights[i].Known {
+			known++
+		}
+	}
+	switch {
+	case known == 0:
+		return Missing
+	case known < len(heights):
+		return Partial
+	}
+	return Complete
+}
+
+// MinAGL returns the lowest of the known heights, and false if none is known.
+func MinAGL(heights []Height) (float64, bool) {
+	var min float64
+	found := false
+	for i := 0; i < len(heights); i++ {
+		if heights[i].Known && (!found || heights[i].AGL < min) {
+			min, found = heights[i].AGL, true
+		}
+	}
+	return min, found
+}
@@ -0,0 +1,62 @@
+/*
+	File: agl_test.go
+  Contains unit tests for agl.go
+*/
+
+package terrain
+
+import (
+	"testing"
+
+	igc "github.com/marni/goigc"
+)
+
+// The ground is at 100 m north of 60 degrees, and unknown south of it.
+func ground(lat float64, lng float64) (float64, bool) {
+	return 100, lat >= 60
+}
+
+// Function to test: Heights().
+// Test that the GNSS altitude is used, or the pressure altitude without it.
+func Test_Heights(t *testing.T) {
+	points := []igc.Point{igc.NewPointFromLatLng(60.5, 6), igc.NewPointFromLatLng(60.5, 6), igc.NewPointFromLatLng(59.5, 6)}
+	points[0].GNSSAltitude, points[0].PressureAltitude = 500, 450
+	points[1].PressureAltitude = 450
+	points[2].GNSSAltitude = 500
+
+	heights := Heights(points, ground)
+	expected := []Height{{400, true}, {350, true}, {0, false}}
+	for i := 0; i < len(expected); i++ {
+		if heights[i] != expected[i] {
+			t.Errorf("Function returned wrong height of fix %d: got %+v want %+v", i, heights[i], expected[i])
+		}
+	}
+}
+
+// Function to test: Coverage().
+func Test_Coverage(t *testing.T) {
+	tests := []struct {
+		heights  []Height
+		expected string
+	}{
+		{[]Height{{10, true}, {20, true}}, Complete},
+		{[]Height{{10, true}, {0, false}}, Partial},
+		{[]Height{{0, false}, {0, false}}, Missing},
+		{nil, Missing},
+	}
+	for i := 0; i < len(tests); i++ {
+		if actual := Coverage(tests[i].heights); actual != tests[i].expected {
+			t.Errorf("Function returned wrong coverage of %v: got %s want %s", tests[i].heights, actual, tests[i].expected)
+		}
+	}
+}
+
+// Function to test: MinAGL().
+func Test_MinAGL(t *testing.T) {
+	if min, ok := MinAGL([]Height{{300, true}, {-20, false}, {120, true}}); !ok || min != 120 {
+		t.Errorf("Function returned wrong min AGL: got %v, %v want %v", min, ok, 120)
+	}
+	if _, ok := MinAGL([]Height{{0, false}}); ok {
+		t.Error("Function returned a min AGL without a known height")
+	}
+}
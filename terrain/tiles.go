@@ -0,0 +1,158 @@
+/*
+	File: tiles.go
+  Contains the ground elevation from SRTM/HGT tiles in a local directory.
+*/
+
+package terrain
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Void is the value of the samples without data in the tiles.
+const Void = -32768
+
+// Tiles is the ground elevation from the HGT tiles in a directory. A tile covers one degree of latitude and
+// longitude, and is named by its south west corner, like "N60E006.hgt". It is a square grid of big-endian 16 bit
+// elevations in meters, from the north west corner, like the 1201 x 1201 samples of SRTM3 and 3601 x 3601 of SRTM1.
+// The tiles are read when they are first used, and kept.
+type Tiles struct {
+	dir   string
+	lock  sync.Mutex
+	tiles map[string]*tile
+}
+
+// A tile read from the directory, its corner is the south west corner.
+type tile struct {
+	lat     int
+	lng     int
+	size    int
+	samples []int16
+}
+
+// Open returns the tiles in the directory.
+func Open(dir string) (*Tiles, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	return &Tiles{dir: dir, tiles: map[string]*tile{}}, nil
+}
+
+// Name returns the name of the tile with the position, like "N60E006.hgt".
+func Name(lat float64, lng float64) string {
+	south, west := int(math.Floor(lat)), int(math.Floor(lng))
+	ns, ew := 'N', 'E'
+	if south < 0 {
+		ns, south = 'S', -south
+	}
+	if west < 0 {
+		ew, west = 'W', -west
+	}
+	return fmt.Sprintf("%c%02d%c%03d.hgt", ns, south, ew, west)
+}
+
+// Elevation returns the ground elevation in meters at a position, interpolated between the samples around it.
+// False is returned if the tile is missing or unreadable, or the samples around it have no data.
+func (t *Tiles) Elevation(lat float64, lng float64) (float64, bool) {
+	tile := t.tile(Name(lat, lng))
+	if tile == nil {
+		return 0, false
+	}
+	return tile.elevation(lat, lng)
+}
+
+// Returns the tile with the name, nil if it is missing or unreadable. Missing tiles are remembered too.
+func (t *Tiles) tile(name string) *tile {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if found, ok := t.tiles[name]; ok {
+		return found
+	}
+	// A tile that is missing or unreadable is nil, the ground is unknown there.
+	found, _ := readTile(t.dir, name)
+	t.tiles[name] = found
+	return found
+}
+
+// Reads the tile with the name from the directory, also when the name is in lower case.
+func readTile(dir string, name string) (*tile, error) {
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		content, err = os.ReadFile(filepath.Join(dir, strings.ToLower(name)))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseTile(name, content)
+}
+
+// Parses the samples of a tile, the corner is read from the name.
+func parseTile(name string, content []byte) (*tile, error) {
+	var ns, ew rune
+	var lat, lng int
+	if _, err := fmt.Sscanf(strings.ToUpper(name), "%c%2d%c%3d.HGT", &ns, &lat, &ew, &lng); err != nil {
+		return nil, fmt.Errorf("%s is not the name of a tile", name)
+	}
+	if ns == 'S' {
+		lat = -lat
+	}
+	if ew == 'W' {
+		lng = -lng
+	}
+
+	size := int(math.Sqrt(float64(len(content) / 2)))
+	if size < 2 || size*size*2 != len(content) {
+		return nil, fmt.Errorf("%s is not a square grid of samples", name)
+	}
+	samples := make([]int16, size*size)
+	for i := 0; i < len(samples); i++ {
+		samples[i] = int16(binary.BigEndian.Uint16(content[2*i:]))
+	}
+	return &tile{lat: lat, lng: lng, size: size, samples: samples}, nil
+}
+
+// Returns the elevation at a position in the tile, weighted between the 4 samples around it.
+// The samples without data are left out.
+func (t *tile) elevation(lat float64, lng float64) (float64, bool) {
+	// The position in samples from the north west corner.
+	y := (float64(t.lat+1) - lat) * float64(t.size-1)
+	x := (lng - float64(t.lng)) * float64(t.size-1)
+	row, col := int(math.Floor(y)), int(math.Floor(x))
+	if row >= t.size-1 {
+		row = t.size - 2
+	}
+	if col >= t.size-1 {
+		col = t.size - 2
+	}
+	dy, dx := y-float64(row), x-float64(col)
+
+	var sum, weights float64
+	corners := [4][3]float64{
+		{0, 0, (1 - dy) * (1 - dx)},
+		{0, 1, (1 - dy) * dx},
+		{1, 0, dy * (1 - dx)},
+		{1, 1, dy * dx},
+	}
+	for i := 0; i < len(corners); i++ {
+		sample := t.samples[(row+int(corners[i][0]))*t.size+col+int(corners[i][1])]
+		if sample == Void {
+			continue
+		}
+		sum += float64(sample) * corners[i][2]
+		weights += corners[i][2]
+	}
+	if weights == 0 {
+		return 0, false
+	}
+	return sum / weights, true
+}
@@ -0,0 +1,115 @@
+/*
+	File: tiles_test.go
+  Contains unit tests for tiles.go
+*/
+
+package terrain
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes a tile of 3 x 3 samples to the directory, from the north west corner.
+func writeTile(t *testing.T, dir string, name string, samples [9]int16) {
+	content := make([]byte, 2*len(samples))
+	for i := 0; i < len(samples); i++ {
+		binary.BigEndian.PutUint16(content[2*i:], uint16(samples[i]))
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Function to test: Name().
+func Test_Name(t *testing.T) {
+	tests := []struct {
+		lat, lng float64
+		expected string
+	}{
+		{60.5, 6.2, "N60E006.hgt"},
+		{-0.5, -72.9, "S01W073.hgt"},
+		{0, 0, "N00E000.hgt"},
+		{45.99, -0.01, "N45W001.hgt"},
+	}
+	for i := 0; i < len(tests); i++ {
+		if actual := Name(tests[i].lat, tests[i].lng); actual != tests[i].expected {
+			t.Errorf("Function returned wrong name for %v, %v: got %s want %s", tests[i].lat, tests[i].lng, actual, tests[i].expected)
+		}
+	}
+}
+
+// Function to test: Open().
+func Test_Open(t *testing.T) {
+	if _, err := Open(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Function returned no error for a missing directory")
+	}
+}
+
+// Method to test: Tiles.Elevation().
+// Test the interpolation between the samples, and that the samples without data are left out.
+func Test_Tiles_Elevation(t *testing.T) {
+	dir := t.TempDir()
+	// The north row at 61 (the edge of the tile N61E006), the middle at 60.5 and the south row at 60.
+	writeTile(t, dir, "N60E006.hgt", [9]int16{
+		100, 200, 300,
+		100, 200, Void,
+		0, 0, Void,
+	})
+	tiles, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		lat, lng float64
+		expected float64
+	}{
+		// On the samples.
+		{60.5, 6, 100},
+		{60.5, 6.5, 200},
+		// Between two samples.
+		{60.5, 6.25, 150},
+		{60.25, 6, 50},
+		// Between four samples.
+		{60.75, 6.25, 150},
+		// Next to a sample without data, the average of the other three.
+		{60.75, 6.75, 700.0 / 3},
+	}
+	for i := 0; i < len(tests); i++ {
+		actual, ok := tiles.Elevation(tests[i].lat, tests[i].lng)
+		if !ok || math.Abs(actual-tests[i].expected) > 1e-9 {
+			t.Errorf("Function returned wrong elevation at %v, %v: got %v, %v want %v", tests[i].lat, tests[i].lng, actual, ok, tests[i].expected)
+		}
+	}
+
+	// Only samples without data around it.
+	if _, ok := tiles.Elevation(60, 7); ok {
+		t.Error("Function returned an elevation without data")
+	}
+	// A missing tile.
+	if _, ok := tiles.Elevation(59.5, 6.5); ok {
+		t.Error("Function returned an elevation for a missing tile")
+	}
+}
+
+// Method to test: Tiles.Elevation().
+// Test that tiles named in lower case are found, and tiles that are not square grids are missing.
+func Test_Tiles_Elevation_Files(t *testing.T) {
+	dir := t.TempDir()
+	writeTile(t, dir, "s01w073.hgt", [9]int16{10, 10, 10, 10, 10, 10, 10, 10, 10})
+	if err := os.WriteFile(filepath.Join(dir, "N60E006.hgt"), []byte{0, 1, 0, 2, 0, 3}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tiles, _ := Open(dir)
+
+	if actual, ok := tiles.Elevation(-0.5, -72.5); !ok || actual != 10 {
+		t.Errorf("Function returned wrong elevation: got %v, %v want %v", actual, ok, 10)
+	}
+	if _, ok := tiles.Elevation(60.5, 6.5); ok {
+		t.Error("Function returned an elevation from a tile that is not a square grid")
+	}
+}
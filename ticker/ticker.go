@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/user"
 )
 
 // CAP emulates paging of 5.
@@ -31,15 +32,65 @@ type Timestamp struct {
 // Collection is the MongoDB collection to use. Gets injected from main or test.
 var Collection string
 
-// GetLastTimestamp - GET: Returns the timestamp of the last added track.
-// Output: text/plain
-func GetLastTimestamp(w http.ResponseWriter, r *http.Request) {
-	// Connects to the database.
-	database := mongodb.DatabaseInit(Collection)
+// Latest returns the timestamp of the last added of the tracks, and false if there are none.
+func Latest(tracks []mongodb.Track) (int64, bool) {
+	if len(tracks) == 0 {
+		return 0, false
+	}
+	// Sorts the track from high to low.
+	sortedTracks := mongodb.SortTrackByTimestamp(tracks)
+	return sortedTracks[0].Timestamp, true
+}
+
+// Page returns the timestamp information of the tracks, with the IDs of at most CAP tracks, and false if
+// there are no tracks. The processing time is counted from the start.
+func Page(tracks []mongodb.Track, start time.Time) (Timestamp, bool) {
+	var newTimestamp Timestamp
+	if len(tracks) == 0 {
+		return newTimestamp, false
+	}
+
+	// Lengt of the track slice (-1 = last index used).
+	len := len(tracks) - 1
+
+	// t_latest: Siste stamp
+	// t_start: Første stamp
+	// t_stop: siste stamp for siste ID i array.
+
+	// Last added timestamp (Last index in the DB).
+	newTimestamp.TimeLatest = tracks[len].Timestamp
+
+	// Sorts the tracks by timestamps, last added in index 0, first added in last index.
+	sortedTracks := mongodb.SortTrackByTimestamp(tracks)
+
+	// Adds timestamps to Timestamp struct.
+	newTimestamp.TimeStart = sortedTracks[len].Timestamp
 
-	// Gets all tracks from the DB.
-	tracks, err := database.FindAll()
-	if err != nil || tracks == nil {
+	// The max allowed IDs to be returned.
+	var maxLoops int
+	if len+1 > CAP {
+		maxLoops = CAP - 1
+	} else {
+		maxLoops = len
+	}
+
+	// The last added timestamp for the last ID in the slice.
+	newTimestamp.TimeStop = tracks[maxLoops].Timestamp
+
+	// Adds the IDs to the slice.
+	for i := 0; i <= maxLoops; i++ {
+		newTimestamp.Tracks = append(newTimestamp.Tracks, tracks[i].ID)
+	}
+
+	// Adds the processing time.
+	newTimestamp.Processing = time.Since(start)
+	return newTimestamp, true
+}
+
+// Writes the timestamp of the last added track.
+func writeLastTimestamp(w http.ResponseWriter, tracks []mongodb.Track, err error) {
+	timestamp, ok := Latest(tracks)
+	if err != nil || !ok {
 		// Sets header content-type to text/plain and status code to 204 (No content).
 		w.Header().Set("Content-Type", "text/plain")
 		w.WriteHeader(http.StatusNoContent)
@@ -51,29 +102,15 @@ func GetLastTimestamp(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain")
 		w.WriteHeader(http.StatusOK)
 
-		// Sorts the track from high to low.
-		sortedTracks := mongodb.SortTrackByTimestamp(tracks)
-
-		// Converts int64 to string.
-		timestamp := strconv.FormatInt(sortedTracks[0].Timestamp, 10)
-
 		// Returns the last added timestamp.
-		w.Write([]byte(timestamp))
+		w.Write([]byte(strconv.FormatInt(timestamp, 10)))
 	}
 }
 
-// GetTimestamps - GET: Returns timestamp information.
-// Output: application/json
-func GetTimestamps(w http.ResponseWriter, r *http.Request) {
-	// Start time of the request.
-	start := time.Now()
-
-	// Connects to the database.
-	database := mongodb.DatabaseInit(Collection)
-
-	// Gets all tracks from the DB.
-	tracks, err := database.FindAll()
-	if err != nil || tracks == nil {
+// Writes the timestamp information of the tracks, with the IDs of at most CAP tracks.
+func writeTimestamps(w http.ResponseWriter, tracks []mongodb.Track, err error, start time.Time) {
+	newTimestamp, ok := Page(tracks, start)
+	if err != nil || !ok {
 		// Sets header content-type to application/json and status code to 204 (No content).
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNoContent)
@@ -81,49 +118,12 @@ func GetTimestamps(w http.ResponseWriter, r *http.Request) {
 		// Outputs error.
 		w.Write([]byte("There are no tracks in the database"))
 	} else {
-		var newTimestamp Timestamp
-
-		// Lengt of the track slice (-1 = last index used).
-		len := len(tracks) - 1
-
-		// t_latest: Siste stamp
-		// t_start: Første stamp
-		// t_stop: siste stamp for siste ID i array.
-
-		// Last added timestamp (Last index in the DB).
-		newTimestamp.TimeLatest = tracks[len].Timestamp
-
-		// Sorts the tracks by timestamps, last added in index 0, first added in last index.
-		sortedTracks := mongodb.SortTrackByTimestamp(tracks)
-
-		// Adds timestamps to Timestamp struct.
-		newTimestamp.TimeStart = sortedTracks[len].Timestamp
-
-		// The max allowed IDs to be returned.
-		var maxLoops int
-		if len+1 > CAP {
-			maxLoops = CAP - 1
-		} else {
-			maxLoops = len
-		}
-
-		// The last added timestamp for the last ID in the slice.
-		newTimestamp.TimeStop = tracks[maxLoops].Timestamp
-
-		// Adds the IDs to the slice.
-		for i := 0; i <= maxLoops; i++ {
-			newTimestamp.Tracks = append(newTimestamp.Tracks, tracks[i].ID)
-		}
-
-		// Adds the processing time.
-		newTimestamp.Processing = time.Since(start)
-
 		// Converts the struct to json.
 		json, err := json.Marshal(newTimestamp)
 		if err != nil {
 			// Sets header status code to 500 "Internal server error" and logs the error.
 			w.WriteHeader(http.StatusInternalServerError)
-			log.Fatal(err)
+			log.Println(err)
 		} else {
 			// Sets header content-type to application/json and status code to 200 (OK).
 			w.Header().Set("Content-Type", "application/json")
@@ -135,6 +135,31 @@ func GetTimestamps(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetLastTimestamp - GET: Returns the timestamp of the last added track.
+// Output: text/plain
+func GetLastTimestamp(w http.ResponseWriter, r *http.Request) {
+	// Connects to the database.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+
+	// Gets all public tracks from the DB, the tickers are seen by everyone.
+	tracks, err := database.FindAllPublic()
+	writeLastTimestamp(w, tracks, err)
+}
+
+// GetTimestamps - GET: Returns timestamp information.
+// Output: application/json
+func GetTimestamps(w http.ResponseWriter, r *http.Request) {
+	// Start time of the request.
+	start := time.Now()
+
+	// Connects to the database.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+
+	// Gets all public tracks from the DB, the tickers are seen by everyone.
+	tracks, err := database.FindAllPublic()
+	writeTimestamps(w, tracks, err, start)
+}
+
 // GetTimestampsNewerThen - GET: Returns timestamp information of newer timestamps then provided.
 // Output: application/json
 func GetTimestampsNewerThen(w http.ResponseWriter, r *http.Request) {
@@ -147,64 +172,76 @@ func GetTimestampsNewerThen(w http.ResponseWriter, r *http.Request) {
 	fmt.Sscanf(r.URL.Path, "/paragliding/api/ticker/%d", &ts)
 
 	// Connects to the database.
-	database := mongodb.DatabaseInit(Collection)
+	database := mongodb.DatabaseContext(r.Context(), Collection)
 
-	// Gets all tracks from the DB.
-	tracks, err := database.FindTrackHigherThen(ts)
-	if err != nil || tracks == nil {
-		// Sets header content-type to application/json and status code to 204 (No content).
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNoContent)
-
-		// Outputs error.
-		w.Write([]byte("There are no tracks in the database"))
-	} else {
-		var newTimestamp Timestamp
-
-		// Lengt of the track slice (-1 = last index used).
-		len := len(tracks) - 1
-
-		// Last added timestamp (Last index in the DB).
-		newTimestamp.TimeLatest = tracks[len].Timestamp
+	// Gets all public tracks from the DB, the tickers are seen by everyone.
+	tracks, err := database.FindPublicTrackHigherThen(ts)
+	writeTimestamps(w, tracks, err, start)
+}
 
-		// Sorts the tracks by timestamps, last added in index 0, first added in last index.
-		sortedTracks := mongodb.SortTrackByTimestamp(tracks)
+// Checks that the request is made by a member of the club, the club tickers are only seen by its members.
+// Writes the error response and returns false if not.
+func clubMember(w http.ResponseWriter, r *http.Request, club int) bool {
+	if !user.ViewerOf(r).Member(club) {
+		// Sets the header code to 401 (Unauthorized).
+		w.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
 
-		// Adds timestamps to Timestamp struct.
-		newTimestamp.TimeStart = sortedTracks[len].Timestamp
+// GetClubLastTimestamp - GET: Returns the timestamp of the last track shared with the club with the provided '<id>'.
+// Output: text/plain
+func GetClubLastTimestamp(w http.ResponseWriter, r *http.Request) {
+	var club int
+	// Gets the club ID from the URL.
+	fmt.Sscanf(r.URL.Path, "/paragliding/api/club/%d/ticker/latest", &club)
+	if !clubMember(w, r, club) {
+		return
+	}
 
-		// The max allowed IDs to be returned.
-		var maxLoops int
-		if len+1 > CAP {
-			maxLoops = CAP - 1
-		} else {
-			maxLoops = len
-		}
+	// Connects to the database, and gets the tracks shared with the club.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+	tracks, err := database.FindClubTracks(club)
+	writeLastTimestamp(w, tracks, err)
+}
 
-		// The last added timestamp for the last ID in the slice.
-		newTimestamp.TimeStop = tracks[maxLoops].Timestamp
+// GetClubTimestamps - GET: Returns timestamp information of the tracks shared with the club with the provided '<id>'.
+// Output: application/json
+func GetClubTimestamps(w http.ResponseWriter, r *http.Request) {
+	// Start time of the request.
+	start := time.Now()
 
-		// Adds the IDs to the slice.
-		for i := 0; i <= maxLoops; i++ {
-			newTimestamp.Tracks = append(newTimestamp.Tracks, tracks[i].ID)
-		}
+	var club int
+	// Gets the club ID from the URL.
+	fmt.Sscanf(r.URL.Path, "/paragliding/api/club/%d/ticker", &club)
+	if !clubMember(w, r, club) {
+		return
+	}
 
-		// Adds the processing time.
-		newTimestamp.Processing = time.Since(start)
+	// Connects to the database, and gets the tracks shared with the club.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+	tracks, err := database.FindClubTracks(club)
+	writeTimestamps(w, tracks, err, start)
+}
 
-		// Converts the struct to json.
-		json, err := json.Marshal(newTimestamp)
-		if err != nil {
-			// Sets header status code to 500 "Internal server error" and logs the error.
-			w.WriteHeader(http.StatusInternalServerError)
-			log.Fatal(err)
-		} else {
-			// Sets header content-type to application/json and status code to 200 (OK).
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
+// GetClubTimestampsNewerThen - GET: Returns timestamp information of the tracks shared with the club with the
+// provided '<id>', with newer timestamps then provided.
+// Output: application/json
+func GetClubTimestampsNewerThen(w http.ResponseWriter, r *http.Request) {
+	// Start time of the request.
+	start := time.Now()
 
-			// Returns API info as json.
-			w.Write([]byte(json))
-		}
+	var club int
+	var ts int64
+	// Gets the club ID and the timestamp from the URL.
+	fmt.Sscanf(r.URL.Path, "/paragliding/api/club/%d/ticker/%d", &club, &ts)
+	if !clubMember(w, r, club) {
+		return
 	}
+
+	// Connects to the database, and gets the tracks shared with the club.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+	tracks, err := database.FindClubTrackHigherThen(club, ts)
+	writeTimestamps(w, tracks, err, start)
 }
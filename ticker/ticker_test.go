@@ -65,9 +65,9 @@ func Test_GetLastTimestamp(t *testing.T) {
 
 	// Connects the the database and inserts 3 tracks.
 	database := mongodb.DatabaseInit(Collection)
-	database.Insert(mongodb.Track{1, 111, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.test"})
-	database.Insert(mongodb.Track{2, 222, time.Now(), "pilot2", "glider2", "glider_id2", 20.2, "http://test2.test"})
-	database.Insert(mongodb.Track{3, 333, time.Now(), "pilot3", "glider3", "glider_id3", 20.3, "http://test3.test"})
+	database.Insert(mongodb.Track{ID: 1, Timestamp: 111, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.test"})
+	database.Insert(mongodb.Track{ID: 2, Timestamp: 222, HDate: time.Now(), Pilot: "pilot2", Glider: "glider2", GliderID: "glider_id2", TrackLength: 20.2, TrackSrcURL: "http://test2.test"})
+	database.Insert(mongodb.Track{ID: 3, Timestamp: 333, HDate: time.Now(), Pilot: "pilot3", Glider: "glider3", GliderID: "glider_id3", TrackLength: 20.3, TrackSrcURL: "http://test3.test"})
 
 	// Creates a request that is passed to the handler.
 	request, _ := http.NewRequest("GET", "/paragliding/api/ticker/latest", nil)
@@ -159,9 +159,9 @@ func Test_GetTimestamps(t *testing.T) {
 
 	// Connects the the database and inserts 3 tracks.
 	database := mongodb.DatabaseInit(Collection)
-	database.Insert(mongodb.Track{1, 111, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.test"})
-	database.Insert(mongodb.Track{2, 222, time.Now(), "pilot2", "glider2", "glider_id2", 20.2, "http://test2.test"})
-	database.Insert(mongodb.Track{3, 333, time.Now(), "pilot3", "glider3", "glider_id3", 20.3, "http://test3.test"})
+	database.Insert(mongodb.Track{ID: 1, Timestamp: 111, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.test"})
+	database.Insert(mongodb.Track{ID: 2, Timestamp: 222, HDate: time.Now(), Pilot: "pilot2", Glider: "glider2", GliderID: "glider_id2", TrackLength: 20.2, TrackSrcURL: "http://test2.test"})
+	database.Insert(mongodb.Track{ID: 3, Timestamp: 333, HDate: time.Now(), Pilot: "pilot3", Glider: "glider3", GliderID: "glider_id3", TrackLength: 20.3, TrackSrcURL: "http://test3.test"})
 
 	// Creates a request that is passed to the handler.
 	request, _ := http.NewRequest("GET", "/paragliding/api/ticker/", nil)
@@ -274,9 +274,9 @@ func Test_GetTimestampsNewerThen_Highest(t *testing.T) {
 
 	// Connects the the database and inserts 3 tracks.
 	database := mongodb.DatabaseInit(Collection)
-	database.Insert(mongodb.Track{1, 111, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.test"})
-	database.Insert(mongodb.Track{2, 222, time.Now(), "pilot2", "glider2", "glider_id2", 20.2, "http://test2.test"})
-	database.Insert(mongodb.Track{3, 333, time.Now(), "pilot3", "glider3", "glider_id3", 20.3, "http://test3.test"})
+	database.Insert(mongodb.Track{ID: 1, Timestamp: 111, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.test"})
+	database.Insert(mongodb.Track{ID: 2, Timestamp: 222, HDate: time.Now(), Pilot: "pilot2", Glider: "glider2", GliderID: "glider_id2", TrackLength: 20.2, TrackSrcURL: "http://test2.test"})
+	database.Insert(mongodb.Track{ID: 3, Timestamp: 333, HDate: time.Now(), Pilot: "pilot3", Glider: "glider3", GliderID: "glider_id3", TrackLength: 20.3, TrackSrcURL: "http://test3.test"})
 
 	// Creates a request that is passed to the handler.
 	request, _ := http.NewRequest("GET", "/paragliding/api/ticker/333", nil)
@@ -308,9 +308,9 @@ func Test_GetTimestampsNewerThen(t *testing.T) {
 
 	// Connects the the database and inserts 3 tracks.
 	database := mongodb.DatabaseInit(Collection)
-	database.Insert(mongodb.Track{1, 111, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.test"})
-	database.Insert(mongodb.Track{2, 222, time.Now(), "pilot2", "glider2", "glider_id2", 20.2, "http://test2.test"})
-	database.Insert(mongodb.Track{3, 333, time.Now(), "pilot3", "glider3", "glider_id3", 20.3, "http://test3.test"})
+	database.Insert(mongodb.Track{ID: 1, Timestamp: 111, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.test"})
+	database.Insert(mongodb.Track{ID: 2, Timestamp: 222, HDate: time.Now(), Pilot: "pilot2", Glider: "glider2", GliderID: "glider_id2", TrackLength: 20.2, TrackSrcURL: "http://test2.test"})
+	database.Insert(mongodb.Track{ID: 3, Timestamp: 333, HDate: time.Now(), Pilot: "pilot3", Glider: "glider3", GliderID: "glider_id3", TrackLength: 20.3, TrackSrcURL: "http://test3.test"})
 
 	// Creates a request that is passed to the handler.
 	request, _ := http.NewRequest("GET", "/paragliding/api/ticker/222", nil)
@@ -372,3 +372,48 @@ func Test_GetTimestampsNewerThen(t *testing.T) {
 	// Removes the test data.
 	database.DeleteAll()
 }
+
+// Function to test: GetClubTimestamps().
+// Test that the ticker of a club is not shown to requests that are not logged in as a member.
+func Test_GetClubTimestamps_NotMember(t *testing.T) {
+	// Creates a request without a token that is passed to the handler.
+	request, _ := http.NewRequest("GET", "/paragliding/api/club/1/ticker", nil)
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/club/{id:[0-9]+}/ticker", GetClubTimestamps).Methods("GET")
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (401).
+	status := recorder.Code
+	if status != http.StatusUnauthorized {
+		t.Errorf("Handler returned wrong status code: got %v want %v",
+			status, http.StatusUnauthorized)
+	}
+}
+
+// Function to test: Latest() and Page().
+// Test that no tracks give no timestamps, and the IDs are capped.
+func Test_Page(t *testing.T) {
+	if _, ok := Latest(nil); ok {
+		t.Error("Function Latest returned a timestamp without tracks")
+	}
+	if _, ok := Page([]mongodb.Track{}, time.Now()); ok {
+		t.Error("Function Page returned timestamps without tracks")
+	}
+
+	tracks := []mongodb.Track{}
+	for i := 1; i <= CAP+2; i++ {
+		tracks = append(tracks, mongodb.Track{ID: i, Timestamp: int64(i * 100)})
+	}
+	if latest, ok := Latest(tracks); !ok || latest != int64((CAP+2)*100) {
+		t.Errorf("Function Latest returned wrong timestamp: got %v want %v", latest, (CAP+2)*100)
+	}
+	page, ok := Page(tracks, time.Now())
+	if !ok || len(page.Tracks) != CAP {
+		t.Errorf("Function Page returned wrong number of IDs: got %v want %v", len(page.Tracks), CAP)
+	}
+}
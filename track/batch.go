@@ -0,0 +1,335 @@
+/*
+	File: batch.go
+  Contains the batch import of many tracks in one request, from a list of urls or a zip of IGC files.
+*/
+
+package track
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	igc "github.com/marni/goigc"
+	"github.com/mats93/paragliding/airspace"
+	"github.com/mats93/paragliding/fetch"
+	"github.com/mats93/paragliding/internal/httpjson"
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/metrics"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/user"
+	"github.com/mats93/paragliding/webhook"
+)
+
+// MaxBatchItems is the number of tracks that can be imported in one request.
+const MaxBatchItems = 500
+
+// MaxBatchSize is the maximum size of a zip of IGC files in bytes.
+const MaxBatchSize = 100 << 20
+
+// BatchWorkers is the number of tracks of a batch that are imported at the same time.
+const BatchWorkers = 4
+
+// BatchWriteTimeout is the time allowed to import a batch, it replaces the shorter write deadline of the server.
+// Batches that take longer are cut off, and should be split.
+const BatchWriteTimeout = 15 * time.Minute
+
+// Format for the body of a batch import of urls.
+type batchBody struct {
+	URLs []string `json:"urls"`
+}
+
+// Format for the result of each track of a batch import.
+// The ID is the ID of the stored track, or of the track it is a duplicate of.
+type batchResult struct {
+	Source    string `json:"source"`
+	ID        int    `json:"id,omitempty"`
+	Duplicate bool   `json:"duplicate"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Format for the response of a batch import.
+type batchResponse struct {
+	DryRun      bool          `json:"dry_run"`
+	Inserted    int           `json:"inserted"`
+	WouldInsert int           `json:"would_insert"`
+	Duplicates  int           `json:"duplicates"`
+	Failed      int           `json:"failed"`
+	Results     []batchResult `json:"results"`
+}
+
+// The owner, privacy level and clubs of the tracks of a batch.
+type batchOwner struct {
+	id      int
+	privacy string
+	clubs   []int
+}
+
+// A track to import, read returns the content of its IGC file.
+type batchItem struct {
+	source   string
+	trackURL string
+	read     func() ([]byte, error)
+}
+
+// The checksums of the tracks seen in a dry run, to find duplicates within the batch.
+type checksums struct {
+	sync.Mutex
+	seen map[string]bool
+}
+
+// Reads the urls of a batch from a json body.
+func urlItems(r *http.Request) ([]batchItem, error) {
+	var body batchBody
+
+	// Decodes the json and converts it to a struct.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&body); err != nil {
+		return nil, err
+	}
+
+	items := make([]batchItem, len(body.URLs))
+	for i := 0; i < len(body.URLs); i++ {
+		trackURL := body.URLs[i]
+		items[i] = batchItem{trackURL, trackURL, func() ([]byte, error) {
+			return fetch.Get(trackURL)
+		}}
+	}
+	return items, nil
+}
+
+// Reads the IGC files of a batch from a zip body, other files are skipped.
+func zipItems(w http.ResponseWriter, r *http.Request) ([]batchItem, error) {
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxBatchSize))
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var items []batchItem
+	for i := 0; i < len(archive.File); i++ {
+		file := archive.File[i]
+		if file.FileInfo().IsDir() || !strings.EqualFold(path.Ext(file.Name), ".igc") {
+			continue
+		}
+		items = append(items, batchItem{file.Name, "zip:" + file.Name, func() ([]byte, error) {
+			return readZipFile(file)
+		}})
+	}
+	return items, nil
+}
+
+// Reads a file of a zip, with the same size limit as fetched files.
+func readZipFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	// Reads one byte more than the limit, to know if the file is too large.
+	limit := fetch.DefaultPolicy.MaxBodySize
+	content, err := ioutil.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		return nil, &fetch.Error{Code: fetch.CodeBodyTooLarge, Err: fmt.Errorf("file is larger than %d bytes", limit)}
+	}
+	return content, nil
+}
+
+// Imports a track of a batch into the database. The metadata of the track is returned if it was stored as a new track.
+func importItem(ctx context.Context, database mongodb.MongoDB, item batchItem, owner batchOwner, dryRun bool, seen *checksums) (batchResult, *mongodb.Track) {
+	result := batchResult{Source: item.source}
+
+	content, err := item.read()
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	trackFile, err := igc.Parse(string(content))
+	if err != nil {
+		metrics.ParseFailures.Inc()
+		result.Error = ErrParse.Error()
+		return result, nil
+	}
+	track, trackFile := newTrack(string(content), trackFile, item.trackURL)
+	track.Owner, track.Privacy, track.Clubs = owner.id, owner.privacy, owner.clubs
+
+	if dryRun {
+		// Only checks for duplicates, in the database and earlier in the batch.
+		if existing, err := database.FindTrackByChecksum(track.Checksum); err == nil {
+			result.ID, result.Duplicate = existing.ID, true
+		}
+		seen.Lock()
+		if seen.seen[track.Checksum] {
+			result.Duplicate = true
+		}
+		seen.seen[track.Checksum] = true
+		seen.Unlock()
+		return result, nil
+	}
+
+	result.ID, result.Duplicate, err = store(ctx, database, track, trackFile.Points, true)
+	if err != nil {
+		logging.FromContext(ctx).Error(err.Error())
+		result.ID, result.Error = 0, "could not store the track"
+		return result, nil
+	}
+	if result.Duplicate {
+		return result, nil
+	}
+	return result, &track
+}
+
+// Imports the tracks of a batch with a pool of workers, and returns the results in the same order.
+func importBatch(ctx context.Context, items []batchItem, owner batchOwner, dryRun bool) batchResponse {
+	response := batchResponse{DryRun: dryRun, Results: make([]batchResult, len(items))}
+	seen := checksums{seen: map[string]bool{}}
+
+	// Connects to the database, each operation of the workers uses its own copy of the session.
+	database := mongodb.DatabaseContext(ctx, Collection)
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	indexes := make(chan int)
+	for i := 0; i < BatchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				result, stored := importItem(ctx, database, items[index], owner, dryRun, &seen)
+				response.Results[index] = result
+
+				lock.Lock()
+				switch {
+				case result.Error != "":
+					response.Failed++
+				case result.Duplicate:
+					response.Duplicates++
+				case dryRun:
+					response.WouldInsert++
+				default:
+					response.Inserted++
+				}
+				lock.Unlock()
+
+				// Airspace infringements are notified for each new public track.
+				if stored != nil && owner.privacy == mongodb.PrivacyPublic {
+					airspace.NotifyInfringements(ctx, result.ID, stored.Airspace)
+				}
+			}
+		}()
+	}
+	for i := 0; i < len(items); i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return response
+}
+
+// HandleBatch - POST: Imports many tracks, from a json list of urls '{"urls": [<url>, ...]}',
+// or from a zip of IGC files (Content-Type: application/zip).
+// The tracks are owned by the logged in user, with the privacy level given by '?privacy=<level>',
+// and are shared with the clubs given by '?clubs=<id>,<id>'.
+// With '?dry_run=true' the tracks are checked, but not stored.
+// Input/Output: application/json
+func HandleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		// Wrong method.
+		// Sets status code to 400 (Bad request).
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Every track has an owner.
+	account, ok := user.Authenticate(r)
+	if !ok {
+		// Sets the header code to 401 (Unauthorized), and returns error message.
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Error: Log in to add tracks, with the 'Authorization: Bearer <token>' header"))
+		return
+	}
+	owner := batchOwner{id: account.ID, privacy: r.URL.Query().Get("privacy")}
+	if !user.ValidPrivacy(owner.privacy) {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Unknown privacy level, should be public, club or private"))
+		return
+	}
+	if owner.privacy == "" {
+		owner.privacy = mongodb.PrivacyPublic
+	}
+
+	// Reads the IDs of the clubs to share the tracks with, '?clubs=<id>,<id>'.
+	if clubs := r.URL.Query().Get("clubs"); clubs != "" {
+		fields := strings.Split(clubs, ",")
+		for i := 0; i < len(fields); i++ {
+			club, err := strconv.Atoi(fields[i])
+			if err != nil {
+				// Sets header status code to 400 "Bad request", and returns error message.
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte("Error: 'clubs' should be a comma separated list of club IDs"))
+				return
+			}
+			owner.clubs = append(owner.clubs, club)
+		}
+		if !(user.Viewer{UserID: account.ID, Clubs: user.ClubsOf(account.ID)}).MemberOfAll(owner.clubs) {
+			// Sets the header code to 401 (Unauthorized), and returns error message.
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte("Error: Tracks can only be shared with the clubs of the owner"))
+			return
+		}
+	}
+
+	// The server deadline is for short requests, the batch sets its own. Not all writers support it.
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(BatchWriteTimeout))
+
+	// Reads the tracks to import.
+	var items []batchItem
+	var err error
+	contentType := r.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "application/zip") || strings.HasPrefix(contentType, "application/x-zip-compressed") {
+		items, err = zipItems(w, r)
+	} else {
+		items, err = urlItems(r)
+	}
+	if err != nil || len(items) == 0 {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Malformed POST request, should be '{\"urls\": [\"<url>\", ...]}' or a zip of IGC files"))
+		return
+	}
+	if len(items) > MaxBatchItems {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("Error: Too many tracks, at most %d can be imported at once", MaxBatchItems)))
+		return
+	}
+
+	// Sets the collection to be used in the webhook package.
+	webhook.CollectionTrack = Collection
+
+	response := importBatch(r.Context(), items, owner, r.URL.Query().Get("dry_run") == "true")
+
+	// The subscribers get one notification for all the new tracks, a dry run stores nothing to notify of.
+	if !response.DryRun && response.Inserted > 0 {
+		notifyNewTracks(r.Context(), mongodb.Track{Privacy: owner.privacy, Clubs: owner.clubs}, response.Inserted)
+	}
+	httpjson.Write(w, http.StatusOK, response)
+}
@@ -0,0 +1,181 @@
+/*
+  File: batch_test.go
+  Contains unit tests for batch.go
+*/
+
+package track
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/webhook"
+)
+
+// Creates a zip with the given files.
+func testZip(files map[string]string) *bytes.Buffer {
+	buffer := new(bytes.Buffer)
+	writer := zip.NewWriter(buffer)
+	for name, content := range files {
+		file, _ := writer.Create(name)
+		file.Write([]byte(content))
+	}
+	writer.Close()
+	return buffer
+}
+
+// Function to test: HandleBatch().
+// Test that the files of a zip that are not IGC data get an error each, and other files are skipped.
+func Test_HandleBatch_Zip(t *testing.T) {
+	body := testZip(map[string]string{"broken.igc": "not igc", "readme.txt": "skipped"})
+
+	// Creates a POST request with the zip that is passed to the handler.
+	request, _ := http.NewRequest("POST", "/paragliding/api/track/batch?dry_run=true", body)
+	request.Header.Set("Content-Type", "application/zip")
+	request.Header.Set("Authorization", "Bearer "+testToken())
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/track/batch", HandleBatch).Methods("POST")
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (200).
+	status := recorder.Code
+	if status != http.StatusOK {
+		t.Fatalf("Handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	// Check the results.
+	var actual batchResponse
+	json.Unmarshal(recorder.Body.Bytes(), &actual)
+	if !actual.DryRun || actual.Failed != 1 || len(actual.Results) != 1 {
+		t.Fatalf("Handler returned wrong response: got %v", actual)
+	}
+	if actual.Results[0].Source != "broken.igc" || actual.Results[0].Error != ErrParse.Error() {
+		t.Errorf("Handler returned wrong result: got %v", actual.Results[0])
+	}
+}
+
+// Function to test: HandleBatch().
+// Test that a dry run counts the new tracks as would_insert, and does not notify the webhooks.
+func Test_HandleBatch_DryRun(t *testing.T) {
+	// Injects the MongoDB collections to use.
+	Collection = "TestTracks"
+	webhook.CollectionWebhook = "TestWebhooks"
+	defer func() { webhook.CollectionWebhook = "" }()
+
+	// A webhook that counts the new tracks.
+	webhooks := mongodb.DatabaseInit(webhook.CollectionWebhook)
+	id, err := webhooks.InsertWebhook(mongodb.Webhook{WebhookURL: "http://test1.local", MinTriggerValue: 100})
+	if err != nil {
+		t.Fatalf("Could not insert the webhook: %v", err)
+	}
+	defer webhooks.DeleteAll()
+
+	// A zip with a valid IGC file.
+	content, err := os.ReadFile("../analysis/testdata/triangle.igc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := testZip(map[string]string{"triangle.igc": string(content)})
+
+	// Creates a POST request with the zip that is passed to the handler.
+	request, _ := http.NewRequest("POST", "/paragliding/api/track/batch?dry_run=true", body)
+	request.Header.Set("Content-Type", "application/zip")
+	request.Header.Set("Authorization", "Bearer "+testToken())
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/track/batch", HandleBatch).Methods("POST")
+	router.ServeHTTP(recorder, request)
+
+	// Check the counts, the track is not stored.
+	var actual batchResponse
+	json.Unmarshal(recorder.Body.Bytes(), &actual)
+	if !actual.DryRun || actual.WouldInsert != 1 || actual.Inserted != 0 {
+		t.Fatalf("Handler returned wrong response: got %v", actual)
+	}
+
+	// Check the webhook was not told of the track.
+	hook, err := webhooks.FindWebhook(id)
+	if err != nil {
+		t.Fatalf("Could not find the webhook: %v", err)
+	}
+	if hook.NumberOfNewInserts != 0 {
+		t.Errorf("Webhook counter changed by a dry run: got %d want %d", hook.NumberOfNewInserts, 0)
+	}
+}
+
+// Function to test: HandleBatch().
+// Test that the results of a list of urls are in the same order as the urls.
+func Test_HandleBatch_URLs(t *testing.T) {
+	// POST data, urls that can not be fetched.
+	var urls []string
+	for i := 0; i < 10; i++ {
+		urls = append(urls, fmt.Sprintf("wrong%d", i))
+	}
+	postData, _ := json.Marshal(batchBody{urls})
+
+	// Creates a POST request that is passed to the handler.
+	request, _ := http.NewRequest("POST", "/paragliding/api/track/batch", bytes.NewReader(postData))
+	request.Header.Set("Authorization", "Bearer "+testToken())
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/track/batch", HandleBatch).Methods("POST")
+	router.ServeHTTP(recorder, request)
+
+	// Check the results.
+	var actual batchResponse
+	json.Unmarshal(recorder.Body.Bytes(), &actual)
+	if actual.Failed != len(urls) || actual.Inserted != 0 {
+		t.Fatalf("Handler returned wrong response: got %v", actual)
+	}
+	for i := 0; i < len(urls); i++ {
+		if actual.Results[i].Source != urls[i] || !strings.Contains(actual.Results[i].Error, "invalid_url") {
+			t.Errorf("Handler returned wrong result %d: got %v", i, actual.Results[i])
+		}
+	}
+}
+
+// Function to test: HandleBatch().
+// Test to check the returned status code when the POST request has wrong json format.
+func Test_HandleBatch_MalformedPost(t *testing.T) {
+	// Creates a malformed POST request that is passed to the handler.
+	request, _ := http.NewRequest("POST", "/paragliding/api/track/batch", strings.NewReader("{\"urls\": []}"))
+	request.Header.Set("Authorization", "Bearer "+testToken())
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/track/batch", HandleBatch).Methods("POST")
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (400).
+	status := recorder.Code
+	if status != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,163 @@
+/*
+	File: edit.go
+  Contains the deletion and correction of tracks, with an audit log of the changes.
+*/
+
+package track
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mats93/paragliding/admin"
+	"github.com/mats93/paragliding/internal/httpjson"
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/user"
+)
+
+// Error message of a malformed track correction.
+const malformedPatch = "Error: Malformed PATCH request, should be '{\"pilot\": <pilot>, \"glider\": <glider>, \"glider_id\": <glider_id>, \"privacy\": <public, club or private>, \"clubs\": [<club id>, ...]}'"
+
+// CollectionAudit is the MongoDB collection to use for the audit log. Gets injected from main or test.
+var CollectionAudit string
+
+// Finds the track with the ID in the path, and checks that the request may change it.
+// Writes the error response and returns false if not.
+func editableTrack(w http.ResponseWriter, r *http.Request) (mongodb.Track, string, bool) {
+	var id int
+	// Gets the ID from the URL and converts it to an integer.
+	fmt.Sscanf(r.URL.Path, "/paragliding/api/track/%d", &id)
+
+	// Finds the track.
+	track, err := Find(r.Context(), user.ViewerOf(r), id)
+	if err != nil {
+		// A track with the given ID does not exist, or is hidden from the request.
+		// Sets the header code to 404 (Not found).
+		w.WriteHeader(http.StatusNotFound)
+		return mongodb.Track{}, "", false
+	}
+
+	actor, ok := Authorize(r, track)
+	if !ok {
+		// Sets the header code to 401 (Unauthorized), and returns error message.
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Error: Only the owner of the track or admins can change it"))
+		return mongodb.Track{}, "", false
+	}
+	return track, actor, true
+}
+
+// DELETE: Deletes a track. The track is kept in the database marked as deleted, and is left out
+// of all responses, including the tickers.
+func deleteTrack(w http.ResponseWriter, r *http.Request) {
+	track, actor, ok := editableTrack(w, r)
+	if !ok {
+		return
+	}
+
+	if err := Delete(r.Context(), track, actor); err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+
+	// Sets the header code to 204 (No content).
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PATCH: Corrects the pilot, glider, glider ID and privacy level of a track, and the clubs it is shared with.
+// Input/Output: application/json
+func updateTrack(w http.ResponseWriter, r *http.Request) {
+	track, actor, ok := editableTrack(w, r)
+	if !ok {
+		return
+	}
+
+	var update Correction
+
+	// Decodes the json and converts it to a struct, other fields can not be changed.
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&update); err != nil {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(malformedPatch))
+		return
+	}
+
+	track, err := Correct(r.Context(), user.ViewerOf(r), track, actor, update)
+	switch err {
+	case nil:
+		httpjson.Write(w, http.StatusOK, track)
+
+	case ErrInvalidPrivacy:
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(malformedPatch))
+
+	case ErrNotMember:
+		// Sets the header code to 401 (Unauthorized), and returns error message.
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Error: Tracks can only be shared with your own clubs"))
+
+	default:
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+	}
+}
+
+// HandleTrack - GET:    Returns the track with the provided '<id>'.
+// HandleTrack - DELETE: Deletes the track with the provided '<id>', only for the owner or admins.
+// HandleTrack - PATCH:  Corrects the pilot, glider, glider ID, privacy level and clubs of the track, only for the owner or admins.
+// Input/Output: application/json
+func HandleTrack(w http.ResponseWriter, r *http.Request) {
+	// Calls functions to handle the GET, DELETE and PATCH requests.
+	switch r.Method {
+	case "GET":
+		GetTrackByID(w, r)
+
+	case "DELETE":
+		deleteTrack(w, r)
+
+	case "PATCH":
+		updateTrack(w, r)
+
+	default:
+		// Wrong method.
+		// Sets status code to 400 (Bad request).
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// GetTrackAudit - GET: Returns the audit log of the track with the provided '<id>', only for admins.
+// The log of deleted tracks is kept.
+// Output: application/json
+func GetTrackAudit(w http.ResponseWriter, r *http.Request) {
+	if !admin.IsAdmin(r) {
+		// Sets the header code to 401 (Unauthorized).
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var id int
+	// Gets the ID from the URL and converts it to an integer.
+	fmt.Sscanf(r.URL.Path, "/paragliding/api/track/%d/audit", &id)
+
+	// Connects to the database, and finds the log.
+	database := mongodb.DatabaseContext(r.Context(), CollectionAudit)
+	entries, err := database.FindAudit(id)
+	if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+	if entries == nil {
+		entries = []mongodb.AuditEntry{}
+	}
+	httpjson.Write(w, http.StatusOK, entries)
+}
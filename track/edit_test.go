@@ -0,0 +1,122 @@
+/*
+  File: edit_test.go
+  Contains unit tests for edit.go
+*/
+
+package track
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mats93/paragliding/admin"
+	"github.com/mats93/paragliding/mongodb"
+)
+
+// Function to test: HandleTrack().
+// Test that a track can be deleted by an admin, and is gone afterwards.
+func Test_HandleTrack_DELETE(t *testing.T) {
+	// Injects the MongoDB collections to use, and the admin token.
+	Collection = "TestTracks"
+	CollectionAudit = "TestAudit"
+	admin.Token = "secret"
+	defer func() { admin.Token = "" }()
+
+	// Connects to the database, and inserts a track.
+	database := mongodb.DatabaseInit(Collection)
+	database.Insert(mongodb.Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", TrackSrcURL: "http://test1.test"})
+
+	// Creates the router.
+	router := mux.NewRouter()
+	router.HandleFunc("/paragliding/api/track/{id:[0-9]+}", HandleTrack)
+
+	// Without the token the track can not be deleted.
+	request, _ := http.NewRequest("DELETE", "/paragliding/api/track/1", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Handler returned wrong status code: got %v want %v", recorder.Code, http.StatusUnauthorized)
+	}
+
+	// With the token it can.
+	request, _ = http.NewRequest("DELETE", "/paragliding/api/track/1", nil)
+	request.Header.Set("Authorization", "Bearer secret")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("Handler returned wrong status code: got %v want %v", recorder.Code, http.StatusNoContent)
+	}
+
+	// The track is gone, and the deletion is logged.
+	request, _ = http.NewRequest("GET", "/paragliding/api/track/1", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Handler returned wrong status code: got %v want %v", recorder.Code, http.StatusNotFound)
+	}
+	audit := mongodb.DatabaseInit(CollectionAudit)
+	entries, _ := audit.FindAudit(1)
+	if len(entries) != 1 || entries[0].Action != mongodb.AuditDelete || entries[0].Actor != "admin" {
+		t.Errorf("Handler logged wrong entries: got %v", entries)
+	}
+
+	// Removes the test data.
+	database.DeleteAll()
+	audit.DeleteAll()
+}
+
+// Function to test: HandleTrack().
+// Test that the pilot of a track can be corrected, and other fields are rejected.
+func Test_HandleTrack_PATCH(t *testing.T) {
+	// Injects the MongoDB collections to use, and the admin token.
+	Collection = "TestTracks"
+	CollectionAudit = "TestAudit"
+	admin.Token = "secret"
+	defer func() { admin.Token = "" }()
+
+	// Connects to the database, and inserts a track.
+	database := mongodb.DatabaseInit(Collection)
+	database.Insert(mongodb.Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", TrackSrcURL: "http://test1.test"})
+
+	// Creates the router.
+	router := mux.NewRouter()
+	router.HandleFunc("/paragliding/api/track/{id:[0-9]+}", HandleTrack)
+
+	// The length can not be changed.
+	request, _ := http.NewRequest("PATCH", "/paragliding/api/track/1", strings.NewReader("{\"track_length\": 1000}"))
+	request.Header.Set("Authorization", "Bearer secret")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code: got %v want %v", recorder.Code, http.StatusBadRequest)
+	}
+
+	// The pilot can.
+	request, _ = http.NewRequest("PATCH", "/paragliding/api/track/1", strings.NewReader("{\"pilot\": \"Pilot Name\"}"))
+	request.Header.Set("Authorization", "Bearer secret")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+	}
+
+	var actual mongodb.Track
+	json.Unmarshal(recorder.Body.Bytes(), &actual)
+	if actual.Pilot != "Pilot Name" || actual.Glider != "glider1" {
+		t.Errorf("Handler returned wrong track: got %v", actual)
+	}
+	audit := mongodb.DatabaseInit(CollectionAudit)
+	entries, _ := audit.FindAudit(1)
+	if len(entries) != 1 || entries[0].Changes["pilot"] != (mongodb.Change{From: "pilot1", To: "Pilot Name"}) {
+		t.Errorf("Handler logged wrong entries: got %v", entries)
+	}
+
+	// Removes the test data.
+	database.DeleteAll()
+	audit.DeleteAll()
+}
@@ -0,0 +1,255 @@
+/*
+	File: service.go
+  Contains the track operations below the HTTP layer, shared by the handlers of all API versions.
+*/
+
+package track
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/mats93/paragliding/admin"
+	"github.com/mats93/paragliding/grecord"
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/ticker"
+	"github.com/mats93/paragliding/user"
+	"github.com/mats93/paragliding/webhook"
+	"github.com/rickb777/date/period"
+)
+
+// Errors of the track operations, the handlers turn them into responses.
+var (
+	// ErrNotFound is returned when the track does not exist, or is hidden from the viewer.
+	ErrNotFound = errors.New("the track does not exist")
+	// ErrUnknownValidation is returned for an unknown G-record validation status.
+	ErrUnknownValidation = errors.New("unknown validation status")
+	// ErrInvalidPrivacy is returned for an unknown privacy level.
+	ErrInvalidPrivacy = errors.New("unknown privacy level, should be public, club or private")
+	// ErrNotMember is returned when a track is shared with a club the user is not a member of.
+	ErrNotMember = errors.New("tracks can only be shared with your own clubs")
+)
+
+// Correction is a correction of a track, only the given fields are changed.
+type Correction struct {
+	Pilot    *string `json:"pilot"`
+	Glider   *string `json:"glider"`
+	GliderID *string `json:"glider_id"`
+	Privacy  *string `json:"privacy"`
+	Clubs    *[]int  `json:"clubs"`
+}
+
+// Info returns the information about the API, the number of tracks is 0 if the database fails.
+func Info(ctx context.Context) APIInfo {
+	// Counts the tracks, the information is still returned if the database fails.
+	database := mongodb.DatabaseContext(ctx, Collection)
+	count, err := database.GetCount()
+	if err != nil {
+		logging.FromContext(ctx).Error(err.Error())
+	}
+	return NewInfo(count)
+}
+
+// NewInfo returns the information about the API, with the number of tracks.
+func NewInfo(count int) APIInfo {
+	// Calculates the duration since application start.
+	// Uses the ISO 8601 Duration format.
+	// The Date package "github.com/rickb777/date/period" is used for this.
+	p, _ := period.NewOf(time.Since(StartTime))
+	return APIInfo{p.String(), INFORMATION, VERSION, Commit, runtime.Version(), STORAGE, count, ticker.CAP}
+}
+
+// CheckValidation checks that a G-record validation status to filter by is known, an empty one is no filter.
+func CheckValidation(validation string) error {
+	if validation != "" && !grecord.ValidStatus(validation) {
+		return ErrUnknownValidation
+	}
+	return nil
+}
+
+// IDs returns the IDs of the tracks the viewer can see, only those with the G-record validation status
+// if it is not empty.
+func IDs(ctx context.Context, viewer user.Viewer, validation string) ([]int, error) {
+	if err := CheckValidation(validation); err != nil {
+		return nil, err
+	}
+
+	// Connects to the database, and finds the tracks.
+	database := mongodb.DatabaseContext(ctx, Collection)
+	var tracks []mongodb.Track
+	var err error
+	if validation != "" {
+		tracks, err = database.FindTracksByValidation(validation)
+	} else {
+		tracks, err = database.FindAll()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Leaves out the tracks hidden from the viewer.
+	ids := []int{}
+	for i := 0; i < len(tracks); i++ {
+		if viewer.CanView(tracks[i]) {
+			ids = append(ids, tracks[i].ID)
+		}
+	}
+	return ids, nil
+}
+
+// Find returns the track with the ID, or ErrNotFound if it does not exist or is hidden from the viewer.
+func Find(ctx context.Context, viewer user.Viewer, id int) (mongodb.Track, error) {
+	database := mongodb.DatabaseContext(ctx, Collection)
+	tracks, err := database.FindByID(id)
+	if err != nil || !viewer.CanView(tracks[0]) {
+		return mongodb.Track{}, ErrNotFound
+	}
+	return tracks[0], nil
+}
+
+// Field returns the value of the named field of a track, and false if there is no such field.
+func Field(track mongodb.Track, name string) (interface{}, bool) {
+	switch name {
+	case "H_date":
+		return track.HDate, true
+	case "pilot":
+		return track.Pilot, true
+	case "glider":
+		return track.Glider, true
+	case "glider_id":
+		return track.GliderID, true
+	case "track_length":
+		return track.TrackLength, true
+	case "track_length_clean":
+		return track.TrackLengthClean, true
+	case "track_src_url":
+		return track.TrackSrcURL, true
+	case "takeoff_site":
+		return track.TakeoffSite, true
+	case "landing_site":
+		return track.LandingSite, true
+	case "min_agl":
+		return track.MinAGL, true
+	case "terrain":
+		return track.Terrain, true
+	case "validation":
+		return track.Validation, true
+	case "owner":
+		return track.Owner, true
+	case "privacy":
+		return track.Privacy, true
+	}
+	return nil, false
+}
+
+// CheckNew checks the privacy level of a new track of the owner, and that the owner is a member of the
+// clubs it is shared with.
+func CheckNew(owner int, privacy string, clubs []int) error {
+	if !user.ValidPrivacy(privacy) {
+		return ErrInvalidPrivacy
+	}
+	if len(clubs) > 0 && !(user.Viewer{UserID: owner, Clubs: user.ClubsOf(owner)}).MemberOfAll(clubs) {
+		return ErrNotMember
+	}
+	return nil
+}
+
+// Authorize returns who is making the request, and if they may change the track.
+func Authorize(r *http.Request, track mongodb.Track) (string, bool) {
+	if admin.IsAdmin(r) {
+		return "admin", true
+	}
+	if owner, ok := user.Authenticate(r); ok && owner.ID == track.Owner {
+		return owner.Username, true
+	}
+	return "", false
+}
+
+// Delete deletes a track by the actor. The track is kept in the database marked as deleted, and is
+// left out of all responses, including the tickers.
+func Delete(ctx context.Context, track mongodb.Track, actor string) error {
+	// Connects to the database, and deletes the track.
+	database := mongodb.DatabaseContext(ctx, Collection)
+	if err := database.SoftDeleteTrack(track.ID); err != nil {
+		return err
+	}
+
+	// Logs who deleted it.
+	audit := mongodb.DatabaseContext(ctx, CollectionAudit)
+	if err := audit.InsertAudit(mongodb.AuditEntry{
+		TrackID: track.ID,
+		Actor:   actor,
+		Action:  mongodb.AuditDelete,
+		Time:    time.Now().UTC(),
+	}); err != nil {
+		logging.FromContext(ctx).Error(err.Error())
+	}
+
+	// Only the subscribers of public tracks are told.
+	if track.Public() {
+		webhook.NotifyEvent(ctx, webhook.EventDeleted, fmt.Sprintf("Track id%d was deleted by %s.", track.ID, actor))
+	}
+	return nil
+}
+
+// Apply applies a correction of a track by the viewer, and returns the corrected track with the changed fields.
+// The viewer must be a member of the clubs the track is shared with.
+func Apply(viewer user.Viewer, track mongodb.Track, c Correction) (mongodb.Track, map[string]mongodb.Change, error) {
+	if c.Privacy != nil && !user.ValidPrivacy(*c.Privacy) {
+		return track, nil, ErrInvalidPrivacy
+	}
+	if c.Clubs != nil && !viewer.MemberOfAll(*c.Clubs) {
+		return track, nil, ErrNotMember
+	}
+
+	// Applies the changes, and records the changed fields.
+	changes := map[string]mongodb.Change{}
+	apply := func(name string, field *string, value *string) {
+		if value != nil && *value != *field {
+			changes[name] = mongodb.Change{From: *field, To: *value}
+			*field = *value
+		}
+	}
+	apply("pilot", &track.Pilot, c.Pilot)
+	apply("glider", &track.Glider, c.Glider)
+	apply("glider_id", &track.GliderID, c.GliderID)
+	apply("privacy", &track.Privacy, c.Privacy)
+	if c.Clubs != nil && fmt.Sprint(*c.Clubs) != fmt.Sprint(track.Clubs) {
+		changes["clubs"] = mongodb.Change{From: fmt.Sprint(track.Clubs), To: fmt.Sprint(*c.Clubs)}
+		track.Clubs = *c.Clubs
+	}
+	return track, changes, nil
+}
+
+// Correct applies a correction of a track by the actor, and returns the corrected track.
+// The viewer must be a member of the clubs the track is shared with.
+func Correct(ctx context.Context, viewer user.Viewer, track mongodb.Track, actor string, c Correction) (mongodb.Track, error) {
+	track, changes, err := Apply(viewer, track, c)
+	if err != nil || len(changes) == 0 {
+		return track, err
+	}
+
+	// Connects to the database, and updates the track.
+	database := mongodb.DatabaseContext(ctx, Collection)
+	if err := database.UpdateTrack(track); err != nil {
+		return track, err
+	}
+
+	// Logs who changed what.
+	audit := mongodb.DatabaseContext(ctx, CollectionAudit)
+	if err := audit.InsertAudit(mongodb.AuditEntry{
+		TrackID: track.ID,
+		Actor:   actor,
+		Action:  mongodb.AuditUpdate,
+		Changes: changes,
+		Time:    time.Now().UTC(),
+	}); err != nil {
+		logging.FromContext(ctx).Error(err.Error())
+	}
+	return track, nil
+}
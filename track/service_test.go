@@ -0,0 +1,52 @@
+/*
+  File: service_test.go
+  Contains unit tests for service.go
+*/
+
+package track
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/user"
+)
+
+// Function to test: Field().
+func Test_Field(t *testing.T) {
+	date := time.Date(2018, 10, 18, 0, 0, 0, 0, time.UTC)
+	minAGL := 35.0
+	track := mongodb.Track{HDate: date, Pilot: "Pilot", TrackLength: 12.5, TrackLengthClean: 12.1, MinAGL: &minAGL,
+		Terrain: "partial", Owner: 3}
+
+	tests := map[string]interface{}{"H_date": date, "pilot": "Pilot", "track_length": 12.5, "track_length_clean": 12.1,
+		"min_agl": &minAGL, "terrain": "partial", "owner": 3}
+	for name, expected := range tests {
+		if actual, ok := Field(track, name); !ok || actual != expected {
+			t.Errorf("Function returned wrong value of %s: got %v want %v", name, actual, expected)
+		}
+	}
+	if _, ok := Field(track, "checksum"); ok {
+		t.Error("Function returned a field that is not shown")
+	}
+}
+
+// Function to test: IDs().
+// Test that an unknown validation status is an error, before the database is used.
+func Test_IDs_UnknownValidation(t *testing.T) {
+	if _, err := IDs(context.Background(), user.Viewer{}, "signed"); err != ErrUnknownValidation {
+		t.Errorf("Function returned wrong error: got %v want %v", err, ErrUnknownValidation)
+	}
+}
+
+// Function to test: CheckNew().
+func Test_CheckNew(t *testing.T) {
+	if err := CheckNew(1, "friends", nil); err != ErrInvalidPrivacy {
+		t.Errorf("Function returned wrong error for an unknown privacy level: got %v want %v", err, ErrInvalidPrivacy)
+	}
+	if err := CheckNew(1, mongodb.PrivacyClub, nil); err != nil {
+		t.Errorf("Function returned unexpected error: %v", err)
+	}
+}
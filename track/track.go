@@ -6,17 +6,30 @@
 package track
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	igc "github.com/marni/goigc"
+	"github.com/mats93/paragliding/airspace"
+	"github.com/mats93/paragliding/analysis"
+	"github.com/mats93/paragliding/fetch"
+	"github.com/mats93/paragliding/grecord"
+	"github.com/mats93/paragliding/internal/httpjson"
+	"github.com/mats93/paragliding/job"
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/metrics"
 	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/openapi"
+	"github.com/mats93/paragliding/site"
+	"github.com/mats93/paragliding/user"
 	"github.com/mats93/paragliding/webhook"
-	"github.com/rickb777/date/period"
 )
 
 // INFORMATION = The API inforomation text.
@@ -25,16 +38,26 @@ const INFORMATION = "Service for Paragliding tracks"
 // VERSION = The API version that is currently running.
 const VERSION = "v1"
 
-// Format for the API information.
-type apiInfo struct {
-	Uptime  string `json:"uptime"`
-	Info    string `json:"info"`
-	Version string `json:"version"`
+// STORAGE = The storage backend of the tracks.
+const STORAGE = "mongodb"
+
+// APIInfo is the format for the API information.
+type APIInfo struct {
+	Uptime    string `json:"uptime"`
+	Info      string `json:"info"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+	Storage   string `json:"storage"`
+	Tracks    int    `json:"tracks"`
+	TickerCap int    `json:"ticker_cap"`
 }
 
-// Format for the url information.
+// Format for the url information, the privacy level of the new track and the clubs it is shared with.
 type url struct {
-	URL string `json:"url"`
+	URL     string `json:"url"`
+	Privacy string `json:"privacy"`
+	Clubs   []int  `json:"clubs"`
 }
 
 // Format for the id to be returned when a new track is posted.
@@ -42,9 +65,17 @@ type id struct {
 	ID int `json:"id"`
 }
 
+// Format for the id to be returned when a new track is posted as a job.
+type queuedJob struct {
+	JobID int `json:"job_id"`
+}
+
 // The start time for the API service. Gets injected from main.
 var StartTime time.Time
 
+// Commit is the commit the service was built from. Gets injected from main.
+var Commit string
+
 // The MongoDB collection to use. Gets injected from main or test.
 var Collection string
 
@@ -56,22 +87,15 @@ func RedirectToInfo(w http.ResponseWriter, r *http.Request) {
 // GET: Returns information about the API.
 // Output: application/json
 func GetAPIInfo(w http.ResponseWriter, r *http.Request) {
-	// Calculates the duration since application start.
-	// Uses the ISO 8601 Duration format.
-	// The Date package "github.com/rickb777/date/period" is used for this.
-	duration := time.Since(StartTime)
-	p, _ := period.NewOf(duration)
-	timeStr := p.String()
-
 	// Creates a new struct for the API info.
-	currentAPI := apiInfo{timeStr, INFORMATION, VERSION}
+	currentAPI := Info(r.Context())
 
 	// Converts the struct to json.
 	json, err := json.Marshal(currentAPI)
 	if err != nil {
 		// Sets header status code to 500 "Internal server error" and logs the error.
 		w.WriteHeader(http.StatusInternalServerError)
-		log.Fatal(err)
+		logging.FromContext(r.Context()).Error(err.Error())
 	} else {
 		// Sets header content-type to application/json and status code to 200 (OK).
 		w.Header().Set("Content-Type", "application/json")
@@ -82,125 +106,229 @@ func GetAPIInfo(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// GET: Returns an array of al track IDs.
+// GET: Returns an array of al track IDs, of the tracks the request can see.
+// The tracks can be filtered by their G-record validation status with '?validation=<status>'.
 // Output: application/json.
 func allTrackIDs(w http.ResponseWriter, r *http.Request) {
-	// Connects to the database.
-	database := mongodb.DatabaseInit(Collection)
-
-	// Gets the Count of Tracks in the DB.
-	count, _ := database.GetCount()
-
-	// Check if there are any tracks in the DB.
-	if count != 0 {
-		// Slice of ints, to hold the IDs.
-		var idSlice []int
-
-		// Gets all tracks from the database.
-		// Loops through them, appending their ID to the new slice.
-		tracks, _ := database.FindAll()
-		for i := 0; i < len(tracks); i++ {
-			idSlice = append(idSlice, tracks[i].ID)
+	status := r.URL.Query().Get("validation")
+	if status == "" {
+		// Connects to the database.
+		database := mongodb.DatabaseContext(r.Context(), Collection)
+
+		// Check if there are any tracks in the DB.
+		if count, _ := database.GetCount(); count == 0 {
+			// There are no tracks stored in the DB.
+			// Sets header content-type to application/json and status code to 404 (Not found).
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+
+			// Returns an empty array.
+			w.Write([]byte("[]"))
+			return
 		}
+	}
 
-		// Converts the struct to json.
-		json, err := json.Marshal(idSlice)
-		if err != nil {
-			// Sets header status code to 500 "Internal server error" and logs the error.
-			w.WriteHeader(http.StatusInternalServerError)
-			log.Fatal(err)
-		} else {
-			// Sets header content-type to application/json and status code to 200 (OK).
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
+	// Gets the IDs of the tracks the request can see.
+	idSlice, err := IDs(r.Context(), user.ViewerOf(r), status)
+	if err == ErrUnknownValidation {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Unknown validation status, should be among: " + strings.Join(grecord.Statuses, ", ")))
+		return
+	} else if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
 
-			// Returns the array of IDs.
-			w.Write([]byte(json))
+	// Converts the slice to json, and returns it.
+	json, _ := json.Marshal(idSlice)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(json)
+}
+
+// ErrParse is returned by Ingest when the fetched file is not valid IGC data.
+var ErrParse = analysis.ErrParse
+
+// Locks the generation of new IDs, since tracks are inserted by the job workers too.
+var insertLock sync.Mutex
+
+// Creates the metadata of a parsed IGC file, the ID, timestamp and sites are set when it is stored.
+// The fixes are cleaned first, the file is returned with the cleaned fixes and the report is kept on the track,
+// with the check of the cleaned fixes against the airspaces.
+func newTrack(content string, trackFile igc.Track, trackURL string) (mongodb.Track, igc.Track) {
+	cleaned, report := analysis.Clean(trackFile)
+	track := analysis.Metadata(content, trackFile, cleaned)
+	track.TrackSrcURL = trackURL
+	track.Quality = &report
+	track.Airspace = airspace.CheckTrack(track.HDate, cleaned.Points)
+	return track, cleaned
+}
+
+// Stores a new track in the database, and returns its ID. If skipDuplicate is set and a track with the same
+// content is stored already, the ID of that track is returned with duplicate set instead.
+func store(ctx context.Context, database mongodb.MongoDB, track mongodb.Track, points []igc.Point, skipDuplicate bool) (newID int, duplicate bool, err error) {
+	// This part is in critical sector, locked since the job workers insert tracks too.
+	insertLock.Lock()
+	defer insertLock.Unlock()
+
+	// Checks for a duplicate before matching the sites, since that clusters the takeoffs.
+	// The same file may be stored by another worker.
+	if skipDuplicate {
+		if existing, err := database.FindTrackByChecksum(track.Checksum); err == nil {
+			return existing.ID, true, nil
 		}
-	} else {
-		// There are no tracks stored in the DB.
-		// Sets header content-type to application/json and status code to 404 (Not found).
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
+	}
+
+	// Matches the takeoff and landing to the sites, a failure only leaves them unmatched.
+	track.TakeoffSite, track.LandingSite, err = site.MatchFlight(database.WithCollection(site.Collection), points)
+	if err != nil {
+		logging.FromContext(ctx).Error(err.Error())
+	}
+
+	// Generates a new ID and a timestamp for the track.
+	track.ID = database.GetNewID()
+	track.Timestamp = mongodb.GenerateTimestamp()
 
-		// Returns an empty array.
-		w.Write([]byte("[]"))
+	// Adds the new track to the database.
+	if err := database.Insert(track); err != nil {
+		return 0, false, err
 	}
+	metrics.TracksIngested.Inc()
+	return track.ID, false, nil
 }
 
-// POST: Takes the post request as json format and inserts a new track to the DB.
-// Input/Output: application/json
-func insertNewTrack(w http.ResponseWriter, r *http.Request) {
+// Notifies the webhooks that n new tracks with the privacy level and clubs of the track are stored.
+// The webhooks not scoped to a club are only told of public tracks, and the webhooks of a club of the
+// tracks shared with it.
+func notifyNewTracks(ctx context.Context, track mongodb.Track, n int) {
+	if track.Public() {
+		webhook.CheckWebhooksN(ctx, n)
+	}
+	if track.Privacy != mongodb.PrivacyPrivate {
+		for i := 0; i < len(track.Clubs); i++ {
+			webhook.CheckClubWebhooksN(ctx, track.Clubs[i], n)
+		}
+	}
+}
+
+// Ingest fetches and parses the IGC file at the url, stores it as a new track of the owner shared with the
+// clubs, and returns the tracks ID. A failed fetch returns a *fetch.Error, and invalid IGC data ErrParse.
+// The context has the logger of the request or job.
+func Ingest(ctx context.Context, trackURL string, owner int, privacy string, clubs []int) (int, error) {
 	// Sets the collection to be used in the webhook package.
 	webhook.CollectionTrack = Collection
 	webhook.CollectionWebhook = "Webhooks"
-	// Connects to the database.
-	database := mongodb.DatabaseInit(Collection)
+
+	content, trackFile, err := fetch.IGC(trackURL)
+	if fetch.Code(err) != "" {
+		return 0, err
+	} else if err != nil {
+		metrics.ParseFailures.Inc()
+		return 0, ErrParse
+	}
+
+	// The igc parser worked, stores the track.
+	track, trackFile := newTrack(content, trackFile, trackURL)
+	track.Owner, track.Privacy, track.Clubs = owner, privacy, clubs
+	newID, _, err := store(ctx, mongodb.DatabaseContext(ctx, Collection), track, trackFile.Points, false)
+	if err != nil {
+		return 0, err
+	}
+
+	// Check if any webhooks needs to be notified of changes.
+	notifyNewTracks(ctx, track, 1)
+	if track.Public() {
+		airspace.NotifyInfringements(ctx, newID, track.Airspace)
+	}
+
+	return newID, nil
+}
+
+// POST: Takes the post request as json format and inserts a new track to the DB, owned by the logged in user.
+// With '?async=true' the track is inserted by a job, and the jobs ID is returned with 202 (Accepted).
+// Input/Output: application/json
+func insertNewTrack(w http.ResponseWriter, r *http.Request) {
+	// Every track has an owner.
+	owner, ok := user.Authenticate(r)
+	if !ok {
+		// Sets the header code to 401 (Unauthorized), and returns error message.
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Error: Log in to add tracks, with the 'Authorization: Bearer <token>' header"))
+		return
+	}
 
 	var newURL url
 
-	// Decodes the json url and converts it to a struct.
-	decoder := json.NewDecoder(r.Body)
-	err := decoder.Decode(&newURL)
+	// Reads the body, and decodes the json url and converts it to a struct.
+	// The body is kept to be validated against the OpenAPI document.
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &newURL)
+	}
 
-	if err != nil {
+	if err != nil || !user.ValidPrivacy(newURL.Privacy) {
 		// The decoding failed.
 		// Sets header status code to 400 "Bad request", and returns error message.
 		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Error: Malformed POST request, should be '{\"url\": \"<url>\"}'"))
-
-	} else {
-		// The decoding was sucessful.
-		// Adds the new track provided by the POST request.
-		trackFile, err := igc.ParseLocation(newURL.URL)
+		w.Write([]byte("Error: Malformed POST request, should be '{\"url\": \"<url>\", \"privacy\": <public, club or private>, \"clubs\": [<club id>, ...]}'"))
+		return
+	}
+	if err := openapi.Validate("TrackRegistration", body); err != nil {
+		// The body does not match the schema of the track registration, e.g. a missing url.
+		// Sets header status code to 400 "Bad request", and returns the invalid field.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Malformed POST request, " + err.Error()))
+		return
+	}
+	if CheckNew(owner.ID, newURL.Privacy, newURL.Clubs) == ErrNotMember {
+		// Sets the header code to 401 (Unauthorized), and returns error message.
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Error: Tracks can only be shared with the clubs of the owner"))
+		return
+	}
+	if newURL.Privacy == "" {
+		newURL.Privacy = mongodb.PrivacyPublic
+	}
 
+	if r.URL.Query().Get("async") == "true" {
+		// Queues a job for the track, the status is found at the jobs path.
+		jobID, err := job.Enqueue(r.Context(), newURL.URL, owner.ID, newURL.Privacy, newURL.Clubs)
 		if err != nil {
-			// The igc parser failed.
-			// Sets header status code to 400 "Bad request", and returns error message.
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Error: Bad url, could not parse the IGC data"))
+			// Sets header status code to 503 "Service unavailable", and returns error message.
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("Error: " + err.Error()))
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("/paragliding/api/job/%d", jobID))
+		httpjson.Write(w, http.StatusAccepted, queuedJob{jobID})
+		return
+	}
 
-		} else {
-			// The igc parser worked.
-			// Calculates the total distance for the track.
-			var sum float64
-			// Loops through all Points[] in the track.
-			for i := 0; i < len(trackFile.Points)-1; i++ {
-				// Adds the distance between two points togheter.
-				sum += trackFile.Points[i].Distance(trackFile.Points[i+1])
-			}
+	// Adds the new track provided by the POST request.
+	newID, err := Ingest(r.Context(), newURL.URL, owner.ID, newURL.Privacy, newURL.Clubs)
+	if fetch.Code(err) != "" {
+		// The url was not allowed, or the fetch failed.
+		// Sets header status code to 400 "Bad request", and returns the error with its code.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Could not fetch the IGC file, " + err.Error()))
 
-			// This part is in critical sector, should be locked by mutex if threads are used.
-			// Generates a new ID to be used.
-			newID := database.GetNewID()
-
-			// Generates a timestamp for the track.
-			timeStamp := mongodb.GenerateTimestamp()
-
-			// Adds the new track to the database.
-			database.Insert(mongodb.Track{newID, timeStamp, trackFile.Header.Date, trackFile.Pilot, trackFile.GliderType,
-				trackFile.GliderID, sum, newURL.URL})
-
-			// Critical sector ends.
-			// Check if any webhooks needs to be notified of changes.
-			webhook.CheckWebhooks()
-
-			// Converts the id to json format by using the id struct and Marshaling the struct to json.
-			idStruct := id{newID}
-			json, err := json.Marshal(idStruct)
-			if err != nil {
-				// Sets header status code to 500 "Internal server error" and logs the error.
-				w.WriteHeader(http.StatusInternalServerError)
-				log.Fatal(err)
-			} else {
-				// Sets header content-type to application/json and status code to 200 (OK).
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusOK)
-
-				// Returns the given tracks ID as json.
-				w.Write([]byte(json))
-			}
-		}
+	} else if err == ErrParse {
+		// The igc parser failed.
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Bad url, could not parse the IGC data"))
+
+	} else if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+
+	} else {
+		// Returns the given tracks ID as json.
+		httpjson.Write(w, http.StatusOK, id{newID})
 	}
 }
 
@@ -220,26 +348,21 @@ func HandleTracks(w http.ResponseWriter, r *http.Request) {
 // GET: Returns metadata about a given track with the provided '<id>'.
 // Output: application/json
 func GetTrackByID(w http.ResponseWriter, r *http.Request) {
-	// Connects to the database.
-	database := mongodb.DatabaseInit(Collection)
-
 	var id int
 	// Gets the ID from the URL and converts it to an integer.
 	fmt.Sscanf(r.URL.Path, "/paragliding/api/track/%d", &id)
 
 	// Tries to retrive the track with the requestet ID.
-	// Retrieves the ID from the url.
-	rTrackSlice, err := database.FindByID(id)
+	rTrack, err := Find(r.Context(), user.ViewerOf(r), id)
 	if err == nil {
 		// The request is valid, the track was found.
-		rTrack := rTrackSlice[0]
 
 		// Converts the struct to json and outputs it.
 		json, err := json.Marshal(rTrack)
 		if err != nil {
 			// Sets header status code to 500 "Internal server error" and logs the error.
 			w.WriteHeader(http.StatusInternalServerError)
-			log.Fatal(err)
+			logging.FromContext(r.Context()).Error(err.Error())
 		} else {
 			// Sets header content-type to application/json and status code to 200 (OK).
 			w.Header().Set("Content-Type", "application/json")
@@ -249,7 +372,7 @@ func GetTrackByID(w http.ResponseWriter, r *http.Request) {
 			w.Write([]byte(json))
 		}
 	} else {
-		// A track with the given ID does not excist.
+		// A track with the given ID does not excist, or is hidden from the request.
 		// Sets the header code to 404 (Not found).
 		w.WriteHeader(http.StatusNotFound)
 	}
@@ -258,9 +381,6 @@ func GetTrackByID(w http.ResponseWriter, r *http.Request) {
 // GET: Returns single detailed metadata about a given tracks field with the provided '<id>' and '<field>'.
 // Output: text/plain.
 func GetDetailedTrack(w http.ResponseWriter, r *http.Request) {
-	// Connects to the database.
-	database := mongodb.DatabaseInit(Collection)
-
 	var id int
 	var field string
 
@@ -268,33 +388,34 @@ func GetDetailedTrack(w http.ResponseWriter, r *http.Request) {
 	fmt.Sscanf(r.URL.Path, "/paragliding/api/track/%d/%s", &id, &field)
 
 	// Tries to retrive the track with the requestet ID.
-	// Retrieves the ID from the url.
-	rTrack, err := database.FindByID(id)
+	rTrack, err := Find(r.Context(), user.ViewerOf(r), id)
 	if err == nil {
 		// The request is valid, the track was found.
 
 		// Retrieves the field specified, or 404 field not found.
 		output := ""
-		switch field {
-		case "H_date":
+		value, ok := Field(rTrack, field)
+		switch v := value.(type) {
+		case time.Time:
 			// Converts time.Time to string with string() method.
-			output = rTrack[0].HDate.String()
-		case "pilot":
-			output = rTrack[0].Pilot
-		case "glider":
-			output = rTrack[0].Glider
-		case "glider_id":
-			output = rTrack[0].GliderID
-		case "track_length":
+			output = v.String()
+		case float64:
 			// Converts float64 to string.
-			output = strconv.FormatFloat(rTrack[0].TrackLength, 'f', 6, 64)
-		case "track_src_url":
-			output = rTrack[0].TrackSrcURL
-		default:
+			output = strconv.FormatFloat(v, 'f', 6, 64)
+		case *float64:
+			// A value that may be unknown, like the min AGL, is empty when it is.
+			if v != nil {
+				output = strconv.FormatFloat(*v, 'f', 6, 64)
+			}
+		case int:
+			output = strconv.Itoa(v)
+		case string:
+			output = v
+		}
+		if !ok {
 			// If the field specified does not match any field in the track.
 			// Sets the header code to 404 (Not found).
 			w.WriteHeader(http.StatusNotFound)
-			output = ""
 		}
 
 		// Sets header content-type to text/plain.
@@ -303,8 +424,32 @@ func GetDetailedTrack(w http.ResponseWriter, r *http.Request) {
 		// Returns the field.
 		w.Write([]byte(output))
 	} else {
-		// A track with the given ID does not excist.
+		// A track with the given ID does not excist, or is hidden from the request.
 		// Sets the header code to 404 (Not found).
 		w.WriteHeader(http.StatusNotFound)
 	}
 }
+
+// GET: Returns the data-quality report of the fixes of the track with the provided '<id>'.
+// Output: application/json
+func GetTrackQuality(w http.ResponseWriter, r *http.Request) {
+	var id int
+	// Gets the ID from the URL and converts it to an integer.
+	fmt.Sscanf(r.URL.Path, "/paragliding/api/track/%d/quality", &id)
+
+	// Tries to retrive the track with the requestet ID.
+	rTrack, err := Find(r.Context(), user.ViewerOf(r), id)
+	if err != nil {
+		// A track with the given ID does not excist, or is hidden from the request.
+		// Sets the header code to 404 (Not found).
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if rTrack.Quality == nil {
+		// Sets the header code to 404 (Not found), and returns error message.
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Error: The track was stored before the quality reports, and has none"))
+		return
+	}
+	httpjson.Write(w, http.StatusOK, rTrack.Quality)
+}
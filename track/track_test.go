@@ -9,18 +9,39 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/site"
+	"github.com/mats93/paragliding/ticker"
+	"github.com/mats93/paragliding/user"
 	"github.com/rickb777/date/period"
 )
 
+// Registers a test user if it does not exist, and returns a login token for it.
+func testToken() string {
+	// Injects the MongoDB collections to use.
+	user.Collection = "TestUsers"
+	user.CollectionSession = "TestSessions"
+
+	users := mongodb.DatabaseInit(user.Collection)
+	pilot, err := users.FindUserByName("testpilot")
+	if err != nil {
+		pilot.ID, _ = users.InsertUser(mongodb.User{Username: "testpilot", Created: time.Now()})
+	}
+	token, _, _ := user.NewToken(pilot.ID)
+	return token
+}
+
 // Function to test: GetAPIInfo().
 // Test to check the returned status code, content-type and data for the function.
 func Test_GetAPIInfo(t *testing.T) {
+	// Injects the MongoDB collection to use.
+	Collection = "TestTracks"
 
 	// Creates a request that is passed to the handler.
 	request, _ := http.NewRequest("GET", "/paragliding/api", nil)
@@ -52,11 +73,15 @@ func Test_GetAPIInfo(t *testing.T) {
 	p, _ := period.NewOf(duration)
 	timeStr := p.String()
 
+	// Counts the tracks in the DB.
+	database := mongodb.DatabaseInit(Collection)
+	count, _ := database.GetCount()
+
 	// Excpected result from the API call.
-	expected := apiInfo{timeStr, INFORMATION, VERSION}
+	expected := APIInfo{timeStr, INFORMATION, VERSION, Commit, runtime.Version(), STORAGE, count, ticker.CAP}
 
 	// Adding test data to compare with.
-	var actual apiInfo
+	var actual APIInfo
 	decoder := json.NewDecoder(recorder.Body)
 	decoder.Decode(&actual)
 
@@ -129,9 +154,9 @@ func Test_HandleTracks(t *testing.T) {
 
 	// Connects the the database and inserts 3 tracks.
 	database := mongodb.DatabaseInit(Collection)
-	database.Insert(mongodb.Track{1, 11, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.test"})
-	database.Insert(mongodb.Track{2, 12, time.Now(), "pilot2", "glider2", "glider_id2", 20.2, "http://test2.test"})
-	database.Insert(mongodb.Track{3, 13, time.Now(), "pilot3", "glider3", "glider_id3", 20.3, "http://test3.test"})
+	database.Insert(mongodb.Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.test"})
+	database.Insert(mongodb.Track{ID: 2, Timestamp: 12, HDate: time.Now(), Pilot: "pilot2", Glider: "glider2", GliderID: "glider_id2", TrackLength: 20.2, TrackSrcURL: "http://test2.test"})
+	database.Insert(mongodb.Track{ID: 3, Timestamp: 13, HDate: time.Now(), Pilot: "pilot3", Glider: "glider3", GliderID: "glider_id3", TrackLength: 20.3, TrackSrcURL: "http://test3.test"})
 
 	// Expected return when 3 tracks are in the DB.
 	expected := "[1,2,3]"
@@ -182,12 +207,58 @@ func Test_HandleTracks(t *testing.T) {
 	}
 }
 
+// Function to test: HandleTracks().
+// Test to check the returned status code when the tracks are filtered by an unknown validation status.
+func Test_HandleTracks_UnknownValidation(t *testing.T) {
+
+	// Creates a request with an unknown status that is passed to the handler.
+	request, _ := http.NewRequest("GET", "/paragliding/api/track?validation=signed", nil)
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/track", HandleTracks).Methods("GET")
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (400).
+	status := recorder.Code
+	if status != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
+
+// Function to test: HandleTracks().
+// Test that tracks can not be posted without logging in.
+func Test_HandleTracks_POST_Unauthorized(t *testing.T) {
+	// Creates a POST request without a token that is passed to the handler.
+	request, _ := http.NewRequest("POST", "/paragliding/api/track", strings.NewReader("{\"url\":\"http://test.test/track.igc\"}"))
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/track", HandleTracks).Methods("POST")
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (401).
+	status := recorder.Code
+	if status != http.StatusUnauthorized {
+		t.Errorf("Handler returned wrong status code: got %v want %v",
+			status, http.StatusUnauthorized)
+	}
+}
+
 // Function to test: HandleTracks().
 // Test to check the returned status code, content-type and data when the POST request has wrong json format.
 func Test_HandleTracks_POST_MalformedPost(t *testing.T) {
 
 	// Creates a malformed (wrong json format) POST request that is passed to the handler.
 	request, _ := http.NewRequest("POST", "/paragliding/api/track", strings.NewReader("wrong"))
+	request.Header.Set("Authorization", "Bearer "+testToken())
 
 	// Creates the recorder and router.
 	recorder := httptest.NewRecorder()
@@ -205,7 +276,7 @@ func Test_HandleTracks_POST_MalformedPost(t *testing.T) {
 	}
 
 	// Check the response body is what we expect, an error.
-	expected := "Error: Malformed POST request, should be '{\"url\": \"<url>\"}'"
+	expected := "Error: Malformed POST request, should be '{\"url\": \"<url>\", \"privacy\": <public, club or private>, \"clubs\": [<club id>, ...]}'"
 	actual := recorder.Body.String()
 
 	if actual != expected {
@@ -214,6 +285,33 @@ func Test_HandleTracks_POST_MalformedPost(t *testing.T) {
 	}
 }
 
+// Function to test: HandleTracks().
+// Test to check the returned status code when a track is posted as a job, but the job workers are not started.
+func Test_HandleTracks_POST_AsyncNotStarted(t *testing.T) {
+
+	// POST data.
+	postString := "{\"url\":\"http://test.test/track.igc\"}"
+
+	// Creates a POST request for a job that is passed to the handler.
+	request, _ := http.NewRequest("POST", "/paragliding/api/track?async=true", strings.NewReader(postString))
+	request.Header.Set("Authorization", "Bearer "+testToken())
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/track", HandleTracks).Methods("POST")
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (503).
+	status := recorder.Code
+	if status != http.StatusServiceUnavailable {
+		t.Errorf("Handler returned wrong status code: got %v want %v",
+			status, http.StatusServiceUnavailable)
+	}
+}
+
 // Function to test: HandleTracks().
 // Test to check the returned status code, content-type and data when the POST request has wrong url, but correct json format.
 func Test_HandleTracks_POST_WrongFile(t *testing.T) {
@@ -223,6 +321,7 @@ func Test_HandleTracks_POST_WrongFile(t *testing.T) {
 
 	// Creates a POST request with an url that does not work that is passed to the handler.
 	request, _ := http.NewRequest("POST", "/paragliding/api/track", strings.NewReader(postString))
+	request.Header.Set("Authorization", "Bearer "+testToken())
 
 	// Creates the recorder and router.
 	recorder := httptest.NewRecorder()
@@ -239,8 +338,8 @@ func Test_HandleTracks_POST_WrongFile(t *testing.T) {
 			status, http.StatusBadRequest)
 	}
 
-	// Check the response body is what we expect, an error.
-	expected := "Error: Bad url, could not parse the IGC data"
+	// Check the response body is what we expect, an error with the code of the failed fetch.
+	expected := "Error: Could not fetch the IGC file, invalid_url: \"wrong\" is not an absolute url"
 	actual := recorder.Body.String()
 
 	if actual != expected {
@@ -252,8 +351,9 @@ func Test_HandleTracks_POST_WrongFile(t *testing.T) {
 // Function to test: HandleTracks().
 // Test to check the returned status code, content-type and data for the function.
 func Test_HandleTracks_POST(t *testing.T) {
-	// Injects the MongoDB collection to use.
+	// Injects the MongoDB collections to use.
 	Collection = "TestTracks"
+	site.Collection = "TestSites"
 
 	// Connects the the database.
 	database := mongodb.DatabaseInit(Collection)
@@ -266,6 +366,7 @@ func Test_HandleTracks_POST(t *testing.T) {
 
 	// Creates a POST request that is passed to the handler.
 	request, _ := http.NewRequest("POST", "/paragliding/api/track", strings.NewReader(postString))
+	request.Header.Set("Authorization", "Bearer "+testToken())
 
 	// Creates the recorder and router.
 	recorder := httptest.NewRecorder()
@@ -307,6 +408,8 @@ func Test_HandleTracks_POST(t *testing.T) {
 
 	// Removes the test data.
 	database.DeleteAll()
+	sites := mongodb.DatabaseInit(site.Collection)
+	sites.DeleteAll()
 }
 
 // Function to test: GetTrackByID().
@@ -339,7 +442,7 @@ func Test_GetTrackByID(t *testing.T) {
 
 	// Connects the the database, and adds test data to the DB.
 	database := mongodb.DatabaseInit(Collection)
-	trackTest := mongodb.Track{1, 11, time.Now(), "pilot1", "glider1", "glider_id1", 21, "http://test.test"}
+	trackTest := mongodb.Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 21, TrackSrcURL: "http://test.test"}
 	database.Insert(trackTest)
 
 	// Creates a request that is passed to the handler.
@@ -387,6 +490,39 @@ func Test_GetTrackByID(t *testing.T) {
 	database.DeleteAll()
 }
 
+// Function to test: GetTrackByID().
+// Test that a private track is hidden from requests that are not logged in as the owner.
+func Test_GetTrackByID_Private(t *testing.T) {
+	// Injects the MongoDB collection to use.
+	Collection = "TestTracks"
+
+	// Connects the the database, and adds a private track of another user to the DB.
+	database := mongodb.DatabaseInit(Collection)
+	database.Insert(mongodb.Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", TrackSrcURL: "http://test.test", Owner: -1, Privacy: mongodb.PrivacyPrivate})
+
+	// Creates a request logged in as the test user that is passed to the handler.
+	request, _ := http.NewRequest("GET", "/paragliding/api/track/1", nil)
+	request.Header.Set("Authorization", "Bearer "+testToken())
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/track/1", GetTrackByID).Methods("GET")
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (404).
+	status := recorder.Code
+	if status != http.StatusNotFound {
+		t.Errorf("Handler returned wrong status code: got %v want %v",
+			status, http.StatusNotFound)
+	}
+
+	// Removes the test data.
+	database.DeleteAll()
+}
+
 // Function to test: GetDetailedTrack().
 // Test to check the returned status code, content-type when the requested ID does not exist.
 func Test_GetDetailedTrack_WrongID(t *testing.T) {
@@ -418,7 +554,7 @@ func Test_GetDetailedTrack_WrongField(t *testing.T) {
 
 	// Connects the the database, and adds test data to the DB.
 	database := mongodb.DatabaseInit(Collection)
-	database.Insert(mongodb.Track{1, 11, time.Now(), "pilot1", "glider1", "glider_id1", 21, "http://test.test"})
+	database.Insert(mongodb.Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 21, TrackSrcURL: "http://test.test"})
 
 	// Creates a request that is passed to the handler.
 	request, _ := http.NewRequest("GET", "/paragliding/api/track/1/feil", nil)
@@ -453,7 +589,7 @@ func Test_GetDetailedTrack(t *testing.T) {
 
 	// Connects the the database, and adds test data to the DB.
 	database := mongodb.DatabaseInit(Collection)
-	database.Insert(mongodb.Track{1, 11, time.Now(), expectedPilot, "glider1", "glider_id1", 21, "http://test.test"})
+	database.Insert(mongodb.Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: expectedPilot, Glider: "glider1", GliderID: "glider_id1", TrackLength: 21, TrackSrcURL: "http://test.test"})
 
 	// Creates a request that is passed to the handler.
 	request, _ := http.NewRequest("GET", "/paragliding/api/track/1/pilot", nil)
@@ -491,3 +627,50 @@ func Test_GetDetailedTrack(t *testing.T) {
 	// Removes the test data.
 	database.DeleteAll()
 }
+
+// Function to test: GetTrackQuality().
+// Test that the quality report of a track is returned, and 404 for a track stored before the reports.
+func Test_GetTrackQuality(t *testing.T) {
+	// Injects the MongoDB collection to use.
+	Collection = "TestTracks"
+
+	// Connects the the database, and adds a track with a report and one without to the DB.
+	database := mongodb.DatabaseInit(Collection)
+	report := mongodb.Quality{Fixes: 10, Duplicates: 1, Removed: 2,
+		Outliers: []mongodb.Outlier{{Index: 3, Time: time.Now(), Reason: "speed", Speed: 900}}, Gaps: []mongodb.Gap{}}
+	database.Insert(mongodb.Track{ID: 1, Timestamp: 11, HDate: time.Now(), Pilot: "pilot1", TrackSrcURL: "http://test.test", Quality: &report})
+	database.Insert(mongodb.Track{ID: 2, Timestamp: 12, HDate: time.Now(), Pilot: "pilot2", TrackSrcURL: "http://test.test"})
+
+	// Creates the router.
+	router := mux.NewRouter()
+	router.HandleFunc("/paragliding/api/track/{id:[0-9]+}/quality", GetTrackQuality).Methods("GET")
+
+	// Tests the function with the track with a report.
+	request, _ := http.NewRequest("GET", "/paragliding/api/track/1/quality", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (200).
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Handler returned wrong status code: got %v want %v", recorder.Code, http.StatusOK)
+	}
+
+	// Check the response body is what we expect.
+	var actual mongodb.Quality
+	if err := json.Unmarshal(recorder.Body.Bytes(), &actual); err != nil || actual.Removed != 2 || len(actual.Outliers) != 1 {
+		t.Errorf("Handler returned wrong data: got %s", recorder.Body.String())
+	}
+
+	// Tests the function with the track without a report.
+	request, _ = http.NewRequest("GET", "/paragliding/api/track/2/quality", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (404).
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Handler returned wrong status code: got %v want %v", recorder.Code, http.StatusNotFound)
+	}
+
+	// Removes the test data.
+	database.DeleteAll()
+}
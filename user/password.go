@@ -0,0 +1,72 @@
+/*
+	File: password.go
+  Hashes and checks passwords with the Argon2id key derivation function.
+*/
+
+package user
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id parameters, as recommended for interactive logins (RFC 9106, second choice).
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+// Encoding of the salt and key in the hash.
+var encoding = base64.RawStdEncoding
+
+// HashPassword hashes a password with a random salt.
+// The hash is in the PHC string format, so the parameters can be changed later.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argonMemory, argonTime,
+		argonThreads, encoding.EncodeToString(salt), encoding.EncodeToString(key)), nil
+}
+
+// CheckPassword checks a password against a hash from HashPassword.
+func CheckPassword(password string, hash string) bool {
+	// The hash is "$argon2id$v=<version>$m=<memory>,t=<time>,p=<threads>$<salt>$<key>".
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false
+	}
+
+	var version int
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false
+	}
+	salt, err := encoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	key, err := encoding.DecodeString(parts[5])
+	if err != nil {
+		return false
+	}
+
+	// Compares in constant time, so the key can not be guessed from the response time.
+	actual := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(actual, key) == 1
+}
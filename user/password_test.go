@@ -0,0 +1,38 @@
+/*
+  File: password_test.go
+  Contains unit tests for password.go
+*/
+
+package user
+
+import (
+	"strings"
+	"testing"
+)
+
+// Function to test: HashPassword() and CheckPassword().
+// Test that only the right password matches the hash, and the same password gets different hashes.
+func Test_CheckPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("Function returned unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=4$") {
+		t.Errorf("Function returned wrong hash format: got %s", hash)
+	}
+
+	if !CheckPassword("correct horse", hash) {
+		t.Error("Function did not match the right password")
+	}
+	if CheckPassword("wrong horse", hash) {
+		t.Error("Function matched a wrong password")
+	}
+	if CheckPassword("correct horse", "plaintext") {
+		t.Error("Function matched a malformed hash")
+	}
+
+	other, _ := HashPassword("correct horse")
+	if other == hash {
+		t.Error("Function returned the same hash twice, the salt is not random")
+	}
+}
@@ -0,0 +1,348 @@
+/*
+	File: user.go
+  Contains functions used by API calls to the "User paths", login tokens and who can see a track.
+*/
+
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mats93/paragliding/admin"
+	"github.com/mats93/paragliding/internal/httpjson"
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/mongodb"
+)
+
+// TokenLifetime is how long a login token is valid.
+const TokenLifetime = 30 * 24 * time.Hour
+
+// MinPasswordLength is the minimum number of characters in a password.
+const MinPasswordLength = 8
+
+// Usernames are 3 to 32 letters, digits, '_', '.' and '-'.
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,32}$`)
+
+// Format for the body of a registration or login.
+type credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Format for the token returned by a login.
+type login struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+// Format for the id to be returned when a new user is registered.
+type id struct {
+	ID int `json:"id"`
+}
+
+// Collection is the MongoDB collection to use for users. Gets injected from main or test.
+var Collection string
+
+// CollectionSession is the MongoDB collection to use for login sessions. Gets injected from main or test.
+var CollectionSession string
+
+// CollectionTrack is the MongoDB collection to use for tracks. Gets injected from main or test.
+var CollectionTrack string
+
+// CollectionClub is the MongoDB collection to use for clubs. Gets injected from main or test.
+var CollectionClub string
+
+// Viewer is who is making a request. UserID is 0 if the request is not logged in.
+// Clubs are the IDs of the clubs the user is a member of.
+type Viewer struct {
+	UserID int
+	Admin  bool
+	Clubs  []int
+}
+
+// Returns the hash of a token, only the hash is stored.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Returns the bearer token of a request.
+func bearerToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// NewToken creates a login session for the user, and returns its token.
+func NewToken(userID int) (string, time.Time, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(random)
+	expires := time.Now().Add(TokenLifetime).UTC()
+
+	// Connects to the database, and stores the session.
+	database := mongodb.DatabaseInit(CollectionSession)
+	err := database.InsertSession(mongodb.Session{TokenHash: hashToken(token), UserID: userID, Expires: expires})
+	return token, expires, err
+}
+
+// Authenticate returns the user logged in with the token of the request.
+func Authenticate(r *http.Request) (mongodb.User, bool) {
+	token := bearerToken(r)
+	if token == "" {
+		return mongodb.User{}, false
+	}
+
+	// Connects to the database, and finds the session.
+	database := mongodb.DatabaseContext(r.Context(), CollectionSession)
+	session, err := database.FindSession(hashToken(token))
+	if err != nil {
+		return mongodb.User{}, false
+	}
+
+	users := mongodb.DatabaseContext(r.Context(), Collection)
+	user, err := users.FindUser(session.UserID)
+	if err != nil {
+		return mongodb.User{}, false
+	}
+	return user, true
+}
+
+// ClubsOf returns the IDs of the clubs the user is a member of.
+func ClubsOf(userID int) []int {
+	// Connects to the database, and finds the clubs.
+	database := mongodb.DatabaseInit(CollectionClub)
+	clubs, err := database.FindClubsByMember(userID)
+	if err != nil {
+		log.Println(err)
+	}
+
+	ids := []int{}
+	for i := 0; i < len(clubs); i++ {
+		ids = append(ids, clubs[i].ID)
+	}
+	return ids
+}
+
+// ViewerOf returns who is making the request.
+func ViewerOf(r *http.Request) Viewer {
+	if admin.IsAdmin(r) {
+		return Viewer{Admin: true}
+	}
+	if user, ok := Authenticate(r); ok {
+		return Viewer{UserID: user.ID, Clubs: ClubsOf(user.ID)}
+	}
+	return Viewer{}
+}
+
+// Member checks if the viewer is a member of the club, admins are members of all clubs.
+func (v Viewer) Member(club int) bool {
+	if v.Admin {
+		return true
+	}
+	for i := 0; i < len(v.Clubs); i++ {
+		if v.Clubs[i] == club {
+			return true
+		}
+	}
+	return false
+}
+
+// MemberOfAll checks if the viewer is a member of all the clubs.
+func (v Viewer) MemberOfAll(clubs []int) bool {
+	for i := 0; i < len(clubs); i++ {
+		if !v.Member(clubs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// CanView checks if the viewer can see the track.
+// Public tracks are seen by everyone, club tracks by the members of the clubs it is shared with,
+// and all tracks by their owner and admins.
+func (v Viewer) CanView(track mongodb.Track) bool {
+	if track.Public() || v.Admin {
+		return true
+	}
+	if v.UserID != 0 && v.UserID == track.Owner {
+		return true
+	}
+	if track.Privacy == mongodb.PrivacyClub {
+		for i := 0; i < len(track.Clubs); i++ {
+			if v.Member(track.Clubs[i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ValidPrivacy checks if the privacy level is known, an empty level is public.
+func ValidPrivacy(privacy string) bool {
+	return privacy == "" || privacy == mongodb.PrivacyPublic || privacy == mongodb.PrivacyClub ||
+		privacy == mongodb.PrivacyPrivate
+}
+
+// Register - POST: Registers a new user, returns the users ID.
+// Input/Output: application/json
+func Register(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		// Wrong method.
+		// Sets status code to 400 (Bad request).
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var body credentials
+
+	// Decodes the json and converts it to a struct.
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&body)
+	if err != nil || !usernamePattern.MatchString(body.Username) || len(body.Password) < MinPasswordLength {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Malformed POST request, should be '{\"username\": <3-32 letters or digits>, \"password\": <at least 8 characters>}'"))
+		return
+	}
+
+	hash, err := HashPassword(body.Password)
+	if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+
+	// Connects to the database, and adds the user.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+	newID, err := database.InsertUser(mongodb.User{Username: body.Username, PasswordHash: hash, Created: time.Now().UTC()})
+	if err == mongodb.ErrUserExists {
+		// Sets header status code to 409 "Conflict", and returns error message.
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte("Error: " + err.Error()))
+		return
+	} else if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+	httpjson.Write(w, http.StatusCreated, id{newID})
+}
+
+// Login - POST: Checks the username and password, and returns a token for the 'Authorization: Bearer <token>' header.
+// Input/Output: application/json
+func Login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		// Wrong method.
+		// Sets status code to 400 (Bad request).
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var body credentials
+
+	// Decodes the json and converts it to a struct.
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&body); err != nil {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Malformed POST request, should be '{\"username\": <username>, \"password\": <password>}'"))
+		return
+	}
+
+	// Connects to the database, and checks the password.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+	user, err := database.FindUserByName(body.Username)
+	if err != nil || !CheckPassword(body.Password, user.PasswordHash) {
+		// The same error for unknown users and wrong passwords, so usernames can not be guessed.
+		// Sets header status code to 401 "Unauthorized", and returns error message.
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("Error: Wrong username or password"))
+		return
+	}
+
+	token, expires, err := NewToken(user.ID)
+	if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+	httpjson.Write(w, http.StatusOK, login{token, expires})
+}
+
+// Logout - POST: Ends the login session of the token.
+func Logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		// Wrong method.
+		// Sets status code to 400 (Bad request).
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := Authenticate(r); !ok {
+		// Sets the header code to 401 (Unauthorized).
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// Connects to the database, and deletes the session.
+	database := mongodb.DatabaseContext(r.Context(), CollectionSession)
+	if err := database.DeleteSession(hashToken(bearerToken(r))); err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+	// Sets the header code to 204 (No content).
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMe - GET: Returns the logged in user.
+// Output: application/json
+func GetMe(w http.ResponseWriter, r *http.Request) {
+	user, ok := Authenticate(r)
+	if !ok {
+		// Sets the header code to 401 (Unauthorized).
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	httpjson.Write(w, http.StatusOK, user)
+}
+
+// GetMyTracks - GET: Returns an array of the IDs of the logged in users tracks, of all privacy levels.
+// Output: application/json
+func GetMyTracks(w http.ResponseWriter, r *http.Request) {
+	user, ok := Authenticate(r)
+	if !ok {
+		// Sets the header code to 401 (Unauthorized).
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	// Connects to the database, and finds the tracks.
+	database := mongodb.DatabaseContext(r.Context(), CollectionTrack)
+	tracks, err := database.FindTracksByOwner(user.ID)
+	if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+
+	ids := []int{}
+	for i := 0; i < len(tracks); i++ {
+		ids = append(ids, tracks[i].ID)
+	}
+	httpjson.Write(w, http.StatusOK, ids)
+}
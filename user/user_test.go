@@ -0,0 +1,146 @@
+/*
+  File: user_test.go
+  Contains unit tests for user.go
+*/
+
+package user
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mats93/paragliding/mongodb"
+)
+
+// Method to test: CanView().
+// Test that public tracks are seen by everyone, club tracks by the members of its clubs,
+// and the rest only by the owner and admins.
+func Test_CanView(t *testing.T) {
+	tests := []struct {
+		viewer   Viewer
+		track    mongodb.Track
+		expected bool
+	}{
+		{Viewer{}, mongodb.Track{Owner: 1}, true},
+		{Viewer{}, mongodb.Track{Owner: 1, Privacy: mongodb.PrivacyPublic}, true},
+		{Viewer{}, mongodb.Track{Owner: 1, Privacy: mongodb.PrivacyPrivate}, false},
+		{Viewer{UserID: 2}, mongodb.Track{Owner: 1, Privacy: mongodb.PrivacyPrivate}, false},
+		{Viewer{UserID: 1}, mongodb.Track{Owner: 1, Privacy: mongodb.PrivacyPrivate}, true},
+		{Viewer{UserID: 2}, mongodb.Track{Owner: 1, Privacy: mongodb.PrivacyClub}, false},
+		{Viewer{UserID: 2, Clubs: []int{3}}, mongodb.Track{Owner: 1, Privacy: mongodb.PrivacyClub, Clubs: []int{4}}, false},
+		{Viewer{UserID: 2, Clubs: []int{3}}, mongodb.Track{Owner: 1, Privacy: mongodb.PrivacyClub, Clubs: []int{3, 4}}, true},
+		{Viewer{UserID: 2, Clubs: []int{3}}, mongodb.Track{Owner: 1, Privacy: mongodb.PrivacyPrivate, Clubs: []int{3}}, false},
+		{Viewer{Admin: true}, mongodb.Track{Owner: 1, Privacy: mongodb.PrivacyPrivate}, true},
+	}
+
+	for i := 0; i < len(tests); i++ {
+		if actual := tests[i].viewer.CanView(tests[i].track); actual != tests[i].expected {
+			t.Errorf("Method returned wrong result for %v and %v: got %v want %v",
+				tests[i].viewer, tests[i].track.Privacy, actual, tests[i].expected)
+		}
+	}
+}
+
+// Method to test: Member() and MemberOfAll().
+func Test_Member(t *testing.T) {
+	viewer := Viewer{UserID: 1, Clubs: []int{2, 3}}
+	if !viewer.Member(3) || viewer.Member(4) {
+		t.Error("Method returned wrong membership")
+	}
+	if !viewer.MemberOfAll([]int{2, 3}) || viewer.MemberOfAll([]int{2, 4}) {
+		t.Error("Method returned wrong membership of all clubs")
+	}
+	if !(Viewer{Admin: true}).Member(4) {
+		t.Error("Method returned false for an admin")
+	}
+}
+
+// Function to test: ValidPrivacy().
+func Test_ValidPrivacy(t *testing.T) {
+	if !ValidPrivacy("") || !ValidPrivacy(mongodb.PrivacyClub) {
+		t.Error("Function returned false for a known privacy level")
+	}
+	if ValidPrivacy("secret") {
+		t.Error("Function returned true for an unknown privacy level")
+	}
+}
+
+// Function to test: Register().
+// Test that short passwords and bad usernames are not accepted.
+func Test_Register_Malformed(t *testing.T) {
+	bodies := []string{
+		"wrong",
+		"{\"username\": \"pilot\", \"password\": \"short\"}",
+		"{\"username\": \"a b\", \"password\": \"long enough\"}",
+	}
+
+	for i := 0; i < len(bodies); i++ {
+		// Creates a POST request that is passed to the handler.
+		request, _ := http.NewRequest("POST", "/paragliding/api/user", strings.NewReader(bodies[i]))
+
+		// Creates the recorder and router.
+		recorder := httptest.NewRecorder()
+		router := mux.NewRouter()
+
+		// Tests the function.
+		router.HandleFunc("/paragliding/api/user", Register).Methods("POST")
+		router.ServeHTTP(recorder, request)
+
+		// Check the status code is what we expect (400).
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Handler returned wrong status code for %s: got %v want %v",
+				bodies[i], recorder.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+// Function to test: Register(), Login() and Authenticate().
+// Test that a registered user can log in, and is found by the token.
+func Test_Login(t *testing.T) {
+	// Injects the MongoDB collections to use.
+	Collection = "TestUsers"
+	CollectionSession = "TestSessions"
+
+	// Registers the user.
+	request, _ := http.NewRequest("POST", "/paragliding/api/user", strings.NewReader("{\"username\": \"pilot\", \"password\": \"long enough\"}"))
+	recorder := httptest.NewRecorder()
+	Register(recorder, request)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Handler returned wrong status code: got %v want %v", recorder.Code, http.StatusCreated)
+	}
+
+	// A wrong password is not accepted.
+	request, _ = http.NewRequest("POST", "/paragliding/api/user/login", strings.NewReader("{\"username\": \"pilot\", \"password\": \"wrong password\"}"))
+	recorder = httptest.NewRecorder()
+	Login(recorder, request)
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Handler returned wrong status code: got %v want %v", recorder.Code, http.StatusUnauthorized)
+	}
+
+	// The right password gives a token.
+	request, _ = http.NewRequest("POST", "/paragliding/api/user/login", strings.NewReader("{\"username\": \"pilot\", \"password\": \"long enough\"}"))
+	recorder = httptest.NewRecorder()
+	Login(recorder, request)
+	var session login
+	if err := json.Unmarshal(recorder.Body.Bytes(), &session); err != nil || session.Token == "" {
+		t.Fatalf("Handler returned no token: got %s", recorder.Body.String())
+	}
+
+	// The token is found.
+	database := mongodb.DatabaseInit(Collection)
+	expected, _ := database.FindUserByName("pilot")
+	request, _ = http.NewRequest("GET", "/paragliding/api/user/me", nil)
+	request.Header.Set("Authorization", "Bearer "+session.Token)
+	if actual, ok := Authenticate(request); !ok || actual.ID != expected.ID {
+		t.Errorf("Function returned wrong user: got %v want %v", actual, expected)
+	}
+
+	// Deletes all from the database.
+	database.DeleteAll()
+	sessions := mongodb.DatabaseInit(CollectionSession)
+	sessions.DeleteAll()
+}
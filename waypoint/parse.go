@@ -0,0 +1,323 @@
+/*
+	File: parse.go
+  Parses waypoint files in the SeeYou (.cup), CompeGPS and OziExplorer (.wpt) and GPX formats.
+*/
+
+package waypoint
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mats93/paragliding/mongodb"
+)
+
+// The supported waypoint file formats.
+const (
+	FormatCUP = "cup"
+	FormatWPT = "wpt"
+	FormatGPX = "gpx"
+)
+
+// Meters per foot.
+const feet = 0.3048
+
+// Detect guesses the format of a waypoint file from its content.
+func Detect(content string) string {
+	trimmed := strings.TrimSpace(content)
+	switch {
+	case strings.HasPrefix(trimmed, "<?xml") || strings.HasPrefix(trimmed, "<gpx"):
+		return FormatGPX
+	case strings.HasPrefix(trimmed, "OziExplorer"):
+		return FormatWPT
+	case strings.HasPrefix(trimmed, "G ") || strings.HasPrefix(trimmed, "B "):
+		// CompeGPS files start with a datum (G) or an optional header (B) line.
+		return FormatWPT
+	default:
+		return FormatCUP
+	}
+}
+
+// Parse parses the waypoints in a file with the given format.
+// If the format is empty, it is detected from the content.
+func Parse(content string, format string) ([]mongodb.Waypoint, error) {
+	if format == "" {
+		format = Detect(content)
+	}
+
+	switch strings.ToLower(format) {
+	case FormatCUP:
+		return ParseCUP(content)
+	case FormatWPT:
+		if strings.HasPrefix(strings.TrimSpace(content), "OziExplorer") {
+			return ParseOziExplorer(content)
+		}
+		return ParseCompeGPS(content)
+	case FormatGPX:
+		return ParseGPX(content)
+	}
+	return nil, fmt.Errorf("unknown waypoint format %q", format)
+}
+
+// ParseCUP parses a SeeYou .cup file.
+// Coordinates are in degrees and decimal minutes (5107.830N), elevation in meters or feet.
+func ParseCUP(content string) ([]mongodb.Waypoint, error) {
+	var waypoints []mongodb.Waypoint
+
+	// The waypoints end where the task section begins.
+	if i := strings.Index(content, "-----Related Tasks-----"); i != -1 {
+		content = content[:i]
+	}
+
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	// The column of each field, the defaults are used if the file has no header.
+	columns := map[string]int{"name": 0, "code": 1, "lat": 3, "lon": 4, "elev": 5, "desc": 10}
+
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		// Reads the column positions from the header.
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "name") {
+			for i := 0; i < len(record); i++ {
+				columns[strings.ToLower(strings.TrimSpace(record[i]))] = i
+			}
+			continue
+		}
+
+		// Gets a field, or an empty string if the record is too short.
+		field := func(name string) string {
+			if columns[name] < len(record) {
+				return strings.TrimSpace(record[columns[name]])
+			}
+			return ""
+		}
+
+		lat, err := parseDegreesMinutes(field("lat"), 2)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		lon, err := parseDegreesMinutes(field("lon"), 3)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		elevation, err := parseElevation(field("elev"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		waypoints = append(waypoints, mongodb.Waypoint{
+			Name:        field("name"),
+			Code:        field("code"),
+			Latitude:    lat,
+			Longitude:   lon,
+			Elevation:   elevation,
+			Description: field("desc"),
+		})
+	}
+	return waypoints, nil
+}
+
+// ParseOziExplorer parses an OziExplorer .wpt file.
+// Coordinates are in decimal degrees, altitude in feet (-777 if not valid).
+func ParseOziExplorer(content string) ([]mongodb.Waypoint, error) {
+	var waypoints []mongodb.Waypoint
+
+	lines := strings.Split(content, "\n")
+	// The first 4 lines are the header.
+	for i := 4; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Split(line, ",")
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("line %d: too few fields", i+1)
+		}
+		for j := 0; j < len(fields); j++ {
+			fields[j] = strings.TrimSpace(fields[j])
+		}
+
+		lat, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid latitude %q", i+1, fields[2])
+		}
+		lon, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid longitude %q", i+1, fields[3])
+		}
+
+		waypoint := mongodb.Waypoint{Name: fields[1], Code: fields[1], Latitude: lat, Longitude: lon}
+		if len(fields) > 10 {
+			// Commas in the description are stored as the character 209.
+			waypoint.Description = strings.Replace(fields[10], "\xd1", ",", -1)
+		}
+		if len(fields) > 14 {
+			if altitude, err := strconv.ParseFloat(fields[14], 64); err == nil && altitude != -777 {
+				waypoint.Elevation = altitude * feet
+			}
+		}
+		waypoints = append(waypoints, waypoint)
+	}
+	return waypoints, nil
+}
+
+// ParseCompeGPS parses a CompeGPS .wpt file.
+// Only files with coordinates in degrees (U 1) are supported, altitude is in meters.
+func ParseCompeGPS(content string) ([]mongodb.Waypoint, error) {
+	var waypoints []mongodb.Waypoint
+
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "U":
+			if len(fields) > 1 && fields[1] != "1" {
+				return nil, fmt.Errorf("line %d: only coordinates in degrees (U 1) are supported", i+1)
+			}
+
+		case "W":
+			// W <name> A <lat> <lon> <date> <time> <altitude> <description>
+			if len(fields) < 5 {
+				return nil, fmt.Errorf("line %d: too few fields", i+1)
+			}
+			lat, err := parseHemisphereDegrees(fields[3], "N", "S")
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+			lon, err := parseHemisphereDegrees(fields[4], "E", "W")
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+
+			waypoint := mongodb.Waypoint{Name: fields[1], Code: fields[1], Latitude: lat, Longitude: lon}
+			if len(fields) > 7 {
+				waypoint.Elevation, _ = strconv.ParseFloat(fields[7], 64)
+			}
+			if len(fields) > 8 {
+				waypoint.Description = strings.Join(fields[8:], " ")
+			}
+			waypoints = append(waypoints, waypoint)
+		}
+	}
+	return waypoints, nil
+}
+
+// ParseGPX parses the waypoints (wpt elements) in a GPX file.
+func ParseGPX(content string) ([]mongodb.Waypoint, error) {
+	var gpx struct {
+		Waypoints []struct {
+			Lat         float64 `xml:"lat,attr"`
+			Lon         float64 `xml:"lon,attr"`
+			Elevation   float64 `xml:"ele"`
+			Name        string  `xml:"name"`
+			Description string  `xml:"desc"`
+		} `xml:"wpt"`
+	}
+	if err := xml.Unmarshal([]byte(content), &gpx); err != nil {
+		return nil, err
+	}
+
+	var waypoints []mongodb.Waypoint
+	for i := 0; i < len(gpx.Waypoints); i++ {
+		wpt := gpx.Waypoints[i]
+		waypoints = append(waypoints, mongodb.Waypoint{
+			Name:        strings.TrimSpace(wpt.Name),
+			Code:        strings.TrimSpace(wpt.Name),
+			Latitude:    wpt.Lat,
+			Longitude:   wpt.Lon,
+			Elevation:   wpt.Elevation,
+			Description: strings.TrimSpace(wpt.Description),
+		})
+	}
+	return waypoints, nil
+}
+
+// Parses a coordinate in degrees and decimal minutes with a hemisphere letter, as used by SeeYou.
+// The number of degree digits is 2 for latitudes and 3 for longitudes.
+func parseDegreesMinutes(s string, degreeDigits int) (float64, error) {
+	if len(s) < degreeDigits+2 {
+		return 0, fmt.Errorf("invalid coordinate %q", s)
+	}
+	hemisphere := strings.ToUpper(s[len(s)-1:])
+	degrees, err := strconv.ParseFloat(s[:degreeDigits], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid coordinate %q", s)
+	}
+	minutes, err := strconv.ParseFloat(s[degreeDigits:len(s)-1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid coordinate %q", s)
+	}
+
+	value := degrees + minutes/60
+	switch hemisphere {
+	case "N", "E":
+		return value, nil
+	case "S", "W":
+		return -value, nil
+	}
+	return 0, fmt.Errorf("invalid coordinate %q", s)
+}
+
+// Parses a coordinate in decimal degrees followed by a degree sign and hemisphere letter, as used by CompeGPS.
+func parseHemisphereDegrees(s string, positive string, negative string) (float64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("invalid coordinate %q", s)
+	}
+	hemisphere := strings.ToUpper(s[len(s)-1:])
+
+	// Removes the hemisphere and the degree sign, in any encoding.
+	number := strings.TrimRight(s[:len(s)-1], "º°\xba\xb0")
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid coordinate %q", s)
+	}
+
+	switch hemisphere {
+	case positive:
+		return value, nil
+	case negative:
+		return -value, nil
+	}
+	return 0, fmt.Errorf("invalid coordinate %q", s)
+}
+
+// Parses an elevation in meters (m) or feet (ft). An empty elevation is 0.
+func parseElevation(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+
+	factor := 1.0
+	if strings.HasSuffix(s, "ft") {
+		factor = feet
+		s = strings.TrimSuffix(s, "ft")
+	} else {
+		s = strings.TrimSuffix(s, "m")
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid elevation %q", s)
+	}
+	return value * factor, nil
+}
@@ -0,0 +1,128 @@
+/*
+  File: parse_test.go
+  Contains unit tests for parse.go
+*/
+
+package waypoint
+
+import (
+	"math"
+	"testing"
+
+	"github.com/mats93/paragliding/mongodb"
+)
+
+// Checks that a parsed waypoint has the expected name and position.
+func checkWaypoint(t *testing.T, actual mongodb.Waypoint, name string, lat, lon, elevation float64) {
+	t.Helper()
+	if actual.Name != name {
+		t.Errorf("Function returned wrong name: got %s want %s", actual.Name, name)
+	}
+	if math.Abs(actual.Latitude-lat) > 1e-6 || math.Abs(actual.Longitude-lon) > 1e-6 {
+		t.Errorf("Function returned wrong position for %s: got %v,%v want %v,%v",
+			name, actual.Latitude, actual.Longitude, lat, lon)
+	}
+	if math.Abs(actual.Elevation-elevation) > 0.01 {
+		t.Errorf("Function returned wrong elevation for %s: got %v want %v", name, actual.Elevation, elevation)
+	}
+}
+
+// Function to test: Parse() with a SeeYou file.
+// Test that the header is used, the task section is ignored and elevations in feet are converted.
+func Test_Parse_CUP(t *testing.T) {
+	content := `name,code,country,lat,lon,elev,style,rwdir,rwlen,freq,desc
+"Voss Hanguren","HANG",NO,6039.000N,00625.500E,820.0m,1,,,,"Takeoff, north"
+"Bomoen","BOMO",NO,6037.500S,00620.250W,1000ft,1,,,,
+-----Related Tasks-----
+"Task",,"HANG","BOMO"
+`
+	waypoints, err := Parse(content, "")
+	if err != nil {
+		t.Fatalf("Function returned unexpected error: %v", err)
+	}
+	if len(waypoints) != 2 {
+		t.Fatalf("Function returned wrong number of waypoints: got %d want %d", len(waypoints), 2)
+	}
+	checkWaypoint(t, waypoints[0], "Voss Hanguren", 60.65, 6.425, 820)
+	checkWaypoint(t, waypoints[1], "Bomoen", -60.625, -6.3375, 304.8)
+
+	if waypoints[0].Code != "HANG" || waypoints[0].Description != "Takeoff, north" {
+		t.Errorf("Function returned wrong code or description: got %s and %s", waypoints[0].Code, waypoints[0].Description)
+	}
+}
+
+// Function to test: Parse() with an OziExplorer file.
+// Test that the header is skipped and the altitude in feet is converted.
+func Test_Parse_OziExplorer(t *testing.T) {
+	content := "OziExplorer Waypoint File Version 1.1\r\nWGS 84\r\nReserved 2\r\nReserved 3\r\n" +
+		"1,HANG,60.650000,6.425000,,0,1,3,0,65535,Takeoff,0,0,0,2690,6,0,17\r\n" +
+		"2,BOMO,60.625000,6.337500,,0,1,3,0,65535,Landing,0,0,0,-777,6,0,17\r\n"
+
+	waypoints, err := Parse(content, "")
+	if err != nil {
+		t.Fatalf("Function returned unexpected error: %v", err)
+	}
+	if len(waypoints) != 2 {
+		t.Fatalf("Function returned wrong number of waypoints: got %d want %d", len(waypoints), 2)
+	}
+	checkWaypoint(t, waypoints[0], "HANG", 60.65, 6.425, 2690*0.3048)
+	checkWaypoint(t, waypoints[1], "BOMO", 60.625, 6.3375, 0)
+}
+
+// Function to test: Parse() with a CompeGPS file.
+// Test that the degree sign and hemisphere are handled.
+func Test_Parse_CompeGPS(t *testing.T) {
+	content := "G  WGS 84\r\nU  1\r\n" +
+		"W  HANG A 60.6500000000ºN 6.4250000000ºE 27-MAR-62 00:00:00 820.000000 Voss takeoff\r\n" +
+		"w Waypoint,0,-1.0,16777215,255,0,0,7,,0.0,\r\n" +
+		"W  SOUTH A 33.5000000000ºS 70.2500000000ºW 27-MAR-62 00:00:00 0.000000\r\n"
+
+	waypoints, err := Parse(content, "wpt")
+	if err != nil {
+		t.Fatalf("Function returned unexpected error: %v", err)
+	}
+	if len(waypoints) != 2 {
+		t.Fatalf("Function returned wrong number of waypoints: got %d want %d", len(waypoints), 2)
+	}
+	checkWaypoint(t, waypoints[0], "HANG", 60.65, 6.425, 820)
+	checkWaypoint(t, waypoints[1], "SOUTH", -33.5, -70.25, 0)
+
+	if waypoints[0].Description != "Voss takeoff" {
+		t.Errorf("Function returned wrong description: got %s", waypoints[0].Description)
+	}
+
+	// UTM coordinates are not supported.
+	if _, err := Parse("G  WGS 84\r\nU  0\r\n", "wpt"); err == nil {
+		t.Error("Function did not return error for UTM coordinates")
+	}
+}
+
+// Function to test: Parse() with a GPX file.
+func Test_Parse_GPX(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <wpt lat="60.65" lon="6.425"><ele>820</ele><name>HANG</name><desc>Takeoff</desc></wpt>
+  <wpt lat="60.625" lon="6.3375"><name>BOMO</name></wpt>
+</gpx>`
+
+	waypoints, err := Parse(content, "")
+	if err != nil {
+		t.Fatalf("Function returned unexpected error: %v", err)
+	}
+	if len(waypoints) != 2 {
+		t.Fatalf("Function returned wrong number of waypoints: got %d want %d", len(waypoints), 2)
+	}
+	checkWaypoint(t, waypoints[0], "HANG", 60.65, 6.425, 820)
+	checkWaypoint(t, waypoints[1], "BOMO", 60.625, 6.3375, 0)
+}
+
+// Function to test: Parse().
+// Test that invalid coordinates and unknown formats return errors.
+func Test_Parse_Invalid(t *testing.T) {
+	if _, err := Parse("\"HANG\",HANG,NO,60X39.000N,00625.500E,820.0m\n", FormatCUP); err == nil {
+		t.Error("Function did not return error for an invalid coordinate")
+	}
+	if _, err := Parse("", "kml"); err == nil {
+		t.Error("Function did not return error for an unknown format")
+	}
+}
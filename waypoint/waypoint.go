@@ -0,0 +1,204 @@
+/*
+	File: waypoint.go
+  Contains functions used by API calls to the "Waypoint paths".
+*/
+
+package waypoint
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+
+	igc "github.com/marni/goigc"
+	"github.com/mats93/paragliding/internal/httpjson"
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/mongodb"
+)
+
+// MaxFileSize is the largest waypoint file that can be imported, in bytes.
+const MaxFileSize = 5 << 20
+
+// Format for the result of an import.
+type importResult struct {
+	Imported int   `json:"imported"`
+	IDs      []int `json:"ids"`
+}
+
+// Format for a waypoint found by a proximity search.
+type nearbyWaypoint struct {
+	mongodb.Waypoint
+	Distance float64 `json:"distance"`
+}
+
+// Collection is the MongoDB collection to use. Gets injected from main or test.
+var Collection string
+
+// Find returns the waypoint with the ID.
+func Find(id int) (mongodb.Waypoint, error) {
+	// Connects to the database, and finds the waypoint.
+	database := mongodb.DatabaseInit(Collection)
+	return database.FindWaypoint(id)
+}
+
+// ResolveTurnpoints sets the position of every turnpoint that refers to a waypoint ID.
+// The name of the waypoint is used if the turnpoint has no name.
+func ResolveTurnpoints(turnpoints []mongodb.Turnpoint) error {
+	var database *mongodb.MongoDB
+
+	for i := 0; i < len(turnpoints); i++ {
+		tp := &turnpoints[i]
+		if tp.WaypointID == 0 {
+			continue
+		}
+
+		// Only connects to the database if a waypoint is used.
+		if database == nil {
+			connected := mongodb.DatabaseInit(Collection)
+			database = &connected
+		}
+		waypoint, err := database.FindWaypoint(tp.WaypointID)
+		if err != nil {
+			return fmt.Errorf("turnpoint %d: waypoint %d was not found", i, tp.WaypointID)
+		}
+		tp.Latitude = waypoint.Latitude
+		tp.Longitude = waypoint.Longitude
+		if tp.Name == "" {
+			tp.Name = waypoint.Name
+		}
+	}
+	return nil
+}
+
+// POST: Imports the waypoints in a .cup, .wpt or .gpx file sent as the request body.
+// The format is given by the 'format' query parameter, or detected from the content.
+// Output: application/json
+func importWaypoints(w http.ResponseWriter, r *http.Request) {
+	// Reads the file, with a limit on the size.
+	content, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxFileSize))
+	if err != nil {
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Could not read the waypoint file"))
+		return
+	}
+
+	// Parses the waypoints.
+	waypoints, err := Parse(string(content), r.URL.Query().Get("format"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: Could not parse the waypoint file: " + err.Error()))
+		return
+	}
+	if len(waypoints) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: The file contains no waypoints"))
+		return
+	}
+
+	// Connects to the database, and adds the waypoints.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+	ids, err := database.InsertWaypoints(waypoints)
+	if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+	httpjson.Write(w, http.StatusOK, importResult{len(ids), ids})
+}
+
+// GET: Lists the waypoints, optionally searched by 'name' (or code),
+// and by proximity with 'lat', 'lon' and 'radius' (in km, defaults to 10).
+// Output: application/json
+func searchWaypoints(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// Connects to the database, and finds the waypoints matching the name.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+	waypoints, err := database.FindWaypointsByName(query.Get("name"))
+	if err != nil {
+		// Sets header status code to 500 "Internal server error" and logs the error.
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+		return
+	}
+
+	// Without a position, all the matching waypoints are returned.
+	if query.Get("lat") == "" && query.Get("lon") == "" {
+		if waypoints == nil {
+			waypoints = []mongodb.Waypoint{}
+		}
+		httpjson.Write(w, http.StatusOK, waypoints)
+		return
+	}
+
+	// Reads the position and radius of the proximity search.
+	lat, errLat := strconv.ParseFloat(query.Get("lat"), 64)
+	lon, errLon := strconv.ParseFloat(query.Get("lon"), 64)
+	radius := 10.0
+	var errRadius error
+	if query.Get("radius") != "" {
+		radius, errRadius = strconv.ParseFloat(query.Get("radius"), 64)
+	}
+	if errLat != nil || errLon != nil || errRadius != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Error: 'lat', 'lon' and 'radius' must be numbers"))
+		return
+	}
+
+	// Finds the waypoints within the radius, the closest first.
+	center := igc.NewPointFromLatLng(lat, lon)
+	nearby := []nearbyWaypoint{}
+	for i := 0; i < len(waypoints); i++ {
+		distance := center.Distance(igc.NewPointFromLatLng(waypoints[i].Latitude, waypoints[i].Longitude))
+		if distance <= radius {
+			nearby = append(nearby, nearbyWaypoint{waypoints[i], distance})
+		}
+	}
+	sort.SliceStable(nearby, func(i, j int) bool {
+		return nearby[i].Distance < nearby[j].Distance
+	})
+	httpjson.Write(w, http.StatusOK, nearby)
+}
+
+// HandleWaypoints - GET:  Lists and searches the waypoints.
+// HandleWaypoints - POST: Imports a waypoint file.
+// Input: .cup, .wpt or .gpx file, Output: application/json
+func HandleWaypoints(w http.ResponseWriter, r *http.Request) {
+	// Calls functions to handle the GET and POST requests.
+	switch r.Method {
+	case "GET":
+		searchWaypoints(w, r)
+
+	case "POST":
+		importWaypoints(w, r)
+
+	default:
+		// Wrong method.
+		// Sets status code to 400 (Bad request).
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// GetWaypointByID - GET: Returns the waypoint with the provided '<id>'.
+// Output: application/json
+func GetWaypointByID(w http.ResponseWriter, r *http.Request) {
+	// Connects to the database.
+	database := mongodb.DatabaseContext(r.Context(), Collection)
+
+	var id int
+	// Gets the ID from the URL and converts it to an integer.
+	fmt.Sscanf(r.URL.Path, "/paragliding/api/waypoint/%d", &id)
+
+	waypoint, err := database.FindWaypoint(id)
+	if err != nil {
+		// A waypoint with the given ID does not exist.
+		// Sets the header code to 404 (Not found).
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	httpjson.Write(w, http.StatusOK, waypoint)
+}
@@ -0,0 +1,99 @@
+/*
+  File: waypoint_test.go
+  Contains unit tests for waypoint.go
+*/
+
+package waypoint
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mats93/paragliding/mongodb"
+)
+
+// Function to test: HandleWaypoints().
+// Test that a file that can not be parsed returns an error.
+func Test_HandleWaypoints_POST_Malformed(t *testing.T) {
+	// Creates a POST request with an invalid SeeYou file.
+	request, _ := http.NewRequest("POST", "/paragliding/api/waypoint?format=cup", strings.NewReader("HANG,HANG,NO,wrong,wrong"))
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/waypoint", HandleWaypoints).Methods("POST")
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (400).
+	status := recorder.Code
+	if status != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
+
+// Function to test: HandleWaypoints() and GetWaypointByID().
+// Test that waypoints can be imported, and found by name and proximity.
+func Test_HandleWaypoints(t *testing.T) {
+	// Injects the MongoDB collection to use.
+	Collection = "TestWaypoints"
+	database := mongodb.DatabaseInit(Collection)
+
+	// Creates the router.
+	router := mux.NewRouter()
+	router.HandleFunc("/paragliding/api/waypoint", HandleWaypoints)
+	router.HandleFunc("/paragliding/api/waypoint/{id:[0-9]+}", GetWaypointByID)
+
+	// Imports two waypoints from a GPX file.
+	content := `<gpx><wpt lat="60.65" lon="6.425"><name>Hanguren</name></wpt><wpt lat="61.0" lon="7.0"><name>Far</name></wpt></gpx>`
+	request, _ := http.NewRequest("POST", "/paragliding/api/waypoint", strings.NewReader(content))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	expected := "{\"imported\":2,\"ids\":[1,2]}"
+	if actual := recorder.Body.String(); actual != expected {
+		t.Errorf("Handler returned wrong data: got %v want %v", actual, expected)
+	}
+
+	// Searches by name, case insensitive.
+	request, _ = http.NewRequest("GET", "/paragliding/api/waypoint?name=hang", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var byName []mongodb.Waypoint
+	json.NewDecoder(recorder.Body).Decode(&byName)
+	if len(byName) != 1 || byName[0].Name != "Hanguren" {
+		t.Errorf("Handler returned wrong waypoints: got %v", byName)
+	}
+
+	// Searches by proximity, only the close waypoint is within 5 km.
+	request, _ = http.NewRequest("GET", "/paragliding/api/waypoint?lat=60.66&lon=6.43&radius=5", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var nearby []nearbyWaypoint
+	json.NewDecoder(recorder.Body).Decode(&nearby)
+	if len(nearby) != 1 || nearby[0].ID != 1 {
+		t.Errorf("Handler returned wrong waypoints: got %v", nearby)
+	}
+
+	// Gets a waypoint by ID.
+	request, _ = http.NewRequest("GET", "/paragliding/api/waypoint/2", nil)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var byID mongodb.Waypoint
+	json.NewDecoder(recorder.Body).Decode(&byID)
+	if byID.Name != "Far" {
+		t.Errorf("Handler returned wrong waypoint: got %v", byID)
+	}
+
+	// Removes the test data.
+	database.DeleteAll()
+}
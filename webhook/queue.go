@@ -0,0 +1,125 @@
+/*
+	File: queue.go
+  Contains the queue of webhook deliveries, sent by workers in the background so the requests that
+  trigger them do not wait for the subscribers.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/metrics"
+)
+
+// DeliveryQueueSize is the number of deliveries that can wait for a worker.
+const DeliveryQueueSize = 1000
+
+// StuckAfter is how long deliveries can wait without any worker taking one, before the queue is stuck.
+var StuckAfter = time.Minute
+
+// A message waiting to be sent to a subscriber.
+type delivery struct {
+	ctx     context.Context
+	url     string
+	message string
+}
+
+// The deliveries waiting for a worker, nil until the workers are started and after they are stopped.
+var deliveries chan delivery
+
+// Guards the queue, so no delivery is queued after it is closed.
+var queueLock sync.RWMutex
+
+// Done when the workers have sent all the queued deliveries.
+var workers sync.WaitGroup
+
+// The time a worker last took a delivery, in Unix nanoseconds.
+var lastTaken int64
+
+// StartDeliveries starts the workers sending the webhook messages. Before they are started, the messages
+// are sent right away.
+func StartDeliveries(n int) {
+	queueLock.Lock()
+	defer queueLock.Unlock()
+
+	deliveries = make(chan delivery, DeliveryQueueSize)
+	atomic.StoreInt64(&lastTaken, time.Now().UnixNano())
+	for i := 0; i < n; i++ {
+		workers.Add(1)
+		go deliveryWorker(deliveries)
+	}
+}
+
+// StopDeliveries stops queueing deliveries, and waits for the workers to send the queued ones, or for the
+// context to be done. The messages after it are sent right away.
+func StopDeliveries(ctx context.Context) error {
+	queueLock.Lock()
+	if deliveries != nil {
+		close(deliveries)
+		deliveries = nil
+	}
+	queueLock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sends the queued deliveries.
+func deliveryWorker(queue chan delivery) {
+	defer workers.Done()
+	for d := range queue {
+		atomic.StoreInt64(&lastTaken, time.Now().UnixNano())
+		send(d.ctx, d.url, d.message)
+	}
+}
+
+// Queues a message to a subscriber. The delivery only keeps the logger of the context, since it is sent
+// after the request is done.
+func deliver(ctx context.Context, url string, message string) {
+	queueLock.RLock()
+	if deliveries == nil {
+		// The lock is not held while sending, so the queue can be stopped meanwhile.
+		queueLock.RUnlock()
+		send(ctx, url, message)
+		return
+	}
+	defer queueLock.RUnlock()
+
+	d := delivery{logging.WithLogger(context.Background(), logging.FromContext(ctx)), url, message}
+	select {
+	case deliveries <- d:
+	default:
+		// The queue is full, the message is dropped.
+		metrics.WebhookDeliveries.WithLabelValues(metrics.ResultFailure).Inc()
+		logging.FromContext(ctx).Error("webhook delivery dropped, the queue is full")
+	}
+}
+
+// CheckQueue returns an error if deliveries are waiting, and no worker has taken one for StuckAfter.
+func CheckQueue() error {
+	queueLock.RLock()
+	defer queueLock.RUnlock()
+
+	if deliveries == nil || len(deliveries) == 0 {
+		return nil
+	}
+	if time.Since(time.Unix(0, atomic.LoadInt64(&lastTaken))) > StuckAfter {
+		return errors.New("the webhook queue is stuck")
+	}
+	return nil
+}
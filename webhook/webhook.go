@@ -7,13 +7,21 @@ package webhook
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/mats93/paragliding/fetch"
+	"github.com/mats93/paragliding/logging"
+	"github.com/mats93/paragliding/metrics"
 	"github.com/mats93/paragliding/mongodb"
+	"github.com/mats93/paragliding/openapi"
+	"github.com/mats93/paragliding/user"
 )
 
 // Message to be sent to subscrber.
@@ -25,6 +33,18 @@ type notifyMessage struct {
 	Processing    time.Duration `json:"processing"`
 }
 
+// EventAirspace is the event type of webhooks notified when a new track infringes airspace.
+const EventAirspace = "track.airspace"
+
+// EventDeleted is the event type of webhooks notified when a track is deleted.
+const EventDeleted = "track.deleted"
+
+// Events are the event types a webhook can subscribe to.
+var Events = []string{mongodb.EventNewTrack, EventAirspace, EventDeleted}
+
+// URLPolicy is the policy webhook URLs are checked against when registered, and when notified.
+var URLPolicy = fetch.DefaultPolicy
+
 // CollectionTrack is the DB collections to use for tracks, gets injected from main or test.
 var CollectionTrack string
 
@@ -32,88 +52,251 @@ var CollectionTrack string
 var CollectionWebhook string
 
 // CheckWebhooks checks if the registrated webhooks need to notify the subscrber.
-// This function should be called everytime a track is added.
-func CheckWebhooks() {
+// This function should be called everytime a public track is added.
+// The context has the logger of the request or job that added the track.
+func CheckWebhooks(ctx context.Context) {
+	CheckWebhooksN(ctx, 1)
+}
+
+// CheckWebhooksN checks if the registrated webhooks need to notify the subscrber, after n tracks
+// are added at once. Each subscriber gets one message for all of them.
+func CheckWebhooksN(ctx context.Context, n int) {
+	checkWebhooks(ctx, 0, n)
+}
+
+// CheckClubWebhooksN checks if the webhooks of the club need to notify the subscriber, after n tracks
+// are shared with the club at once.
+func CheckClubWebhooksN(ctx context.Context, club int, n int) {
+	checkWebhooks(ctx, club, n)
+}
+
+// Notifies the webhooks of the club, or the webhooks not scoped to a club if club is 0.
+func checkWebhooks(ctx context.Context, club int, n int) {
 	// Start time of the request.
 	start := time.Now()
 
 	// Connects to the database, uses the Webhook collection.
-	dbWebhooks := mongodb.DatabaseInit(CollectionWebhook)
+	dbWebhooks := mongodb.DatabaseContext(ctx, CollectionWebhook)
 
-	// Adds 1 to the 'newInserts' field, and returns a slice
+	// Adds n to the 'newInserts' field, and returns a slice
 	// of all webhooks that need to be notified.
-	webhooks, err := dbWebhooks.InvokeWebhooks()
+	webhooks, err := dbWebhooks.InvokeClubWebhooksN(club, n)
 	if err != nil {
 		// Logs the error.
-		log.Fatal(err)
+		logging.FromContext(ctx).Error(err.Error(), "club", club)
+		return
 	}
 	if webhooks != nil {
 		// Creates a new db session against track collection.
-		dbTracks := mongodb.DatabaseInit(CollectionTrack)
+		dbTracks := mongodb.DatabaseContext(ctx, CollectionTrack)
 
-		// Getts all tracks from the database.
-		tracks, err := dbTracks.FindAll()
+		// Getts all public tracks from the database, the subscribers are not told of the others.
+		// The webhooks of a club get the tracks shared with the club.
+		var tracks []mongodb.Track
+		if club == 0 {
+			tracks, err = dbTracks.FindAllPublic()
+		} else {
+			tracks, err = dbTracks.FindClubTracks(club)
+		}
 		if err != nil {
 			// Logs the error.
-			log.Fatal(err)
-		} else {
-			var messages []notifyMessage
-			var ids []int
-
-			// The last index in the tracks slice.
-			lenTracks := len(tracks) - 1
+			logging.FromContext(ctx).Error(err.Error(), "club", club)
+			return
+		}
+		// The tracks may have been deleted or made private since they were added, then there is nothing to tell.
+		if len(tracks) == 0 {
+			return
+		}
 
-			// Loops through all webhooks.
-			for i := 0; i < len(webhooks); i++ {
+		var messages []notifyMessage
+		var ids []int
 
-				// Loop through all tracks that should be in the message, and adds them.
-				for j := 0; j < webhooks[i].NumberOfNewInserts+1; j++ {
-					ids = append(ids, tracks[j].ID)
-				}
+		// The last index in the tracks slice.
+		lenTracks := len(tracks) - 1
 
-				// Creates the new message and adds it to a slice.
-				messages = append(messages, notifyMessage{
-					webhooks[i].WebhookURL, "", tracks[lenTracks].Timestamp, ids, time.Since(start)})
+		// Loops through all webhooks.
+		for i := 0; i < len(webhooks); i++ {
 
-				// Removes content from the slice.
-				ids = nil
+			// Loop through all tracks that should be in the message, and adds them.
+			for j := 0; j < webhooks[i].NumberOfNewInserts+n && j < len(tracks); j++ {
+				ids = append(ids, tracks[j].ID)
 			}
 
-			// Formats the messages to a human-readable format.
-			formatIDs := ""
-			formatMessage := ""
-
-			// Loops through all messages.
-			for i := 0; i < len(messages); i++ {
-				formatIDs = ""
-				// Loops through all IDs.
-				for j := 0; j < len(messages[i].Tracks); j++ {
-					// Dont add "," on last id.
-					if j == len(messages[i].Tracks)-1 {
-						formatIDs += fmt.Sprintf("id%v", messages[i].Tracks[j])
-					} else {
-						formatIDs += fmt.Sprintf("id%v,", messages[i].Tracks[j])
-					}
-				}
-				// Formats the message.
-				formatMessage = fmt.Sprintf("Latest timestamp: %d, %d new tracks are %s.(processing:%v)",
-					messages[i].TimeLatest, len(messages[i].Tracks), formatIDs, messages[i].Processing)
-
-				// Converts formatet message to json, uses 'content' to support Discord webhooks.
-				jsonMessage := map[string]string{"content": formatMessage}
-				body, err := json.Marshal(jsonMessage)
-				if err != nil {
-					log.Fatal(err)
-				}
+			// Creates the new message and adds it to a slice.
+			messages = append(messages, notifyMessage{
+				webhooks[i].WebhookURL, "", tracks[lenTracks].Timestamp, ids, time.Since(start)})
+
+			// Removes content from the slice.
+			ids = nil
+		}
 
-				// Send the message.
-				resp, err := http.Post(messages[i].URL, "application/json", bytes.NewBuffer(body))
-				if err == nil {
-					defer resp.Body.Close()
+		// Formats the messages to a human-readable format.
+		formatIDs := ""
+		formatMessage := ""
+
+		// Loops through all messages.
+		for i := 0; i < len(messages); i++ {
+			formatIDs = ""
+			// Loops through all IDs.
+			for j := 0; j < len(messages[i].Tracks); j++ {
+				// Dont add "," on last id.
+				if j == len(messages[i].Tracks)-1 {
+					formatIDs += fmt.Sprintf("id%v", messages[i].Tracks[j])
+				} else {
+					formatIDs += fmt.Sprintf("id%v,", messages[i].Tracks[j])
 				}
 			}
+			// Formats the message.
+			formatMessage = fmt.Sprintf("Latest timestamp: %d, %d new tracks are %s.(processing:%v)",
+				messages[i].TimeLatest, len(messages[i].Tracks), formatIDs, messages[i].Processing)
+
+			// Queues the message.
+			deliver(ctx, messages[i].URL, formatMessage)
+		}
+	}
+}
+
+// Attempts is the number of times a message is sent to a subscriber before giving up.
+var Attempts = 3
+
+// RetryDelay is the time waited before the first retry, it is doubled for each retry after it.
+var RetryDelay = 500 * time.Millisecond
+
+// Sends a message to a subscriber, and retries if the subscriber could not be reached or had a temporary error.
+// The attempts are logged with the logger of the context.
+func send(ctx context.Context, url string, message string) {
+	logger := logging.FromContext(ctx)
+
+	// Converts the message to json, uses 'content' to support Discord webhooks.
+	jsonMessage := map[string]string{"content": message}
+	body, err := json.Marshal(jsonMessage)
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+
+	delay := RetryDelay
+	for attempt := 1; ; attempt++ {
+		// Send the message.
+		retry, err := post(url, body)
+		if err == nil {
+			metrics.WebhookDeliveries.WithLabelValues(metrics.ResultSuccess).Inc()
+			logger.Debug("webhook delivered", "attempt", attempt)
+			return
+		}
+		if !retry || attempt >= Attempts {
+			metrics.WebhookDeliveries.WithLabelValues(metrics.ResultFailure).Inc()
+			logger.Error("webhook delivery failed", "attempt", attempt, "error", err)
+			return
+		}
+		metrics.WebhookRetries.Inc()
+		logger.Warn("webhook delivery failed, retrying", "attempt", attempt, "error", err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// Posts a message to a subscriber. Failures that may pass, like connection errors, 429 (Too many requests)
+// and 5xx, should be retried.
+func post(url string, body []byte) (retry bool, err error) {
+	resp, err := URLPolicy.Client().Post(url, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return fetch.Code(err) != fetch.CodeBlockedAddress, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("webhook %s returned %s", url, resp.Status)
+	}
+	return false, nil
+}
+
+// NotifyEvent sends a message to all webhooks subscribing to the given event type.
+// The context has the logger of the request or job the event happened in.
+func NotifyEvent(ctx context.Context, event string, message string) {
+	// Connects to the database, uses the Webhook collection.
+	dbWebhooks := mongodb.DatabaseContext(ctx, CollectionWebhook)
+
+	webhooks, err := dbWebhooks.FindWebhooksByEvent(event)
+	if err != nil {
+		// Logs the error.
+		logging.FromContext(ctx).Error(err.Error(), "event", event)
+		return
+	}
+	for i := 0; i < len(webhooks); i++ {
+		deliver(ctx, webhooks[i].WebhookURL, message)
+	}
+}
+
+// Checks that all the event types are known.
+func validEvents(events []string) bool {
+	for i := 0; i < len(events); i++ {
+		known := false
+		for j := 0; j < len(Events); j++ {
+			if events[i] == Events[j] {
+				known = true
+			}
+		}
+		if !known {
+			return false
 		}
 	}
+	return true
+}
+
+// Errors of the webhook registration, the handlers turn them into responses.
+var (
+	// ErrUnknownEvent is returned when a webhook subscribes to an unknown event type.
+	ErrUnknownEvent = errors.New("unknown event type, the events should be among: " + strings.Join(Events, ", "))
+	// ErrNotMember is returned when a webhook is scoped to a club the viewer is not a member of.
+	ErrNotMember = errors.New("only the members of the club can subscribe to its tracks")
+)
+
+// Check checks a new webhook by the viewer, and returns it ready to be stored. The minimum trigger value
+// is 1 if not set. A url not allowed by the URLPolicy returns a *fetch.Error.
+func Check(viewer user.Viewer, hook mongodb.Webhook) (mongodb.Webhook, error) {
+	if !validEvents(hook.Events) {
+		return hook, ErrUnknownEvent
+	}
+	if hook.Club != 0 && !viewer.Member(hook.Club) {
+		// Only the members of a club can subscribe to its tracks.
+		return hook, ErrNotMember
+	}
+	if err := URLPolicy.CheckURL(hook.WebhookURL); err != nil {
+		// The url is not allowed, e.g. an internal address.
+		return hook, err
+	}
+
+	// Check if the optional field 'minTriggerValue' is set.
+	if hook.MinTriggerValue == 0 {
+		// If not, set it to 1 (default).
+		hook.MinTriggerValue = 1
+	}
+	// The owner is kept, so the club webhooks are removed when the owner leaves the club.
+	hook.Owner = viewer.UserID
+	return hook, nil
+}
+
+// Register registers a new webhook by the viewer, and returns its ID. The webhook is checked like with Check,
+// and a registered url returns mongodb.ErrWebhookExists.
+func Register(ctx context.Context, viewer user.Viewer, hook mongodb.Webhook) (string, error) {
+	hook, err := Check(viewer, hook)
+	if err != nil {
+		return "", err
+	}
+
+	// Adds the new webhook to the db.
+	database := mongodb.DatabaseContext(ctx, CollectionWebhook)
+	return database.InsertWebhook(hook)
+}
+
+// RemoveClubWebhooks deletes the webhooks the user registered for the club, called when the user leaves it,
+// so a former member is not told of the tracks shared with the club.
+func RemoveClubWebhooks(ctx context.Context, club int, userID int) error {
+	database := mongodb.DatabaseContext(ctx, CollectionWebhook)
+	_, err := database.DeleteClubWebhooks(club, userID)
+	return err
 }
 
 // NewWebhook - POST: Registrates a new webhook.
@@ -125,59 +308,71 @@ func NewWebhook(w http.ResponseWriter, r *http.Request) {
 		// Sets header content-type to application/json and status code to 400 (Bad request).
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
-	} else {
-		// The request is POST.
-		// Connects the the database.
-		database := mongodb.DatabaseInit(CollectionWebhook)
-		var newWebhook mongodb.Webhook
-
-		// Decodes the json url and converts it to a struct.
-		decoder := json.NewDecoder(r.Body)
-		err := decoder.Decode(&newWebhook)
-
-		// Check for error or if the 'URL' section is empty.
-		if err != nil || newWebhook.WebhookURL == "" {
-			// The decoding failed.
-			// Sets header status code to 400 "Bad request", and returns error message.
-			w.WriteHeader(http.StatusBadRequest)
-			w.Header().Set("Content-Type", "application/json")
-			w.Write([]byte("malformed POST request, should be '{\"webhookURL\": \"<url>\"}, [optional: (\"minTriggerValue\": <number>')]"))
-		} else {
-			// The decoding was sucessful.
+		return
+	}
 
-			// Check if the optional field 'minTriggerValue' is set.
-			if newWebhook.MinTriggerValue == 0 {
-				// If not, set it to 1 (default).
-				newWebhook.MinTriggerValue = 1
-			}
+	var newWebhook mongodb.Webhook
 
-			// Adds the new webhook to the db.
-			id, err := database.InsertWebhook(newWebhook)
-			if err != nil {
-				if err.Error() == "the webhook allready exists" {
-					// The request is valid but it exists.
-					// but the webhook is allready registrated, output error code 409 and error.
-					w.WriteHeader(http.StatusConflict)
-					w.Write([]byte(err.Error()))
-				} else {
-					// Sets header status code to 500 "Internal server error".
-					w.WriteHeader(http.StatusInternalServerError)
-					log.Fatal(err)
-				}
-			} else {
-				// The webhook was created, returns the ID.
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusCreated)
-				w.Write([]byte(id))
-			}
-		}
+	// Reads the body, and decodes the json url and converts it to a struct.
+	// The body is kept to be validated against the OpenAPI document.
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &newWebhook)
+	}
+
+	// Check for error or if the 'URL' section is empty.
+	if err != nil || newWebhook.WebhookURL == "" {
+		// The decoding failed.
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("malformed POST request, should be '{\"webhookURL\": \"<url>\"}, [optional: (\"minTriggerValue\": <number>')]"))
+		return
+	}
+	if !validEvents(newWebhook.Events) {
+		// An unknown event type, checked before the body so the known events are listed.
+		// Sets header status code to 400 "Bad request", and returns error message.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(ErrUnknownEvent.Error()))
+		return
+	}
+	if err := openapi.Validate("WebhookRegistration", body); err != nil {
+		// The body does not match the schema of the webhook registration, e.g. an unknown field.
+		// Sets header status code to 400 "Bad request", and returns the invalid field.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("malformed POST request, " + err.Error()))
+		return
+	}
+
+	id, err := Register(r.Context(), user.ViewerOf(r), newWebhook)
+	if err == ErrNotMember {
+		// Sets header status code to 401 "Unauthorized", and returns error message.
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(err.Error()))
+	} else if fetch.Code(err) != "" {
+		// Sets header status code to 400 "Bad request", and returns the error with its code.
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("the webhook url is not allowed, " + err.Error()))
+	} else if err == mongodb.ErrWebhookExists {
+		// The request is valid but the webhook is allready registrated, output error code 409 and error.
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(err.Error()))
+	} else if err != nil {
+		// Sets header status code to 500 "Internal server error".
+		w.WriteHeader(http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error(err.Error())
+	} else {
+		// The webhook was created, returns the ID.
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(id))
 	}
 }
 
 // Gets information about a registrated webhook.
 func getWebhookInfo(w http.ResponseWriter, r *http.Request) {
 	// Connects the the database.
-	database := mongodb.DatabaseInit(CollectionWebhook)
+	database := mongodb.DatabaseContext(r.Context(), CollectionWebhook)
 	var id string
 
 	// Gets the ID from the URL and converts it to a string.
@@ -197,7 +392,7 @@ func getWebhookInfo(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			// Error: Sets header status code to 500 "Internal server error" and logs the error.
 			w.WriteHeader(http.StatusInternalServerError)
-			log.Fatal(err)
+			logging.FromContext(r.Context()).Error(err.Error())
 		} else {
 			// OK: Sets header content-type to application/json and status code to 200 (OK).
 			w.Header().Set("Content-Type", "application/json")
@@ -212,7 +407,7 @@ func getWebhookInfo(w http.ResponseWriter, r *http.Request) {
 // Deletes a webhook.
 func deleteWebhook(w http.ResponseWriter, r *http.Request) {
 	// Connects the the database.
-	database := mongodb.DatabaseInit(CollectionWebhook)
+	database := mongodb.DatabaseContext(r.Context(), CollectionWebhook)
 	var id string
 
 	// Gets the ID from the URL and converts it to a string.
@@ -232,7 +427,7 @@ func deleteWebhook(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			// Error: Sets header status code to 500 "Internal server error" and logs the error.
 			w.WriteHeader(http.StatusInternalServerError)
-			log.Fatal(err)
+			logging.FromContext(r.Context()).Error(err.Error())
 		} else {
 			// OK: Sets header content-type to application/json and status code to 200 (OK).
 			w.Header().Set("Content-Type", "application/json")
@@ -6,17 +6,25 @@
 package webhook
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/mats93/paragliding/fetch"
 	"github.com/mats93/paragliding/mongodb"
 )
 
+// The test webhooks use ".local" hosts that do not resolve, so their addresses are not checked.
+func init() {
+	URLPolicy.AllowPrivate = true
+}
+
 // Function to test: CheckWebhooks()
 // Test to check if the discord channel resieves the message.
 func Test_CheckWebhooks(t *testing.T) {
@@ -27,16 +35,16 @@ func Test_CheckWebhooks(t *testing.T) {
 
 	// Adds some webhooks.
 	database := mongodb.DatabaseInit(CollectionWebhook)
-	database.InsertWebhook(mongodb.Webhook{discordTestChannel, 3, 0})
+	database.InsertWebhook(mongodb.Webhook{WebhookURL: discordTestChannel, MinTriggerValue: 3, NumberOfNewInserts: 0})
 
 	databaseTracks := mongodb.DatabaseInit(CollectionTrack)
 
 	// Add 3 tracks to the DB.
-	databaseTracks.Insert(mongodb.Track{1, 111, time.Now(), "pilot1", "glider1", "glider_id1", 20.1, "http://test1.local"})
-	CheckWebhooks()
-	databaseTracks.Insert(mongodb.Track{2, 222, time.Now(), "pilot2", "glider2", "glider_id2", 20.2, "http://test2.local"})
-	CheckWebhooks()
-	databaseTracks.Insert(mongodb.Track{3, 333, time.Now(), "pilot3", "glider3", "glider_id3", 20.3, "http://test3.local"})
+	databaseTracks.Insert(mongodb.Track{ID: 1, Timestamp: 111, HDate: time.Now(), Pilot: "pilot1", Glider: "glider1", GliderID: "glider_id1", TrackLength: 20.1, TrackSrcURL: "http://test1.local"})
+	CheckWebhooks(context.Background())
+	databaseTracks.Insert(mongodb.Track{ID: 2, Timestamp: 222, HDate: time.Now(), Pilot: "pilot2", Glider: "glider2", GliderID: "glider_id2", TrackLength: 20.2, TrackSrcURL: "http://test2.local"})
+	CheckWebhooks(context.Background())
+	databaseTracks.Insert(mongodb.Track{ID: 3, Timestamp: 333, HDate: time.Now(), Pilot: "pilot3", Glider: "glider3", GliderID: "glider_id3", TrackLength: 20.3, TrackSrcURL: "http://test3.local"})
 
 	// Uncomment this, run the unit-test for the discord webhook to get the message.
 	// CheckWebhooks()
@@ -99,6 +107,118 @@ func Test_NewWebhook_MalformedPost(t *testing.T) {
 	database.DeleteAll()
 }
 
+// Function to test: NewWebhook()
+// Test if the request body is validated against the OpenAPI document.
+func Test_NewWebhook_InvalidBody(t *testing.T) {
+	CollectionWebhook = "TestWebhooks"
+
+	// Data to send, has a misspelled field.
+	postString := "{\"webhookURL\":\"http://webhook.local\",\"minTrigger\":3}"
+
+	// Creates a request that is passed to the handler.
+	request, _ := http.NewRequest("POST", "/paragliding/api/webhook/new_track/", strings.NewReader(postString))
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/webhook/new_track/", NewWebhook).Methods("POST")
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (400).
+	status := recorder.Code
+	if status != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+
+	// Check if the handler returns the invalid field.
+	actual := recorder.Body.String()
+	expected := "malformed POST request, body.minTrigger is not a known field"
+	if actual != expected {
+		t.Errorf("Handler returned wrong error: got %s want %s",
+			actual, expected)
+	}
+}
+
+// Function to test: NewWebhook()
+// Test if the correct error is displayed when an unknown event type is posted.
+func Test_NewWebhook_UnknownEvent(t *testing.T) {
+	CollectionTrack = "TestTracks"
+	CollectionWebhook = "TestWebhooks"
+
+	// Connects to a daabase.
+	database := mongodb.DatabaseInit(CollectionWebhook)
+
+	// Data to send, has an unknown event type.
+	postString := "{\"webhookURL\":\"http://webhook.local\",\"events\":[\"track.unknown\"]}"
+
+	// Creates a request that is passed to the handler.
+	request, _ := http.NewRequest("POST", "/paragliding/api/webhook/new_track/", strings.NewReader(postString))
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/webhook/new_track/", NewWebhook).Methods("POST")
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (400).
+	status := recorder.Code
+	if status != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+
+	// Check if the handler returns correct data.
+	actual := recorder.Body.String()
+	expected := "unknown event type, the events should be among: new_track, track.airspace, track.deleted"
+	if actual != expected {
+		t.Errorf("Handler returned wrong error: got %s want %s",
+			actual, expected)
+	}
+
+	// Deletes all tracks and webhooks from the database.
+	database.DeleteAll()
+}
+
+// Function to test: NewWebhook()
+// Test if a webhook to an internal address is rejected.
+func Test_NewWebhook_BlockedAddress(t *testing.T) {
+	CollectionWebhook = "TestWebhooks"
+	URLPolicy = fetch.DefaultPolicy
+	defer func() { URLPolicy.AllowPrivate = true }()
+
+	// Data to send, the url is the cloud metadata service.
+	postString := "{\"webhookURL\":\"http://169.254.169.254/latest/meta-data\"}"
+
+	// Creates a request that is passed to the handler.
+	request, _ := http.NewRequest("POST", "/paragliding/api/webhook/new_track/", strings.NewReader(postString))
+
+	// Creates the recorder and router.
+	recorder := httptest.NewRecorder()
+	router := mux.NewRouter()
+
+	// Tests the function.
+	router.HandleFunc("/paragliding/api/webhook/new_track/", NewWebhook).Methods("POST")
+	router.ServeHTTP(recorder, request)
+
+	// Check the status code is what we expect (400).
+	status := recorder.Code
+	if status != http.StatusBadRequest {
+		t.Errorf("Handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+
+	// Check if the handler returns the code of the error.
+	actual := recorder.Body.String()
+	if !strings.Contains(actual, fetch.CodeBlockedAddress) {
+		t.Errorf("Handler returned wrong error: got %s want %s", actual, fetch.CodeBlockedAddress)
+	}
+}
+
 // Function to test: NewWebhook()
 // Test if the correct error is displayed when duplicate webhook is posted.
 func Test_NewWebhook_DuplicateWebhook(t *testing.T) {
@@ -109,7 +229,7 @@ func Test_NewWebhook_DuplicateWebhook(t *testing.T) {
 	database := mongodb.DatabaseInit(CollectionWebhook)
 
 	// Inserts a webhook.
-	database.InsertWebhook(mongodb.Webhook{"http://test1.local", 3, 0})
+	database.InsertWebhook(mongodb.Webhook{WebhookURL: "http://test1.local", MinTriggerValue: 3, NumberOfNewInserts: 0})
 
 	// Try to post a webhook, that allready exists.
 	postString := "{ \"webhookURL\": \"http://test1.local\", \"minTriggerValue\": 3 }"
@@ -307,7 +427,7 @@ func Test_getWebhookInfo(t *testing.T) {
 	database := mongodb.DatabaseInit(CollectionWebhook)
 
 	// Creates the new webhook.
-	newHook := mongodb.Webhook{"http://test1.local", 3, 0}
+	newHook := mongodb.Webhook{WebhookURL: "http://test1.local", MinTriggerValue: 3, NumberOfNewInserts: 0}
 
 	// Inserts the webhook, and get its ID.
 	id, _ := database.InsertWebhook(newHook)
@@ -386,7 +506,7 @@ func Test_deleteWebhook(t *testing.T) {
 	database := mongodb.DatabaseInit(CollectionWebhook)
 
 	// Creates the new webhook.
-	newHook := mongodb.Webhook{"http://test1.local", 3, 0}
+	newHook := mongodb.Webhook{WebhookURL: "http://test1.local", MinTriggerValue: 3, NumberOfNewInserts: 0}
 
 	// Inserts the webhook, and get its ID.
 	id, _ := database.InsertWebhook(newHook)
@@ -443,3 +563,61 @@ func Test_deleteWebhook(t *testing.T) {
 		}
 	}
 }
+
+// Function to test: send().
+// Test that a message is sent again after a temporary error, and not after a permanent one.
+func Test_send_Retry(t *testing.T) {
+	RetryDelay = time.Millisecond
+	defer func() { RetryDelay = 500 * time.Millisecond }()
+
+	// The subscriber fails the first request with the status code, and accepts the rest.
+	var requests int
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		status   int
+		expected int
+	}{
+		{http.StatusServiceUnavailable, 2},
+		{http.StatusTooManyRequests, 2},
+		{http.StatusNotFound, 1},
+	}
+	for i := 0; i < len(tests); i++ {
+		requests, status = 0, tests[i].status
+		send(context.Background(), server.URL, "message")
+		if requests != tests[i].expected {
+			t.Errorf("Function sent wrong number of requests after %d: got %d want %d", status, requests, tests[i].expected)
+		}
+	}
+}
+
+// Function to test: CheckQueue().
+// Test that the queue is stuck when deliveries wait and no worker has taken one for a while.
+func Test_CheckQueue(t *testing.T) {
+	defer func() { deliveries = nil }()
+
+	// A queue without workers.
+	deliveries = make(chan delivery, 1)
+	atomic.StoreInt64(&lastTaken, time.Now().UnixNano())
+	if err := CheckQueue(); err != nil {
+		t.Errorf("Function returned error for an empty queue: %v", err)
+	}
+
+	deliveries <- delivery{context.Background(), "http://test.local", "message"}
+	if err := CheckQueue(); err != nil {
+		t.Errorf("Function returned error for a new delivery: %v", err)
+	}
+	atomic.StoreInt64(&lastTaken, time.Now().Add(-2*StuckAfter).UnixNano())
+	if err := CheckQueue(); err == nil {
+		t.Error("Function did not return error for a stuck queue")
+	}
+}